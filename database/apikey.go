@@ -0,0 +1,45 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// RevokeAPIKey 记录吊销的 API 密钥编号
+func RevokeAPIKey(id string) error {
+	result := db.FirstOrCreate(&models.RevokedAPIKey{}, models.RevokedAPIKey{ID: id})
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke api key: %w", result.Error)
+	}
+	return nil
+}
+
+// GetRevokedAPIKeys 获取所有已吊销的 API 密钥编号
+func GetRevokedAPIKeys() ([]string, error) {
+	var ids []string
+	result := db.Model(&models.RevokedAPIKey{}).Pluck("id", &ids)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query revoked api keys: %w", result.Error)
+	}
+	return ids, nil
+}
+
+// CreateAPIKey 保存生成的 API 密钥
+func CreateAPIKey(key *models.APIKey) error {
+	result := db.Create(key)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create api key: %w", result.Error)
+	}
+	return nil
+}
+
+// GetAPIKeys 获取所有生成的 API 密钥
+func GetAPIKeys() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	result := db.Order("created_at ASC").Find(&keys)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", result.Error)
+	}
+	return keys, nil
+}
@@ -2,7 +2,7 @@ package database
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
@@ -57,11 +57,16 @@ func InitDB() error {
 			return
 		}
 
-		log.Printf("Database initialized at: %s", dbPath)
+		slog.Info("database initialized", slog.String("path", dbPath))
 	})
 	return err
 }
 
+// DataDir 数据库文件所在目录，其他数据文件也保存在这里
+func DataDir() string {
+	return filepath.Dir(dbPath)
+}
+
 // GetDB 获取数据库实例
 func GetDB() *gorm.DB {
 	return db
@@ -74,6 +79,13 @@ func createTables() error {
 		&models.SMS{},
 		&models.Webhook{},
 		&models.Setting{},
+		&models.ModemConfig{},
+		&models.ForwardRule{},
+		&models.ScheduledSMS{},
+		&models.ATMacro{},
+		&models.SMSTemplate{},
+		&models.RevokedAPIKey{},
+		&models.APIKey{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to auto migrate: %w", err)
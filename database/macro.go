@@ -0,0 +1,56 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rehiy/web-modem/models"
+	"gorm.io/gorm"
+)
+
+// CreateMacro 创建 AT 命令宏，名称已存在时返回错误
+func CreateMacro(macro *models.ATMacro) error {
+	if _, err := GetMacro(macro.Name); err == nil {
+		return fmt.Errorf("macro %q already exists", macro.Name)
+	}
+	result := db.Create(macro)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create macro: %w", result.Error)
+	}
+	return nil
+}
+
+// GetMacro 按名称获取 AT 命令宏
+func GetMacro(name string) (*models.ATMacro, error) {
+	var macro models.ATMacro
+	result := db.Where("name = ?", name).First(&macro)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("macro not found")
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query macro: %w", result.Error)
+	}
+	return &macro, nil
+}
+
+// GetMacros 获取所有 AT 命令宏
+func GetMacros() ([]models.ATMacro, error) {
+	var macros []models.ATMacro
+	result := db.Order("name ASC").Find(&macros)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query macros: %w", result.Error)
+	}
+	return macros, nil
+}
+
+// DeleteMacro 删除 AT 命令宏
+func DeleteMacro(name string) error {
+	result := db.Where("name = ?", name).Delete(&models.ATMacro{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete macro: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("macro not found")
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rehiy/web-modem/models"
+	"gorm.io/gorm"
+)
+
+// GetModemConfig 获取模块配置，不存在时返回默认配置
+func GetModemConfig(name string) (*models.ModemConfig, error) {
+	var config models.ModemConfig
+	result := db.Where("name = ?", name).First(&config)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return &models.ModemConfig{Name: name, AudioCommands: []string{}, DiagCommands: []string{}}, nil
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get modem config: %w", result.Error)
+	}
+	return &config, nil
+}
+
+// GetModemConfigs 获取所有已保存的模块配置
+func GetModemConfigs() ([]models.ModemConfig, error) {
+	var configs []models.ModemConfig
+	result := db.Find(&configs)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query modem configs: %w", result.Error)
+	}
+	return configs, nil
+}
+
+// SaveModemConfig 保存模块配置
+func SaveModemConfig(config *models.ModemConfig) error {
+	result := db.Save(config)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save modem config: %w", result.Error)
+	}
+	return nil
+}
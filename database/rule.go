@@ -0,0 +1,38 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// CreateForwardRule 创建短信转发规则
+func CreateForwardRule(rule *models.ForwardRule) error {
+	result := db.Create(rule)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create forward rule: %w", result.Error)
+	}
+	return nil
+}
+
+// DeleteForwardRule 删除短信转发规则
+func DeleteForwardRule(id int) error {
+	result := db.Delete(&models.ForwardRule{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete forward rule: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("forward rule not found")
+	}
+	return nil
+}
+
+// GetForwardRules 获取所有短信转发规则
+func GetForwardRules() ([]models.ForwardRule, error) {
+	var rules []models.ForwardRule
+	result := db.Order("id ASC").Find(&rules)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query forward rules: %w", result.Error)
+	}
+	return rules, nil
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// CreateScheduledSMS 创建定时短信
+func CreateScheduledSMS(sms *models.ScheduledSMS) error {
+	result := db.Create(sms)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create scheduled sms: %w", result.Error)
+	}
+	return nil
+}
+
+// ListScheduledSMS 按发送时间获取定时短信，status 为空时返回全部
+func ListScheduledSMS(status string) ([]models.ScheduledSMS, error) {
+	var list []models.ScheduledSMS
+	query := db.Order("send_at ASC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if result := query.Find(&list); result.Error != nil {
+		return nil, fmt.Errorf("failed to query scheduled sms: %w", result.Error)
+	}
+	return list, nil
+}
+
+// DueScheduledSMS 获取已到发送时间且尚未发送的定时短信
+func DueScheduledSMS(now time.Time) ([]models.ScheduledSMS, error) {
+	var list []models.ScheduledSMS
+	result := db.Where("status = ? AND send_at <= ?", models.ScheduledPending, now).Order("send_at ASC").Find(&list)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query due scheduled sms: %w", result.Error)
+	}
+	return list, nil
+}
+
+// UpdateScheduledSMS 更新定时短信的发送结果
+func UpdateScheduledSMS(id, status, errMsg string, sentAt *time.Time) error {
+	result := db.Model(&models.ScheduledSMS{}).Where("id = ?", id).Updates(map[string]any{
+		"status":  status,
+		"error":   errMsg,
+		"sent_at": sentAt,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update scheduled sms: %w", result.Error)
+	}
+	return nil
+}
+
+// DeleteScheduledSMS 删除尚未发送的定时短信
+func DeleteScheduledSMS(id string) error {
+	result := db.Where("id = ? AND status = ?", id, models.ScheduledPending).Delete(&models.ScheduledSMS{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete scheduled sms: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("scheduled sms not found or already processed")
+	}
+	return nil
+}
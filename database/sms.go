@@ -36,6 +36,12 @@ func GetSMSList(filter *models.SMSFilter) ([]models.SMS, int, error) {
 	if filter.SendNumber != "" {
 		query = query.Where("send_number = ?", filter.SendNumber)
 	}
+	if filter.Modem != "" {
+		query = query.Where("modem = ?", filter.Modem)
+	}
+	if filter.Unread {
+		query = query.Where("read = ?", false)
+	}
 	if !filter.StartTime.IsZero() {
 		query = query.Where("receive_time >= ?", filter.StartTime)
 	}
@@ -75,6 +81,18 @@ func DeleteSMSByID(id int) error {
 	return nil
 }
 
+// MarkSMSRead 将短信标记为已读
+func MarkSMSRead(ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	result := db.Model(&models.SMS{}).Where("id IN ?", ids).Update("read", true)
+	if result.Error != nil {
+		return fmt.Errorf("failed to mark SMS read: %w", result.Error)
+	}
+	return nil
+}
+
 // BatchDeleteSMS 批量删除短信
 func BatchDeleteSMS(ids []int) error {
 	if len(ids) == 0 {
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rehiy/web-modem/models"
+	"gorm.io/gorm"
+)
+
+// CreateSMSTemplate 创建短信模板
+func CreateSMSTemplate(tpl *models.SMSTemplate) error {
+	result := db.Create(tpl)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create sms template: %w", result.Error)
+	}
+	return nil
+}
+
+// GetSMSTemplate 按编号获取短信模板
+func GetSMSTemplate(id int) (*models.SMSTemplate, error) {
+	var tpl models.SMSTemplate
+	result := db.First(&tpl, id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("sms template not found")
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query sms template: %w", result.Error)
+	}
+	return &tpl, nil
+}
+
+// GetSMSTemplates 获取所有短信模板
+func GetSMSTemplates() ([]models.SMSTemplate, error) {
+	var tpls []models.SMSTemplate
+	result := db.Order("id ASC").Find(&tpls)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query sms templates: %w", result.Error)
+	}
+	return tpls, nil
+}
+
+// UpdateSMSTemplate 更新短信模板的名称和内容
+func UpdateSMSTemplate(tpl *models.SMSTemplate) error {
+	result := db.Model(&models.SMSTemplate{ID: tpl.ID}).Select("name", "body").Updates(tpl)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update sms template: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("sms template not found")
+	}
+	return nil
+}
+
+// DeleteSMSTemplate 删除短信模板
+func DeleteSMSTemplate(id int) error {
+	result := db.Delete(&models.SMSTemplate{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete sms template: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("sms template not found")
+	}
+	return nil
+}
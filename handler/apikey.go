@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/rehiy/web-modem/service"
+)
+
+// APIKeyAuth 请求带有 X-API-Key 时按 API 密钥认证，否则交给原有认证
+// fallback 为 nil 表示没有其他认证方式，此时配置了 API 密钥后不带密钥的请求被拒绝
+func APIKeyAuth(fallback func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		other := next
+		if fallback != nil {
+			other = fallback(next)
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("X-API-Key")
+			if key == "" {
+				if fallback == nil && service.APIKeys.Enabled() {
+					respondJSON(w, http.StatusUnauthorized, H{"error": "api key required"})
+					return
+				}
+				other.ServeHTTP(w, r)
+				return
+			}
+			if _, ok := service.APIKeys.Verify(key); !ok {
+				respondJSON(w, http.StatusUnauthorized, H{"error": "invalid api key"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// APIKeyHandler API 密钥管理处理器
+type APIKeyHandler struct{}
+
+// NewAPIKeyHandler 创建 API 密钥管理处理器
+func NewAPIKeyHandler() *APIKeyHandler {
+	return &APIKeyHandler{}
+}
+
+// Create 生成新的 API 密钥，密钥只在响应中返回一次
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Description string     `json:"description"`
+		ExpiresAt   *time.Time `json:"expiresAt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	key, info, err := service.APIKeys.Generate(req.Description, req.ExpiresAt)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+	respondJSON(w, http.StatusCreated, H{"key": key, "info": info})
+}
+
+// List 列出 API 密钥，只返回编号和描述，不返回密钥本身
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, service.APIKeys.List())
+}
+
+// Revoke 吊销 API 密钥
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "id is required"})
+		return
+	}
+
+	if err := service.APIKeys.Revoke(id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			status = http.StatusNotFound
+		}
+		respondJSON(w, status, H{"error": err.Error()})
+		return
+	}
+	respondJSON(w, http.StatusOK, H{"status": "revoked"})
+}
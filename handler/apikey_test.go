@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/rehiy/web-modem/database"
+	"github.com/rehiy/web-modem/service"
+)
+
+var testDBOnce sync.Once
+
+// initTestDB 在临时目录中初始化数据库，同一测试进程内共享
+func initTestDB(t *testing.T) {
+	t.Helper()
+	var err error
+	testDBOnce.Do(func() {
+		var dir string
+		if dir, err = os.MkdirTemp("", "web-modem-test"); err != nil {
+			return
+		}
+		os.Setenv("DB_PATH", filepath.Join(dir, "data.db"))
+		err = database.InitDB()
+	})
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	initTestDB(t)
+	t.Setenv("API_KEYS", "ci.secret")
+	t.Setenv("API_KEYS_FILE", "")
+	if err := service.LoadAPIKeys(); err != nil {
+		t.Fatal(err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	cases := []struct {
+		name     string
+		fallback func(http.Handler) http.Handler
+		key      string
+		basic    bool
+		want     int
+	}{
+		{"no other auth, no key", nil, "", false, http.StatusUnauthorized},
+		{"no other auth, valid key", nil, "ci.secret", false, http.StatusOK},
+		{"invalid key", nil, "ci.wrong", false, http.StatusUnauthorized},
+		{"basic auth, no key", BasicAuth("user", "pass"), "", false, http.StatusUnauthorized},
+		{"basic auth, credentials", BasicAuth("user", "pass"), "", true, http.StatusOK},
+		{"basic auth, valid key", BasicAuth("user", "pass"), "ci.secret", false, http.StatusOK},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/modem/list", nil)
+		if c.key != "" {
+			req.Header.Set("X-API-Key", c.key)
+		}
+		if c.basic {
+			req.SetBasicAuth("user", "pass")
+		}
+		rec := httptest.NewRecorder()
+		APIKeyAuth(c.fallback)(ok).ServeHTTP(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("%s: status %d, want %d", c.name, rec.Code, c.want)
+		}
+	}
+}
@@ -2,15 +2,19 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/websocket"
+
+	"github.com/rehiy/web-modem/service"
 )
 
 type H map[string]any
 
 var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool { return true },
+	CheckOrigin: checkOrigin,
 }
 
 func respondJSON(w http.ResponseWriter, status int, data any) {
@@ -18,3 +22,27 @@ func respondJSON(w http.ResponseWriter, status int, data any) {
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(data)
 }
+
+// setModemTiming 设置模块端口和底层 AT 操作耗时响应头
+func setModemTiming(w http.ResponseWriter, name string, start time.Time) {
+	w.Header().Set("X-Modem-Port", name)
+	w.Header().Set("X-Modem-Command-Duration", time.Since(start).String())
+}
+
+// errorStatus 根据服务层错误选择响应状态码
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrUnsupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, service.ErrLocationAcquiring), errors.Is(err, service.ErrNoModem):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, service.ErrNoActiveCall), errors.Is(err, service.ErrDataMode),
+		errors.Is(err, service.ErrCallInProgress), errors.Is(err, service.ErrScanInProgress):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrLocked):
+		return http.StatusLocked
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+)
+
+// authRealm Basic 认证域
+const authRealm = "modem-manager"
+
+// BasicAuth HTTP Basic 认证中间件，用户名和密码均为空时不启用认证
+// 使用常量时间比较，避免通过响应时间猜测凭据
+func BasicAuth(username, password string) func(http.Handler) http.Handler {
+	if username == "" && password == "" {
+		slog.Warn("AUTH_USER and AUTH_PASSWORD are not set, API authentication is disabled")
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, pass, ok := r.BasicAuth()
+			userOK := subtle.ConstantTimeCompare([]byte(user), []byte(username)) == 1
+			passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(password)) == 1
+			if !ok || !userOK || !passOK {
+				w.Header().Set("WWW-Authenticate", `Basic realm="`+authRealm+`"`)
+				respondJSON(w, http.StatusUnauthorized, H{"error": "unauthorized"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
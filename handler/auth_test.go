@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	h := BasicAuth("admin", "secret")(ok)
+
+	cases := []struct {
+		name       string
+		user, pass string
+		set        bool
+		status     int
+	}{
+		{"valid", "admin", "secret", true, http.StatusNoContent},
+		{"wrong password", "admin", "guess", true, http.StatusUnauthorized},
+		{"wrong user", "root", "secret", true, http.StatusUnauthorized},
+		{"missing", "", "", false, http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/modem/list", nil)
+			if c.set {
+				r.SetBasicAuth(c.user, c.pass)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != c.status {
+				t.Fatalf("status = %d, want %d", w.Code, c.status)
+			}
+			if c.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != `Basic realm="`+authRealm+`"` {
+				t.Fatalf("WWW-Authenticate = %q", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestBasicAuthDisabledWithoutCredentials(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	w := httptest.NewRecorder()
+	BasicAuth("", "")(ok).ServeHTTP(w, httptest.NewRequest("GET", "/api/modem/list", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 令牌角色
+const (
+	RoleAdmin    = "admin"    // 可调用全部接口
+	RoleReadonly = "readonly" // 只能调用不修改状态的接口和 WebSocket
+)
+
+const defaultJWTExpiryHours = 24
+
+var errInvalidToken = errors.New("invalid token")
+
+// jwtClaims 令牌声明
+type jwtClaims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+type roleKey struct{}
+
+// canModify 请求的角色是否可以修改状态，未使用令牌认证时总是可以
+// 用于会修改状态的 GET 接口，如重新扫描模块、搜网
+func canModify(r *http.Request) bool {
+	role, _ := r.Context().Value(roleKey{}).(string)
+	return role != RoleReadonly
+}
+
+// JWTAuth HS256 令牌认证，令牌由 Basic 认证的用户名和密码换取
+type JWTAuth struct {
+	secret   []byte
+	username string
+	password string
+	expiry   time.Duration
+}
+
+// NewJWTAuth 创建令牌认证，有效期由 JWT_EXPIRY_HOURS 设置
+func NewJWTAuth(secret, username, password string) *JWTAuth {
+	if username == "" && password == "" {
+		slog.Warn("JWT_SECRET is set but AUTH_USER and AUTH_PASSWORD are not, tokens cannot be issued")
+	}
+	hours := envFloat("JWT_EXPIRY_HOURS", defaultJWTExpiryHours)
+	return &JWTAuth{
+		secret:   []byte(secret),
+		username: username,
+		password: password,
+		expiry:   time.Duration(hours * float64(time.Hour)),
+	}
+}
+
+// checkPassword 常量时间比较凭据，未配置凭据时总是失败
+func (a *JWTAuth) checkPassword(user, pass string) bool {
+	if a.username == "" && a.password == "" {
+		return false
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(a.username)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(a.password)) == 1
+	return userOK && passOK
+}
+
+func (a *JWTAuth) sign(c jwtClaims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(a.secret)
+}
+
+// verify 校验签名、算法和有效期，返回令牌声明
+func (a *JWTAuth) verify(token string) (*jwtClaims, error) {
+	var c jwtClaims
+	_, err := jwt.ParseWithClaims(token, &c, func(*jwt.Token) (any, error) {
+		return a.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, errors.New("token expired")
+	}
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	if c.Role != RoleAdmin && c.Role != RoleReadonly {
+		return nil, errInvalidToken
+	}
+	return &c, nil
+}
+
+// roleAllows 只读角色仅允许不修改状态的请求
+func roleAllows(role, method string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// Middleware 校验 Bearer 令牌和角色权限，Basic 认证凭据视为管理员
+// 浏览器无法为 WebSocket 设置请求头，此时可通过 access_token 查询参数传递令牌
+// 未提供凭据时同时返回 Basic 质询，浏览器中的页面可以直接登录
+func (a *JWTAuth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role := ""
+		if user, pass, ok := r.BasicAuth(); ok {
+			if !a.checkPassword(user, pass) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="`+authRealm+`"`)
+				respondJSON(w, http.StatusUnauthorized, H{"error": "unauthorized"})
+				return
+			}
+			role = RoleAdmin
+		} else {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok {
+				token = r.URL.Query().Get("access_token")
+			}
+			if token == "" {
+				w.Header().Add("WWW-Authenticate", `Basic realm="`+authRealm+`"`)
+				w.Header().Add("WWW-Authenticate", `Bearer realm="`+authRealm+`"`)
+				respondJSON(w, http.StatusUnauthorized, H{"error": "unauthorized"})
+				return
+			}
+			c, err := a.verify(token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+authRealm+`", error="invalid_token"`)
+				respondJSON(w, http.StatusUnauthorized, H{"error": err.Error()})
+				return
+			}
+			role = c.Role
+		}
+
+		if !roleAllows(role, r.Method) {
+			respondJSON(w, http.StatusForbidden, H{"error": "role " + role + " is not allowed to " + r.Method + " " + r.URL.Path})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), roleKey{}, role)))
+	})
+}
+
+// IssueToken 校验用户名和密码后签发令牌，role 默认为 admin
+func (a *JWTAuth) IssueToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Role     string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+	if req.Role == "" {
+		req.Role = RoleAdmin
+	}
+	if req.Role != RoleAdmin && req.Role != RoleReadonly {
+		respondJSON(w, http.StatusBadRequest, H{"error": "role must be admin or readonly"})
+		return
+	}
+	if !a.checkPassword(req.Username, req.Password) {
+		respondJSON(w, http.StatusUnauthorized, H{"error": "invalid username or password"})
+		return
+	}
+
+	now := time.Now()
+	exp := now.Add(a.expiry)
+	token, err := a.sign(jwtClaims{Role: req.Role, RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   req.Username,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(exp),
+	}})
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+	respondJSON(w, http.StatusOK, H{
+		"token":     token,
+		"tokenType": "Bearer",
+		"role":      req.Role,
+		"expiresAt": exp.UTC().Format(time.RFC3339),
+	})
+}
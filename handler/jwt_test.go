@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func testToken(t *testing.T, a *JWTAuth, role string, exp time.Time) string {
+	t.Helper()
+	token, err := a.sign(jwtClaims{Role: role, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(exp)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestJWTVerify(t *testing.T) {
+	a := NewJWTAuth("secret", "user", "pass")
+	hour := time.Now().Add(time.Hour)
+
+	if c, err := a.verify(testToken(t, a, RoleReadonly, hour)); err != nil || c.Role != RoleReadonly {
+		t.Fatalf("valid token: %+v, %v", c, err)
+	}
+	if _, err := a.verify(testToken(t, a, RoleAdmin, time.Now().Add(-time.Minute))); err == nil {
+		t.Fatal("expired token accepted")
+	}
+	if _, err := a.verify(testToken(t, NewJWTAuth("other", "user", "pass"), RoleAdmin, hour)); err == nil {
+		t.Fatal("token signed with another secret accepted")
+	}
+	if _, err := a.verify(testToken(t, a, "root", hour)); err == nil {
+		t.Fatal("unknown role accepted")
+	}
+	none, _ := jwt.NewWithClaims(jwt.SigningMethodNone, jwtClaims{Role: RoleAdmin}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if _, err := a.verify(none); err == nil {
+		t.Fatal("alg none accepted")
+	}
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	a := NewJWTAuth("secret", "user", "pass")
+	readonly := testToken(t, a, RoleReadonly, time.Now().Add(time.Hour))
+	var modify bool
+	h := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { modify = canModify(r) }))
+
+	serve := func(method, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/modem/list", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "")
+	if rec.Code != http.StatusUnauthorized || !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Basic ") {
+		t.Fatalf("no credentials: %d %q", rec.Code, rec.Header().Values("WWW-Authenticate"))
+	}
+
+	if rec = serve(http.MethodGet, "Bearer "+readonly); rec.Code != http.StatusOK || modify {
+		t.Fatalf("readonly GET: %d, canModify %v", rec.Code, modify)
+	}
+	if rec = serve(http.MethodPost, "Bearer "+readonly); rec.Code != http.StatusForbidden {
+		t.Fatalf("readonly POST: %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/modem/send", nil)
+	req.SetBasicAuth("user", "pass")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !modify {
+		t.Fatalf("basic admin: %d, canModify %v", rec.Code, modify)
+	}
+}
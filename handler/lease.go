@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// leaseHeader 租约令牌请求头
+const leaseHeader = "X-Lease-Token"
+
+// leaseToken 从请求头或 lease 查询参数获取租约令牌
+func leaseToken(r *http.Request) string {
+	if token := r.Header.Get(leaseHeader); token != "" {
+		return token
+	}
+	return r.URL.Query().Get("lease")
+}
+
+// requestModemName 从查询参数或 JSON 请求体获取模块名称，读取后恢复请求体
+func requestModemName(r *http.Request) string {
+	if name := r.URL.Query().Get("name"); name != "" {
+		return name
+	}
+	if r.Body == nil || r.Method == http.MethodGet {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	json.Unmarshal(body, &req)
+	return req.Name
+}
+
+// LeaseGuard 模块被租用时，要求 /modem/ 下的请求携带租约令牌，否则返回 423
+func (h *ModemHandler) LeaseGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			tpl, _ := route.GetPathTemplate()
+			if !strings.HasPrefix(tpl, "/api/modem/") || tpl == "/api/modem/lease" {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if name := requestModemName(r); name != "" {
+			if err := h.ms.CheckLease(name, leaseToken(r)); err != nil {
+				respondJSON(w, errorStatus(err), H{"error": err.Error()})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AcquireLease 租用或续期模块
+func (h *ModemHandler) AcquireLease(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name  string `json:"name"`
+		Token string `json:"token"`
+		TTL   int    `json:"ttl"` // 秒
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+	if req.Token == "" {
+		req.Token = leaseToken(r)
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	lease, err := h.ms.AcquireLease(conn.Name, req.Token, time.Duration(req.TTL)*time.Second)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, lease)
+}
+
+// ReleaseLease 释放模块租约
+func (h *ModemHandler) ReleaseLease(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	if err := h.ms.ReleaseLease(name, leaseToken(r)); err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "released"})
+}
@@ -1,10 +1,16 @@
 package handler
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/rehiy/web-modem/database"
+	"github.com/rehiy/web-modem/models"
 	"github.com/rehiy/web-modem/service"
 )
 
@@ -22,21 +28,70 @@ func NewModemHandler() *ModemHandler {
 
 // List 返回可用调制解调器的列表
 func (h *ModemHandler) List(w http.ResponseWriter, r *http.Request) {
-	h.ms.ScanModems()
+	// 重新扫描会打开和关闭串口，只读令牌只返回当前连接的模块
+	if canModify(r) {
+		h.ms.ScanModems()
+	}
 	modems := h.ms.GetModems()
 	respondJSON(w, http.StatusOK, modems)
 }
 
+// Dashboard 返回模块状态快照，未指定 name 时返回全部模块
+func (h *ModemHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		result := map[string]any{}
+		for _, modem := range h.ms.GetModems() {
+			result[modem.Name] = modem.GetDashboard()
+		}
+		respondJSON(w, http.StatusOK, result)
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	dashboard := conn.GetDashboard()
+	setModemTiming(w, name, start)
+
+	respondJSON(w, http.StatusOK, dashboard)
+}
+
+// StartupReport 返回启动自检报告
+func (h *ModemHandler) StartupReport(w http.ResponseWriter, r *http.Request) {
+	report := h.ms.StartupReport()
+	if report == nil {
+		respondJSON(w, http.StatusServiceUnavailable, H{"error": "startup check in progress"})
+		return
+	}
+	respondJSON(w, http.StatusOK, report)
+}
+
+// Health 立即检查所有已连接的模块，返回各端口的检查结果
+// 只读检查，没有响应的模块由后台健康检查移除
+func (h *ModemHandler) Health(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.ms.HealthCheck(r.Context()))
+}
+
 // Command 向调制解调器发送原始 AT 命令
 func (h *ModemHandler) Command(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Name    string `json:"name"`
-		Command string `json:"command"`
+		Name      string `json:"name"`
+		Command   string `json:"command"`
+		TimeoutMS int    `json:"timeout_ms"` // 可选，耗时命令（如 AT+COPS=?）的等待时间
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
 		return
 	}
+	if req.TimeoutMS < 0 {
+		respondJSON(w, http.StatusBadRequest, H{"error": "timeout_ms must not be negative"})
+		return
+	}
 
 	conn, err := h.ms.GetConnect(req.Name)
 	if conn == nil {
@@ -44,9 +99,11 @@ func (h *ModemHandler) Command(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	responses, err := conn.SendCommand(req.Command)
+	start := time.Now()
+	responses, err := conn.SendATCommand(r.Context(), req.Command, time.Duration(req.TimeoutMS)*time.Millisecond)
+	setModemTiming(w, req.Name, start)
 	if err != nil {
-		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
 		return
 	}
 
@@ -71,38 +128,94 @@ func (h *ModemHandler) BasicInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	info := H{"name": name}
-	// 获取制造商
-	if manufacturer, err := conn.GetManufacturer(); err == nil {
-		info["manufacturer"] = manufacturer
+	start := time.Now()
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	info := conn.GetBasicInfo(force)
+	info.Name = name
+	setModemTiming(w, name, start)
+
+	respondJSON(w, http.StatusOK, info)
+}
+
+// SignalStrength 获取当前信号强度
+func (h *ModemHandler) SignalStrength(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
 	}
-	// 获取型号
-	if model, err := conn.GetModel(); err == nil {
-		info["model"] = model
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
 	}
-	// 获取IMEI/序列号
-	if imei, err := conn.GetSerialNumber(); err == nil {
-		info["imei"] = imei
+
+	start := time.Now()
+	signal, err := conn.GetSignal()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
 	}
-	// 获取IMSI
-	if imsi, err := conn.GetIMSI(); err == nil {
-		info["imsi"] = imsi
+
+	respondJSON(w, http.StatusOK, signal)
+}
+
+// NetworkRegistration 获取各注册域的网络注册状态
+func (h *ModemHandler) NetworkRegistration(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
 	}
-	// 获取运营商
-	if _, _, operator, act, err := conn.GetOperator(); err == nil {
-		info["operator"] = operator
-		info["act"] = act
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
 	}
-	// 获取手机号
-	if phone, _, err := conn.GetPhoneNumber(); err == nil {
-		info["phone"] = phone
+
+	start := time.Now()
+	reg, err := conn.GetNetworkRegistration()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
 	}
 
-	respondJSON(w, http.StatusOK, info)
+	respondJSON(w, http.StatusOK, reg)
 }
 
-// SignalStrength 获取当前信号强度
-func (h *ModemHandler) SignalStrength(w http.ResponseWriter, r *http.Request) {
+// SignalHistory 获取后台采样的信号历史
+func (h *ModemHandler) SignalHistory(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l <= 0 {
+			respondJSON(w, http.StatusBadRequest, H{"error": "invalid limit"})
+			return
+		}
+		limit = l
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, conn.GetSignalHistory(limit))
+}
+
+// ActiveBand 获取当前驻留的频段和信道
+func (h *ModemHandler) ActiveBand(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Query().Get("name")
 	if name == "" {
 		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
@@ -115,64 +228,102 @@ func (h *ModemHandler) SignalStrength(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rssi, ber, err := conn.GetSignalQuality()
+	start := time.Now()
+	band, err := conn.GetActiveBand()
+	setModemTiming(w, name, start)
 	if err != nil {
-		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, band)
+}
+
+// Diag 获取诊断报告
+func (h *ModemHandler) Diag(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
 		return
 	}
 
-	// 计算信号等级
-	level := 0
-	if rssi >= 20 {
-		level = 5
-	} else if rssi >= 15 {
-		level = 4
-	} else if rssi >= 10 {
-		level = 3
-	} else if rssi >= 5 {
-		level = 2
-	} else if rssi >= 1 {
-		level = 1
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
 	}
 
-	// 将 RSSI 转换为 dBm: dBm = -113 + (rssi * 2)
-	dbm := -113 + (rssi * 2)
+	start := time.Now()
+	report, err := conn.GetDiagReport()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
 
-	respondJSON(w, http.StatusOK, map[string]any{
-		"rssi":  rssi,
-		"ber":   ber,
-		"level": level,
-		"dbm":   dbm,
-	})
+	respondJSON(w, http.StatusOK, report)
 }
 
-// SendSMS 发送短信
-func (h *ModemHandler) SendSMS(w http.ResponseWriter, r *http.Request) {
+// Charset 获取当前和支持的字符集
+func (h *ModemHandler) Charset(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	charset, err := conn.GetCharset()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, charset)
+}
+
+// SetCharset 设置字符集
+func (h *ModemHandler) SetCharset(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Name    string `json:"name"`
-		Number  string `json:"number"`
-		Message string `json:"message"`
+		Charset string `json:"charset"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
 		return
 	}
 
+	if req.Charset == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "charset is empty"})
+		return
+	}
+
 	conn, err := h.ms.GetConnect(req.Name)
 	if conn == nil {
 		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
 		return
 	}
 
-	if err := conn.SendSMSPdu(req.Number, req.Message); err != nil {
-		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
-	} else {
-		respondJSON(w, http.StatusOK, H{"status": "sent"})
+	start := time.Now()
+	err = conn.SetCharset(req.Charset)
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
 	}
+
+	respondJSON(w, http.StatusOK, H{"status": "updated", "charset": req.Charset})
 }
 
-// ListSMS 获取调制解调器中的所有短信
-func (h *ModemHandler) ListSMS(w http.ResponseWriter, r *http.Request) {
+// Contacts 获取 SIM 卡电话簿
+func (h *ModemHandler) Contacts(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Query().Get("name")
 	if name == "" {
 		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
@@ -185,20 +336,22 @@ func (h *ModemHandler) ListSMS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	smsList, err := conn.ListSMSPdu(4)
+	start := time.Now()
+	contacts, err := conn.ListContacts()
+	setModemTiming(w, name, start)
 	if err != nil {
-		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
 		return
 	}
 
-	respondJSON(w, http.StatusOK, smsList)
+	respondJSON(w, http.StatusOK, contacts)
 }
 
-// DeleteSMS 删除短信
-func (h *ModemHandler) DeleteSMS(w http.ResponseWriter, r *http.Request) {
+// WriteContact 写入电话簿条目，已存在的条目会被覆盖
+func (h *ModemHandler) WriteContact(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Name    string `json:"name"`
-		Indices []int  `json:"indices"`
+		Name    string         `json:"name"`
+		Contact models.Contact `json:"contact"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
@@ -211,9 +364,1845 @@ func (h *ModemHandler) DeleteSMS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := conn.DeleteSMS(req.Indices); err != nil {
-		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+	start := time.Now()
+	err = conn.WriteContact(req.Contact)
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "saved", "contact": req.Contact})
+}
+
+// DeleteContact 删除电话簿条目
+func (h *ModemHandler) DeleteContact(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+	index, err := strconv.Atoi(r.URL.Query().Get("index"))
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": "invalid index"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = conn.DeleteContact(index)
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "deleted"})
+}
+
+// Operators 获取可用网络列表，scan=true 时重新搜索（耗时可达数分钟），否则返回上次搜索结果
+func (h *ModemHandler) Operators(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	if r.URL.Query().Get("scan") != "true" {
+		scan := conn.LastOperatorScan()
+		if scan == nil {
+			respondJSON(w, http.StatusNotFound, H{"error": "no scan result, use scan=true to search networks"})
+			return
+		}
+		respondJSON(w, http.StatusOK, scan)
+		return
+	}
+	if !canModify(r) {
+		respondJSON(w, http.StatusForbidden, H{"error": "role readonly is not allowed to scan networks"})
+		return
+	}
+
+	start := time.Now()
+	scan, err := conn.ScanOperators()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, scan)
+}
+
+// SelectOperator 选择网络，code 为空时恢复自动选网
+func (h *ModemHandler) SelectOperator(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+		Code string `json:"code"`
+		Mode int    `json:"mode"` // 1 手动，4 手动失败后自动，默认 1
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+	if req.Mode == 0 {
+		req.Mode = 1
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	if req.Code == "" {
+		err = conn.SetAutoOperator()
 	} else {
-		respondJSON(w, http.StatusOK, H{"status": "deleted", "count": len(req.Indices)})
+		err = conn.SelectOperator(req.Code, req.Mode)
+	}
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "updated"})
+}
+
+// CreateMacro 创建 AT 命令宏
+func (h *ModemHandler) CreateMacro(w http.ResponseWriter, r *http.Request) {
+	var macro models.ATMacro
+	if err := json.NewDecoder(r.Body).Decode(&macro); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	if err := service.ValidateMacro(&macro); err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	if err := database.CreateMacro(&macro); err != nil {
+		respondJSON(w, http.StatusConflict, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, macro)
+}
+
+// ListMacros 获取所有 AT 命令宏
+func (h *ModemHandler) ListMacros(w http.ResponseWriter, r *http.Request) {
+	macros, err := database.GetMacros()
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, macros)
+}
+
+// DeleteMacro 删除 AT 命令宏
+func (h *ModemHandler) DeleteMacro(w http.ResponseWriter, r *http.Request) {
+	macro := r.URL.Query().Get("macro")
+	if macro == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "macro is empty"})
+		return
+	}
+
+	if err := database.DeleteMacro(macro); err != nil {
+		respondJSON(w, http.StatusNotFound, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "deleted"})
+}
+
+// RunMacro 在指定模块上执行 AT 命令宏，有命令失败时返回 422 及已执行部分的结果
+func (h *ModemHandler) RunMacro(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name  string `json:"name"`
+		Macro string `json:"macro"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	macro, err := database.GetMacro(req.Macro)
+	if err != nil {
+		respondJSON(w, http.StatusNotFound, H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	result := conn.RunMacro(*macro)
+	setModemTiming(w, req.Name, start)
+	if result.Failed {
+		respondJSON(w, http.StatusUnprocessableEntity, result)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// SetAPN 设置 APN
+func (h *ModemHandler) SetAPN(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+		models.APNConfig
 	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+	if err := service.ValidateAPN(&req.APNConfig); err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = conn.SetAPN(req.APNConfig)
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "updated", "cid": req.CID, "apn": req.APN})
+}
+
+// ActivatePDP 激活 PDP 上下文，cid 默认 1
+func (h *ModemHandler) ActivatePDP(w http.ResponseWriter, r *http.Request) {
+	h.switchPDP(w, r, true)
+}
+
+// DeactivatePDP 去激活 PDP 上下文，cid 默认 1
+func (h *ModemHandler) DeactivatePDP(w http.ResponseWriter, r *http.Request) {
+	h.switchPDP(w, r, false)
+}
+
+// switchPDP 激活或去激活 PDP 上下文
+func (h *ModemHandler) switchPDP(w http.ResponseWriter, r *http.Request, active bool) {
+	var req struct {
+		Name string `json:"name"`
+		CID  int    `json:"cid"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+	if req.CID == 0 {
+		req.CID = 1
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	if active {
+		err = conn.ActivatePDP(req.CID)
+	} else {
+		err = conn.DeactivatePDP(req.CID)
+	}
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "updated", "cid": req.CID, "active": active})
+}
+
+// PDPStatus 获取 PDP 上下文状态，cid 默认 1
+func (h *ModemHandler) PDPStatus(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+	cid := 1
+	if v := r.URL.Query().Get("cid"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			respondJSON(w, http.StatusBadRequest, H{"error": "invalid cid"})
+			return
+		}
+		cid = n
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	status, err := conn.GetPDPStatus(cid)
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, status)
+}
+
+// Reset 重启模块，type 为 soft（默认）、min 或 factory
+// soft / factory 重启后端口会短暂消失，后台重新连接，通过 modem_reset 和 reconnected 事件通知
+func (h *ModemHandler) Reset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+	if req.Type == "" {
+		req.Type = service.ResetSoft
+	}
+	h.reset(w, req.Name, req.Type)
+}
+
+// FactoryReset 恢复出厂设置（AT&F）并重启模块
+func (h *ModemHandler) FactoryReset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+	h.reset(w, req.Name, service.ResetFactory)
+}
+
+// reset 执行重启并返回结果
+func (h *ModemHandler) reset(w http.ResponseWriter, name, typ string) {
+	start := time.Now()
+	err := h.ms.ResetModem(name, typ)
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	if typ == service.ResetMin {
+		respondJSON(w, http.StatusOK, H{"status": "minimum functionality", "type": typ})
+		return
+	}
+	respondJSON(w, http.StatusAccepted, H{"status": "restarting", "type": typ})
+}
+
+// CellInfo 获取当前服务小区信息
+func (h *ModemHandler) CellInfo(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	info, err := conn.GetCellInfo()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, info)
+}
+
+// Capabilities 获取模块支持的 AT 命令列表
+func (h *ModemHandler) Capabilities(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	caps, err := conn.GetCapabilities()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, caps)
+}
+
+// ResultFormat 获取结果码格式
+func (h *ModemHandler) ResultFormat(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	format, err := conn.GetResultFormat()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, format)
+}
+
+// SetResultFormat 设置结果码格式
+func (h *ModemHandler) SetResultFormat(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name    string `json:"name"`
+		Verbose bool   `json:"verbose"`
+		Quiet   bool   `json:"quiet"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = conn.SetResultFormat(req.Verbose, req.Quiet)
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "updated", "verbose": req.Verbose, "quiet": req.Quiet})
+}
+
+// PowerSaving 获取省电模式配置
+func (h *ModemHandler) PowerSaving(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	ps, err := conn.GetPowerSaving()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ps)
+}
+
+// SetPowerSaving 设置省电模式，psm 和 edrx 可单独设置
+func (h *ModemHandler) SetPowerSaving(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+		PSM  *struct {
+			Enabled    bool   `json:"enabled"`
+			TAU        string `json:"tau"`
+			ActiveTime string `json:"activeTime"`
+		} `json:"psm"`
+		EDRX *struct {
+			Enabled bool   `json:"enabled"`
+			AcT     int    `json:"act"`
+			Cycle   string `json:"cycle"`
+		} `json:"edrx"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	if req.PSM == nil && req.EDRX == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": "psm or edrx is required"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = nil
+	if req.PSM != nil {
+		err = conn.SetPowerSaving(req.PSM.Enabled, req.PSM.TAU, req.PSM.ActiveTime)
+	}
+	if err == nil && req.EDRX != nil {
+		act := req.EDRX.AcT
+		if act == 0 {
+			act = 4 // 默认 E-UTRAN
+		}
+		err = conn.SetEDRX(req.EDRX.Enabled, act, req.EDRX.Cycle)
+	}
+	if err != nil {
+		setModemTiming(w, req.Name, start)
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	ps, err := conn.GetPowerSaving()
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ps)
+}
+
+// CallHistory 获取通话记录
+func (h *ModemHandler) CallHistory(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, conn.GetCallHistory())
+}
+
+// CallStatus 获取当前所有通话的状态
+func (h *ModemHandler) CallStatus(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	states, err := conn.GetCallStatus()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, states)
+}
+
+// ClearCallHistory 清空通话记录
+func (h *ModemHandler) ClearCallHistory(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	conn.ClearCallHistory()
+	respondJSON(w, http.StatusOK, H{"status": "deleted"})
+}
+
+// Dial 拨打语音电话
+func (h *ModemHandler) Dial(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name   string `json:"name"`
+		Number string `json:"number"`
+		Wait   bool   `json:"wait"` // 等待接通或结束后返回通话记录
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	if req.Wait {
+		start := time.Now()
+		record, err := conn.DialWait(req.Number)
+		setModemTiming(w, req.Name, start)
+		if err != nil {
+			respondJSON(w, errorStatus(err), H{"error": err.Error()})
+			return
+		}
+		respondJSON(w, http.StatusOK, record)
+		return
+	}
+
+	start := time.Now()
+	err = conn.Dial(req.Number)
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "dialing", "number": req.Number})
+}
+
+// AnswerCall 接听来电
+func (h *ModemHandler) AnswerCall(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = conn.AnswerCall()
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "answered"})
+}
+
+// HangupCall 挂断通话
+func (h *ModemHandler) HangupCall(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = conn.HangupCall()
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "hungup"})
+}
+
+// RejectCall 拒接来电
+func (h *ModemHandler) RejectCall(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = conn.RejectCall()
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "rejected"})
+}
+
+// SendDTMF 通话中发送 DTMF 按键
+func (h *ModemHandler) SendDTMF(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name       string `json:"name"`
+		Digits     string `json:"digits"`
+		IntervalMS int    `json:"intervalMs"` // 按键间隔，默认由 DTMF_INTERVAL_MS 设置
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = conn.SendDTMF(req.Digits, time.Duration(req.IntervalMS)*time.Millisecond)
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "sent", "digits": req.Digits})
+}
+
+// Config 获取模块配置
+func (h *ModemHandler) Config(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	config, err := conn.GetConfig()
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, config)
+}
+
+// UpdateConfig 更新模块配置
+func (h *ModemHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	config, err := conn.GetConfig()
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(config); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	if err := conn.SaveConfig(config); err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, config)
+}
+
+// SetAlias 设置模块别名，别名可在接口和事件订阅中代替端口名
+func (h *ModemHandler) SetAlias(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	var req struct {
+		Alias string `json:"alias"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	if err := h.ms.SetAlias(conn.Name, req.Alias); err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"name": conn.Name, "alias": req.Alias})
+}
+
+// DataTest 测试数据连接
+func (h *ModemHandler) DataTest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+		CID  int    `json:"cid"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+	if req.CID == 0 {
+		req.CID = 1
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	result, err := conn.TestDataConnection(req.CID)
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// CellLock 获取小区锁定配置
+func (h *ModemHandler) CellLock(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	lock, err := conn.GetCellLock()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, lock)
+}
+
+// SetCellLock 锁定到指定小区
+func (h *ModemHandler) SetCellLock(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name   string `json:"name"`
+		EARFCN int    `json:"earfcn"`
+		PCI    int    `json:"pci"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = conn.SetCellLock(req.EARFCN, req.PCI)
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "updated", "earfcn": req.EARFCN, "pci": req.PCI})
+}
+
+// ClearCellLock 解除小区锁定
+func (h *ModemHandler) ClearCellLock(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = conn.ClearCellLock()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "deleted"})
+}
+
+// ESIMProfiles 列出 eSIM 配置文件
+func (h *ModemHandler) ESIMProfiles(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	profiles, err := conn.ListESIMProfiles()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, profiles)
+}
+
+// ESIMProfileAction 启用、停用或删除 eSIM 配置文件
+func (h *ModemHandler) ESIMProfileAction(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name   string `json:"name"`
+		Action string `json:"action"` // enable / disable / delete
+		ICCID  string `json:"iccid"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	switch req.Action {
+	case "enable":
+		err = conn.EnableESIMProfile(req.ICCID)
+	case "disable":
+		err = conn.DisableESIMProfile(req.ICCID)
+	case "delete":
+		err = conn.DeleteESIMProfile(req.ICCID)
+	default:
+		respondJSON(w, http.StatusBadRequest, H{"error": "action must be one of enable, disable, delete"})
+		return
+	}
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "updated", "action": req.Action, "iccid": req.ICCID})
+}
+
+// SendUSSD 发起 USSD 请求或回复当前会话
+func (h *ModemHandler) SendUSSD(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name  string `json:"name"`
+		Code  string `json:"code"`
+		Reply bool   `json:"reply"` // 为 true 时作为当前会话的回复发送
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	send := conn.SendUSSD
+	if req.Reply {
+		send = conn.ContinueUSSD
+	}
+	resp, err := send(req.Code)
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// USSDResponse 获取最近一次 USSD 响应
+func (h *ModemHandler) USSDResponse(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	resp := conn.LastUSSD()
+	if resp == nil {
+		respondJSON(w, http.StatusNotFound, H{"error": "no ussd response"})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// CancelUSSD 结束当前 USSD 会话
+func (h *ModemHandler) CancelUSSD(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = conn.CancelUSSD()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "cancelled"})
+}
+
+// PINStatus 获取 SIM 卡锁定状态
+func (h *ModemHandler) PINStatus(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	status, err := conn.GetPINStatus()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, status)
+}
+
+// UnlockPIN 输入 PIN 码（或 PUK 码和新 PIN 码）解锁 SIM 卡
+func (h *ModemHandler) UnlockPIN(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+		PIN  string `json:"pin"`
+		PUK  string `json:"puk"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = conn.UnlockPIN(req.PIN, req.PUK)
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "unlocked"})
+}
+
+// PINRetries 获取 PIN/PUK 剩余尝试次数
+func (h *ModemHandler) PINRetries(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	retries, err := conn.GetPINRetries()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, retries)
+}
+
+// Location 获取定位信息
+func (h *ModemHandler) Location(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	allowStale := r.URL.Query().Get("allowStale") == "true"
+
+	start := time.Now()
+	location, err := conn.GetLocation(allowStale)
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, location)
+}
+
+// SetBaud 切换模块波特率
+func (h *ModemHandler) SetBaud(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+		Baud int    `json:"baud"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = conn.SetBaud(req.Baud)
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "updated", "baud": req.Baud})
+}
+
+// SendSMS 发送短信
+func (h *ModemHandler) SendSMS(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name        string `json:"name"`
+		Number      string `json:"number"`
+		Message     string `json:"message"`
+		Verify      bool   `json:"verify"`
+		Class       *int   `json:"class"`
+		ReplaceType int    `json:"replaceType"`
+
+		StatusReport bool `json:"statusReport"`
+		Flash        bool `json:"flash"`  // 闪信，等同于 class 0
+		DryRun       bool `json:"dryRun"` // 只校验编码结果，不发送
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	if req.Flash {
+		if req.Class != nil && *req.Class != 0 {
+			respondJSON(w, http.StatusBadRequest, H{"error": "flash conflicts with class " + strconv.Itoa(*req.Class)})
+			return
+		}
+		req.Class = new(int)
+	}
+
+	opts := service.SendOptions{
+		Verify:      req.Verify,
+		Class:       req.Class,
+		ReplaceType: req.ReplaceType,
+
+		StatusReport: req.StatusReport,
+	}
+	if err := opts.Validate(); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	if req.DryRun {
+		validation, err := service.ValidatePDU(req.Number, req.Message, opts)
+		if err != nil {
+			respondJSON(w, errorStatus(err), H{"error": err.Error()})
+			return
+		}
+		respondJSON(w, http.StatusOK, validation)
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	refs, err := conn.SendSMS(r.Context(), req.Number, req.Message, opts)
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error(), "references": refs})
+	} else if req.Verify {
+		respondJSON(w, http.StatusOK, H{"status": "sent", "verified": true, "references": refs})
+	} else if req.StatusReport {
+		respondJSON(w, http.StatusOK, H{"status": "sent", "references": refs})
+	} else {
+		respondJSON(w, http.StatusOK, H{"status": "sent"})
+	}
+}
+
+// SendSMSBalanced 由服务端选择模块发送短信，strategy 为 round-robin（默认）或 signal-strength
+func (h *ModemHandler) SendSMSBalanced(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Number  string `json:"number"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	port, err := h.ms.LoadBalancedSend(r.Context(), req.Number, req.Message, r.URL.Query().Get("strategy"))
+	if err != nil && port == "" {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+	setModemTiming(w, port, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error(), "port": port})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "sent", "port": port, "alias": service.AliasOf(port)})
+}
+
+// SendSMSFailover 按顺序尝试多个模块发送短信，第一个成功即停止
+func (h *ModemHandler) SendSMSFailover(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Ports   []string `json:"ports"`
+		Number  string   `json:"number"`
+		Message string   `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	port, attempts, err := h.ms.SendSMSWithFailover(r.Context(), req.Number, req.Message, req.Ports)
+	if err != nil && port == "" {
+		respondJSON(w, errorStatus(err), H{"error": err.Error(), "attempts": attempts})
+		return
+	}
+	setModemTiming(w, port, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error(), "port": port, "attempts": attempts})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "sent", "port": port, "alias": service.AliasOf(port), "attempts": attempts})
+}
+
+// SendSMSBulk 向多个号码发送同一条短信，dryRun=true 时只校验号码
+func (h *ModemHandler) SendSMSBulk(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name    string   `json:"name"`
+		Numbers []string `json:"numbers"`
+		Message string   `json:"message"`
+
+		StatusReport bool `json:"statusReport"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	if r.URL.Query().Get("dryRun") == "true" {
+		results, err := service.ValidateBulkSMS(req.Numbers, req.Message)
+		if err != nil {
+			respondJSON(w, errorStatus(err), H{"error": err.Error()})
+			return
+		}
+		respondJSON(w, http.StatusOK, H{"dryRun": true, "results": results})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	results, err := conn.SendSMSBulk(r.Context(), req.Numbers, req.Message, service.SendOptions{StatusReport: req.StatusReport})
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"results": results})
+}
+
+// ScheduleSMS 创建定时短信，sendAt 为 RFC3339 时间
+func (h *ModemHandler) ScheduleSMS(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name    string    `json:"name"`
+		Number  string    `json:"number"`
+		Message string    `json:"message"`
+		SendAt  time.Time `json:"sendAt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	sms, err := service.ScheduleSMS(req.Name, req.Number, req.Message, req.SendAt)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, sms)
+}
+
+// ListScheduledSMS 获取定时短信，可按 status 过滤
+func (h *ModemHandler) ListScheduledSMS(w http.ResponseWriter, r *http.Request) {
+	list, err := database.ListScheduledSMS(r.URL.Query().Get("status"))
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, list)
+}
+
+// CancelScheduledSMS 取消尚未发送的定时短信
+func (h *ModemHandler) CancelScheduledSMS(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "id is empty"})
+		return
+	}
+
+	if err := database.DeleteScheduledSMS(id); err != nil {
+		respondJSON(w, http.StatusNotFound, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "deleted"})
+}
+
+// DeliveryReports 获取短信状态报告，指定 ref 时返回该参考号的最新报告
+func (h *ModemHandler) DeliveryReports(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	refStr := r.URL.Query().Get("ref")
+	if refStr == "" {
+		respondJSON(w, http.StatusOK, conn.GetDeliveryReports())
+		return
+	}
+
+	ref, err := strconv.Atoi(refStr)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": "invalid ref"})
+		return
+	}
+	report, ok := conn.GetDeliveryReport(ref)
+	if !ok {
+		respondJSON(w, http.StatusNotFound, H{"error": "no status report for this reference"})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// SMSBearer 获取短信承载方式
+func (h *ModemHandler) SMSBearer(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	bearer, err := conn.GetSMSBearer()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, bearer)
+}
+
+// SetSMSBearer 设置短信承载方式
+func (h *ModemHandler) SetSMSBearer(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+		Mode string `json:"mode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	switch req.Mode {
+	case service.SMSBearerAuto, service.SMSBearerIMS, service.SMSBearerCS:
+	default:
+		respondJSON(w, http.StatusBadRequest, H{"error": "mode must be one of auto, ims, cs"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = conn.SetSMSBearer(req.Mode)
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "updated", "mode": req.Mode})
+}
+
+// SMSStorage 获取短信存储区使用情况
+func (h *ModemHandler) SMSStorage(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	storage, err := conn.GetSMSStorage()
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, storage)
+}
+
+// SetSMSStorage 选择短信存储区，storage 依次为读取、写入和接收存储区
+func (h *ModemHandler) SetSMSStorage(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name    string   `json:"name"`
+		Storage []string `json:"storage"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = conn.SetSMSStorage(req.Storage...)
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "updated", "storage": req.Storage})
+}
+
+// EstimateSMS 估算短信编码方式和分段数量
+func (h *ModemHandler) EstimateSMS(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	estimate, err := service.EstimateSMS(req.Message)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, estimate)
+}
+
+// ListSMS 分页获取调制解调器中的短信
+// page 从 1 开始，也可使用上一页返回的 cursor；page_size 默认 20
+func (h *ModemHandler) ListSMS(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	page, pageSize := 1, 20
+	cursor := r.URL.Query().Get("cursor")
+	if cursor == "" {
+		cursor = r.URL.Query().Get("page")
+	}
+	if cursor != "" {
+		p, err := strconv.Atoi(cursor)
+		if err != nil || p < 1 {
+			respondJSON(w, http.StatusBadRequest, H{"error": "page must be a positive integer"})
+			return
+		}
+		page = p
+	}
+	if v := r.URL.Query().Get("page_size"); v != "" {
+		s, err := strconv.Atoi(v)
+		if err != nil || s < 1 || s > 250 {
+			respondJSON(w, http.StatusBadRequest, H{"error": "page_size must be 1-250"})
+			return
+		}
+		pageSize = s
+	}
+
+	// source=db 时从数据库分页读取历史接收短信，模块离线或重启后仍可查询；不支持按状态和内容筛选
+	if r.URL.Query().Get("source") == "db" {
+		q := r.URL.Query()
+		if q.Get("status") != "" || q.Get("number") != "" || q.Get("contains") != "" {
+			respondJSON(w, http.StatusBadRequest, H{"error": "status, number and contains are not supported with source=db"})
+			return
+		}
+		smsList, total, err := service.ListStoredSMS(name, (page-1)*pageSize, pageSize)
+		if err != nil {
+			respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+			return
+		}
+		respondJSON(w, http.StatusOK, pagedSMS(smsList, total, page, pageSize))
+		return
+	}
+
+	stat, err := service.ParseSMSStat(r.URL.Query().Get("status"))
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+	filter := models.ModemSMSFilter{
+		Number:   pathQuery(r, "number"),
+		Contains: r.URL.Query().Get("contains"),
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	smsList, total, err := conn.ListSMSPaged(r.Context(), stat, filter, (page-1)*pageSize, pageSize)
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+	conn.ClearUnreadSMS()
+	respondJSON(w, http.StatusOK, pagedSMS(smsList, total, page, pageSize))
+}
+
+// pagedSMS 生成短信分页结果，不是最后一页时附带下一页的 cursor
+func pagedSMS(smsList []models.ModemSMS, total, page, pageSize int) models.PagedSMSResponse {
+	resp := models.PagedSMSResponse{
+		Messages: smsList,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if page*pageSize < total {
+		resp.NextCursor = strconv.Itoa(page + 1)
+	}
+	return resp
+}
+
+// pathQuery 读取查询参数，按路径规则解码，"+" 保留为加号而不是空格
+// 用于国际号码等以 + 开头的参数，调用方无需编码为 %2B
+func pathQuery(r *http.Request, key string) string {
+	for _, kv := range strings.Split(r.URL.RawQuery, "&") {
+		k, v, _ := strings.Cut(kv, "=")
+		if k != key {
+			continue
+		}
+		if value, err := url.PathUnescape(v); err == nil {
+			return value
+		}
+		return v
+	}
+	return ""
+}
+
+// ExportSMS 导出模块中的全部短信，format=csv 时以附件形式输出 CSV，默认输出 JSON 数组
+// since 为 RFC3339 时间，仅导出之后收到的短信；逐条写出，避免整体序列化
+func (h *ModemHandler) ExportSMS(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "name is empty"})
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "format must be json or csv"})
+		return
+	}
+
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			respondJSON(w, http.StatusBadRequest, H{"error": "since must be an RFC3339 time"})
+			return
+		}
+		since = t
+	}
+
+	conn, err := h.ms.GetConnect(name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	smsList, err := conn.ListSMS(r.Context(), 4)
+	setModemTiming(w, name, start)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+	if !since.IsZero() {
+		smsList = service.SMSSince(smsList, since)
+	}
+
+	flusher, _ := w.(http.Flusher)
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment; filename=sms_export.csv")
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"index", "status", "number", "time", "text"})
+		for _, sms := range smsList {
+			cw.Write([]string{strconv.Itoa(sms.Index), sms.Status, sms.PhoneNumber, sms.ReceivedAt.Format(time.RFC3339), sms.Text})
+			cw.Flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	w.Write([]byte("["))
+	for i, sms := range smsList {
+		if i > 0 {
+			w.Write([]byte(","))
+		}
+		if err := enc.Encode(sms); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	w.Write([]byte("]\n"))
+}
+
+// DeleteSMS 删除短信
+func (h *ModemHandler) DeleteSMS(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name    string `json:"name"`
+		Indices []int  `json:"indices"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = conn.DeleteSMS(req.Indices)
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+	} else {
+		respondJSON(w, http.StatusOK, H{"status": "deleted", "count": len(req.Indices)})
+	}
+}
+
+// DeleteSMSByStatus 按状态批量删除短信
+func (h *ModemHandler) DeleteSMSByStatus(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name   string `json:"name"`
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	err = conn.DeleteSMSByStatus(req.Status)
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "deleted", "filter": req.Status})
 }
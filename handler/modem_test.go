@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPathQueryKeepsPlus(t *testing.T) {
+	cases := map[string]string{
+		"/api/modem/sms/list?name=tty&number=+8613800138000": "+8613800138000",
+		"/api/modem/sms/list?number=%2B8613800138000":        "+8613800138000",
+		"/api/modem/sms/list?number=10086&contains=a+b":      "10086",
+		"/api/modem/sms/list?name=tty":                       "",
+		"/api/modem/sms/list?number=%zz":                     "%zz",
+	}
+	for target, want := range cases {
+		r := httptest.NewRequest("GET", target, nil)
+		if got := pathQuery(r, "number"); got != want {
+			t.Errorf("pathQuery(%q) = %q, want %q", target, got, want)
+		}
+	}
+}
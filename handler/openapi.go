@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// schema OpenAPI 模式对象
+type schema = map[string]any
+
+// apiParam 查询参数
+type apiParam struct {
+	name     string
+	typ      string
+	desc     string
+	required bool
+}
+
+// apiOperation 接口描述，body 和 resp 为模型值（按类型生成模式）或直接给出的 schema
+type apiOperation struct {
+	method  string
+	path    string
+	tag     string
+	summary string
+	query   []apiParam
+	body    any
+	resp    any
+	status  int  // 成功状态码，默认 200
+	noAuth  bool // 不需要认证
+}
+
+// 常用查询参数
+var (
+	nameQuery = apiParam{name: "name", typ: "string", desc: "端口名或别名", required: true}
+	idQuery   = apiParam{name: "id", typ: "integer", required: true}
+)
+
+func optQuery(name, typ, desc string) apiParam {
+	return apiParam{name: name, typ: typ, desc: desc}
+}
+
+// obj 按 "字段:类型" 描述生成对象模式，字段名以 * 结尾表示必填
+// 类型为 string / integer / boolean / number / object / date-time 或以 [] 开头的数组
+func obj(fields ...string) schema {
+	props := schema{}
+	required := []string{}
+	for _, f := range fields {
+		name, typ, _ := strings.Cut(f, ":")
+		if strings.HasSuffix(name, "*") {
+			name = strings.TrimSuffix(name, "*")
+			required = append(required, name)
+		}
+		props[name] = typeSchema(typ)
+	}
+	s := schema{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func typeSchema(typ string) schema {
+	if item, ok := strings.CutPrefix(typ, "[]"); ok {
+		return schema{"type": "array", "items": typeSchema(item)}
+	}
+	if typ == "date-time" {
+		return schema{"type": "string", "format": "date-time"}
+	}
+	return schema{"type": typ}
+}
+
+// withModem 在请求体模式中加入必填的 name 字段
+func withModem(fields ...string) schema {
+	return obj(append([]string{"name*:string"}, fields...)...)
+}
+
+// anyOf 随参数返回不同结构的响应，各结构的字段可能重叠，不能用 oneOf
+type anyOf []any
+
+var statusResp = obj("status:string")
+
+// openAPISpec 生成接口描述文档，模型的模式由结构体定义反射生成，保证与响应一致
+var openAPISpec = sync.OnceValue(func() []byte {
+	g := &schemaGen{components: schema{}}
+	paths := schema{}
+	for _, op := range apiOperations {
+		item, _ := paths[op.path].(schema)
+		if item == nil {
+			item = schema{}
+			paths[op.path] = item
+		}
+		item[strings.ToLower(op.method)] = g.operation(op)
+	}
+
+	doc := schema{
+		"openapi": "3.0.3",
+		"info": schema{
+			"title":       "Modem Manager API",
+			"version":     "1.0.0",
+			"description": "AT modem management API. Modem-scoped endpoints take the port name (or alias) as `name`.",
+		},
+		"servers": []schema{{"url": "/api"}},
+		"paths":   paths,
+		"components": schema{
+			"schemas": g.components,
+			"securitySchemes": schema{
+				"basicAuth":  schema{"type": "http", "scheme": "basic"},
+				"bearerAuth": schema{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKeyAuth": schema{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+			"responses": schema{
+				"Error": schema{
+					"description": "Error",
+					"content":     schema{"application/json": schema{"schema": obj("error*:string")}},
+				},
+			},
+		},
+		"security": []schema{{"basicAuth": []string{}}, {"bearerAuth": []string{}}, {"apiKeyAuth": []string{}}},
+	}
+	b, _ := json.MarshalIndent(doc, "", "  ")
+	return b
+})
+
+// OpenAPI 返回 OpenAPI 3.0 接口描述
+func OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec())
+}
+
+// SwaggerUI 返回浏览接口描述的 Swagger UI 页面
+func SwaggerUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerHTML))
+}
+
+// swaggerHTML Swagger UI 页面，脚本和样式从 CDN 加载
+const swaggerHTML = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Modem Manager API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// schemaGen 由 Go 类型生成模式，具名结构体放入 components
+type schemaGen struct {
+	components schema
+}
+
+func (g *schemaGen) operation(op apiOperation) schema {
+	o := schema{"summary": op.summary, "tags": []string{op.tag}}
+	if op.noAuth {
+		o["security"] = []schema{}
+	}
+
+	params := []schema{}
+	for _, p := range op.query {
+		param := schema{"name": p.name, "in": "query", "required": p.required, "schema": typeSchema(p.typ)}
+		if p.desc != "" {
+			param["description"] = p.desc
+		}
+		params = append(params, param)
+	}
+	if len(params) > 0 {
+		o["parameters"] = params
+	}
+
+	if op.body != nil {
+		o["requestBody"] = schema{
+			"required": true,
+			"content":  schema{"application/json": schema{"schema": g.schemaOf(op.body)}},
+		}
+	}
+
+	status := op.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	ok := schema{"description": http.StatusText(status)}
+	if op.resp != nil {
+		ok["content"] = schema{"application/json": schema{"schema": g.schemaOf(op.resp)}}
+	}
+	o["responses"] = schema{
+		strconv.Itoa(status): ok,
+		"default":            schema{"$ref": "#/components/responses/Error"},
+	}
+	return o
+}
+
+func (g *schemaGen) schemaOf(v any) schema {
+	switch v := v.(type) {
+	case schema:
+		return v
+	case anyOf:
+		alts := []schema{}
+		for _, alt := range v {
+			alts = append(alts, g.schemaOf(alt))
+		}
+		return schema{"anyOf": alts}
+	}
+	return g.typeOf(reflect.TypeOf(v))
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+)
+
+func (g *schemaGen) typeOf(t reflect.Type) schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return schema{"type": "string", "format": "date-time"}
+	case rawType:
+		return schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return schema{"type": "number"}
+	case reflect.String:
+		return schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return schema{"type": "array", "items": g.typeOf(t.Elem())}
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": g.typeOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structOf(t)
+		}
+		if _, ok := g.components[t.Name()]; !ok {
+			g.components[t.Name()] = schema{} // 占位，防止递归类型无限展开
+			g.components[t.Name()] = g.structOf(t)
+		}
+		return schema{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return schema{}
+}
+
+// structOf 按 encoding/json 的规则生成结构体模式，展开匿名嵌入字段
+func (g *schemaGen) structOf(t reflect.Type) schema {
+	props := schema{}
+	required := []string{}
+	g.fields(t, props, &required)
+	s := schema{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func (g *schemaGen) fields(t reflect.Type, props schema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.fields(ft, props, required)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.typeOf(f.Type)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
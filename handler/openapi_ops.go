@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/rehiy/web-modem/models"
+	"github.com/rehiy/web-modem/service"
+)
+
+// apiOperations 全部接口，新增或修改路由时同步更新
+var apiOperations = []apiOperation{
+	// 认证
+	{method: "POST", path: "/auth/token", tag: "auth", summary: "Exchange credentials for a JWT (only when JWT_SECRET is set)",
+		body: obj("username*:string", "password*:string", "role:string"),
+		resp: obj("token*:string", "tokenType*:string", "role*:string", "expiresAt*:date-time"), noAuth: true},
+
+	// 模块租用
+	{method: "POST", path: "/modem/lease", tag: "lease", summary: "Acquire or renew an exclusive lease on a modem",
+		body: withModem("token:string", "ttl:integer"), resp: models.Lease{}},
+	{method: "DELETE", path: "/modem/lease", tag: "lease", summary: "Release a lease (X-Lease-Token header)",
+		query: []apiParam{nameQuery}, resp: statusResp},
+
+	// 模块列表
+	{method: "GET", path: "/modem/list", tag: "modem", summary: "Rescan ports and list connected modems (readonly tokens only list)",
+		resp: []*service.ModemInfo{}},
+	{method: "GET", path: "/modem/health", tag: "modem", summary: "Ping every connected modem with AT; unresponsive modems are removed by the background check",
+		resp: map[string]bool{}},
+	{method: "GET", path: "/startup-report", tag: "modem", summary: "Device probe report from the last startup check",
+		resp: models.StartupReport{}},
+	{method: "GET", path: "/dashboard", tag: "modem", summary: "Status snapshot of one modem, or of all modems keyed by port when name is omitted",
+		query: []apiParam{optQuery("name", "string", "端口名或别名")}, resp: anyOf{models.Dashboard{}, map[string]models.Dashboard{}}},
+
+	// AT 命令宏
+	{method: "POST", path: "/modem/macro", tag: "macro", summary: "Create an AT command macro",
+		body: models.ATMacro{}, resp: models.ATMacro{}, status: http.StatusCreated},
+	{method: "GET", path: "/modem/macro/list", tag: "macro", summary: "List AT command macros",
+		resp: []models.ATMacro{}},
+	{method: "DELETE", path: "/modem/macro/delete", tag: "macro", summary: "Delete an AT command macro",
+		query: []apiParam{{name: "macro", typ: "string", required: true}}, resp: statusResp},
+
+	// 模块操作
+	{method: "POST", path: "/modem/send", tag: "modem", summary: "Send a raw AT command",
+		body: withModem("command*:string", "timeout_ms:integer"), resp: obj("name:string", "command:string", "response:string")},
+	{method: "POST", path: "/modem/macro/run", tag: "macro", summary: "Run a stored macro on a modem",
+		body: withModem("macro*:string"), resp: models.MacroResult{}},
+	{method: "GET", path: "/modem/info", tag: "modem", summary: "Basic modem and SIM information (static fields cached for MODEM_INFO_CACHE_TTL_SECONDS)",
+		query: []apiParam{nameQuery, optQuery("force", "boolean", "忽略缓存")}, resp: models.ModemBasicInfo{}},
+	{method: "GET", path: "/modem/capabilities", tag: "modem", summary: "Supported AT commands (AT+CLAC)",
+		query: []apiParam{nameQuery}, resp: models.ModemCapabilities{}},
+	{method: "GET", path: "/modem/signal", tag: "network", summary: "Signal quality",
+		query: []apiParam{nameQuery}, resp: models.Signal{}},
+	{method: "GET", path: "/modem/network", tag: "network", summary: "Network registration",
+		query: []apiParam{nameQuery}, resp: models.NetworkRegistration{}},
+	{method: "GET", path: "/modem/contacts", tag: "phonebook", summary: "List SIM phonebook entries",
+		query: []apiParam{nameQuery}, resp: []models.Contact{}},
+	{method: "POST", path: "/modem/contacts", tag: "phonebook", summary: "Write a SIM phonebook entry",
+		body: struct {
+			Name    string         `json:"name"`
+			Contact models.Contact `json:"contact"`
+		}{}, resp: obj("status:string", "contact:object")},
+	{method: "DELETE", path: "/modem/contacts", tag: "phonebook", summary: "Delete a SIM phonebook entry",
+		query: []apiParam{nameQuery, {name: "index", typ: "integer", required: true}}, resp: statusResp},
+	{method: "GET", path: "/modem/network/operators", tag: "network", summary: "Last operator scan, or a new scan with scan=true",
+		query: []apiParam{nameQuery, optQuery("scan", "boolean", "")}, resp: models.OperatorScan{}},
+	{method: "POST", path: "/modem/network/operator", tag: "network", summary: "Select an operator manually, or automatic selection when code is empty",
+		body: withModem("code:string", "mode:integer"), resp: statusResp},
+	{method: "GET", path: "/modem/cell", tag: "network", summary: "Serving cell information",
+		query: []apiParam{nameQuery}, resp: models.CellInfo{}},
+	{method: "GET", path: "/modem/signal/history", tag: "network", summary: "Recent signal samples",
+		query: []apiParam{nameQuery, optQuery("limit", "integer", "")}, resp: []models.SignalSample{}},
+	{method: "GET", path: "/modem/active-band", tag: "network", summary: "Active radio band",
+		query: []apiParam{nameQuery}, resp: models.ActiveBand{}},
+	{method: "GET", path: "/modem/diag", tag: "modem", summary: "Diagnostic report",
+		query: []apiParam{nameQuery}, resp: models.DiagReport{}},
+	{method: "GET", path: "/modem/result-format", tag: "modem", summary: "Result code format (ATV/ATQ)",
+		query: []apiParam{nameQuery}, resp: models.ResultFormat{}},
+	{method: "POST", path: "/modem/result-format", tag: "modem", summary: "Set result code format",
+		body: withModem("verbose:boolean", "quiet:boolean"), resp: obj("status:string", "verbose:boolean", "quiet:boolean")},
+	{method: "GET", path: "/modem/pin", tag: "sim", summary: "SIM PIN status",
+		query: []apiParam{nameQuery}, resp: models.PINStatus{}},
+	{method: "POST", path: "/modem/pin", tag: "sim", summary: "Unlock the SIM with a PIN, or PUK and new PIN",
+		body: withModem("pin*:string", "puk:string"), resp: statusResp},
+	{method: "GET", path: "/modem/pin/retries", tag: "sim", summary: "Remaining PIN/PUK attempts",
+		query: []apiParam{nameQuery}, resp: models.PINRetries{}},
+	{method: "POST", path: "/modem/apn", tag: "data", summary: "Configure a PDP context APN",
+		body: struct {
+			Name string `json:"name"`
+			models.APNConfig
+		}{}, resp: obj("status:string", "cid:integer", "apn:string")},
+	{method: "POST", path: "/modem/data/activate", tag: "data", summary: "Activate a PDP context",
+		body: withModem("cid:integer"), resp: obj("status:string", "cid:integer", "active:boolean")},
+	{method: "POST", path: "/modem/data/deactivate", tag: "data", summary: "Deactivate a PDP context",
+		body: withModem("cid:integer"), resp: obj("status:string", "cid:integer", "active:boolean")},
+	{method: "GET", path: "/modem/data/status", tag: "data", summary: "PDP context status",
+		query: []apiParam{nameQuery, optQuery("cid", "integer", "")}, resp: models.PDPStatus{}},
+	{method: "POST", path: "/modem/data/test", tag: "data", summary: "Test the data connection",
+		body: withModem("cid:integer"), resp: models.DataTestResult{}},
+	{method: "GET", path: "/modem/celllock", tag: "network", summary: "Cell lock state",
+		query: []apiParam{nameQuery}, resp: models.CellLock{}},
+	{method: "POST", path: "/modem/celllock", tag: "network", summary: "Lock to a cell",
+		body: withModem("earfcn*:integer", "pci*:integer"), resp: obj("status:string", "earfcn:integer", "pci:integer")},
+	{method: "DELETE", path: "/modem/celllock", tag: "network", summary: "Clear the cell lock",
+		query: []apiParam{nameQuery}, resp: statusResp},
+	{method: "GET", path: "/modem/esim/profiles", tag: "sim", summary: "List eSIM profiles",
+		query: []apiParam{nameQuery}, resp: []models.ESIMProfile{}},
+	{method: "POST", path: "/modem/esim/profiles", tag: "sim", summary: "Enable, disable or delete an eSIM profile",
+		body: withModem("action*:string", "iccid*:string"), resp: obj("status:string", "action:string", "iccid:string")},
+	{method: "GET", path: "/modem/charset", tag: "modem", summary: "TE character set",
+		query: []apiParam{nameQuery}, resp: models.Charset{}},
+	{method: "POST", path: "/modem/charset", tag: "modem", summary: "Set the TE character set",
+		body: withModem("charset*:string"), resp: obj("status:string", "charset:string")},
+	{method: "POST", path: "/modem/reset", tag: "modem", summary: "Soft reset (soft) or minimum functionality (min)",
+		body: withModem("type:string"), resp: obj("status:string", "type:string"), status: http.StatusAccepted},
+	{method: "POST", path: "/modem/factory-reset", tag: "modem", summary: "Restore factory defaults and restart",
+		body: withModem(), resp: obj("status:string", "type:string"), status: http.StatusAccepted},
+	{method: "GET", path: "/modem/powersave", tag: "modem", summary: "PSM and eDRX settings",
+		query: []apiParam{nameQuery}, resp: models.PowerSaving{}},
+	{method: "POST", path: "/modem/powersave", tag: "modem", summary: "Configure PSM and eDRX",
+		body: withModem("psm:object", "edrx:object"), resp: models.PowerSaving{}},
+	{method: "GET", path: "/modem/location", tag: "modem", summary: "GNSS location",
+		query: []apiParam{nameQuery, optQuery("allowStale", "boolean", "")}, resp: models.Location{}},
+	{method: "POST", path: "/modem/baud", tag: "modem", summary: "Change the serial baud rate",
+		body: withModem("baud*:integer"), resp: obj("status:string", "baud:integer")},
+	{method: "GET", path: "/modem/config", tag: "modem", summary: "Per-modem configuration",
+		query: []apiParam{nameQuery}, resp: models.ModemConfig{}},
+	{method: "PUT", path: "/modem/config", tag: "modem", summary: "Update per-modem configuration",
+		query: []apiParam{nameQuery}, body: models.ModemConfig{}, resp: models.ModemConfig{}},
+	{method: "PUT", path: "/modem/alias", tag: "modem", summary: "Set or clear the modem alias",
+		query: []apiParam{nameQuery}, body: obj("alias:string"), resp: obj("name:string", "alias:string")},
+
+	// 通话
+	{method: "GET", path: "/modem/calls", tag: "call", summary: "Call history",
+		query: []apiParam{nameQuery}, resp: []models.CallRecord{}},
+	{method: "DELETE", path: "/modem/calls", tag: "call", summary: "Clear call history",
+		query: []apiParam{nameQuery}, resp: statusResp},
+	{method: "GET", path: "/modem/call/status", tag: "call", summary: "Current calls",
+		query: []apiParam{nameQuery}, resp: []models.CallState{}},
+	{method: "POST", path: "/modem/call/dial", tag: "call", summary: "Dial a number, optionally waiting for the call to end",
+		body: withModem("number*:string", "wait:boolean"), resp: anyOf{models.CallRecord{}, obj("status:string", "number:string")}},
+	{method: "POST", path: "/modem/call/answer", tag: "call", summary: "Answer an incoming call",
+		body: withModem(), resp: statusResp},
+	{method: "POST", path: "/modem/call/hangup", tag: "call", summary: "Hang up",
+		body: withModem(), resp: statusResp},
+	{method: "POST", path: "/modem/call/reject", tag: "call", summary: "Reject a ringing incoming call",
+		body: withModem(), resp: statusResp},
+	{method: "POST", path: "/modem/call/dtmf", tag: "call", summary: "Send DTMF tones",
+		body: withModem("digits*:string", "intervalMs:integer"), resp: obj("status:string", "digits:string")},
+
+	// USSD
+	{method: "POST", path: "/modem/ussd", tag: "ussd", summary: "Send a USSD code or reply to the current session",
+		body: withModem("code*:string", "reply:boolean"), resp: models.USSDResponse{}},
+	{method: "DELETE", path: "/modem/ussd", tag: "ussd", summary: "Cancel the USSD session",
+		query: []apiParam{nameQuery}, resp: statusResp},
+	{method: "GET", path: "/modem/ussd/response", tag: "ussd", summary: "Last USSD response",
+		query: []apiParam{nameQuery}, resp: models.USSDResponse{}},
+
+	// 短信读写
+	{method: "GET", path: "/modem/sms/list", tag: "sms", summary: "List SMS stored on the modem, paginated; source=db lists saved messages instead",
+		query: []apiParam{nameQuery, optQuery("source", "string", "db"), optQuery("status", "string", ""),
+			optQuery("number", "string", "号码部分匹配，+ 按原样保留，无需编码为 %2B"), optQuery("contains", "string", ""), optQuery("page", "integer", ""),
+			optQuery("cursor", "string", ""), optQuery("page_size", "integer", "")},
+		resp: models.PagedSMSResponse{}},
+	{method: "GET", path: "/modem/sms/export", tag: "sms", summary: "Export SMS stored on the modem as JSON or CSV",
+		query: []apiParam{nameQuery, optQuery("format", "string", "json 或 csv"), optQuery("since", "string", "RFC3339")},
+		resp:  []models.ModemSMS{}},
+	{method: "POST", path: "/modem/sms/send", tag: "sms", summary: "Send an SMS",
+		body: withModem("number*:string", "message*:string", "verify:boolean", "class:integer", "replaceType:integer",
+			"statusReport:boolean", "flash:boolean", "dryRun:boolean"),
+		resp: anyOf{obj("status:string", "verified:boolean", "references:[]integer"), models.PDUValidation{}}},
+	{method: "POST", path: "/modem/sms/send-balanced", tag: "sms", summary: "Send an SMS through a modem chosen by the server",
+		query: []apiParam{optQuery("strategy", "string", "round-robin (default) or signal-strength")},
+		body:  obj("number*:string", "message*:string"), resp: obj("status:string", "port:string", "alias:string")},
+	{method: "POST", path: "/modem/sms/send-failover", tag: "sms", summary: "Send an SMS through the first working modem in a list",
+		body: obj("ports*:[]string", "number*:string", "message*:string"),
+		resp: obj("status:string", "port:string", "alias:string", "attempts:integer")},
+	{method: "POST", path: "/modem/sms/send-template", tag: "sms", summary: "Render a stored SMS template with variables and send it",
+		body: withModem("id*:integer", "number*:string", "variables:object"), resp: obj("status:string", "message:string")},
+	{method: "POST", path: "/modem/sms/bulk", tag: "sms", summary: "Send one SMS to several numbers",
+		query: []apiParam{optQuery("dryRun", "boolean", "")}, body: withModem("numbers*:[]string", "message*:string", "statusReport:boolean"),
+		resp: struct {
+			DryRun  bool                   `json:"dryRun,omitempty"`
+			Results []models.BulkSMSResult `json:"results"`
+		}{}},
+	{method: "POST", path: "/modem/sms/estimate", tag: "sms", summary: "Estimate SMS encoding and segment count",
+		body: obj("message*:string"), resp: models.SMSEstimate{}},
+	{method: "POST", path: "/modem/sms/delete", tag: "sms", summary: "Delete SMS by storage index",
+		body: withModem("indices*:[]integer"), resp: obj("status:string", "count:integer")},
+	{method: "POST", path: "/modem/sms/delete-batch", tag: "sms", summary: "Delete SMS by status",
+		body: withModem("status*:string"), resp: obj("status:string", "filter:string")},
+	{method: "GET", path: "/modem/sms/delivery", tag: "sms", summary: "Delivery reports, or the report for one reference",
+		query: []apiParam{nameQuery, optQuery("ref", "integer", "")}, resp: anyOf{[]models.DeliveryReport{}, models.DeliveryReport{}}},
+	{method: "POST", path: "/modem/sms/schedule", tag: "sms", summary: "Schedule an SMS",
+		body: withModem("number*:string", "message*:string", "sendAt*:date-time"), resp: models.ScheduledSMS{}, status: http.StatusCreated},
+	{method: "GET", path: "/modem/sms/schedule", tag: "sms", summary: "List scheduled SMS",
+		query: []apiParam{optQuery("status", "string", "")}, resp: []models.ScheduledSMS{}},
+	{method: "DELETE", path: "/modem/sms/schedule", tag: "sms", summary: "Cancel a pending scheduled SMS",
+		query: []apiParam{{name: "id", typ: "string", required: true}}, resp: statusResp},
+	{method: "GET", path: "/modem/sms/bearer", tag: "sms", summary: "SMS bearer (CGSMS)",
+		query: []apiParam{nameQuery}, resp: models.SMSBearer{}},
+	{method: "POST", path: "/modem/sms/bearer", tag: "sms", summary: "Set the SMS bearer",
+		body: withModem("mode*:string"), resp: obj("status:string", "mode:string")},
+	{method: "GET", path: "/modem/sms/storage", tag: "sms", summary: "SMS storage usage",
+		query: []apiParam{nameQuery}, resp: []models.SMSStorage{}},
+	{method: "POST", path: "/modem/sms/storage", tag: "sms", summary: "Select SMS storage (CPMS)",
+		body: withModem("storage*:[]string"), resp: obj("status:string", "storage:[]string")},
+
+	// 短信存储管理
+	{method: "GET", path: "/smsdb/list", tag: "smsdb", summary: "List saved SMS",
+		query: []apiParam{optQuery("direction", "string", "in 或 out"), optQuery("send_number", "string", ""),
+			optQuery("modem", "string", ""), optQuery("unread", "boolean", ""), optQuery("start_time", "string", "RFC3339"),
+			optQuery("end_time", "string", "RFC3339"), optQuery("limit", "integer", ""), optQuery("offset", "integer", "")},
+		resp: struct {
+			Data   []models.SMS `json:"data"`
+			Total  int          `json:"total"`
+			Limit  int          `json:"limit"`
+			Offset int          `json:"offset"`
+		}{}},
+	{method: "POST", path: "/smsdb/delete", tag: "smsdb", summary: "Delete saved SMS",
+		body: obj("ids*:[]integer"), resp: obj("status:string", "count:integer")},
+	{method: "POST", path: "/smsdb/read", tag: "smsdb", summary: "Mark saved SMS as read",
+		body: obj("ids*:[]integer"), resp: obj("status:string", "count:integer")},
+	{method: "GET", path: "/smsdb/settings", tag: "smsdb", summary: "SMS database settings",
+		resp: map[string]string{}},
+	{method: "PUT", path: "/smsdb/settings", tag: "smsdb", summary: "Enable or disable saving SMS",
+		body: obj("smsdb_enabled*:boolean"), resp: obj("status:string", "smsdb_enabled:boolean")},
+
+	// Webhook
+	{method: "POST", path: "/webhook", tag: "webhook", summary: "Create a webhook",
+		body: models.Webhook{}, resp: models.Webhook{}, status: http.StatusCreated},
+	{method: "GET", path: "/webhook/list", tag: "webhook", summary: "List webhooks",
+		resp: []models.Webhook{}},
+	{method: "GET", path: "/webhook/get", tag: "webhook", summary: "Get a webhook",
+		query: []apiParam{idQuery}, resp: models.Webhook{}},
+	{method: "PUT", path: "/webhook/update", tag: "webhook", summary: "Update a webhook",
+		query: []apiParam{idQuery}, body: models.Webhook{}, resp: models.Webhook{}},
+	{method: "DELETE", path: "/webhook/delete", tag: "webhook", summary: "Delete a webhook",
+		query: []apiParam{idQuery}, resp: obj("status:string", "id:integer")},
+	{method: "POST", path: "/webhook/test", tag: "webhook", summary: "Send a test payload",
+		query: []apiParam{idQuery}, resp: obj("status:string", "message:string")},
+	{method: "GET", path: "/webhook/dlq", tag: "webhook", summary: "Webhook deliveries that exhausted retries",
+		query: []apiParam{optQuery("limit", "integer", "")}, resp: []models.WebhookDeadLetter{}},
+	{method: "POST", path: "/webhook/dlq/replay", tag: "webhook", summary: "Requeue dead-lettered deliveries",
+		resp: obj("status:string", "count:integer")},
+	{method: "GET", path: "/webhook/settings", tag: "webhook", summary: "Webhook settings",
+		resp: map[string]string{}},
+	{method: "PUT", path: "/webhook/settings", tag: "webhook", summary: "Enable or disable webhooks",
+		body: obj("webhook_enabled*:boolean"), resp: obj("status:string", "webhook_enabled:boolean")},
+
+	// 短信转发规则
+	{method: "POST", path: "/rule", tag: "rule", summary: "Create an SMS forwarding rule",
+		body: models.ForwardRule{}, resp: models.ForwardRule{}, status: http.StatusCreated},
+	{method: "GET", path: "/rule/list", tag: "rule", summary: "List SMS forwarding rules",
+		resp: []models.ForwardRule{}},
+	{method: "DELETE", path: "/rule/delete", tag: "rule", summary: "Delete an SMS forwarding rule",
+		query: []apiParam{idQuery}, resp: obj("status:string", "id:integer")},
+
+	// 短信模板
+	{method: "POST", path: "/template", tag: "template", summary: "Create an SMS template ({{.Var}} placeholders)",
+		body: models.SMSTemplate{}, resp: models.SMSTemplate{}, status: http.StatusCreated},
+	{method: "GET", path: "/template/list", tag: "template", summary: "List SMS templates",
+		resp: []models.SMSTemplate{}},
+	{method: "PUT", path: "/template/update", tag: "template", summary: "Update an SMS template",
+		query: []apiParam{idQuery}, body: models.SMSTemplate{}, resp: models.SMSTemplate{}},
+	{method: "DELETE", path: "/template/delete", tag: "template", summary: "Delete an SMS template",
+		query: []apiParam{idQuery}, resp: obj("status:string", "id:integer")},
+
+	// 来电拒接列表
+	{method: "POST", path: "/reject-list", tag: "call", summary: "Add a reject list rule (number regular expression)",
+		body: models.RejectRule{}, resp: models.RejectRule{}, status: http.StatusCreated},
+	{method: "GET", path: "/reject-list/list", tag: "call", summary: "List reject list rules",
+		resp: []models.RejectRule{}},
+	{method: "DELETE", path: "/reject-list/delete", tag: "call", summary: "Delete a reject list rule",
+		query: []apiParam{idQuery}, resp: obj("status:string", "id:integer")},
+
+	// API 密钥
+	{method: "POST", path: "/apikey", tag: "apikey", summary: "Generate an API key (<id>.<secret>, returned only once)",
+		body: obj("description:string", "expiresAt:date-time"), resp: obj("key:string", "info:object"), status: http.StatusCreated},
+	{method: "GET", path: "/apikey/list", tag: "apikey", summary: "List API keys (ids and descriptions, never the keys)",
+		resp: []models.APIKeyInfo{}},
+	{method: "DELETE", path: "/apikey/delete", tag: "apikey", summary: "Revoke an API key",
+		query: []apiParam{{name: "id", typ: "string", required: true}}, resp: statusResp},
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// specSchemas 将接口描述作为 JSON Schema 资源编译，按接口取成功响应的模式
+type specSchemas struct {
+	t        *testing.T
+	compiler *jsonschema.Compiler
+}
+
+func newSpecSchemas(t *testing.T) *specSchemas {
+	t.Helper()
+	c := jsonschema.NewCompiler()
+	c.Draft = jsonschema.Draft7
+	if err := c.AddResource("openapi.json", bytes.NewReader(openAPISpec())); err != nil {
+		t.Fatalf("load spec: %v", err)
+	}
+	return &specSchemas{t: t, compiler: c}
+}
+
+// response 编译接口成功响应的模式
+func (s *specSchemas) response(method, path string, status int) *jsonschema.Schema {
+	s.t.Helper()
+	escape := strings.NewReplacer("~", "~0", "/", "~1").Replace
+	ptr := "openapi.json#/paths/" + escape(path) + "/" + strings.ToLower(method) +
+		"/responses/" + strconv.Itoa(status) + "/content/" + escape("application/json") + "/schema"
+	sch, err := s.compiler.Compile(ptr)
+	if err != nil {
+		s.t.Fatalf("%s %s: compile response schema: %v", method, path, err)
+	}
+	return sch
+}
+
+// validate 按响应模式校验 JSON 数据
+func (s *specSchemas) validate(method, path string, status int, data []byte) {
+	s.t.Helper()
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		s.t.Fatalf("%s %s: invalid json: %v", method, path, err)
+	}
+	if err := s.response(method, path, status).Validate(v); err != nil {
+		s.t.Errorf("%s %s: response does not match spec: %v\n%s", method, path, err, data)
+	}
+}
+
+// sampleValue 生成字段全部填充的示例值，切片和映射各含一个元素，用于检查模型的 JSON 输出与模式一致
+func sampleValue(t reflect.Type, depth int) reflect.Value {
+	v := reflect.New(t).Elem()
+	if depth > 4 {
+		return v
+	}
+	switch t {
+	case timeType:
+		v.Set(reflect.ValueOf(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)))
+		return v
+	case rawType:
+		v.SetBytes([]byte(`{}`))
+		return v
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		v.Set(sampleValue(t.Elem(), depth+1).Addr())
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1.5)
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Slice:
+		v.Set(reflect.Append(reflect.MakeSlice(t, 0, 1), sampleValue(t.Elem(), depth+1)))
+	case reflect.Array:
+		for i := 0; i < t.Len(); i++ {
+			v.Index(i).Set(sampleValue(t.Elem(), depth+1))
+		}
+	case reflect.Map:
+		v.Set(reflect.MakeMap(t))
+		v.SetMapIndex(sampleValue(t.Key(), depth+1), sampleValue(t.Elem(), depth+1))
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() || f.Tag.Get("json") == "-" {
+				continue
+			}
+			v.Field(i).Set(sampleValue(f.Type, depth+1))
+		}
+	}
+	return v
+}
+
+// TestOpenAPIConsistency 检查接口描述与模型和处理函数的实际输出一致
+func TestOpenAPIConsistency(t *testing.T) {
+	specs := newSpecSchemas(t)
+
+	// 每个由模型生成的响应模式都能校验通过该模型的 JSON 输出
+	for _, op := range apiOperations {
+		status := op.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		alts := []any{op.resp}
+		if o, ok := op.resp.(anyOf); ok {
+			alts = o
+		}
+		for _, resp := range alts {
+			if resp == nil {
+				continue
+			}
+			if _, ok := resp.(schema); ok {
+				specs.response(op.method, op.path, status)
+				continue
+			}
+			data, err := json.Marshal(sampleValue(reflect.TypeOf(resp), 0).Interface())
+			if err != nil {
+				t.Fatalf("%s %s: marshal %T: %v", op.method, op.path, resp, err)
+			}
+			specs.validate(op.method, op.path, status, data)
+		}
+	}
+
+	// 不依赖模块的处理函数，响应与描述一致
+	h := NewModemHandler()
+	for _, c := range []struct {
+		method, path string
+		handler      http.HandlerFunc
+	}{
+		{"GET", "/modem/list", h.List},
+		{"GET", "/modem/health", h.Health},
+	} {
+		w := httptest.NewRecorder()
+		c.handler(w, httptest.NewRequest(c.method, "/api"+c.path, nil))
+		specs.validate(c.method, c.path, w.Code, w.Body.Bytes())
+	}
+
+	// 错误响应与描述中的默认响应一致
+	errSchema, err := specs.compiler.Compile("openapi.json#/components/responses/Error/content/application~1json/schema")
+	if err != nil {
+		t.Fatalf("compile error schema: %v", err)
+	}
+	w := httptest.NewRecorder()
+	h.Dashboard(w, httptest.NewRequest("GET", "/api/modem/dashboard?name=ttyNONE", nil))
+	if w.Code < 400 {
+		t.Fatalf("dashboard of unknown modem: status %d", w.Code)
+	}
+	var body any
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if err := errSchema.Validate(body); err != nil {
+		t.Errorf("error response does not match spec: %v\n%s", err, w.Body.Bytes())
+	}
+}
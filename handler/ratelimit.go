@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultRateLimit = 10 // 默认每个客户端每秒请求数
+	defaultRateBurst = 20 // 默认突发请求数
+	maxRateClients   = 10000
+)
+
+// RateLimiter 按客户端 IP 限制请求频率的令牌桶
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    rate.Limit // 每秒补充的令牌数
+	burst   int        // 桶容量
+	clients map[string]*rate.Limiter
+}
+
+// NewRateLimiter 创建限流器
+func NewRateLimiter(r, burst float64) *RateLimiter {
+	return &RateLimiter{rate: rate.Limit(r), burst: max(1, int(burst)), clients: map[string]*rate.Limiter{}}
+}
+
+// Allow 消耗一个令牌，不足时返回需要等待的时间
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	limiter, ok := l.clients[key]
+	if !ok {
+		if len(l.clients) >= maxRateClients {
+			l.prune(now)
+		}
+		limiter = rate.NewLimiter(l.rate, l.burst)
+		l.clients[key] = limiter
+	}
+
+	r := limiter.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// prune 删除令牌已补满的客户端，调用方需持有锁
+func (l *RateLimiter) prune(now time.Time) {
+	for key, limiter := range l.clients {
+		if limiter.TokensAt(now) >= float64(l.burst) {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// envFloat 读取正数环境变量，无效时返回默认值
+func envFloat(key string, def float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+// commandLimiter AT 命令和短信发送接口的限流器
+// 频率和突发数由 AT_RATE_LIMIT、AT_RATE_BURST 设置
+var commandLimiter = sync.OnceValue(func() *RateLimiter {
+	return NewRateLimiter(envFloat("AT_RATE_LIMIT", defaultRateLimit), envFloat("AT_RATE_BURST", defaultRateBurst))
+})
+
+// RateLimit 按客户端 IP 限流，超出时返回 429
+func RateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		if ok, wait := commandLimiter().Allow(ip); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			respondJSON(w, http.StatusTooManyRequests, H{
+				"error":          "rate limit exceeded",
+				"retry_after_ms": wait.Milliseconds(),
+			})
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	l := NewRateLimiter(1, 3)
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d within burst denied", i)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok || wait <= 0 {
+		t.Fatalf("over burst: ok %v, wait %s", ok, wait)
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Fatal("other client limited")
+	}
+}
+
+func TestRateLimiterConcurrent(t *testing.T) {
+	l := NewRateLimiter(0.001, 50)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := l.Allow("a"); ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 50 {
+		t.Fatalf("allowed %d of 200, burst 50", allowed)
+	}
+}
+
+func TestRateLimitResponds429(t *testing.T) {
+	h := RateLimit(func(w http.ResponseWriter, r *http.Request) {})
+	limit := commandLimiter()
+	var rec *httptest.ResponseRecorder
+	for i := 0; i <= int(limit.burst); i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/modem/sms/bulk", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec = httptest.NewRecorder()
+		h(rec, req)
+	}
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("status %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
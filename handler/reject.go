@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/rehiy/web-modem/models"
+	"github.com/rehiy/web-modem/service"
+)
+
+// RejectListHandler 来电拒接列表处理器
+type RejectListHandler struct{}
+
+// NewRejectListHandler 创建来电拒接列表处理器
+func NewRejectListHandler() *RejectListHandler {
+	return &RejectListHandler{}
+}
+
+// Create 添加拒接规则
+func (h *RejectListHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var rule models.RejectRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	if err := service.RejectList.Add(&rule); err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, rule)
+}
+
+// List 获取所有拒接规则
+func (h *RejectListHandler) List(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, service.RejectList.List())
+}
+
+// Delete 删除拒接规则
+func (h *RejectListHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	idStr := r.URL.Query().Get("id")
+	if idStr == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "id is required"})
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": "invalid id"})
+		return
+	}
+
+	if err := service.RejectList.Delete(id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrRejectRuleNotFound) {
+			status = http.StatusNotFound
+		}
+		respondJSON(w, status, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "deleted", "id": id})
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var uuidRe = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFrom(r.Context())
+	}))
+
+	cases := []struct {
+		name, header string
+		keep         bool
+	}{
+		{"client id", "trace-123", true},
+		{"missing", "", false},
+		{"control chars", "bad\tid", false},
+		{"too long", strings.Repeat("a", maxRequestIDLen+1), false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/modem/list", nil)
+			if c.header != "" {
+				r.Header.Set(RequestIDHeader, c.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			got := w.Header().Get(RequestIDHeader)
+			if got != seen {
+				t.Fatalf("response id %q, context id %q", got, seen)
+			}
+			if c.keep && got != c.header {
+				t.Fatalf("id = %q, want %q", got, c.header)
+			}
+			if !c.keep && !uuidRe.MatchString(got) {
+				t.Fatalf("id = %q, want a generated UUID", got)
+			}
+		})
+	}
+}
+
+func TestRequestIDLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(RequestIDLogHandler(slog.NewTextHandler(&buf, nil))).With("port", "ttyUSB0")
+
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoContext(r.Context(), "at command")
+	}))
+	r := httptest.NewRequest("GET", "/api/modem/list", nil)
+	r.Header.Set(RequestIDHeader, "trace-123")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if out := buf.String(); !strings.Contains(out, "request_id=trace-123") || !strings.Contains(out, "port=ttyUSB0") {
+		t.Fatalf("log = %q", out)
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/rehiy/web-modem/database"
+	"github.com/rehiy/web-modem/models"
+	"github.com/rehiy/web-modem/service"
+)
+
+// RuleHandler 短信转发规则处理器
+type RuleHandler struct{}
+
+// NewRuleHandler 创建新的短信转发规则处理器
+func NewRuleHandler() *RuleHandler {
+	return &RuleHandler{}
+}
+
+// Create 创建转发规则
+func (h *RuleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	rule := models.ForwardRule{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+	rule.ID = 0
+
+	if err := service.ValidateForwardRule(&rule); err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	if err := database.CreateForwardRule(&rule); err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+	if err := service.ForwardRules.Reload(); err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, rule)
+}
+
+// List 获取所有转发规则
+func (h *RuleHandler) List(w http.ResponseWriter, r *http.Request) {
+	rules, err := database.GetForwardRules()
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, rules)
+}
+
+// Delete 删除转发规则
+func (h *RuleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	idStr := r.URL.Query().Get("id")
+	if idStr == "" {
+		respondJSON(w, http.StatusBadRequest, H{"error": "id is required"})
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": "invalid id"})
+		return
+	}
+
+	if err := database.DeleteForwardRule(id); err != nil {
+		respondJSON(w, http.StatusNotFound, H{"error": err.Error()})
+		return
+	}
+	if err := service.ForwardRules.Reload(); err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{
+		"status": "deleted",
+		"id":     id,
+	})
+}
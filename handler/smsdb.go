@@ -31,6 +31,14 @@ func (h *SmsdbHandler) List(w http.ResponseWriter, r *http.Request) {
 		filter.SendNumber = sendNumber
 	}
 
+	if modem := r.URL.Query().Get("modem"); modem != "" {
+		filter.Modem = modem
+	}
+
+	if r.URL.Query().Get("unread") == "true" {
+		filter.Unread = true
+	}
+
 	if startTime := r.URL.Query().Get("start_time"); startTime != "" {
 		if t, err := time.Parse(time.RFC3339, startTime); err == nil {
 			filter.StartTime = t
@@ -98,6 +106,33 @@ func (h *SmsdbHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// MarkRead 将数据库中的短信标记为已读
+func (h *SmsdbHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs []int `json:"ids"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	if len(req.IDs) == 0 {
+		respondJSON(w, http.StatusBadRequest, H{"error": "no IDs provided"})
+		return
+	}
+
+	if err := database.MarkSMSRead(req.IDs); err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{
+		"status": "read",
+		"count":  len(req.IDs),
+	})
+}
+
 // GetSettings 获取短信存储设置
 func (h *SmsdbHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
 	settings, err := database.GetSettings()
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rehiy/web-modem/database"
+	"github.com/rehiy/web-modem/models"
+	"github.com/rehiy/web-modem/service"
+)
+
+// TemplateHandler 短信模板处理器
+type TemplateHandler struct{}
+
+// NewTemplateHandler 创建短信模板处理器
+func NewTemplateHandler() *TemplateHandler {
+	return &TemplateHandler{}
+}
+
+// Create 创建短信模板
+func (h *TemplateHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var tpl models.SMSTemplate
+	if err := json.NewDecoder(r.Body).Decode(&tpl); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+	tpl.ID = 0
+
+	if err := service.ValidateSMSTemplate(&tpl); err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	if err := database.CreateSMSTemplate(&tpl); err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, tpl)
+}
+
+// List 获取所有短信模板
+func (h *TemplateHandler) List(w http.ResponseWriter, r *http.Request) {
+	tpls, err := database.GetSMSTemplates()
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, tpls)
+}
+
+// Update 更新短信模板
+func (h *TemplateHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": "invalid id"})
+		return
+	}
+
+	var tpl models.SMSTemplate
+	if err := json.NewDecoder(r.Body).Decode(&tpl); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+	tpl.ID = id
+
+	if err := service.ValidateSMSTemplate(&tpl); err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	if err := database.UpdateSMSTemplate(&tpl); err != nil {
+		respondJSON(w, http.StatusNotFound, H{"error": err.Error()})
+		return
+	}
+
+	updated, err := database.GetSMSTemplate(id)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+	respondJSON(w, http.StatusOK, updated)
+}
+
+// Delete 删除短信模板
+func (h *TemplateHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": "invalid id"})
+		return
+	}
+
+	if err := database.DeleteSMSTemplate(id); err != nil {
+		respondJSON(w, http.StatusNotFound, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "deleted", "id": id})
+}
+
+// SendSMSTemplate 使用变量渲染短信模板后发送
+func (h *ModemHandler) SendSMSTemplate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name      string            `json:"name"`
+		ID        int               `json:"id"`
+		Number    string            `json:"number"`
+		Variables map[string]string `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	tpl, err := database.GetSMSTemplate(req.ID)
+	if err != nil {
+		respondJSON(w, http.StatusNotFound, H{"error": err.Error()})
+		return
+	}
+	message, err := service.RenderSMSTemplate(tpl.Body, req.Variables)
+	if err != nil {
+		respondJSON(w, errorStatus(err), H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.ms.GetConnect(req.Name)
+	if conn == nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	refs, err := conn.SendSMS(r.Context(), req.Number, message, service.SendOptions{})
+	setModemTiming(w, req.Name, start)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error(), "references": refs})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{"status": "sent", "message": message})
+}
@@ -22,6 +22,16 @@ func NewWebhookHandler() *WebhookHandler {
 	}
 }
 
+// secretMask 响应中代替签名密钥的掩码
+const secretMask = "****"
+
+// maskSecret 隐藏签名密钥，未设置时保持为空
+func maskSecret(webhook *models.Webhook) {
+	if webhook.Secret != "" {
+		webhook.Secret = secretMask
+	}
+}
+
 // Create 创建Webhook配置
 func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var webhook models.Webhook
@@ -36,6 +46,18 @@ func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 未指定事件类型时默认为短信，* 接收全部事件
+	if webhook.Type == "" {
+		webhook.Type = models.WebhookSMS
+	} else if !service.ValidWebhookType(webhook.Type) {
+		respondJSON(w, http.StatusBadRequest, H{"error": "type must be sms, call, * or a modem event type"})
+		return
+	}
+	if webhook.MaxRetries != nil && *webhook.MaxRetries < 0 {
+		respondJSON(w, http.StatusBadRequest, H{"error": "max_retries must not be negative"})
+		return
+	}
+
 	// 如果模板为空，使用默认模板
 	if webhook.Template == "" {
 		webhook.Template = "{}"
@@ -46,6 +68,7 @@ func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	maskSecret(&webhook)
 	respondJSON(w, http.StatusCreated, webhook)
 }
 
@@ -64,13 +87,22 @@ func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var webhook models.Webhook
-	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+	var req struct {
+		models.Webhook
+		Secret *string `json:"secret"` // 未提供或为掩码时保留原有密钥，空字符串表示取消签名
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
 		return
 	}
 
+	webhook := req.Webhook
 	webhook.ID = id
+	if req.Secret != nil && *req.Secret != secretMask {
+		webhook.Secret = *req.Secret
+	} else if current, err := database.Detail(id); err == nil {
+		webhook.Secret = current.Secret
+	}
 
 	// 验证必填字段
 	if webhook.Name == "" || webhook.URL == "" {
@@ -78,6 +110,18 @@ func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 未指定事件类型时默认为短信，* 接收全部事件
+	if webhook.Type == "" {
+		webhook.Type = models.WebhookSMS
+	} else if !service.ValidWebhookType(webhook.Type) {
+		respondJSON(w, http.StatusBadRequest, H{"error": "type must be sms, call, * or a modem event type"})
+		return
+	}
+	if webhook.MaxRetries != nil && *webhook.MaxRetries < 0 {
+		respondJSON(w, http.StatusBadRequest, H{"error": "max_retries must not be negative"})
+		return
+	}
+
 	// 如果模板为空，使用默认模板
 	if webhook.Template == "" {
 		webhook.Template = "{}"
@@ -88,6 +132,7 @@ func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	maskSecret(&webhook)
 	respondJSON(w, http.StatusOK, webhook)
 }
 
@@ -138,6 +183,7 @@ func (h *WebhookHandler) Detail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	maskSecret(webhook)
 	respondJSON(w, http.StatusOK, webhook)
 }
 
@@ -149,6 +195,9 @@ func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	for i := range webhooks {
+		maskSecret(&webhooks[i])
+	}
 	respondJSON(w, http.StatusOK, webhooks)
 }
 
@@ -184,6 +233,38 @@ func (h *WebhookHandler) Test(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// DeadLetters 获取最近投递失败的Webhook
+func (h *WebhookHandler) DeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if l, err := strconv.Atoi(v); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	letters, err := h.ws.DeadLetters(limit)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, letters)
+}
+
+// ReplayDeadLetters 重新投递失败的Webhook
+func (h *WebhookHandler) ReplayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	count, err := h.ws.ReplayDeadLetters()
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, H{"error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, H{
+		"status": "queued",
+		"count":  count,
+	})
+}
+
 // DetailSettings 获取Webhook设置
 func (h *WebhookHandler) DetailSettings(w http.ResponseWriter, r *http.Request) {
 	settings, err := database.GetSettings()
@@ -1,13 +1,23 @@
 package handler
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/rehiy/web-modem/service"
 )
 
+const (
+	defaultPingInterval = 30 // 默认心跳间隔，秒
+	defaultPongTimeout  = 10 // 默认等待 pong 的超时，秒
+)
+
 // WebSocketHandler WebSocket处理器
 type WebSocketHandler struct {
 	upgrader websocket.Upgrader
@@ -17,27 +27,146 @@ type WebSocketHandler struct {
 func NewWebSocketHandler() *WebSocketHandler {
 	return &WebSocketHandler{
 		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool { return true },
+			CheckOrigin: checkOrigin,
 		},
 	}
 }
 
+// wsKeepalive 心跳间隔和等待 pong 的超时，由 WS_PING_INTERVAL、WS_PONG_TIMEOUT 设置（秒）
+var wsKeepalive = sync.OnceValues(func() (time.Duration, time.Duration) {
+	ping := envFloat("WS_PING_INTERVAL", defaultPingInterval)
+	pong := envFloat("WS_PONG_TIMEOUT", defaultPongTimeout)
+	return time.Duration(ping * float64(time.Second)), time.Duration(pong * float64(time.Second))
+})
+
+// checkOrigin 允许无 Origin 的非浏览器客户端，浏览器发起的连接只允许与请求的 Host 同源的页面
+// 来源可以是 http/https/ws/wss，启用 TLS 后页面以 https 或 wss 发起连接
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https", "ws", "wss":
+		return strings.EqualFold(u.Host, r.Host)
+	}
+	return false
+}
+
+// subscribeEvents 根据 port 或 ports（逗号分隔）参数订阅指定端口的事件
+// 未指定端口时订阅全部事件，不属于任何端口的全局事件始终推送
+func subscribeEvents(r *http.Request, since uint64, policy service.BackpressurePolicy) ([]service.Event, chan service.Event, func()) {
+	ports := map[string]bool{}
+	query := r.URL.Query()
+	for _, name := range strings.Split(query.Get("port")+","+query.Get("ports"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			ports[service.PortName(name)] = true
+		}
+	}
+
+	if len(ports) == 0 {
+		return service.ModemEvent.SubscribeWithPolicy(since, 100, policy)
+	}
+	return service.ModemEvent.FilteredSubscription(since, 100, policy, func(port string) bool {
+		return port == "" || ports[port]
+	})
+}
+
 // HandleWebSocket 处理WebSocket连接
+// 支持 ?since=<seq> 补发断线期间缓冲区内的事件，?port= 或 ?ports= 仅接收指定端口的事件
+// ?backpressure=drop|oldest|block 设置客户端读取不及时时的处理方式，默认丢弃最早的事件
 func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	policy, err := service.ParseBackpressure(r.URL.Query().Get("backpressure"), service.BackpressureDropOldest)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, H{"error": err.Error()})
+		return
+	}
+
 	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade failed: %v", err)
+		slog.Warn("websocket upgrade failed", slog.Any("error", err))
 		return
 	}
 	defer conn.Close()
 
-	log.Printf("WebSocket client connected: %s", r.RemoteAddr)
+	slog.Info("websocket client connected", slog.String("remote", r.RemoteAddr))
+
+	pingInterval, pongTimeout := wsKeepalive()
+	readDone := readControl(conn, pingInterval, pongTimeout)
 
-	// 直接推送Modem事件到客户端
-	for event := range service.ModemEvent {
-		if err := conn.WriteMessage(websocket.TextMessage, []byte(event)); err != nil {
-			log.Printf("WebSocket write error: %v", err)
+	// 订阅事件，并获取需要补发的历史事件
+	since, _ := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+	replay, events, cancel := subscribeEvents(r, since, policy)
+	defer cancel()
+
+	// 握手消息，告知客户端最新序号以及是否有事件已超出缓冲区
+	oldest := service.ModemEvent.Oldest()
+	hello := H{
+		"type":     "hello",
+		"seq":      service.ModemEvent.Latest(),
+		"replayed": len(replay),
+		"gap":      since > 0 && oldest > since+1,
+	}
+	if err := conn.WriteJSON(hello); err != nil {
+		slog.Warn("websocket write failed", slog.Any("error", err))
+		return
+	}
+
+	// 补发历史事件，事件在发布时已编码，直接写出
+	for _, event := range replay {
+		if err := conn.WriteMessage(websocket.TextMessage, event.JSON()); err != nil {
+			slog.Warn("websocket write failed", slog.Any("error", err))
 			return
 		}
 	}
-}
\ No newline at end of file
+
+	// 推送实时事件到客户端，定期发送 ping，读取循环结束（超时未收到 pong 或客户端断开）时退出
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, event.JSON()); err != nil {
+				slog.Warn("websocket write failed", slog.Any("error", err))
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(pongTimeout)); err != nil {
+				slog.Warn("websocket ping failed", slog.Any("error", err))
+				return
+			}
+		case <-readDone:
+			slog.Info("websocket client disconnected", slog.String("remote", r.RemoteAddr))
+			return
+		}
+	}
+}
+
+// readControl 读取并丢弃客户端消息，以便处理 pong 和 close 控制帧
+// 每次收到 pong 延长读取期限，超过 ping 间隔加 pong 超时仍无响应则读取失败
+func readControl(conn *websocket.Conn, pingInterval, pongTimeout time.Duration) chan struct{} {
+	done := make(chan struct{})
+	conn.SetReadDeadline(time.Now().Add(pingInterval + pongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pingInterval + pongTimeout))
+	})
+
+	go func() {
+		// 关闭连接，使可能阻塞在写入上的推送循环返回错误
+		defer conn.Close()
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	return done
+}
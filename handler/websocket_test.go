@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	cases := []struct {
+		host, origin string
+		want         bool
+	}{
+		{"modem.lan:8080", "", true},
+		{"modem.lan:8080", "http://modem.lan:8080", true},
+		{"modem.lan:8443", "https://MODEM.lan:8443", true},
+		{"modem.lan:8080", "http://evil.example", false},
+		{"modem.lan:8080", "http://modem.lan:9090", false},
+		{"modem.lan:8080", "file://modem.lan:8080", false},
+		{"modem.lan:8080", "null", false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/ws/modem", nil)
+		r.Host = c.host
+		if c.origin != "" {
+			r.Header.Set("Origin", c.origin)
+		}
+		if got := checkOrigin(r); got != c.want {
+			t.Errorf("host %q origin %q: got %v, want %v", c.host, c.origin, got, c.want)
+		}
+	}
+}
+
+// startWebSocketServer 以较短的心跳参数启动 WebSocket 服务，返回地址和处理结束通知
+func startWebSocketServer(t *testing.T) (string, chan struct{}) {
+	t.Helper()
+	old := wsKeepalive
+	wsKeepalive = func() (time.Duration, time.Duration) { return 50 * time.Millisecond, 50 * time.Millisecond }
+	t.Cleanup(func() { wsKeepalive = old })
+
+	h := NewWebSocketHandler()
+	finished := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.HandleWebSocket(w, r)
+		close(finished)
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http"), finished
+}
+
+func TestWebSocketPongTimeout(t *testing.T) {
+	url, finished := startWebSocketServer(t)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// 不读取就不会回复 pong，服务端应在 ping 间隔加 pong 超时后断开
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("connection kept open without pong")
+	}
+}
+
+func TestWebSocketPongKeepsAlive(t *testing.T) {
+	url, finished := startWebSocketServer(t)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// 读取时默认的 ping 处理函数自动回复 pong
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-finished:
+		t.Fatal("connection closed although client answers pings")
+	case <-time.After(400 * time.Millisecond):
+	}
+}
@@ -1,21 +1,34 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/rehiy/web-modem/database"
+	"github.com/rehiy/web-modem/handler"
+	"github.com/rehiy/web-modem/metrics"
 	"github.com/rehiy/web-modem/router"
+	"github.com/rehiy/web-modem/service"
 )
 
 const (
-	listenPort = "8080"
+	listenPort         = "8080"
+	defaultMetricsPort = "9090"
+	defaultMetricsHost = "127.0.0.1"
+
+	defaultShutdownTimeout = 30 * time.Second
 )
 
 func main() {
+	setupLogger()
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = listenPort
@@ -23,21 +36,146 @@ func main() {
 
 	// 初始化数据库
 	if err := database.InitDB(); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		slog.Error("failed to initialize database", slog.Any("error", err))
+		os.Exit(1)
 	}
 	defer database.Close()
 
+	// 加载模块别名
+	if err := service.LoadAliases(); err != nil {
+		slog.Error("failed to load modem aliases", slog.Any("error", err))
+	}
+
+	// 加载 API 密钥
+	// 密钥配置有误时退出，避免在缺少密钥的情况下放开认证
+	if err := service.LoadAPIKeys(); err != nil {
+		slog.Error("failed to load api keys", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// 加载来电拒接列表
+	if err := service.LoadRejectList(); err != nil {
+		slog.Error("failed to load reject list", slog.Any("error", err))
+	}
+
+	// 启动自检，扫描并连接设备
+	go service.GetModemService().StartupCheck()
+
+	// 发送到期的定时短信
+	service.GetModemService().StartScheduler()
+
+	// 监听串口设备插拔，自动连接或移除模块
+	service.NewHotPlugWatcher(service.GetModemService()).Start()
+
+	// 定期查询未读短信，补充可能丢失的新短信通知
+	service.GetModemService().StartSMSPoller()
+
+	// 定期检查模块是否响应，移除失联的模块
+	service.GetModemService().StartHealthCheck()
+
+	// 按事件类型将模块事件投递给 webhook
+	service.NewWebhookService().StartEventDispatch()
+
+	// 在独立端口提供 Prometheus 指标，不经过主 API 的跨域和认证处理，METRICS_PORT=0 时关闭
+	// 默认只监听本机，需要远程抓取时设置 METRICS_HOST（如 0.0.0.0）
+	var metricsServer *http.Server
+	if addr := metricsAddr(); addr != "" {
+		metricsServer = &http.Server{Addr: addr, Handler: metrics.Handler()}
+		go func() {
+			slog.Info("metrics server starting", slog.String("addr", addr))
+			if err := metricsServer.ListenAndServe(); err != http.ErrServerClosed {
+				slog.Error("metrics server stopped", slog.Any("error", err))
+			}
+		}()
+	}
+
+	// 配置 TLS 证书
+	certFile, keyFile, err := tlsFiles()
+	if err != nil {
+		slog.Error("failed to prepare TLS certificate", slog.Any("error", err))
+		os.Exit(1)
+	}
+
 	// 启动服务器
-	log.Printf("Server starting on :%s", port)
-	
+	slog.Info("server starting", slog.String("addr", ":"+port), slog.Bool("tls", certFile != ""))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: ":" + port, Handler: router.Apply()}
 	go func() {
-		log.Fatal(http.ListenAndServe(":"+port, router.Apply()))
+		var err error
+		if certFile != "" {
+			err = server.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != http.ErrServerClosed {
+			slog.Error("server failed", slog.Any("error", err))
+			os.Exit(1)
+		}
 	}()
 
 	// 等待中断信号
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	<-sigChan
-	
-	log.Println("Shutting down server...")
+	<-ctx.Done()
+	stop()
+
+	shutdown(server, metricsServer, shutdownTimeout())
+	slog.Info("server stopped")
+}
+
+// shutdown 停止接收新请求，等待处理中的请求完成后关闭全部模块，最多等待 timeout
+func shutdown(server, metricsServer *http.Server, timeout time.Duration) {
+	slog.Info("shutting down server", slog.Duration("timeout", timeout))
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("server shutdown incomplete", slog.Any("error", err))
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			slog.Warn("metrics server shutdown incomplete", slog.Any("error", err))
+		}
+	}
+	service.GetModemService().CloseAll(ctx)
+}
+
+// metricsAddr 指标服务的监听地址，由 METRICS_HOST 和 METRICS_PORT 设置，关闭时为空
+func metricsAddr() string {
+	port := os.Getenv("METRICS_PORT")
+	if port == "" {
+		port = defaultMetricsPort
+	}
+	if port == "0" {
+		return ""
+	}
+	host := os.Getenv("METRICS_HOST")
+	if host == "" {
+		host = defaultMetricsHost
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// shutdownTimeout 退出时等待请求和模块命令完成的最长时间，由 SHUTDOWN_TIMEOUT_SECONDS 设置
+func shutdownTimeout() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("SHUTDOWN_TIMEOUT_SECONDS")); err == nil && v > 0 {
+		return time.Duration(v) * time.Second
+	}
+	return defaultShutdownTimeout
+}
+
+// setupLogger 配置默认日志，LOG_LEVEL 为 debug/info/warn/error（默认 info），LOG_FORMAT 为 text/json（默认 text）
+// 标准库 log 的输出同样经由此处理器
+func setupLogger() {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(os.Getenv("LOG_LEVEL"))); err != nil {
+		level = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler = slog.NewTextHandler(os.Stderr, opts)
+	if os.Getenv("LOG_FORMAT") == "json" {
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(handler.RequestIDLogHandler(h)))
 }
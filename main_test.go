@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMetricsAddr(t *testing.T) {
+	cases := []struct{ host, port, want string }{
+		{"", "", "127.0.0.1:9090"},
+		{"", "9100", "127.0.0.1:9100"},
+		{"0.0.0.0", "", "0.0.0.0:9090"},
+		{"::", "9100", "[::]:9100"},
+		{"0.0.0.0", "0", ""},
+	}
+	for _, c := range cases {
+		t.Setenv("METRICS_HOST", c.host)
+		t.Setenv("METRICS_PORT", c.port)
+		if got := metricsAddr(); got != c.want {
+			t.Errorf("host %q port %q: %q, want %q", c.host, c.port, got, c.want)
+		}
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":    defaultShutdownTimeout,
+		"5":   5 * time.Second,
+		"0":   defaultShutdownTimeout,
+		"-3":  defaultShutdownTimeout,
+		"abc": defaultShutdownTimeout,
+	}
+	for v, want := range cases {
+		t.Setenv("SHUTDOWN_TIMEOUT_SECONDS", v)
+		if got := shutdownTimeout(); got != want {
+			t.Errorf("SHUTDOWN_TIMEOUT_SECONDS=%q: %s, want %s", v, got, want)
+		}
+	}
+}
+
+func TestShutdownWaitsForInFlightRequest(t *testing.T) {
+	started := make(chan struct{})
+	var finished atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		finished.Store(true)
+		io.WriteString(w, "done")
+	}))
+	defer srv.Close()
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	resp := make(chan result, 1)
+	go func() {
+		r, err := http.Get(srv.URL)
+		if err != nil {
+			resp <- result{err: err}
+			return
+		}
+		defer r.Body.Close()
+		body, err := io.ReadAll(r.Body)
+		resp <- result{status: r.StatusCode, body: string(body), err: err}
+	}()
+	<-started
+
+	start := time.Now()
+	shutdown(srv.Config, nil, 5*time.Second)
+	if !finished.Load() {
+		t.Fatalf("shutdown returned after %s before the request finished", time.Since(start))
+	}
+
+	res := <-resp
+	if res.err != nil || res.status != http.StatusOK || res.body != "done" {
+		t.Fatalf("in-flight request: status %d body %q err %v", res.status, res.body, res.err)
+	}
+	if _, err := http.Get(srv.URL); err == nil {
+		t.Fatal("new request accepted after shutdown")
+	}
+}
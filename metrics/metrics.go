@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// 模块相关指标
+var (
+	ATCommands = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "modem_at_commands_total",
+		Help: "AT commands sent, by result.",
+	}, []string{"port", "status"})
+	ATDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "modem_at_command_duration_seconds",
+		Help:    "AT command round-trip time.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 180}, // 网络搜索等命令可达数分钟
+	}, []string{"port"})
+	SMSSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "modem_sms_sent_total",
+		Help: "SMS send attempts, by result.",
+	}, []string{"port", "status"})
+	SMSReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "modem_sms_received_total",
+		Help: "SMS received.",
+	}, []string{"port"})
+	SignalRSSI = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "modem_signal_rssi",
+		Help: "Last sampled RSSI (AT+CSQ, 0-31, 99 unknown).",
+	}, []string{"port"})
+	ModemConnected = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "modem_connected",
+		Help: "Whether the modem is connected (1) or not (0).",
+	}, []string{"port"})
+)
+
+// registry 模块指标和 Go 运行时、进程指标
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		ATCommands, ATDuration, SMSSent, SMSReceived, SignalRSSI, ModemConnected,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler 返回 Prometheus 抓取接口
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	return mux
+}
@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	ATCommands.WithLabelValues("ttyTEST", "ok").Inc()
+	ATDuration.WithLabelValues("ttyTEST").Observe(0.2)
+	ModemConnected.WithLabelValues(`tty"Q`).Set(1)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	for _, want := range []string{
+		`modem_at_commands_total{port="ttyTEST",status="ok"} 1`,
+		`modem_at_command_duration_seconds_bucket{port="ttyTEST",le="0.25"} 1`,
+		`modem_connected{port="tty\"Q"} 1`,
+		"go_goroutines ",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,517 @@
+package models
+
+import "time"
+
+// ModemConfig 模块配置，按端口名保存
+type ModemConfig struct {
+	Name          string   `json:"name" gorm:"primaryKey;type:text"`
+	Alias         string   `json:"alias" gorm:"type:text;default:''"`               // 便于识别的模块别名，可代替端口名使用
+	AudioCommands []string `json:"audio_commands" gorm:"type:text;serializer:json"` // 拨号和接听前执行的厂商音频命令
+	DiagCommands  []string `json:"diag_commands" gorm:"type:text;serializer:json"`  // 诊断报告中追加的只读命令，仅限 AT+X? 或 AT+X=? 形式
+	EmptyRetry    int      `json:"empty_retry" gorm:"default:0"`                    // 信息查询没有数据行时的重试次数
+
+	// 弱信号看门狗：信号持续低于阈值时触发运营商重选
+	Watchdog         bool   `json:"watchdog" gorm:"default:false"`
+	WatchdogMinDBM   int    `json:"watchdog_min_dbm" gorm:"default:0"`  // 信号阈值（dBm），0 表示默认 -105
+	WatchdogDuration int    `json:"watchdog_duration" gorm:"default:0"` // 持续时长（秒），0 表示默认 600
+	WatchdogAction   string `json:"watchdog_action" gorm:"default:''"`  // cops: 自动重选运营商，cfun: 重启射频，默认 cops
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// Lease 模块租约
+type Lease struct {
+	Name      string    `json:"name"`
+	Token     string    `json:"token"`
+	TTL       int       `json:"ttl"` // 租约时长（秒）
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Signal 信号质量
+type Signal struct {
+	RSSI  int `json:"rssi"`
+	BER   int `json:"ber"`
+	Level int `json:"level"`
+	DBM   int `json:"dbm"`
+}
+
+// SignalSample 带采样时间的信号质量
+type SignalSample struct {
+	Signal
+	Time time.Time `json:"time"`
+}
+
+// Operator 运营商信息
+type Operator struct {
+	Mode   int    `json:"mode"`
+	Format int    `json:"format"`
+	Name   string `json:"name"`
+	Act    int    `json:"act"`
+}
+
+// NetworkOperator AT+COPS=? 搜索到的网络
+type NetworkOperator struct {
+	Status           string `json:"status"` // unknown / available / current / forbidden
+	LongName         string `json:"longName"`
+	ShortName        string `json:"shortName"`
+	NumericCode      string `json:"numericCode"` // MCC+MNC
+	AccessTechnology string `json:"accessTechnology,omitempty"`
+}
+
+// OperatorScan 网络搜索结果
+type OperatorScan struct {
+	Operators []NetworkOperator `json:"operators"`
+	ScannedAt time.Time         `json:"scannedAt"`
+}
+
+// ModemSMS 模块中存储的短信
+type ModemSMS struct {
+	PhoneNumber string       `json:"phoneNumber"`
+	Text        string       `json:"text"`
+	Time        string       `json:"time"`
+	ReceivedAt  time.Time    `json:"receivedAt"`        // 服务中心时间戳，带时区
+	Index       int          `json:"index"`             // 首个分片的索引
+	Indices     []int        `json:"indices"`           // 所有分片的索引
+	Status      string       `json:"status"`            // 短信状态 [0: "REC UNREAD", 1: "REC READ", 2: "STO UNSENT", 3: "STO SENT"]
+	Headers     []UDHElement `json:"headers,omitempty"` // 除拼接信息外的用户数据头信息单元
+}
+
+// PagedSMSResponse 模块短信分页结果
+type PagedSMSResponse struct {
+	Messages   []ModemSMS `json:"messages"`
+	Total      int        `json:"total"`
+	Page       int        `json:"page"`
+	PageSize   int        `json:"pageSize"`
+	NextCursor string     `json:"nextCursor,omitempty"` // 下一页的 cursor 参数，最后一页为空
+}
+
+// ModemSMSFilter 模块短信筛选条件
+type ModemSMSFilter struct {
+	Number   string `json:"number,omitempty"`   // 发送方号码，部分匹配
+	Contains string `json:"contains,omitempty"` // 短信内容包含的文本
+}
+
+// UDHElement 用户数据头信息单元
+type UDHElement struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Data string `json:"data"` // 十六进制数据
+}
+
+// PINRetries SIM 卡剩余尝试次数，-1 表示未知
+type PINRetries struct {
+	PIN  int `json:"pin"`
+	PIN2 int `json:"pin2"`
+	PUK  int `json:"puk"`
+	PUK2 int `json:"puk2"`
+}
+
+// BulkSMSResult 群发短信单个号码的结果
+type BulkSMSResult struct {
+	Number     string `json:"number"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+	References []int  `json:"references,omitempty"` // 各分段的消息参考号
+}
+
+// DeliveryReport 短信状态报告
+type DeliveryReport struct {
+	Reference       int       `json:"reference"` // 发送时的消息参考号
+	RecipientNumber string    `json:"recipientNumber"`
+	SubmitTime      time.Time `json:"submitTime"`    // 短信中心收到短信的时间
+	DischargeTime   time.Time `json:"dischargeTime"` // 投递或最终失败的时间
+	StatusCode      int       `json:"statusCode"`    // TP-ST 原始值
+	Status          string    `json:"status"`        // delivered / pending / failed
+}
+
+// PINStatus SIM 卡锁定状态
+type PINStatus struct {
+	Status  string      `json:"status"` // READY / SIM PIN / SIM PUK 等
+	Retries *PINRetries `json:"retries"`
+}
+
+// Location 定位信息
+type Location struct {
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	Altitude   float64   `json:"altitude"`
+	Satellites int       `json:"satellites"`
+	FixTime    time.Time `json:"fixTime"` // 定位时间（UTC）
+	Age        float64   `json:"age"`     // 距离获取该定位的秒数
+	Stale      bool      `json:"stale"`   // 是否为缓存的历史定位
+}
+
+// SMSBearer 短信承载方式
+type SMSBearer struct {
+	Mode  string `json:"mode"`  // auto / ims / cs
+	Value int    `json:"value"` // 厂商命令原始取值
+}
+
+// DeviceProbe 设备探测结果
+type DeviceProbe struct {
+	Device       string `json:"device"`
+	Name         string `json:"name"`
+	FriendlyName string `json:"friendlyName,omitempty"`
+	Connected    bool   `json:"connected"`
+	Vendor       string `json:"vendor,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Model        string `json:"model,omitempty"`
+	IMSI         string `json:"imsi,omitempty"`
+	PhoneNumber  string `json:"phoneNumber,omitempty"`
+	SIMStatus    string `json:"simStatus,omitempty"`
+	Role         string `json:"role,omitempty"` // 接口角色
+	Baud         int    `json:"baud,omitempty"` // 检测到的波特率
+	Error        string `json:"error,omitempty"`
+	Hint         string `json:"hint,omitempty"` // 故障排查建议
+}
+
+// ModemInterface 模块的一个串口接口
+type ModemInterface struct {
+	Device       string `json:"device"`
+	Name         string `json:"name"`
+	FriendlyName string `json:"friendlyName,omitempty"` // 设备描述，如 USB 产品名称
+	Interface    int    `json:"interface"`              // USB 接口编号，非 USB 设备为 -1
+	Role         string `json:"role"`                   // at / data / gnss / diag / audio / unknown
+}
+
+// StartupReport 启动自检报告
+type StartupReport struct {
+	Time      time.Time     `json:"time"`
+	Devices   []DeviceProbe `json:"devices"`
+	Connected int           `json:"connected"`
+	Hint      string        `json:"hint,omitempty"`
+}
+
+// ActiveBand 当前驻留的频段和信道
+type ActiveBand struct {
+	Rat       string `json:"rat"`       // 接入技术 GSM / WCDMA / LTE / NR5G
+	Band      string `json:"band"`      // 频段，如 LTE BAND 3
+	Channel   int    `json:"channel"`   // ARFCN / UARFCN / EARFCN，-1 表示未知
+	Bandwidth string `json:"bandwidth"` // 下行带宽
+	Duplex    string `json:"duplex"`    // FDD / TDD
+	Raw       string `json:"raw"`       // 原始响应，格式无法解析时可供参考
+}
+
+// SMSEstimate 短信长度估算
+type SMSEstimate struct {
+	Encoding   string `json:"encoding"`   // GSM7 / UCS2
+	Units      int    `json:"units"`      // GSM7 为 septet 数（扩展字符计 2），UCS2 为 UTF-16 码元数
+	Segments   int    `json:"segments"`   // 分段数量
+	PerSegment int    `json:"perSegment"` // 每个分段的容量
+	Remaining  int    `json:"remaining"`  // 最后一个分段的剩余容量
+}
+
+// PDUValidation 短信编码后再解码的校验结果，不实际发送
+type PDUValidation struct {
+	Encoding        string   `json:"encoding"`        // GSM7 / UCS2
+	PartsCount      int      `json:"partsCount"`      // 分段数量
+	EncodedLength   int      `json:"encodedLength"`   // 全部分段的 TPDU 字节数
+	ActualCharCount int      `json:"actualCharCount"` // 原文字符数
+	DecodedText     string   `json:"decodedText"`     // 解码得到的文本
+	RoundTrip       bool     `json:"roundTrip"`       // 解码结果与原文一致
+	WillBeTruncated bool     `json:"willBeTruncated"` // 解码结果的字符数少于原文
+	Diff            string   `json:"diff,omitempty"`  // 第一处不一致的位置和内容
+	PDUs            []string `json:"pdus"`            // 各分段的十六进制 PDU
+}
+
+// Charset TE 字符集
+type Charset struct {
+	Current   string   `json:"current"`
+	Supported []string `json:"supported"`
+}
+
+// ResultFormat 结果码格式
+type ResultFormat struct {
+	Verbose bool `json:"verbose"` // ATV1 文本结果码，ATV0 数字结果码
+	Quiet   bool `json:"quiet"`   // ATQ1 不返回结果码
+}
+
+// Identity 模块和 SIM 卡身份信息
+type Identity struct {
+	Manufacturer string `json:"manufacturer"`
+	Model        string `json:"model"`
+	Revision     string `json:"revision"`
+	IMEI         string `json:"imei"`
+	IMSI         string `json:"imsi"`
+	ICCID        string `json:"iccid"`
+}
+
+// ModemBasicInfo 模块和 SIM 卡基本信息，获取失败的字段为空
+type ModemBasicInfo struct {
+	Name         string    `json:"name"`
+	Manufacturer string    `json:"manufacturer,omitempty"`
+	Model        string    `json:"model,omitempty"`
+	Revision     string    `json:"revision,omitempty"`
+	IMEI         string    `json:"imei,omitempty"`
+	IMSI         string    `json:"imsi,omitempty"`
+	Phone        string    `json:"phone,omitempty"`
+	Operator     string    `json:"operator,omitempty"` // 运营商和接入技术每次实时查询
+	Act          *int      `json:"act,omitempty"`
+	FetchedAt    time.Time `json:"fetchedAt"` // 静态信息的查询时间
+}
+
+// Registration 网络注册状态
+type Registration struct {
+	Stat        int    `json:"stat"`                  // 3GPP TS 27.007 注册状态码
+	Status      string `json:"status"`                // 注册状态描述
+	ServingPLMN string `json:"servingPlmn,omitempty"` // 当前服务网络 MCC+MNC
+	HomePLMN    string `json:"homePlmn,omitempty"`    // SIM 卡归属网络 MCC+MNC
+	Roaming     *bool  `json:"roaming,omitempty"`     // 根据服务网络与归属网络比较得出，无法比较时按注册状态判断
+}
+
+// DomainRegistration 单个注册域的注册状态
+type DomainRegistration struct {
+	Registration
+	Registered bool   `json:"registered"`    // 是否已注册（本地或漫游）
+	Lac        string `json:"lac,omitempty"` // 位置区码 / 跟踪区码
+	CellID     string `json:"cellId,omitempty"`
+	AcT        *int   `json:"act,omitempty"` // 接入技术
+}
+
+// NetworkRegistration 各注册域的注册状态，模块不支持的注册域为空
+type NetworkRegistration struct {
+	CS  *DomainRegistration `json:"cs,omitempty"`  // 电路域（+CREG，GSM）
+	PS  *DomainRegistration `json:"ps,omitempty"`  // 分组域（+CGREG，GPRS/UMTS）
+	EPS *DomainRegistration `json:"eps,omitempty"` // 演进分组域（+CEREG，LTE）
+}
+
+// CellInfo 当前服务小区信息
+type CellInfo struct {
+	LAC         string `json:"lac"`         // 位置区码 / 跟踪区码（十六进制）
+	CellID      string `json:"cellId"`      // 小区标识（十六进制）
+	MCC         string `json:"mcc"`         // 移动国家码
+	MNC         string `json:"mnc"`         // 移动网络码
+	NetworkType string `json:"networkType"` // 接入技术，如 GSM / UTRAN / LTE
+	Domain      string `json:"domain"`      // 提供小区信息的注册域 cs / ps / eps
+}
+
+// RegistrationEvent 网络注册状态变化（+CREG / +CGREG / +CEREG 通知）
+type RegistrationEvent struct {
+	DomainRegistration
+	Domain   string `json:"domain"`             // cs / ps / eps
+	Previous *int   `json:"previous,omitempty"` // 变化前的状态码，首次上报时为空
+}
+
+// SMSStorage 短信存储使用情况
+type SMSStorage struct {
+	Memory   string   `json:"memory"` // 存储区，如 SM / ME
+	Used     int      `json:"used"`
+	Total    int      `json:"total"`
+	Selected []string `json:"selected,omitempty"` // 当前用于 read / write / receive
+}
+
+// Dashboard 模块状态快照
+type Dashboard struct {
+	Name         string            `json:"name"`
+	Time         time.Time         `json:"time"`
+	Identity     *Identity         `json:"identity,omitempty"`
+	Signal       *Signal           `json:"signal,omitempty"`
+	Operator     *Operator         `json:"operator,omitempty"`
+	Registration *Registration     `json:"registration,omitempty"`
+	UnreadSMS    int               `json:"unreadSms"` // 用户上次读取短信后新收到的短信数量
+	DataMode     bool              `json:"dataMode"`  // 处于数据模式时不查询其他动态信息
+	Storage      []SMSStorage      `json:"storage,omitempty"`
+	ConnectedAt  time.Time         `json:"connectedAt"`
+	Uptime       float64           `json:"uptime"` // 连接时长（秒）
+	Errors       map[string]string `json:"errors,omitempty"`
+}
+
+// PowerSaving 省电模式（PSM / eDRX）配置
+type PowerSaving struct {
+	PSMEnabled          bool   `json:"psmEnabled"`
+	RequestedTAU        string `json:"requestedTau,omitempty"`        // 请求的 T3412 扩展定时器位串
+	RequestedActiveTime string `json:"requestedActiveTime,omitempty"` // 请求的 T3324 定时器位串
+	NetworkTAU          *Timer `json:"networkTau,omitempty"`          // 网络分配的 T3412 扩展定时器
+	NetworkActiveTime   *Timer `json:"networkActiveTime,omitempty"`   // 网络分配的 T3324 定时器
+	EDRX                []EDRX `json:"edrx"`
+}
+
+// Timer 3GPP TS 24.008 GPRS 定时器
+type Timer struct {
+	Bits    string `json:"bits"`
+	Seconds int    `json:"seconds"` // -1 表示已停用
+}
+
+// EDRX 扩展非连续接收配置
+type EDRX struct {
+	AcT          int     `json:"act"`                    // 接入技术类型，4: E-UTRAN，5: NB-IoT
+	Requested    string  `json:"requested"`              // 请求的 eDRX 值位串
+	Network      string  `json:"network,omitempty"`      // 网络分配的 eDRX 值位串
+	PagingWindow string  `json:"pagingWindow,omitempty"` // 网络分配的寻呼时间窗位串
+	Cycle        float64 `json:"cycle,omitempty"`        // 网络分配的 eDRX 周期（秒）
+}
+
+// CallRecord 通话记录
+type CallRecord struct {
+	ID         int        `json:"id"`
+	Number     string     `json:"number"`
+	Direction  string     `json:"direction"` // in / out
+	Status     string     `json:"status"`    // ringing / active / answered / missed / rejected
+	Rings      int        `json:"rings"`
+	StartedAt  time.Time  `json:"startedAt"`
+	AnsweredAt *time.Time `json:"answeredAt,omitempty"`
+	EndedAt    *time.Time `json:"endedAt,omitempty"`
+	Result     string     `json:"result,omitempty"` // 结束时模块上报的结果码：NO CARRIER / BUSY / NO ANSWER
+}
+
+// RejectRule 来电拒接规则，保存在拒接列表文件中
+type RejectRule struct {
+	ID      int    `json:"id"`
+	Pattern string `json:"pattern"`        // 来电号码正则表达式
+	Note    string `json:"note,omitempty"` // 备注
+}
+
+// CallState AT+CLCC 通话状态
+type CallState struct {
+	ID         int    `json:"id"`
+	Direction  string `json:"direction"` // in / out
+	Stat       int    `json:"stat"`
+	State      string `json:"state"` // active / held / dialing / alerting / incoming / waiting
+	Mode       int    `json:"mode"`  // 0: 语音，1: 数据，2: 传真
+	Multiparty bool   `json:"multiparty"`
+	Number     string `json:"number,omitempty"`
+}
+
+// DiagReport 诊断报告
+type DiagReport struct {
+	Name      string      `json:"name"`
+	Vendor    string      `json:"vendor"`
+	Time      time.Time   `json:"time"`
+	EchoCount int64       `json:"echoCount"` // 关闭回显后仍收到的命令回显次数，持续增长说明模块忽略 ATE0
+	Commands  []DiagEntry `json:"commands"`
+}
+
+// DiagEntry 诊断命令及其原始输出
+type DiagEntry struct {
+	Command  string   `json:"command"`
+	Response []string `json:"response"`
+	Error    string   `json:"error,omitempty"`
+	Duration int64    `json:"duration"` // 执行耗时（毫秒）
+}
+
+// ESIMProfile eSIM 配置文件
+type ESIMProfile struct {
+	ICCID    string `json:"iccid"`
+	Enabled  bool   `json:"enabled"`
+	Name     string `json:"name,omitempty"`     // 配置文件昵称或名称
+	Provider string `json:"provider,omitempty"` // 运营商名称
+}
+
+// CellLock 小区锁定配置
+type CellLock struct {
+	Enabled bool         `json:"enabled"`
+	Cells   []LockedCell `json:"cells"`
+	Raw     []string     `json:"raw"` // 原始响应，不同固件格式可能不同
+}
+
+// LockedCell 锁定的小区
+type LockedCell struct {
+	EARFCN int `json:"earfcn"`
+	PCI    int `json:"pci"`
+}
+
+// DataTestResult 数据连接测试结果
+type DataTestResult struct {
+	Success  bool           `json:"success"`
+	CID      int            `json:"cid"`
+	IP       string         `json:"ip,omitempty"`
+	Duration int64          `json:"duration"` // 总耗时（毫秒）
+	Steps    []DataTestStep `json:"steps"`
+}
+
+// DataTestStep 数据连接测试步骤
+type DataTestStep struct {
+	Step     string   `json:"step"` // attach / activate / address / deactivate / detach
+	Command  string   `json:"command"`
+	Response []string `json:"response"`
+	Error    string   `json:"error,omitempty"`
+	Duration int64    `json:"duration"` // 耗时（毫秒）
+}
+
+// USSDResponse USSD 响应
+type USSDResponse struct {
+	Status        int       `json:"status"` // +CUSD <m>：0 无需操作，1 需要回复，2 网络终止，4 不支持，5 超时
+	StatusText    string    `json:"statusText"`
+	Text          string    `json:"text"`
+	DCS           int       `json:"dcs"`
+	SessionActive bool      `json:"sessionActive"` // 会话是否等待用户回复
+	Time          time.Time `json:"time"`
+}
+
+// ModemCapabilities 模块支持的功能
+type ModemCapabilities struct {
+	SupportedCommands []string `json:"supportedCommands"` // AT+CLAC 列出的命令，模块不支持该命令时为空
+}
+
+// Contact SIM 卡电话簿条目
+type Contact struct {
+	Index  int    `json:"index"`
+	Number string `json:"number"`
+	Name   string `json:"name"`
+}
+
+// ATMacro 按顺序执行的 AT 命令序列
+type ATMacro struct {
+	Name           string    `json:"name" gorm:"primaryKey;type:text"`
+	Commands       []string  `json:"commands" gorm:"serializer:json;type:text"`
+	DelayBetweenMS int       `json:"delay_between_ms" gorm:"default:0"` // 相邻命令之间的等待时间
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// MacroStep 宏中单条命令的执行结果
+type MacroStep struct {
+	Command    string   `json:"command"`
+	Responses  []string `json:"responses"`
+	Error      string   `json:"error,omitempty"`
+	DurationMS int64    `json:"duration_ms"`
+}
+
+// MacroResult 宏执行结果，命令失败时停止执行，Steps 只包含已执行的命令
+type MacroResult struct {
+	Macro  string      `json:"macro"`
+	Failed bool        `json:"failed"`
+	Steps  []MacroStep `json:"steps"`
+}
+
+// APNConfig PDP 上下文的 APN 配置
+type APNConfig struct {
+	CID      int    `json:"cid"`     // 上下文编号，默认 1
+	PDPType  string `json:"pdpType"` // IP / IPV6 / IPV4V6，默认 IP
+	APN      string `json:"apn"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// PDPStatus PDP 上下文状态
+type PDPStatus struct {
+	CID      int    `json:"cid"`
+	Attached bool   `json:"attached"` // 是否已附着分组域
+	Active   bool   `json:"active"`
+	IP       string `json:"ip,omitempty"`
+}
+
+// RevokedAPIKey 已吊销的 API 密钥编号
+type RevokedAPIKey struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:text"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// APIKey 接口生成的 API 密钥，只保存 bcrypt 哈希
+type APIKey struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:text"`
+	Description string     `json:"description"`
+	Hash        string     `json:"-" gorm:"not null"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// APIKeyInfo API 密钥信息，不包含密钥本身
+type APIKeyInfo struct {
+	ID          string     `json:"id"` // 密钥中 . 之前的编号
+	Description string     `json:"description"`
+	Source      string     `json:"source"` // env / file / db
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	Expired     bool       `json:"expired"`
+	Revoked     bool       `json:"revoked"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -13,6 +14,8 @@ type SMS struct {
 	ReceiveNumber string    `json:"receive_number" gorm:"type:text;index:idx_sms_receive_number"`
 	SendNumber    string    `json:"send_number" gorm:"type:text;index:idx_sms_send_number"`
 	Direction     string    `json:"direction" gorm:"not null;type:text;check:direction IN ('in', 'out');index:idx_sms_direction"` // "in" 或 "out"
+	Modem         string    `json:"modem" gorm:"type:text;default:''"`                                                            // 收发短信的模块端口
+	Read          bool      `json:"read" gorm:"default:false"`                                                                    // 是否已读
 	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
 }
 
@@ -20,6 +23,8 @@ type SMS struct {
 type SMSFilter struct {
 	Direction  string    `json:"direction,omitempty"`
 	SendNumber string    `json:"send_number,omitempty"`
+	Modem      string    `json:"modem,omitempty"`
+	Unread     bool      `json:"unread,omitempty"`
 	StartTime  time.Time `json:"start_time,omitempty"`
 	EndTime    time.Time `json:"end_time,omitempty"`
 	Limit      int       `json:"limit,omitempty"`
@@ -28,15 +33,76 @@ type SMSFilter struct {
 
 // Webhook Webhook配置模型
 type Webhook struct {
+	ID         int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name       string    `json:"name" gorm:"not null;unique;type:text"`
+	URL        string    `json:"url" gorm:"not null;type:text"`
+	Template   string    `json:"template" gorm:"type:text;default:'{}'"`
+	Enabled    bool      `json:"enabled" gorm:"default:true"`
+	Modem      string    `json:"modem" gorm:"type:text;default:''"`   // 仅接收指定模块（端口名或 IMEI）的事件，为空表示全局
+	Type       string    `json:"type" gorm:"type:text;default:'sms'"` // 事件类型：sms / call / 其他模块事件类型，* 表示全部
+	Secret     string    `json:"secret" gorm:"type:text;default:''"`  // 签名密钥，设置后请求带 X-Modem-Signature 头
+	MaxRetries *int      `json:"max_retries,omitempty"`               // 投递失败的最大重试次数，为空时使用 WEBHOOK_MAX_RETRIES
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// WebhookDeadLetter 重试耗尽后仍投递失败的 webhook
+type WebhookDeadLetter struct {
+	WebhookID int             `json:"webhook_id"`
+	Name      string          `json:"name"`
+	URL       string          `json:"url"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"`
+	Error     string          `json:"error"`
+	Time      time.Time       `json:"time"`
+}
+
+// Webhook 事件类型
+const (
+	WebhookSMS  = "sms"  // 收到短信
+	WebhookCall = "call" // 来电及通话状态变化
+	WebhookAll  = "*"    // 全部事件
+)
+
+// ForwardRule 短信转发规则
+type ForwardRule struct {
+	ID          int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	FromPattern string    `json:"from_pattern" gorm:"not null;type:text"` // 发送方号码正则表达式
+	ToNumber    string    `json:"to_number" gorm:"type:text;default:''"`  // 转发到的号码
+	ToWebhook   string    `json:"to_webhook" gorm:"type:text;default:''"` // 转发到的 webhook 地址
+	Enabled     bool      `json:"enabled" gorm:"default:true"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// SMSTemplate 短信模板，Body 中可使用 {{.Name}} 形式的变量
+type SMSTemplate struct {
 	ID        int       `json:"id" gorm:"primaryKey;autoIncrement"`
-	Name      string    `json:"name" gorm:"not null;unique;type:text"`
-	URL       string    `json:"url" gorm:"not null;type:text"`
-	Template  string    `json:"template" gorm:"type:text;default:'{}'"`
-	Enabled   bool      `json:"enabled" gorm:"default:true"`
+	Name      string    `json:"name" gorm:"not null;type:text"`
+	Body      string    `json:"body" gorm:"not null;type:text"`
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
+// 定时短信状态
+const (
+	ScheduledPending = "pending"
+	ScheduledSent    = "sent"
+	ScheduledFailed  = "failed"
+)
+
+// ScheduledSMS 定时发送的短信
+type ScheduledSMS struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:text"`
+	Port      string     `json:"port" gorm:"not null;type:text"`
+	Number    string     `json:"number" gorm:"not null;type:text"`
+	Message   string     `json:"message" gorm:"not null;type:text"`
+	SendAt    time.Time  `json:"send_at" gorm:"not null;index"`
+	Status    string     `json:"status" gorm:"type:text;default:'pending';index"`
+	Error     string     `json:"error,omitempty" gorm:"type:text;default:''"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
 // Setting 系统设置模型
 type Setting struct {
 	Key       string    `json:"key" gorm:"primaryKey;type:text"`
@@ -1,23 +1,59 @@
 package router
 
 import (
+	"log/slog"
 	"net/http"
+	"os"
 
 	"github.com/gorilla/mux"
 	"github.com/rehiy/web-modem/handler"
+	"github.com/rehiy/web-modem/service"
 )
 
 func Apply() *mux.Router {
 	r := mux.NewRouter()
 
+	// API 认证，设置 AUTH_USER / AUTH_PASSWORD 后需要 Basic 认证
+	// 设置 JWT_SECRET 后改用令牌认证，令牌以上述凭据换取，WebSocket 同样需要认证
+	user, pass := os.Getenv("AUTH_USER"), os.Getenv("AUTH_PASSWORD")
+	var apiAuth, wsAuth mux.MiddlewareFunc
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		ja := handler.NewJWTAuth(secret, user, pass)
+		apiAuth, wsAuth = ja.Middleware, ja.Middleware
+		// 签发接口本身不需要令牌，须在 API 子路由之前注册
+		issue := handler.RequestID(handler.RequestLogger(http.HandlerFunc(ja.IssueToken)))
+		r.Handle("/api/auth/token", issue).Methods("POST")
+	} else if user != "" || pass != "" {
+		apiAuth = handler.BasicAuth(user, pass)
+	} else if !service.APIKeys.Enabled() {
+		slog.Warn("AUTH_USER, AUTH_PASSWORD and API keys are not set, API authentication is disabled")
+	}
+
 	// API 路由
 	api := r.PathPrefix("/api").Subrouter()
+	api.Use(handler.RequestID)
+	api.Use(handler.RequestLogger)
+	// 带有 X-API-Key 的请求按 API 密钥认证，密钥由 API_KEYS / API_KEYS_FILE 设置或通过接口生成
+	// 没有配置其他认证时，配置了 API 密钥后所有请求都必须带密钥
+	api.Use(handler.APIKeyAuth(apiAuth))
+	// 接口描述文档
+	api.HandleFunc("/openapi.json", handler.OpenAPI).Methods("GET")
+	api.HandleFunc("/swagger-ui", handler.SwaggerUI).Methods("GET")
+
 	ModemRegister(api)
 	SmsdbRegister(api)
 	WebhookRegister(api)
+	RuleRegister(api)
+	RejectListRegister(api)
+	TemplateRegister(api)
+	APIKeyRegister(api)
 
 	// WebSocket
-	WebSocketRegister(r)
+	ws := r.NewRoute().Subrouter()
+	if wsAuth != nil {
+		ws.Use(wsAuth)
+	}
+	WebSocketRegister(ws)
 
 	// 静态文件服务
 	StaticServer(r)
@@ -28,18 +64,99 @@ func Apply() *mux.Router {
 func ModemRegister(r *mux.Router) {
 	mh := handler.NewModemHandler()
 
+	// 模块租用，被租用的模块需携带租约令牌才能操作
+	r.Use(mh.LeaseGuard)
+	r.HandleFunc("/modem/lease", mh.AcquireLease).Methods("POST")
+	r.HandleFunc("/modem/lease", mh.ReleaseLease).Methods("DELETE")
+
 	// 模块列表
 	r.HandleFunc("/modem/list", mh.List).Methods("GET")
+	r.HandleFunc("/modem/health", mh.Health).Methods("GET")
+	r.HandleFunc("/startup-report", mh.StartupReport).Methods("GET")
+	r.HandleFunc("/dashboard", mh.Dashboard).Methods("GET")
+
+	// AT 命令宏
+	r.HandleFunc("/modem/macro", mh.CreateMacro).Methods("POST")
+	r.HandleFunc("/modem/macro/list", mh.ListMacros).Methods("GET")
+	r.HandleFunc("/modem/macro/delete", mh.DeleteMacro).Methods("DELETE")
 
 	// 模块操作
-	r.HandleFunc("/modem/send", mh.Command).Methods("POST")
+	r.HandleFunc("/modem/send", handler.RateLimit(mh.Command)).Methods("POST")
+	r.HandleFunc("/modem/macro/run", handler.RateLimit(mh.RunMacro)).Methods("POST")
 	r.HandleFunc("/modem/info", mh.BasicInfo).Methods("GET")
+	r.HandleFunc("/modem/capabilities", mh.Capabilities).Methods("GET")
 	r.HandleFunc("/modem/signal", mh.SignalStrength).Methods("GET")
+	r.HandleFunc("/modem/network", mh.NetworkRegistration).Methods("GET")
+	r.HandleFunc("/modem/contacts", mh.Contacts).Methods("GET")
+	r.HandleFunc("/modem/contacts", mh.WriteContact).Methods("POST")
+	r.HandleFunc("/modem/contacts", mh.DeleteContact).Methods("DELETE")
+	r.HandleFunc("/modem/network/operators", mh.Operators).Methods("GET")
+	r.HandleFunc("/modem/network/operator", mh.SelectOperator).Methods("POST")
+	r.HandleFunc("/modem/cell", mh.CellInfo).Methods("GET")
+	r.HandleFunc("/modem/signal/history", mh.SignalHistory).Methods("GET")
+	r.HandleFunc("/modem/active-band", mh.ActiveBand).Methods("GET")
+	r.HandleFunc("/modem/diag", mh.Diag).Methods("GET")
+	r.HandleFunc("/modem/result-format", mh.ResultFormat).Methods("GET")
+	r.HandleFunc("/modem/result-format", mh.SetResultFormat).Methods("POST")
+	r.HandleFunc("/modem/pin", mh.PINStatus).Methods("GET")
+	r.HandleFunc("/modem/pin", mh.UnlockPIN).Methods("POST")
+	r.HandleFunc("/modem/pin/retries", mh.PINRetries).Methods("GET")
+	r.HandleFunc("/modem/apn", mh.SetAPN).Methods("POST")
+	r.HandleFunc("/modem/data/activate", mh.ActivatePDP).Methods("POST")
+	r.HandleFunc("/modem/data/deactivate", mh.DeactivatePDP).Methods("POST")
+	r.HandleFunc("/modem/data/status", mh.PDPStatus).Methods("GET")
+	r.HandleFunc("/modem/data/test", mh.DataTest).Methods("POST")
+	r.HandleFunc("/modem/celllock", mh.CellLock).Methods("GET")
+	r.HandleFunc("/modem/celllock", mh.SetCellLock).Methods("POST")
+	r.HandleFunc("/modem/celllock", mh.ClearCellLock).Methods("DELETE")
+	r.HandleFunc("/modem/esim/profiles", mh.ESIMProfiles).Methods("GET")
+	r.HandleFunc("/modem/esim/profiles", mh.ESIMProfileAction).Methods("POST")
+	r.HandleFunc("/modem/charset", mh.Charset).Methods("GET")
+	r.HandleFunc("/modem/charset", mh.SetCharset).Methods("POST")
+	r.HandleFunc("/modem/reset", mh.Reset).Methods("POST")
+	r.HandleFunc("/modem/factory-reset", mh.FactoryReset).Methods("POST")
+	r.HandleFunc("/modem/powersave", mh.PowerSaving).Methods("GET")
+	r.HandleFunc("/modem/powersave", mh.SetPowerSaving).Methods("POST")
+	r.HandleFunc("/modem/location", mh.Location).Methods("GET")
+	r.HandleFunc("/modem/baud", mh.SetBaud).Methods("POST")
+	r.HandleFunc("/modem/config", mh.Config).Methods("GET")
+	r.HandleFunc("/modem/config", mh.UpdateConfig).Methods("PUT")
+	r.HandleFunc("/modem/alias", mh.SetAlias).Methods("PUT")
+
+	// 通话
+	r.HandleFunc("/modem/calls", mh.CallHistory).Methods("GET")
+	r.HandleFunc("/modem/calls", mh.ClearCallHistory).Methods("DELETE")
+	r.HandleFunc("/modem/call/status", mh.CallStatus).Methods("GET")
+	r.HandleFunc("/modem/call/dial", mh.Dial).Methods("POST")
+	r.HandleFunc("/modem/call/answer", mh.AnswerCall).Methods("POST")
+	r.HandleFunc("/modem/call/hangup", mh.HangupCall).Methods("POST")
+	r.HandleFunc("/modem/call/reject", mh.RejectCall).Methods("POST")
+	r.HandleFunc("/modem/call/dtmf", mh.SendDTMF).Methods("POST")
+
+	// USSD
+	r.HandleFunc("/modem/ussd", mh.SendUSSD).Methods("POST")
+	r.HandleFunc("/modem/ussd", mh.CancelUSSD).Methods("DELETE")
+	r.HandleFunc("/modem/ussd/response", mh.USSDResponse).Methods("GET")
 
 	// 短信读写
 	r.HandleFunc("/modem/sms/list", mh.ListSMS).Methods("GET")
-	r.HandleFunc("/modem/sms/send", mh.SendSMS).Methods("POST")
+	r.HandleFunc("/modem/sms/export", mh.ExportSMS).Methods("GET")
+	r.HandleFunc("/modem/sms/send", handler.RateLimit(mh.SendSMS)).Methods("POST")
+	r.HandleFunc("/modem/sms/send-balanced", handler.RateLimit(mh.SendSMSBalanced)).Methods("POST")
+	r.HandleFunc("/modem/sms/send-failover", handler.RateLimit(mh.SendSMSFailover)).Methods("POST")
+	r.HandleFunc("/modem/sms/send-template", handler.RateLimit(mh.SendSMSTemplate)).Methods("POST")
+	r.HandleFunc("/modem/sms/bulk", handler.RateLimit(mh.SendSMSBulk)).Methods("POST")
+	r.HandleFunc("/modem/sms/estimate", mh.EstimateSMS).Methods("POST")
 	r.HandleFunc("/modem/sms/delete", mh.DeleteSMS).Methods("POST")
+	r.HandleFunc("/modem/sms/delete-batch", mh.DeleteSMSByStatus).Methods("POST")
+	r.HandleFunc("/modem/sms/delivery", mh.DeliveryReports).Methods("GET")
+	r.HandleFunc("/modem/sms/schedule", mh.ScheduleSMS).Methods("POST")
+	r.HandleFunc("/modem/sms/schedule", mh.ListScheduledSMS).Methods("GET")
+	r.HandleFunc("/modem/sms/schedule", mh.CancelScheduledSMS).Methods("DELETE")
+	r.HandleFunc("/modem/sms/bearer", mh.SMSBearer).Methods("GET")
+	r.HandleFunc("/modem/sms/bearer", mh.SetSMSBearer).Methods("POST")
+	r.HandleFunc("/modem/sms/storage", mh.SMSStorage).Methods("GET")
+	r.HandleFunc("/modem/sms/storage", mh.SetSMSStorage).Methods("POST")
 }
 
 func SmsdbRegister(r *mux.Router) {
@@ -48,6 +165,7 @@ func SmsdbRegister(r *mux.Router) {
 	// 短信存储管理
 	r.HandleFunc("/smsdb/list", dh.List).Methods("GET")
 	r.HandleFunc("/smsdb/delete", dh.Delete).Methods("POST")
+	r.HandleFunc("/smsdb/read", dh.MarkRead).Methods("POST")
 	r.HandleFunc("/smsdb/settings", dh.GetSettings).Methods("GET")
 	r.HandleFunc("/smsdb/settings", dh.UpdateSettings).Methods("PUT")
 }
@@ -62,10 +180,49 @@ func WebhookRegister(r *mux.Router) {
 	r.HandleFunc("/webhook/update", wh.Update).Methods("PUT")
 	r.HandleFunc("/webhook/delete", wh.Delete).Methods("DELETE")
 	r.HandleFunc("/webhook/test", wh.Test).Methods("POST")
+	r.HandleFunc("/webhook/dlq", wh.DeadLetters).Methods("GET")
+	r.HandleFunc("/webhook/dlq/replay", wh.ReplayDeadLetters).Methods("POST")
 	r.HandleFunc("/webhook/settings", wh.DetailSettings).Methods("GET")
 	r.HandleFunc("/webhook/settings", wh.UpdateSettings).Methods("PUT")
 }
 
+func RuleRegister(r *mux.Router) {
+	rh := handler.NewRuleHandler()
+
+	// 短信转发规则
+	r.HandleFunc("/rule", rh.Create).Methods("POST")
+	r.HandleFunc("/rule/list", rh.List).Methods("GET")
+	r.HandleFunc("/rule/delete", rh.Delete).Methods("DELETE")
+}
+
+func RejectListRegister(r *mux.Router) {
+	rh := handler.NewRejectListHandler()
+
+	// 来电拒接列表
+	r.HandleFunc("/reject-list", rh.Create).Methods("POST")
+	r.HandleFunc("/reject-list/list", rh.List).Methods("GET")
+	r.HandleFunc("/reject-list/delete", rh.Delete).Methods("DELETE")
+}
+
+func TemplateRegister(r *mux.Router) {
+	th := handler.NewTemplateHandler()
+
+	// 短信模板
+	r.HandleFunc("/template", th.Create).Methods("POST")
+	r.HandleFunc("/template/list", th.List).Methods("GET")
+	r.HandleFunc("/template/update", th.Update).Methods("PUT")
+	r.HandleFunc("/template/delete", th.Delete).Methods("DELETE")
+}
+
+func APIKeyRegister(r *mux.Router) {
+	ah := handler.NewAPIKeyHandler()
+
+	// API 密钥管理
+	r.HandleFunc("/apikey", ah.Create).Methods("POST")
+	r.HandleFunc("/apikey/list", ah.List).Methods("GET")
+	r.HandleFunc("/apikey/delete", ah.Revoke).Methods("DELETE")
+}
+
 func WebSocketRegister(r *mux.Router) {
 	ws := handler.NewWebSocketHandler()
 
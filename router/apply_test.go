@@ -0,0 +1,73 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rehiy/web-modem/handler"
+)
+
+// TestRoutesDocumented 检查注册的接口与接口描述文档一一对应
+func TestRoutesDocumented(t *testing.T) {
+	r := mux.NewRouter()
+	ModemRegister(r)
+	SmsdbRegister(r)
+	WebhookRegister(r)
+	RuleRegister(r)
+	RejectListRegister(r)
+	TemplateRegister(r)
+	APIKeyRegister(r)
+
+	routes := map[string]bool{}
+	r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, _ := route.GetMethods()
+		for _, method := range methods {
+			routes[strings.ToLower(method)+" "+path] = true
+		}
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	handler.OpenAPI(w, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	var spec struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("decode spec: %v", err)
+	}
+	documented := map[string]bool{}
+	for path, ops := range spec.Paths {
+		for method := range ops {
+			documented[method+" "+path] = true
+		}
+	}
+
+	var missing, stale []string
+	for route := range routes {
+		if !documented[route] {
+			missing = append(missing, route)
+		}
+	}
+	for route := range documented {
+		// 签发令牌的接口只在设置 JWT_SECRET 时注册
+		if !routes[route] && route != "post /auth/token" {
+			stale = append(stale, route)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(stale)
+	if len(missing) > 0 {
+		t.Errorf("routes missing from spec: %v", missing)
+	}
+	if len(stale) > 0 {
+		t.Errorf("spec paths without route: %v", stale)
+	}
+}
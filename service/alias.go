@@ -0,0 +1,147 @@
+package service
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sync"
+
+	"github.com/rehiy/web-modem/database"
+)
+
+// aliasRe 别名允许字母、数字、下划线、点和连字符
+var aliasRe = regexp.MustCompile(`^[\w.\-]{1,64}$`)
+
+var (
+	aliasMu sync.RWMutex
+	aliases = map[string]string{} // 端口名 -> 别名
+)
+
+// LoadAliases 从模块配置加载别名，启动时调用
+func LoadAliases() error {
+	configs, err := database.GetModemConfigs()
+	if err != nil {
+		return err
+	}
+
+	aliasMu.Lock()
+	defer aliasMu.Unlock()
+	clear(aliases)
+	for _, config := range configs {
+		if config.Alias != "" {
+			aliases[config.Name] = config.Alias
+		}
+	}
+	return nil
+}
+
+// AliasOf 返回端口的别名，未设置时为空
+func AliasOf(port string) string {
+	aliasMu.RLock()
+	defer aliasMu.RUnlock()
+	return aliases[port]
+}
+
+// portName 将设备路径、端口名或别名统一转换为端口名
+func portName(u string) string {
+	n := path.Base(u)
+	aliasMu.RLock()
+	defer aliasMu.RUnlock()
+	if _, ok := aliases[n]; ok {
+		return n
+	}
+	for port, alias := range aliases {
+		if alias == n {
+			return port
+		}
+	}
+	return n
+}
+
+// PortName 将别名解析为端口名，不是别名时返回端口名本身
+func PortName(u string) string {
+	return portName(u)
+}
+
+// validateAlias 校验别名格式，且不能与其他端口的别名、已知端口名或设备路径重复
+// known 为已知的端口名或设备路径，别名与其相同时 portName 无法区分两者
+func validateAlias(port, alias string, known []string) error {
+	if alias == "" {
+		return nil
+	}
+	if !aliasRe.MatchString(alias) {
+		return fmt.Errorf("%w: alias may only contain letters, digits, '_', '.' and '-' (max 64)", ErrInvalid)
+	}
+	for _, k := range known {
+		if n := path.Base(k); n != port && (n == alias || k == alias) {
+			return fmt.Errorf("%w: alias %q conflicts with port %s", ErrInvalid, alias, k)
+		}
+	}
+
+	aliasMu.RLock()
+	defer aliasMu.RUnlock()
+	for p, a := range aliases {
+		if p != port && (a == alias || p == alias) {
+			return fmt.Errorf("%w: alias %q is already used by %s", ErrInvalid, alias, p)
+		}
+	}
+	return nil
+}
+
+// knownPorts 返回已连接、已配置和当前扫描到的端口
+func (m *ModemService) knownPorts() []string {
+	m.mu.Lock()
+	known := make([]string, 0, len(m.pool))
+	for n := range m.pool {
+		known = append(known, n)
+	}
+	m.mu.Unlock()
+
+	known = append(known, discoverer.Discover(nil)...)
+	if configs, err := database.GetModemConfigs(); err == nil {
+		for _, config := range configs {
+			known = append(known, config.Name)
+		}
+	}
+	return known
+}
+
+// setAlias 更新别名缓存，并同步到已连接的模块
+func (m *ModemService) setAlias(port, alias string) {
+	aliasMu.Lock()
+	if alias == "" {
+		delete(aliases, port)
+	} else {
+		aliases[port] = alias
+	}
+	aliasMu.Unlock()
+
+	m.mu.Lock()
+	modem := m.pool[port]
+	m.mu.Unlock()
+	if modem != nil {
+		modem.stateMu.Lock()
+		modem.Alias = alias
+		modem.stateMu.Unlock()
+	}
+}
+
+// SetAlias 设置端口别名，alias 为空时清除
+func (m *ModemService) SetAlias(port, alias string) error {
+	port = portName(port)
+	if err := validateAlias(port, alias, m.knownPorts()); err != nil {
+		return err
+	}
+
+	config, err := database.GetModemConfig(port)
+	if err != nil {
+		return err
+	}
+	config.Alias = alias
+	if err := database.SaveModemConfig(config); err != nil {
+		return err
+	}
+
+	m.setAlias(port, alias)
+	return nil
+}
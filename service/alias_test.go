@@ -0,0 +1,41 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateAlias(t *testing.T) {
+	aliasMu.Lock()
+	aliases["ttyUSB2"] = "backup"
+	aliasMu.Unlock()
+	t.Cleanup(func() {
+		aliasMu.Lock()
+		delete(aliases, "ttyUSB2")
+		aliasMu.Unlock()
+	})
+	known := []string{"/dev/ttyUSB0", "/dev/ttyUSB1", "COM3"}
+
+	for _, tc := range []struct {
+		port, alias string
+		ok          bool
+	}{
+		{"ttyUSB0", "primary", true},
+		{"ttyUSB0", "", true},
+		{"ttyUSB0", "ttyUSB0", true}, // 与自身端口名相同不会混淆
+		{"ttyUSB0", "ttyUSB1", false},
+		{"ttyUSB0", "COM3", false},
+		{"ttyUSB0", "ttyUSB2", false},
+		{"ttyUSB0", "backup", false},
+		{"ttyUSB2", "backup", true},
+		{"ttyUSB0", "bad/alias", false},
+	} {
+		err := validateAlias(tc.port, tc.alias, known)
+		if tc.ok && err != nil {
+			t.Errorf("%s -> %q: %v", tc.port, tc.alias, err)
+		}
+		if !tc.ok && !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s -> %q: got %v, want ErrInvalid", tc.port, tc.alias, err)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rehiy/web-modem/database"
+	"github.com/rehiy/web-modem/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrAPIKeyNotFound 吊销不存在的密钥
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// apiKeyFileCheckInterval 检查密钥文件变更的间隔
+const apiKeyFileCheckInterval = 10 * time.Second
+
+// apiKeyFileEntry 密钥文件中的条目
+type apiKeyFileEntry struct {
+	Key         string     `json:"key"`
+	Description string     `json:"description"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// apiKey 只保存密钥的 bcrypt 哈希
+type apiKey struct {
+	models.APIKeyInfo
+	hash []byte
+}
+
+// APIKeyStore API 密钥，来自 API_KEYS 环境变量（逗号分隔）、API_KEYS_FILE 指向的 JSON 文件和接口生成的密钥
+// 密钥格式为 <编号>.<密文>，校验时按编号查找，每次最多计算一次 bcrypt
+// 密钥文件变更后自动重新加载，生成的密钥和吊销记录保存在数据库中
+type APIKeyStore struct {
+	mu       sync.RWMutex
+	env      []apiKey
+	file     []apiKey
+	stored   []apiKey
+	revoked  map[string]bool
+	verified map[[sha256.Size]byte]string // 已验证密钥的摘要 -> 编号，避免每次请求都计算 bcrypt
+
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// APIKeys 全局 API 密钥
+var APIKeys = &APIKeyStore{}
+
+// splitAPIKey 拆分密钥中的编号和密文
+func splitAPIKey(key string) (id, secret string, ok bool) {
+	id, secret, ok = strings.Cut(key, ".")
+	return id, secret, ok && id != "" && secret != ""
+}
+
+func newAPIKey(key, description, source string, expiresAt *time.Time) (apiKey, error) {
+	id, _, ok := splitAPIKey(key)
+	if !ok {
+		return apiKey{}, fmt.Errorf("%w: api key must be in the form <id>.<secret>", ErrInvalid)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(key), bcrypt.DefaultCost)
+	if err != nil {
+		return apiKey{}, err
+	}
+	return apiKey{
+		APIKeyInfo: models.APIKeyInfo{ID: id, Description: description, Source: source, ExpiresAt: expiresAt},
+		hash:       hash,
+	}, nil
+}
+
+// storedAPIKey 转换数据库中保存的密钥
+func storedAPIKey(k models.APIKey) apiKey {
+	return apiKey{
+		APIKeyInfo: models.APIKeyInfo{ID: k.ID, Description: k.Description, Source: "db", ExpiresAt: k.ExpiresAt},
+		hash:       []byte(k.Hash),
+	}
+}
+
+// LoadAPIKeys 加载密钥和吊销记录，设置了密钥文件时启动变更检查，启动时调用
+func LoadAPIKeys() error {
+	s := APIKeys
+	revoked, err := database.GetRevokedAPIKeys()
+	if err != nil {
+		return err
+	}
+	records, err := database.GetAPIKeys()
+	if err != nil {
+		return err
+	}
+	stored := make([]apiKey, 0, len(records))
+	for _, k := range records {
+		stored = append(stored, storedAPIKey(k))
+	}
+
+	env := []apiKey{}
+	for _, key := range strings.Split(os.Getenv("API_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key == "" {
+			continue
+		}
+		k, err := newAPIKey(key, "API_KEYS", "env", nil)
+		if err != nil {
+			return fmt.Errorf("API_KEYS: %w", err)
+		}
+		env = append(env, k)
+	}
+
+	s.mu.Lock()
+	s.env = env
+	s.stored = stored
+	s.revoked = map[string]bool{}
+	for _, id := range revoked {
+		s.revoked[id] = true
+	}
+	s.verified = map[[sha256.Size]byte]string{}
+	s.path = os.Getenv("API_KEYS_FILE")
+	s.mu.Unlock()
+
+	if s.path == "" {
+		return nil
+	}
+	err = s.reloadFile()
+	go s.watchFile()
+	return err
+}
+
+// reloadFile 文件大小或修改时间变化时重新加载密钥文件
+func (s *APIKeyStore) reloadFile() error {
+	st, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	if st.ModTime().Equal(s.modTime) && st.Size() == s.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var entries []apiKeyFileEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("parse %s: %w", s.path, err)
+	}
+
+	keys := make([]apiKey, 0, len(entries))
+	for i, e := range entries {
+		if e.Key == "" {
+			return fmt.Errorf("parse %s: entry %d has no key", s.path, i)
+		}
+		k, err := newAPIKey(e.Key, e.Description, "file", e.ExpiresAt)
+		if err != nil {
+			return fmt.Errorf("parse %s: entry %d: %w", s.path, i, err)
+		}
+		keys = append(keys, k)
+	}
+
+	s.mu.Lock()
+	s.file = keys
+	s.verified = map[[sha256.Size]byte]string{}
+	s.mu.Unlock()
+	s.modTime, s.size = st.ModTime(), st.Size()
+	slog.Info("api keys loaded", slog.String("file", s.path), slog.Int("keys", len(keys)))
+	return nil
+}
+
+// watchFile 定期检查密钥文件，加载失败时保留原有密钥
+func (s *APIKeyStore) watchFile() {
+	for range time.Tick(apiKeyFileCheckInterval) {
+		if err := s.reloadFile(); err != nil {
+			slog.Error("failed to reload api keys", slog.String("file", s.path), slog.Any("error", err))
+		}
+	}
+}
+
+// Enabled 是否配置了 API 密钥
+func (s *APIKeyStore) Enabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.env)+len(s.file)+len(s.stored) > 0
+}
+
+// find 按编号查找密钥，调用方需持有锁
+func (s *APIKeyStore) find(id string) *apiKey {
+	for _, list := range [][]apiKey{s.env, s.file, s.stored} {
+		for i := range list {
+			if list[i].ID == id {
+				return &list[i]
+			}
+		}
+	}
+	return nil
+}
+
+// usable 密钥未吊销且未过期，调用方需持有锁
+func (s *APIKeyStore) usable(k *apiKey, now time.Time) bool {
+	return !s.revoked[k.ID] && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// Verify 校验密钥，成功时返回密钥编号
+// 按编号找到唯一的候选密钥，编号不存在时不计算 bcrypt
+func (s *APIKeyStore) Verify(key string) (string, bool) {
+	id, _, ok := splitAPIKey(key)
+	if !ok {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(key))
+	now := time.Now()
+
+	s.mu.RLock()
+	k := s.find(id)
+	if k == nil || !s.usable(k, now) {
+		s.mu.RUnlock()
+		return "", false
+	}
+	if s.verified[sum] == id {
+		s.mu.RUnlock()
+		return id, true
+	}
+	hash := k.hash
+	s.mu.RUnlock()
+
+	if bcrypt.CompareHashAndPassword(hash, []byte(key)) != nil {
+		return "", false
+	}
+	s.mu.Lock()
+	s.verified[sum] = id
+	s.mu.Unlock()
+	return id, true
+}
+
+// Generate 生成随机编号和密文的新密钥并保存哈希，密钥只在生成时返回一次
+func (s *APIKeyStore) Generate(description string, expiresAt *time.Time) (string, *models.APIKeyInfo, error) {
+	idBuf, secretBuf := make([]byte, 8), make([]byte, 24)
+	if _, err := rand.Read(idBuf); err != nil {
+		return "", nil, err
+	}
+	if _, err := rand.Read(secretBuf); err != nil {
+		return "", nil, err
+	}
+	key := hex.EncodeToString(idBuf) + "." + base64.RawURLEncoding.EncodeToString(secretBuf)
+
+	k, err := newAPIKey(key, description, "db", expiresAt)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := database.CreateAPIKey(&models.APIKey{ID: k.ID, Description: description, Hash: string(k.hash), ExpiresAt: expiresAt}); err != nil {
+		return "", nil, err
+	}
+
+	s.mu.Lock()
+	s.stored = append(s.stored, k)
+	s.mu.Unlock()
+	slog.Info("api key created", slog.String("id", k.ID))
+	info := k.APIKeyInfo
+	return key, &info, nil
+}
+
+// List 列出全部密钥信息，不包含密钥本身
+func (s *APIKeyStore) List() []models.APIKeyInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	infos := []models.APIKeyInfo{}
+	for _, list := range [][]apiKey{s.env, s.file, s.stored} {
+		for _, k := range list {
+			info := k.APIKeyInfo
+			info.Revoked = s.revoked[k.ID]
+			info.Expired = k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// Revoke 吊销密钥，吊销记录持久保存，密钥文件重新加载后仍然有效
+func (s *APIKeyStore) Revoke(id string) error {
+	s.mu.RLock()
+	found := s.find(id) != nil
+	s.mu.RUnlock()
+	if !found {
+		return ErrAPIKeyNotFound
+	}
+
+	if err := database.RevokeAPIKey(id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.revoked[id] = true
+	s.mu.Unlock()
+	slog.Info("api key revoked", slog.String("id", id))
+	return nil
+}
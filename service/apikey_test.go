@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rehiy/web-modem/database"
+)
+
+// newTestKeyStore 创建只包含给定环境变量密钥的密钥库
+func newTestKeyStore(t *testing.T, keys ...string) *APIKeyStore {
+	t.Helper()
+	s := &APIKeyStore{revoked: map[string]bool{}, verified: map[[32]byte]string{}}
+	for _, key := range keys {
+		k, err := newAPIKey(key, "", "env", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.env = append(s.env, k)
+	}
+	return s
+}
+
+func TestNewAPIKeyRequiresID(t *testing.T) {
+	for _, key := range []string{"secret", ".secret", "id."} {
+		if _, err := newAPIKey(key, "", "env", nil); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%q: err = %v", key, err)
+		}
+	}
+}
+
+func TestAPIKeyVerify(t *testing.T) {
+	s := newTestKeyStore(t, "ci.first-secret", "ops.second-secret")
+
+	for i := 0; i < 2; i++ { // 第二次命中已验证缓存
+		if id, ok := s.Verify("ops.second-secret"); !ok || id != "ops" {
+			t.Fatalf("valid key: %q, %v", id, ok)
+		}
+	}
+	for _, key := range []string{"ops.first-secret", "unknown.second-secret", "second-secret", ""} {
+		if _, ok := s.Verify(key); ok {
+			t.Errorf("%q accepted", key)
+		}
+	}
+
+	s.revoked["ops"] = true
+	if _, ok := s.Verify("ops.second-secret"); ok {
+		t.Fatal("revoked key accepted from cache")
+	}
+
+	past := time.Now().Add(-time.Minute)
+	s.env[0].ExpiresAt = &past
+	if _, ok := s.Verify("ci.first-secret"); ok {
+		t.Fatal("expired key accepted")
+	}
+}
+
+func TestAPIKeyGenerate(t *testing.T) {
+	initTestDB(t)
+	s := newTestKeyStore(t)
+
+	key, info, err := s.Generate("deploy", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _, ok := splitAPIKey(key)
+	if !ok || id != info.ID || len(id) != 16 || info.Source != "db" {
+		t.Fatalf("key %q, info %+v", key, info)
+	}
+	if got, ok := s.Verify(key); !ok || got != id {
+		t.Fatalf("generated key rejected: %q, %v", got, ok)
+	}
+
+	// 只保存哈希，重启后仍可校验
+	records, err := database.GetAPIKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded := newTestKeyStore(t)
+	for _, r := range records {
+		if r.Hash == key {
+			t.Fatal("plain key stored")
+		}
+		reloaded.stored = append(reloaded.stored, storedAPIKey(r))
+	}
+	if _, ok := reloaded.Verify(key); !ok {
+		t.Fatal("stored key rejected after reload")
+	}
+}
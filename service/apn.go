@@ -0,0 +1,116 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+var (
+	// apnRe APN 只允许字母、数字、点和连字符，避免注入 AT 命令
+	apnRe = regexp.MustCompile(`^[a-zA-Z0-9.\-]+$`)
+	// apnCredentialRe 用户名和密码允许除双引号外的可打印 ASCII 字符
+	apnCredentialRe = regexp.MustCompile(`^[\x20\x21\x23-\x7e]*$`)
+)
+
+// pdpTypes AT+CGDCONT 支持的 PDP 类型
+var pdpTypes = map[string]bool{"IP": true, "IPV6": true, "IPV4V6": true}
+
+// ValidateAPN 校验 APN 配置，并填充默认的上下文编号和 PDP 类型
+func ValidateAPN(cfg *models.APNConfig) error {
+	if cfg.CID == 0 {
+		cfg.CID = 1
+	}
+	if cfg.PDPType == "" {
+		cfg.PDPType = "IP"
+	}
+	if err := validateCID(cfg.CID); err != nil {
+		return err
+	}
+	if !pdpTypes[cfg.PDPType] {
+		return fmt.Errorf("%w: pdp type must be one of IP, IPV6, IPV4V6", ErrInvalid)
+	}
+	if !apnRe.MatchString(cfg.APN) {
+		return fmt.Errorf("%w: apn may only contain letters, digits, '.' and '-'", ErrInvalid)
+	}
+	if !apnCredentialRe.MatchString(cfg.User) || !apnCredentialRe.MatchString(cfg.Password) {
+		return fmt.Errorf("%w: user and password must be printable ASCII without '\"'", ErrInvalid)
+	}
+	return nil
+}
+
+// validateCID 校验 PDP 上下文编号
+func validateCID(cid int) error {
+	if cid < 1 || cid > 24 {
+		return fmt.Errorf("%w: cid must be 1-24", ErrInvalid)
+	}
+	return nil
+}
+
+// SetAPN 设置 PDP 上下文的 APN，提供用户名时使用 PAP 认证，否则清除认证信息
+func (m *ModemInfo) SetAPN(cfg models.APNConfig) error {
+	if err := ValidateAPN(&cfg); err != nil {
+		return err
+	}
+
+	if err := m.sendCheck(fmt.Sprintf(`AT+CGDCONT=%d,"%s","%s"`, cfg.CID, cfg.PDPType, cfg.APN), atTimeout); err != nil {
+		return err
+	}
+	if cfg.User == "" {
+		m.SendCommand(fmt.Sprintf("AT+CGAUTH=%d,0", cfg.CID)) // 不支持 AT+CGAUTH 的模块忽略
+		return nil
+	}
+	return m.sendCheck(fmt.Sprintf(`AT+CGAUTH=%d,1,"%s","%s"`, cfg.CID, cfg.User, cfg.Password), atTimeout)
+}
+
+// ActivatePDP 激活 PDP 上下文
+func (m *ModemInfo) ActivatePDP(cid int) error {
+	if err := validateCID(cid); err != nil {
+		return err
+	}
+	if m.InDataMode() {
+		return ErrDataMode
+	}
+	return m.sendCheck(fmt.Sprintf("AT+CGACT=1,%d", cid), activateTimeout)
+}
+
+// DeactivatePDP 去激活 PDP 上下文
+func (m *ModemInfo) DeactivatePDP(cid int) error {
+	if err := validateCID(cid); err != nil {
+		return err
+	}
+	if m.InDataMode() {
+		return ErrDataMode
+	}
+	return m.sendCheck(fmt.Sprintf("AT+CGACT=0,%d", cid), activateTimeout)
+}
+
+// GetPDPStatus 查询 PDP 上下文是否激活及分配的地址
+func (m *ModemInfo) GetPDPStatus(cid int) (*models.PDPStatus, error) {
+	if err := validateCID(cid); err != nil {
+		return nil, err
+	}
+	if m.InDataMode() {
+		return nil, ErrDataMode
+	}
+
+	status := &models.PDPStatus{CID: cid, Attached: m.packetAttached(), Active: m.contextActive(cid)}
+	if !status.Active {
+		return status, nil
+	}
+
+	// +CGPADDR: <cid>,<address>[,<ipv6 address>]
+	responses, err := m.SendCommand(fmt.Sprintf("AT+CGPADDR=%d", cid))
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label == "+CGPADDR" && len(param) > 1 && param[0] == strconv.Itoa(cid) && param[1] != "0.0.0.0" {
+			status.IP = param[1]
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// 短信负载均衡策略
+const (
+	StrategyRoundRobin     = "round-robin"     // 按端口名顺序轮流使用
+	StrategySignalStrength = "signal-strength" // 优先使用最近采样信号最强的模块
+)
+
+// ErrNoModem 没有可用于发送的模块
+var ErrNoModem = errors.New("no modem available")
+
+// balanceCandidates 按策略排序可用模块，跳过已被租用的模块
+func (m *ModemService) balanceCandidates(strategy string) ([]*ModemInfo, error) {
+	if strategy != "" && strategy != StrategyRoundRobin && strategy != StrategySignalStrength {
+		return nil, fmt.Errorf("%w: strategy must be %s or %s", ErrInvalid, StrategyRoundRobin, StrategySignalStrength)
+	}
+
+	modems := []*ModemInfo{}
+	for _, modem := range m.GetModems() {
+		if modem.IsOpen() && m.CheckLease(modem.Name, "") == nil {
+			modems = append(modems, modem)
+		}
+	}
+	if len(modems) == 0 {
+		return nil, ErrNoModem
+	}
+	sort.Slice(modems, func(i, j int) bool { return modems[i].Name < modems[j].Name })
+
+	switch strategy {
+	case "", StrategyRoundRobin:
+		start := int((m.balance.Add(1) - 1) % uint64(len(modems)))
+		modems = append(modems[start:], modems[:start]...)
+	case StrategySignalStrength:
+		rssi := map[*ModemInfo]int{}
+		for _, modem := range modems {
+			rssi[modem] = -1
+			if s := modem.GetSignalHistory(1); len(s) == 1 && s[0].RSSI != 99 {
+				rssi[modem] = s[0].RSSI
+			}
+		}
+		sort.SliceStable(modems, func(i, j int) bool { return rssi[modems[i]] > rssi[modems[j]] })
+	}
+	return modems, nil
+}
+
+// LoadBalancedSend 按策略选择模块发送短信，返回实际使用的端口名
+// 选中的模块发送失败时依次尝试下一个；参数无效或已有 PDU 写入模块时不再重试，避免重复发送
+func (m *ModemService) LoadBalancedSend(ctx context.Context, number, message, strategy string) (string, error) {
+	modems, err := m.balanceCandidates(strategy)
+	if err != nil {
+		return "", err
+	}
+
+	for _, modem := range modems {
+		_, err = modem.SendSMS(ctx, number, message, SendOptions{})
+		if err == nil {
+			return modem.Name, nil
+		}
+		if errors.Is(err, ErrSMSSubmitted) || errors.Is(err, ErrInvalid) || ctx.Err() != nil {
+			return modem.Name, err
+		}
+		slog.WarnContext(ctx, "balanced sms failed, trying next modem", slog.String("port", modem.Name), slog.Any("error", err))
+	}
+	return "", fmt.Errorf("%w: all %d modems failed: %v", ErrNoModem, len(modems), err)
+}
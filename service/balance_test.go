@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLoadBalancedSendStopsAfterSubmit(t *testing.T) {
+	a, b := rejectSubmit(1), rejectSubmit(1)
+	s := newSendTestService(t, a, b)
+
+	port, err := s.LoadBalancedSend(context.Background(), "10086", "hello", StrategyRoundRobin)
+	if !errors.Is(err, ErrSMSSubmitted) || port == "" {
+		t.Fatalf("port %q, err %v", port, err)
+	}
+	if len(a.received()) > 0 && len(b.received()) > 0 {
+		t.Fatal("second modem used after submit")
+	}
+}
+
+func TestLoadBalancedSendTriesNextBeforeSubmit(t *testing.T) {
+	s := newSendTestService(t, rejectPrompt(), smsModem(1))
+
+	for i := 0; i < 2; i++ {
+		port, err := s.LoadBalancedSend(context.Background(), "10086", "hello", StrategyRoundRobin)
+		if err != nil || port != "ttyB" {
+			t.Fatalf("round %d: port %q, err %v", i, port, err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// lteBandwidths LTE 带宽编码（0-5）对应的带宽
+var lteBandwidths = []string{"1.4MHz", "3MHz", "5MHz", "10MHz", "15MHz", "20MHz"}
+
+// huaweiSysModes Huawei ^HFREQINFO 系统模式
+var huaweiSysModes = map[string]string{
+	"1": "GSM", "3": "WCDMA", "4": "TD-SCDMA", "6": "LTE", "7": "NR5G",
+}
+
+func init() {
+	RegisterParser("", `AT+QENG="servingcell"`, parseQuectelActiveBand)
+	RegisterParser("", "AT^HFREQINFO?", parseHuaweiActiveBand)
+	RegisterParser("", "AT+CPSI?", parseSimcomActiveBand)
+}
+
+// GetActiveBand 查询当前驻留的频段和信道
+func (m *ModemInfo) GetActiveBand() (*models.ActiveBand, error) {
+	cmd, ok := vendorCommand("active_band", m.Vendor)
+	if !ok {
+		return nil, fmt.Errorf("active band %w by %s modem", ErrUnsupported, m.Vendor)
+	}
+
+	v, err := m.Query(cmd)
+	if err != nil {
+		return nil, err
+	}
+	band, ok := v.(*models.ActiveBand)
+	if !ok {
+		return nil, fmt.Errorf("unexpected active band parser result %T", v)
+	}
+	return band, nil
+}
+
+// parseChannel 解析信道号，无法解析时返回 -1
+func parseChannel(s string) int {
+	if v, err := strconv.Atoi(s); err == nil {
+		return v
+	}
+	return -1
+}
+
+// lteBandwidth 转换 LTE 带宽编码，无法识别时原样返回
+func lteBandwidth(code string) string {
+	if i, err := strconv.Atoi(code); err == nil && i >= 0 && i < len(lteBandwidths) {
+		return lteBandwidths[i]
+	}
+	return code
+}
+
+// parseQuectelActiveBand 解析 +QENG: "servingcell",<state>,<rat>,...
+func parseQuectelActiveBand(responses []string) (any, error) {
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "+QENG" || len(param) < 3 || param[0] != "servingcell" {
+			continue
+		}
+		band := &models.ActiveBand{Rat: param[2], Channel: -1, Raw: line}
+		switch {
+		case param[2] == "LTE" && len(param) >= 12:
+			// "servingcell",<state>,"LTE",<is_tdd>,<mcc>,<mnc>,<cellid>,<pcid>,<earfcn>,<band>,<ul_bw>,<dl_bw>,...
+			band.Duplex = param[3]
+			band.Channel = parseChannel(param[8])
+			band.Band = "LTE BAND " + param[9]
+			band.Bandwidth = lteBandwidth(param[11])
+		case strings.HasPrefix(param[2], "NR5G-SA") && len(param) >= 12:
+			// "servingcell",<state>,"NR5G-SA",<duplex>,<mcc>,<mnc>,<cellid>,<pcid>,<tac>,<arfcn>,<band>,<dl_bw>,...
+			band.Rat = "NR5G"
+			band.Duplex = param[3]
+			band.Channel = parseChannel(param[9])
+			band.Band = "NR5G BAND " + param[10]
+			band.Bandwidth = param[11]
+		case param[2] == "WCDMA" && len(param) >= 8:
+			// "servingcell",<state>,"WCDMA",<mcc>,<mnc>,<lac>,<cellid>,<uarfcn>,...
+			band.Duplex = "FDD"
+			band.Channel = parseChannel(param[7])
+		case param[2] == "GSM" && len(param) >= 10:
+			// "servingcell",<state>,"GSM",<mcc>,<mnc>,<lac>,<cellid>,<bsic>,<arfcn>,<band>,...
+			band.Channel = parseChannel(param[8])
+			band.Band = "GSM " + param[9]
+		}
+		return band, nil
+	}
+	return nil, fmt.Errorf("failed to parse active band")
+}
+
+// parseHuaweiActiveBand 解析 ^HFREQINFO: <n>,<sysmode>,<band>,<dl_fcn>,<dl_freq>,<dl_bw>,<ul_fcn>,<ul_freq>,<ul_bw>
+func parseHuaweiActiveBand(responses []string) (any, error) {
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "^HFREQINFO" || len(param) < 2 {
+			continue
+		}
+		band := &models.ActiveBand{Rat: huaweiSysModes[param[1]], Channel: -1, Raw: line}
+		if len(param) >= 6 {
+			band.Band = strings.TrimSpace(band.Rat + " BAND " + param[2])
+			band.Channel = parseChannel(param[3])
+			// 带宽单位为 kHz
+			if bw, err := strconv.Atoi(param[5]); err == nil {
+				band.Bandwidth = strconv.FormatFloat(float64(bw)/1000, 'f', -1, 64) + "MHz"
+			}
+		}
+		return band, nil
+	}
+	return nil, fmt.Errorf("failed to parse active band")
+}
+
+// parseSimcomActiveBand 解析 +CPSI: <rat>,<status>,...
+// LTE 格式：LTE,Online,<mcc-mnc>,<tac>,<cellid>,<pcid>,<band>,<earfcn>,<dlbw>,<ulbw>,...
+func parseSimcomActiveBand(responses []string) (any, error) {
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "+CPSI" || len(param) < 1 {
+			continue
+		}
+		band := &models.ActiveBand{Rat: param[0], Channel: -1, Raw: line}
+		switch {
+		case param[0] == "LTE" && len(param) >= 9:
+			band.Band = strings.Replace(param[6], "EUTRAN-BAND", "LTE BAND ", 1)
+			band.Channel = parseChannel(param[7])
+			band.Bandwidth = lteBandwidth(param[8])
+		case param[0] == "WCDMA" && len(param) >= 8:
+			band.Band = param[6]
+			band.Channel = parseChannel(param[7])
+			band.Duplex = "FDD"
+		}
+		return band, nil
+	}
+	return nil, fmt.Errorf("failed to parse active band")
+}
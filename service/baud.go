@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// baudRates 支持切换的标准波特率
+var baudRates = map[int]bool{
+	9600: true, 19200: true, 38400: true, 57600: true,
+	115200: true, 230400: true, 460800: true, 921600: true,
+}
+
+// SetBaud 通过 AT+IPR 切换模块波特率，并以新波特率重新打开串口
+// 整个切换过程在命令队列中执行，期间不会插入其他命令
+// 新波特率下无法通信时按原波特率重新打开并确认模块响应，恢复失败时一并返回
+func (m *ModemInfo) SetBaud(rate int) error {
+	if !baudRates[rate] {
+		return fmt.Errorf("%w: unsupported baud rate %d", ErrInvalid, rate)
+	}
+
+	var err error
+	if qerr := m.exec(false, func() {
+		err = m.switchBaud(rate)
+	}); qerr != nil {
+		return qerr
+	}
+	return err
+}
+
+// switchBaud 切换波特率，仅在命令队列的调度协程中调用
+func (m *ModemInfo) switchBaud(rate int) error {
+	old := m.port.Baud()
+	if rate == old {
+		return nil
+	}
+
+	// 模块以原波特率返回 OK 后切换，必须在下一条命令前重新打开串口
+	if err := m.checkCommand(fmt.Sprintf("AT+IPR=%d", rate)); err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if err := m.port.Reopen(rate); err != nil {
+		return m.restoreBaud(old, fmt.Errorf("reopen port at %d: %v", rate, err))
+	}
+	if err := m.checkCommand("AT"); err != nil {
+		return m.restoreBaud(old, fmt.Errorf("modem not responding at %d: %v", rate, err))
+	}
+
+	m.setBaud(rate)
+	return nil
+}
+
+// restoreBaud 按原波特率重新打开串口，兼容未实际切换的模块
+// 恢复成功时返回 cause；重新打开失败或模块仍无响应时返回包含两者的错误，连接需要重新扫描
+func (m *ModemInfo) restoreBaud(old int, cause error) error {
+	if err := m.port.Reopen(old); err != nil {
+		return fmt.Errorf("%v; reopen port at previous baud %d: %v", cause, old, err)
+	}
+	if err := m.checkCommand("AT"); err != nil {
+		return fmt.Errorf("%v; modem not responding at previous baud %d either: %v", cause, old, err)
+	}
+	return cause
+}
+
+// checkCommand 直接发送命令并检查最终响应，仅在命令队列的调度协程中调用
+func (m *ModemInfo) checkCommand(cmd string) error {
+	responses, err := m.sendCommand(cmd)
+	if err != nil {
+		return err
+	}
+	if len(responses) == 0 {
+		return fmt.Errorf("no response")
+	}
+	return finalError(responses)
+}
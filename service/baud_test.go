@@ -0,0 +1,104 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rehiy/modem/at"
+	"github.com/tarm/serial"
+)
+
+// baudModem 模拟切换波特率的模块：串口以 open 返回的波特率打开，只有与模块当前波特率一致时才有响应
+type baudModem struct {
+	current  int  // 模块当前波特率
+	switches bool // 收到 AT+IPR 后是否切换
+	openErr  map[int]error
+}
+
+func (b *baudModem) open(c *serial.Config) (at.Port, error) {
+	if err := b.openErr[c.Baud]; err != nil {
+		return nil, err
+	}
+	f := newFakeSerial()
+	script := &scriptedModem{silent: c.Baud != b.current}
+	script.attach(f)
+	next := f.onWrite
+	f.onWrite = func(f *fakeSerial, data []byte) {
+		next(f, data)
+		if b.switches && strings.HasPrefix(string(data), "AT+IPR=") {
+			b.current = 921600
+		}
+	}
+	return f, nil
+}
+
+func newBaudTestModem(t *testing.T, b *baudModem) *ModemInfo {
+	t.Helper()
+	m, _ := newTestModem(t, &scriptedModem{})
+	config := &serial.Config{Name: "/dev/ttyFAKE0", Baud: 115200}
+	port, _ := b.open(config)
+	m.port.mu.Lock()
+	m.port.config, m.port.port, m.port.open = config, port, b.open
+	m.port.mu.Unlock()
+	return m
+}
+
+func TestSetBaudSwitches(t *testing.T) {
+	m := newBaudTestModem(t, &baudModem{current: 115200, switches: true})
+	if err := m.SetBaud(921600); err != nil {
+		t.Fatalf("SetBaud: %v", err)
+	}
+	if m.port.Baud() != 921600 || m.Baud != 921600 {
+		t.Fatalf("port baud %d, modem baud %d, want 921600", m.port.Baud(), m.Baud)
+	}
+}
+
+func TestSetBaudRestoresOldRate(t *testing.T) {
+	m := newBaudTestModem(t, &baudModem{current: 115200})
+	err := m.SetBaud(921600)
+	if err == nil || !strings.Contains(err.Error(), "not responding at 921600") {
+		t.Fatalf("SetBaud: %v", err)
+	}
+	if strings.Contains(err.Error(), "previous") {
+		t.Fatalf("restore should have succeeded: %v", err)
+	}
+	if m.port.Baud() != 115200 || m.Baud != 115200 {
+		t.Fatalf("port baud %d, modem baud %d, want 115200", m.port.Baud(), m.Baud)
+	}
+	if err := m.checkCommand("AT"); err != nil {
+		t.Fatalf("modem not usable after restore: %v", err)
+	}
+}
+
+func TestSetBaudReportsFailedRestore(t *testing.T) {
+	b := &baudModem{current: 115200, switches: true}
+	m := newBaudTestModem(t, b)
+	b.openErr = map[int]error{921600: errors.New("busy")}
+	// 模块已切换到新波特率，但串口无法以新波特率打开，按原波特率也不再响应
+	err := m.SetBaud(921600)
+	if err == nil || !strings.Contains(err.Error(), "previous baud 115200") {
+		t.Fatalf("SetBaud: %v", err)
+	}
+	if m.Baud != 115200 {
+		t.Fatalf("modem baud %d changed after failure", m.Baud)
+	}
+}
+
+func TestSetBaudRejectsUnsupportedRate(t *testing.T) {
+	m, _ := newTestModem(t, &scriptedModem{})
+	if err := m.SetBaud(12345); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("SetBaud: %v", err)
+	}
+}
+
+func TestBaudDoesNotBlockWhilePaused(t *testing.T) {
+	m, _ := newTestModem(t, &scriptedModem{})
+	if err := m.PauseReadLoop(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.ResumeReadLoop()
+	if m.port.Baud() != 115200 {
+		t.Fatalf("baud %d", m.port.Baud())
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// 短信承载方式
+const (
+	SMSBearerAuto = "auto" // 由模块配置决定
+	SMSBearerIMS  = "ims"  // 优先通过 IMS 发送
+	SMSBearerCS   = "cs"   // 关闭 IMS，使用电路域
+)
+
+// quectelIMSModes Quectel AT+QCFG="ims" 取值与承载方式的对应关系
+var quectelIMSModes = map[int]string{
+	0: SMSBearerAuto,
+	1: SMSBearerIMS,
+	2: SMSBearerCS,
+}
+
+func init() {
+	RegisterParser("", `AT+QCFG="ims"`, parseQuectelIMS)
+}
+
+// GetSMSBearer 查询短信承载方式
+func (m *ModemInfo) GetSMSBearer() (*models.SMSBearer, error) {
+	cmd, ok := vendorCommand("sms_bearer", m.Vendor)
+	if !ok {
+		return nil, fmt.Errorf("sms bearer %w by %s modem", ErrUnsupported, m.Vendor)
+	}
+
+	v, err := m.Query(cmd)
+	if err != nil {
+		return nil, err
+	}
+	bearer, ok := v.(*models.SMSBearer)
+	if !ok {
+		return nil, fmt.Errorf("unexpected sms bearer parser result %T", v)
+	}
+	return bearer, nil
+}
+
+// SetSMSBearer 设置短信承载方式，部分模块需重启后生效
+func (m *ModemInfo) SetSMSBearer(mode string) error {
+	cmd, ok := vendorCommand("sms_bearer_set", m.Vendor)
+	if !ok {
+		return fmt.Errorf("sms bearer %w by %s modem", ErrUnsupported, m.Vendor)
+	}
+
+	for value, name := range quectelIMSModes {
+		if name == mode {
+			return m.SendCommandExpect(fmt.Sprintf(cmd, value), "OK")
+		}
+	}
+	return fmt.Errorf("invalid sms bearer %q, must be one of auto, ims, cs", mode)
+}
+
+// parseQuectelIMS 解析 +QCFG: "ims",<value>[,<volte_state>]
+func parseQuectelIMS(responses []string) (any, error) {
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "+QCFG" || len(param) < 2 || param[0] != "ims" {
+			continue
+		}
+		value, err := strconv.Atoi(param[1])
+		if err != nil {
+			break
+		}
+		mode, ok := quectelIMSModes[value]
+		if !ok {
+			break
+		}
+		return &models.SMSBearer{Mode: mode, Value: value}, nil
+	}
+	return nil, fmt.Errorf("failed to parse sms bearer")
+}
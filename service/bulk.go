@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+const (
+	defaultBulkDelay = 500 * time.Millisecond // 默认逐条发送间隔
+	defaultMaxBulk   = 100                    // 默认单次最多发送的号码数量
+)
+
+// bulkDelay 逐条发送间隔，由 BULK_SMS_DELAY_MS 设置
+func bulkDelay() time.Duration {
+	if ms, err := strconv.Atoi(os.Getenv("BULK_SMS_DELAY_MS")); err == nil && ms >= 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return defaultBulkDelay
+}
+
+// MaxBulkSMS 单次最多发送的号码数量，由 MAX_BULK_SMS 设置
+func MaxBulkSMS() int {
+	if n, err := strconv.Atoi(os.Getenv("MAX_BULK_SMS")); err == nil && n > 0 {
+		return n
+	}
+	return defaultMaxBulk
+}
+
+// ValidateBulkSMS 校验群发请求，返回每个号码的校验结果；dry run 时不再发送
+func ValidateBulkSMS(numbers []string, message string) ([]models.BulkSMSResult, error) {
+	if len(numbers) == 0 {
+		return nil, fmt.Errorf("%w: numbers is empty", ErrInvalid)
+	}
+	if max := MaxBulkSMS(); len(numbers) > max {
+		return nil, fmt.Errorf("%w: at most %d numbers per request", ErrInvalid, max)
+	}
+	if message == "" {
+		return nil, fmt.Errorf("%w: message is empty", ErrInvalid)
+	}
+
+	results := make([]models.BulkSMSResult, len(numbers))
+	for i, number := range numbers {
+		results[i] = models.BulkSMSResult{Number: number, Success: true}
+		if !dialNumberRe.MatchString(number) {
+			results[i].Success = false
+			results[i].Error = "invalid number"
+		}
+	}
+	return results, nil
+}
+
+// SendSMSBulk 逐个号码发送同一条短信，号码之间按配置间隔等待
+// 单个号码失败不影响其他号码，结果顺序与号码顺序一致
+func (m *ModemInfo) SendSMSBulk(ctx context.Context, numbers []string, message string, opts SendOptions) ([]models.BulkSMSResult, error) {
+	results, err := ValidateBulkSMS(numbers, message)
+	if err != nil {
+		return nil, err
+	}
+
+	delay := bulkDelay()
+	sent := 0
+	for i := range results {
+		if !results[i].Success {
+			continue
+		}
+		if sent > 0 {
+			time.Sleep(delay)
+		}
+		sent++
+
+		refs, err := m.SendSMS(ctx, results[i].Number, message, opts)
+		results[i].References = refs
+		if err != nil {
+			results[i].Success = false
+			results[i].Error = err.Error()
+			slog.WarnContext(ctx, "bulk sms failed", slog.String("port", m.Name), slog.String("number", results[i].Number), slog.Any("error", err))
+		}
+	}
+	return results, nil
+}
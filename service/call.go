@@ -0,0 +1,603 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+const (
+	callHistorySize  = 200                    // 每个模块保留的通话记录数量
+	ringTimeout      = 8 * time.Second        // 超过该时间未收到 RING 且未接听，视为未接来电
+	dtmfInterval     = 100 * time.Millisecond // 默认 DTMF 按键间隔
+	maxDTMFInterval  = 5 * time.Second        // 最长 DTMF 按键间隔
+	callPollInterval = time.Second            // 通话期间 AT+CLCC 轮询间隔
+	dialWaitTimeout  = 30 * time.Second       // 等待拨号结果的最长时间
+)
+
+// callStates +CLCC 状态码描述
+var callStates = map[int]string{
+	0: "active",
+	1: "held",
+	2: "dialing",
+	3: "alerting",
+	4: "incoming",
+	5: "waiting",
+}
+
+// dtmfDigits 允许发送的 DTMF 字符
+const dtmfDigits = "0123456789*#ABCD"
+
+// ErrNoActiveCall 当前没有进行中的通话
+var ErrNoActiveCall = errors.New("no active call")
+
+// ErrCallInProgress 已有进行中的通话
+var ErrCallInProgress = errors.New("call in progress")
+
+// 通话状态
+const (
+	CallRinging    = "ringing"
+	CallDialing    = "dialing"
+	CallActive     = "active"
+	CallAnswered   = "answered"
+	CallMissed     = "missed"
+	CallUnanswered = "unanswered"
+	CallRejected   = "rejected"
+)
+
+// dialNumberRe 允许拨打的号码格式
+var dialNumberRe = regexp.MustCompile(`^\+?[0-9*#]{1,32}$`)
+
+// callLog 模块通话记录
+type callLog struct {
+	mu      sync.Mutex
+	nextID  int
+	records []*models.CallRecord
+	current *models.CallRecord // 进行中的通话
+	timer   *time.Timer        // 振铃超时计时器
+	states  []models.CallState // 最近一次 AT+CLCC 轮询结果
+	stop    chan struct{}      // 停止轮询
+}
+
+// handleCallURC 根据 RING / +CLIP / NO CARRIER / BUSY 通知更新通话记录
+func (m *ModemInfo) handleCallURC(label string, param map[int]string) {
+	switch label {
+	case "RING":
+		m.ringing("")
+	case "+CLIP":
+		m.ringing(param[0])
+	case "+CLCC":
+		// +CLCC: <id>,<dir>,<stat>,<mode>,<mpty>[,<number>,<type>]
+		if stat, err := strconv.Atoi(param[2]); err == nil {
+			m.updateCallStat(stat)
+		}
+	case "NO CARRIER", "BUSY", "NO ANSWER":
+		m.endCall(label)
+	}
+}
+
+// updateCallStat 根据 +CLCC 状态更新当前通话，0 表示通话已接通
+func (m *ModemInfo) updateCallStat(stat int) {
+	m.calls.mu.Lock()
+	defer m.calls.mu.Unlock()
+	if stat == 0 {
+		m.markConnected()
+	}
+}
+
+// markConnected 标记当前通话已接通，调用方需持有锁
+func (m *ModemInfo) markConnected() {
+	c := &m.calls
+	if c.current == nil || c.current.AnsweredAt != nil {
+		return
+	}
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+
+	now := time.Now()
+	c.current.AnsweredAt = &now
+	c.current.Status = CallActive
+	m.publishCall(c.current)
+}
+
+// ringing 记录来电振铃，number 为空表示号码未知
+func (m *ModemInfo) ringing(number string) {
+	c := &m.calls
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.current == nil {
+		m.startCall("in", CallRinging, "")
+	}
+
+	if c.current.Status != CallRinging {
+		return
+	}
+	if number != "" {
+		// 首次获取到来电号码时再次广播，号码在拒接列表中时自动拒接
+		if c.current.Number == "" {
+			c.current.Number = number
+			m.publishCall(c.current)
+			if rule, ok := RejectList.Match(number); ok {
+				go m.autoReject(number, rule)
+			}
+		}
+	} else {
+		c.current.Rings++
+	}
+
+	// 主叫挂断时部分模块不上报 NO CARRIER，以振铃停止判断未接
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	record := c.current
+	c.timer = time.AfterFunc(ringTimeout, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.current == record && record.Status == CallRinging {
+			m.finishCall()
+		}
+	})
+}
+
+// startCall 创建新的通话记录，调用方需持有锁
+func (m *ModemInfo) startCall(direction, status, number string) {
+	c := &m.calls
+	c.nextID++
+	c.current = &models.CallRecord{
+		ID:        c.nextID,
+		Number:    number,
+		Direction: direction,
+		Status:    status,
+		StartedAt: time.Now(),
+	}
+	c.records = append(c.records, c.current)
+	if len(c.records) > callHistorySize {
+		c.records = c.records[len(c.records)-callHistorySize:]
+	}
+	m.publishCall(c.current)
+
+	c.stop = make(chan struct{})
+	go m.pollCalls(c.stop)
+}
+
+// pollCalls 通话期间轮询 AT+CLCC，跟踪每路通话的状态变化
+// 连续两次没有通话时，视为通话已结束
+func (m *ModemInfo) pollCalls(stop chan struct{}) {
+	ticker := time.NewTicker(callPollInterval)
+	defer ticker.Stop()
+
+	empty := 0
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		// 数据模式下暂停轮询，避免破坏数据会话
+		if m.InDataMode() {
+			continue
+		}
+
+		states, err := m.GetCallStatus()
+		if err != nil {
+			continue
+		}
+
+		c := &m.calls
+		c.mu.Lock()
+		if c.stop != stop {
+			c.mu.Unlock()
+			return
+		}
+		if !sameCallStates(c.states, states) {
+			c.states = states
+			ModemEvent.Publish(EventCallState, m.Name, states)
+		}
+		for _, s := range states {
+			if s.Stat == 0 {
+				m.markConnected()
+			}
+		}
+		if len(states) == 0 {
+			if empty++; empty >= 2 {
+				m.finishCall()
+			}
+		} else {
+			empty = 0
+		}
+		c.mu.Unlock()
+	}
+}
+
+// GetCallStatus 查询当前所有通话的状态
+func (m *ModemInfo) GetCallStatus() ([]models.CallState, error) {
+	responses, err := m.SendCommand("AT+CLCC")
+	if err != nil {
+		return nil, err
+	}
+	if err := finalError(responses); err != nil {
+		return nil, err
+	}
+	return parseCallStates(responses), nil
+}
+
+// parseCallStates 解析多行 +CLCC: <id>,<dir>,<stat>,<mode>,<mpty>[,<number>,<type>]
+func parseCallStates(responses []string) []models.CallState {
+	states := []models.CallState{}
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "+CLCC" || len(param) < 5 {
+			continue
+		}
+		stat := parseRetry(param[2])
+		state := models.CallState{
+			ID:         parseRetry(param[0]),
+			Direction:  "out",
+			Stat:       stat,
+			State:      callStates[stat],
+			Mode:       parseRetry(param[3]),
+			Multiparty: param[4] == "1",
+		}
+		if param[1] == "1" {
+			state.Direction = "in"
+		}
+		if len(param) > 5 {
+			state.Number = param[5]
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// sameCallStates 比较两次轮询结果是否相同
+func sameCallStates(a, b []models.CallState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// endCall 通话结束，result 为模块上报的结果码，未知时为空
+func (m *ModemInfo) endCall(result string) {
+	m.calls.mu.Lock()
+	defer m.calls.mu.Unlock()
+	if m.calls.current != nil && result != "" {
+		m.calls.current.Result = result
+	}
+	m.finishCall()
+}
+
+// finishCall 结束当前通话并记录结果，调用方需持有锁
+func (m *ModemInfo) finishCall() {
+	c := &m.calls
+	if c.current == nil {
+		return
+	}
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+
+	now := time.Now()
+	c.current.EndedAt = &now
+	switch {
+	case c.current.Status == CallRejected: // 已拒接，保留状态
+	case c.current.AnsweredAt != nil:
+		c.current.Status = CallAnswered
+	case c.current.Direction == "out":
+		c.current.Status = CallUnanswered
+	default:
+		c.current.Status = CallMissed
+	}
+	m.publishCall(c.current)
+	c.current = nil
+
+	// 没有通话时停止轮询，避免打扰空闲的模块
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+	c.states = nil
+}
+
+// publishCall 广播通话记录变化，并触发 call 类型的 webhook
+func (m *ModemInfo) publishCall(record *models.CallRecord) {
+	ModemEvent.Publish(EventCall, m.Name, *record)
+	go func(record models.CallRecord) {
+		if err := NewWebhookService().TriggerCallWebhooks(m.Name, record); err != nil {
+			slog.Error("failed to trigger call webhooks", slog.String("port", m.Name), slog.Any("error", err))
+		}
+	}(*record)
+}
+
+// Dial 拨打语音电话（ATD<number>;），拨号前执行配置的音频命令
+// 接通和结束由 +CLCC / NO CARRIER 等通知更新
+func (m *ModemInfo) Dial(number string) error {
+	if !dialNumberRe.MatchString(number) {
+		return fmt.Errorf("%w: number %q", ErrInvalid, number)
+	}
+
+	// 网络搜索期间模块无法拨号，直接返回而不在队列中等待
+	if m.scanning.Load() {
+		return ErrScanInProgress
+	}
+	if err := m.applyAudioConfig(); err != nil {
+		return err
+	}
+
+	c := &m.calls
+	c.mu.Lock()
+	if c.current != nil {
+		c.mu.Unlock()
+		return ErrCallInProgress
+	}
+	m.startCall("out", CallDialing, number)
+	c.mu.Unlock()
+
+	// 开启通话状态主动上报，不支持时忽略
+	m.SendCommand("AT+CLCC=1")
+
+	responses, err := m.SendCommand("ATD" + number + ";")
+	if err == nil {
+		err = finalError(responses)
+	}
+	if err != nil {
+		m.endCall("")
+		return fmt.Errorf("dial failed, modem may not support voice calls: %v", err)
+	}
+	return nil
+}
+
+// DialWait 拨打语音电话并等待结果，直到接通、结束或超时
+// 超时返回拨号中的通话记录，通话不会被挂断
+func (m *ModemInfo) DialWait(number string) (*models.CallRecord, error) {
+	// 先订阅再拨号，避免遗漏结果
+	_, events, cancel := ModemEvent.Subscribe(ModemEvent.Latest(), 16)
+	defer cancel()
+
+	if err := m.Dial(number); err != nil {
+		return nil, err
+	}
+
+	m.calls.mu.Lock()
+	if m.calls.current == nil {
+		m.calls.mu.Unlock()
+		return nil, fmt.Errorf("call ended before dial result")
+	}
+	record := *m.calls.current
+	m.calls.mu.Unlock()
+
+	timeout := time.After(dialWaitTimeout)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return &record, nil
+			}
+			r, match := event.Data.(models.CallRecord)
+			if event.Type != EventCall || event.Port != m.Name || !match || r.ID != record.ID {
+				continue
+			}
+			record = r
+			if r.Status != CallDialing {
+				return &record, nil
+			}
+		case <-timeout:
+			return &record, nil
+		}
+	}
+}
+
+// AnswerCall 接听来电，接听前执行配置的音频命令
+// 接听命令优先于队列中的其他命令执行，等待响应期间不持有锁，避免阻塞通话通知处理
+func (m *ModemInfo) AnswerCall() error {
+	c := &m.calls
+	c.mu.Lock()
+	ringing := c.current != nil && c.current.Status == CallRinging
+	c.mu.Unlock()
+	if !ringing {
+		return fmt.Errorf("%w: no incoming call", ErrNoActiveCall)
+	}
+
+	if err := m.applyAudioConfig(); err != nil {
+		return err
+	}
+
+	var err error
+	if qerr := m.exec(true, func() {
+		err = m.sendCommandExpect("ATA", "OK")
+	}); qerr != nil {
+		return qerr
+	}
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current == nil || c.current.Status != CallRinging {
+		return fmt.Errorf("%w: call ended before answer", ErrNoActiveCall)
+	}
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+
+	now := time.Now()
+	c.current.AnsweredAt = &now
+	c.current.Status = CallActive
+	m.publishCall(c.current)
+	return nil
+}
+
+// HangupCall 挂断通话
+// 挂断命令优先于队列中的其他命令执行；未跟踪到通话时以 AT+CLCC 确认，模块不支持 AT+CLCC 时仍发送挂断命令
+func (m *ModemInfo) HangupCall() error {
+	m.calls.mu.Lock()
+	tracked := m.calls.current != nil
+	m.calls.mu.Unlock()
+	if !tracked {
+		if states, err := m.GetCallStatus(); err == nil && len(states) == 0 {
+			return ErrNoActiveCall
+		}
+	}
+
+	var err error
+	if qerr := m.exec(true, func() {
+		err = m.sendCommandExpect("ATH", "OK")
+	}); qerr != nil {
+		return qerr
+	}
+	if err != nil {
+		return err
+	}
+	m.endCall("")
+	return nil
+}
+
+// RejectCall 拒接来电（ATH），通话记录标记为已拒接
+// 挂断命令优先于队列中的其他命令执行
+func (m *ModemInfo) RejectCall() error {
+	c := &m.calls
+	c.mu.Lock()
+	ringing := c.current != nil && c.current.Status == CallRinging
+	c.mu.Unlock()
+	if !ringing {
+		return fmt.Errorf("%w: no incoming call", ErrNoActiveCall)
+	}
+
+	var err error
+	if qerr := m.exec(true, func() {
+		err = m.sendCommandExpect("ATH", "OK")
+	}); qerr != nil {
+		return qerr
+	}
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current != nil && c.current.Status == CallRinging {
+		c.current.Status = CallRejected
+	}
+	m.finishCall()
+	return nil
+}
+
+// autoReject 拒接匹配拒接列表的来电
+func (m *ModemInfo) autoReject(number string, rule *models.RejectRule) {
+	if err := m.RejectCall(); err != nil {
+		slog.Error("failed to reject call", slog.String("port", m.Name), slog.String("number", number), slog.Any("error", err))
+		return
+	}
+	slog.Info("call rejected", slog.String("port", m.Name), slog.String("number", number), slog.Int("rule", rule.ID))
+}
+
+// GetCallHistory 获取通话记录，最新的在前
+func (m *ModemInfo) GetCallHistory() []models.CallRecord {
+	c := &m.calls
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := make([]models.CallRecord, 0, len(c.records))
+	for i := len(c.records) - 1; i >= 0; i-- {
+		result = append(result, *c.records[i])
+	}
+	return result
+}
+
+// ClearCallHistory 清空已结束的通话记录
+func (m *ModemInfo) ClearCallHistory() {
+	c := &m.calls
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.records = nil
+	if c.current != nil {
+		c.records = append(c.records, c.current)
+	}
+}
+
+// defaultDTMFInterval DTMF 按键间隔，由 DTMF_INTERVAL_MS 设置
+var defaultDTMFInterval = sync.OnceValue(func() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("DTMF_INTERVAL_MS")); err == nil && v >= 0 {
+		return min(time.Duration(v)*time.Millisecond, maxDTMFInterval)
+	}
+	return dtmfInterval
+})
+
+// hasActiveCall 通过 AT+CLCC 确认存在已接通的语音通话
+// 模块不支持 AT+CLCC 时以通知跟踪的通话状态为准
+func (m *ModemInfo) hasActiveCall() bool {
+	states, err := m.GetCallStatus()
+	if err != nil {
+		m.calls.mu.Lock()
+		defer m.calls.mu.Unlock()
+		return m.calls.current != nil && m.calls.current.Status == CallActive
+	}
+	for _, state := range states {
+		if state.Stat == 0 && state.Mode == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SendDTMF 在通话中逐个发送 DTMF 按键（每个按键一条 AT+VTS，不依赖部分模块不支持的逗号分隔形式）
+// interval 为按键间隔，为 0 时使用默认间隔；发送期间占用命令队列
+func (m *ModemInfo) SendDTMF(digits string, interval time.Duration) error {
+	digits = strings.ToUpper(digits)
+	if digits == "" {
+		return fmt.Errorf("%w: digits is empty", ErrInvalid)
+	}
+	for _, d := range digits {
+		if !strings.ContainsRune(dtmfDigits, d) {
+			return fmt.Errorf("%w: dtmf digit %q, allowed: 0-9 * # A-D", ErrInvalid, d)
+		}
+	}
+
+	if interval < 0 || interval > maxDTMFInterval {
+		return fmt.Errorf("%w: interval must be 0-%d ms", ErrInvalid, maxDTMFInterval.Milliseconds())
+	}
+	if interval == 0 {
+		interval = defaultDTMFInterval()
+	}
+
+	if !m.hasActiveCall() {
+		return ErrNoActiveCall
+	}
+
+	// 全部按键作为一个紧急任务执行，其他命令不会插入按键之间
+	var err error
+	if qerr := m.exec(true, func() {
+		for i, d := range digits {
+			if i > 0 {
+				time.Sleep(interval)
+			}
+			if err = m.sendCommandExpect(fmt.Sprintf("AT+VTS=%c", d), "OK"); err != nil {
+				return
+			}
+		}
+	}); qerr != nil {
+		return qerr
+	}
+	return err
+}
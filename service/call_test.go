@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAnswerCallNoIncomingCall(t *testing.T) {
+	m, _ := newTestModem(t, &scriptedModem{})
+	if err := m.AnswerCall(); !errors.Is(err, ErrNoActiveCall) {
+		t.Fatalf("err = %v, want ErrNoActiveCall", err)
+	}
+}
+
+func TestAnswerCallDoesNotHoldLock(t *testing.T) {
+	initTestDB(t)
+	script := &scriptedModem{}
+	script.delay("ATA", 200*time.Millisecond)
+	m, _ := newTestModem(t, script)
+	m.Name = t.Name()
+	m.handleCallURC("+CLIP", map[int]string{0: "+8613800000000"})
+	defer m.endCall("")
+
+	done := make(chan error, 1)
+	go func() { done <- m.AnswerCall() }()
+	time.Sleep(50 * time.Millisecond)
+
+	// 等待 ATA 响应期间仍能处理振铃通知
+	start := time.Now()
+	m.handleCallURC("RING", nil)
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Fatalf("RING handling blocked for %s", d)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetCallHistory()[0].Status; got != CallActive {
+		t.Fatalf("status = %q, want %q", got, CallActive)
+	}
+	if cmds := script.received(); len(cmds) == 0 || cmds[0] != "ATA" {
+		t.Fatalf("commands = %q", cmds)
+	}
+}
+
+func TestHangupCallNoActiveCall(t *testing.T) {
+	script := &scriptedModem{}
+	m, _ := newTestModem(t, script)
+	if err := m.HangupCall(); !errors.Is(err, ErrNoActiveCall) {
+		t.Fatalf("err = %v, want ErrNoActiveCall", err)
+	}
+	if cmds := script.received(); len(cmds) != 1 || cmds[0] != "AT+CLCC" {
+		t.Fatalf("commands = %q, want only AT+CLCC", cmds)
+	}
+
+	// 模块上有未跟踪的通话时仍然挂断
+	script.reply("AT+CLCC", "+CLCC: 1,0,0,0,0,\"10086\",129\r\nOK")
+	if err := m.HangupCall(); err != nil {
+		t.Fatal(err)
+	}
+	if cmds := script.received(); cmds[len(cmds)-1] != "ATH" {
+		t.Fatalf("commands = %q, want ATH last", cmds)
+	}
+}
+
+func TestSendDTMFRunsAsOneTask(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CLCC", "+CLCC: 1,0,0,0,0,\"10086\",129\r\nOK")
+	m, _ := newTestModem(t, script)
+
+	done := make(chan error, 1)
+	go func() { done <- m.SendDTMF("12#", 50*time.Millisecond) }()
+	time.Sleep(70 * time.Millisecond)
+
+	// 按键之间排队的命令在全部按键发送后执行
+	if _, err := m.SendCommand("AT+CSQ"); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"AT+CLCC", "AT+VTS=1", "AT+VTS=2", "AT+VTS=#", "AT+CSQ"}
+	if got := script.received(); !slices.Equal(got, want) {
+		t.Fatalf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestSendDTMFStopsOnError(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CLCC", "+CLCC: 1,0,0,0,0,\"10086\",129\r\nOK")
+	script.reply("AT+VTS=2", "ERROR")
+	m, _ := newTestModem(t, script)
+
+	if err := m.SendDTMF("123", time.Millisecond); err == nil {
+		t.Fatal("expected error")
+	}
+	want := []string{"AT+CLCC", "AT+VTS=1", "AT+VTS=2"}
+	if got := script.received(); !slices.Equal(got, want) {
+		t.Fatalf("commands = %q, want %q", got, want)
+	}
+}
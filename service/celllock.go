@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+func init() {
+	RegisterParser("", `AT+QNWLOCK="common/4g"`, parseQuectelCellLock)
+}
+
+// GetCellLock 查询 LTE 小区锁定配置
+func (m *ModemInfo) GetCellLock() (*models.CellLock, error) {
+	cmd, ok := vendorCommand("cell_lock", m.Vendor)
+	if !ok {
+		return nil, fmt.Errorf("cell lock %w by %s modem", ErrUnsupported, m.Vendor)
+	}
+
+	responses, err := m.SendCommand(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if err := finalError(responses); err != nil {
+		return nil, err
+	}
+
+	fn := lookupParser(m.Vendor, cmd)
+	if fn == nil {
+		return nil, fmt.Errorf("no parser registered for %s", cmd)
+	}
+	v, err := fn(responses)
+	if err != nil {
+		return nil, err
+	}
+	lock, ok := v.(*models.CellLock)
+	if !ok {
+		return nil, fmt.Errorf("unexpected cell lock parser result %T", v)
+	}
+	return lock, nil
+}
+
+// SetCellLock 将模块锁定到指定 EARFCN 和 PCI 的 LTE 小区
+func (m *ModemInfo) SetCellLock(earfcn, pci int) error {
+	cmd, ok := vendorCommand("cell_lock_set", m.Vendor)
+	if !ok {
+		return fmt.Errorf("cell lock %w by %s modem", ErrUnsupported, m.Vendor)
+	}
+	if earfcn < 0 || earfcn > 262143 {
+		return fmt.Errorf("%w: earfcn must be 0-262143", ErrInvalid)
+	}
+	if pci < 0 || pci > 503 {
+		return fmt.Errorf("%w: pci must be 0-503", ErrInvalid)
+	}
+
+	responses, err := m.SendCommand(fmt.Sprintf(cmd, earfcn, pci))
+	if err != nil {
+		return err
+	}
+	return finalError(responses)
+}
+
+// ClearCellLock 解除小区锁定
+func (m *ModemInfo) ClearCellLock() error {
+	cmd, ok := vendorCommand("cell_lock_clear", m.Vendor)
+	if !ok {
+		return fmt.Errorf("cell lock %w by %s modem", ErrUnsupported, m.Vendor)
+	}
+
+	responses, err := m.SendCommand(cmd)
+	if err != nil {
+		return err
+	}
+	return finalError(responses)
+}
+
+// parseQuectelCellLock 解析 +QNWLOCK: "common/4g",<num>[,<earfcn>,<pci>]...
+func parseQuectelCellLock(responses []string) (any, error) {
+	lock := &models.CellLock{Cells: []models.LockedCell{}, Raw: responses}
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "+QNWLOCK" || len(param) < 2 {
+			continue
+		}
+		num, err := strconv.Atoi(param[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cell lock count %q", param[1])
+		}
+		for i := 0; i < num && 3+2*i < len(param); i++ {
+			earfcn, _ := strconv.Atoi(param[2+2*i])
+			pci, _ := strconv.Atoi(param[3+2*i])
+			lock.Cells = append(lock.Cells, models.LockedCell{EARFCN: earfcn, PCI: pci})
+		}
+		lock.Enabled = num > 0
+		return lock, nil
+	}
+	return nil, fmt.Errorf("failed to parse cell lock")
+}
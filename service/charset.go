@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// GetCharset 查询当前和支持的 TE 字符集
+func (m *ModemInfo) GetCharset() (*models.Charset, error) {
+	supported, err := m.GetSupportedCharsets()
+	if err != nil {
+		return nil, err
+	}
+
+	responses, err := m.SendCommand("AT+CSCS?")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range responses {
+		if label, param := splitParam(line); label == "+CSCS" && len(param) > 0 {
+			return &models.Charset{Current: param[0], Supported: supported}, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to parse current charset")
+}
+
+// GetSupportedCharsets 查询支持的 TE 字符集，解析 +CSCS: ("IRA","GSM","UCS2")
+func (m *ModemInfo) GetSupportedCharsets() ([]string, error) {
+	responses, err := m.SendCommand("AT+CSCS=?")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range responses {
+		if label, _ := splitParam(line); label == "+CSCS" {
+			return parseBracketList(line[strings.Index(line, ":")+1:]), nil
+		}
+	}
+	return nil, fmt.Errorf("failed to parse supported charsets")
+}
+
+// SetCharset 设置 TE 字符集，仅允许模块支持的字符集
+func (m *ModemInfo) SetCharset(charset string) error {
+	supported, err := m.GetSupportedCharsets()
+	if err != nil {
+		return err
+	}
+	for _, cs := range supported {
+		if strings.EqualFold(cs, charset) {
+			return m.SendCommandExpect(fmt.Sprintf(`AT+CSCS="%s"`, cs), "OK")
+		}
+	}
+	return fmt.Errorf("%w: charset %q not supported, available: %s", ErrInvalid, charset, strings.Join(supported, ", "))
+}
+
+// parseBracketList 解析 ("a","b",...) 格式的取值列表
+func parseBracketList(s string) []string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "("), ")")
+
+	values := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.Trim(strings.TrimSpace(v), `"'`); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetCharsetErrors(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CSCS=?", `+CSCS: ("IRA","GSM","UCS2")`+"\r\nOK")
+	m, _ := newTestModem(t, script)
+
+	if err := m.SetCharset("ucs2"); err != nil {
+		t.Fatal(err)
+	}
+	if cmds := script.received(); cmds[len(cmds)-1] != `AT+CSCS="UCS2"` {
+		t.Fatalf("commands = %q", cmds)
+	}
+
+	// 模块不支持的字符集属于请求错误
+	if err := m.SetCharset("8859-1"); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("unsupported charset: err = %v, want ErrInvalid", err)
+	}
+
+	// 模块拒绝设置不是请求错误
+	script.reply(`AT+CSCS="GSM"`, "ERROR")
+	if err := m.SetCharset("GSM"); err == nil || errors.Is(err, ErrInvalid) {
+		t.Fatalf("modem error: err = %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rehiy/modem/sms"
+	"github.com/rehiy/modem/sms/pdumode"
+	"github.com/rehiy/modem/sms/tpdu"
+	"github.com/rehiy/web-modem/models"
+)
+
+const (
+	directSMSTapBuffer  = 16
+	directSMSReassembly = 10 * time.Minute // 长短信分片的最长等待时间
+)
+
+// smsDirectMode 是否让模块直接推送新短信（+CMT），由 SMS_DIRECT_MODE 设置
+// 默认关闭，短信先保存到存储再通过 +CMTI 通知
+var smsDirectMode = sync.OnceValue(func() bool {
+	v, _ := strconv.ParseBool(os.Getenv("SMS_DIRECT_MODE"))
+	return v
+})
+
+// parseIncomingSMS 解析 +CMT 通知后一行的十六进制 PDU
+func parseIncomingSMS(data string) (*tpdu.TPDU, error) {
+	pdu, err := pdumode.UnmarshalHexString(strings.TrimSpace(data))
+	if err != nil {
+		return nil, err
+	}
+	t, err := sms.Unmarshal(pdu.TPDU)
+	if err != nil {
+		return nil, err
+	}
+	if t.SmsType() != tpdu.SmsDeliver {
+		return nil, fmt.Errorf("unexpected tpdu type %v", t.SmsType())
+	}
+	return t, nil
+}
+
+// watchPushedPDU 监听串口上直接推送 PDU 的 +CMT 和 +CDS 通知
+// 通知行的下一行为 PDU，URC 参数中不包含 PDU，因此使用原始行
+// 直接推送的短信和状态报告不保存到存储，没有索引
+func (m *ModemService) watchPushedPDU(conn *ModemInfo) {
+	lines, stop := conn.port.Tap(directSMSTapBuffer)
+	defer stop()
+
+	collector := sms.NewCollector(sms.WithReassemblyTimeout(directSMSReassembly, func(segments []*tpdu.TPDU) {
+		slog.Warn("incomplete direct sms discarded", slog.String("port", conn.Name), slog.Int("segments", len(segments)))
+	}))
+	defer collector.Close()
+
+	w := NewWebhookService()
+	pending := ""
+	for {
+		select {
+		case line := <-lines:
+			if label, _, ok := strings.Cut(line, ":"); ok && (label == "+CMT" || label == "+CDS") {
+				pending = label
+				continue
+			}
+			label := pending
+			pending = ""
+
+			switch label {
+			case "+CMT":
+				t, err := parseIncomingSMS(line)
+				if err != nil {
+					slog.Warn("failed to parse direct sms", slog.String("port", conn.Name), slog.Any("error", err))
+					continue
+				}
+				segments, err := collector.Collect(*t)
+				if err != nil || len(segments) == 0 {
+					continue
+				}
+				msg, err := directSMS(segments)
+				if err != nil {
+					slog.Warn("failed to decode direct sms", slog.String("port", conn.Name), slog.Any("error", err))
+					continue
+				}
+				m.deliverSMS(conn, msg, w)
+			case "+CDS":
+				conn.handlePushedStatusReport(line)
+			}
+		case <-time.After(5 * time.Second):
+			if !conn.IsOpen() {
+				return
+			}
+		}
+	}
+}
+
+// directSMS 由完整的分片生成短信，状态视为未读
+func directSMS(segments []*tpdu.TPDU) (models.ModemSMS, error) {
+	text, err := sms.Decode(segments)
+	if err != nil {
+		return models.ModemSMS{}, err
+	}
+	headers := []models.UDHElement{}
+	for _, s := range segments {
+		headers = append(headers, udhElements(s.UDH)...)
+	}
+	return models.ModemSMS{
+		PhoneNumber: segments[0].OA.Number(),
+		Text:        string(text),
+		Time:        segments[0].SCTS.Time.Format("2006/01/02 15:04:05"),
+		ReceivedAt:  segments[0].SCTS.Time,
+		Status:      "REC UNREAD",
+		Headers:     headers,
+	}, nil
+}
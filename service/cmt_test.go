@@ -0,0 +1,134 @@
+package service
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rehiy/modem/sms/tpdu"
+	"github.com/rehiy/web-modem/models"
+)
+
+const (
+	testDeliverPDU      = "07911326040000F0040B911346610089F60000208062917314080CC8F71D14969741F977FD07" // +31641600986: How are you?
+	testStatusReportPDU = "0006070D91683108000000F0211061214365002110612143650000"                       // 参考号 7，已送达
+)
+
+func TestParseIncomingSMS(t *testing.T) {
+	segment, err := parseIncomingSMS(testDeliverPDU)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := directSMS([]*tpdu.TPDU{segment})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.PhoneNumber != "+31641600986" || msg.Text != "How are you?" || len(msg.Indices) != 0 {
+		t.Fatalf("msg = %+v", msg)
+	}
+
+	if _, err := parseIncomingSMS(testStatusReportPDU); err == nil {
+		t.Fatal("status report parsed as sms")
+	}
+}
+
+func TestPushedPDUNotInCommandResponse(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CSQ", "+CMT: ,24\r\n"+testDeliverPDU+"\r\n+CSQ: 20,99\r\nOK")
+	m, _ := newTestModem(t, script)
+	lines, stop := m.port.Tap(8)
+	defer stop()
+
+	responses, err := m.SendCommand("AT+CSQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(responses, []string{"+CSQ: 20,99", "OK"}) {
+		t.Fatalf("responses = %q", responses)
+	}
+
+	// PDU 行仍交给监听者
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case line := <-lines:
+			if line == testDeliverPDU {
+				return
+			}
+		case <-timeout:
+			t.Fatal("pdu line not delivered to taps")
+		}
+	}
+}
+
+func TestWatchPushedStatusReport(t *testing.T) {
+	m, f := newTestModem(t, &scriptedModem{})
+	go (&ModemService{}).watchPushedPDU(m)
+	time.Sleep(20 * time.Millisecond)
+
+	f.push("\r\n+CDS: 25\r\n" + testStatusReportPDU + "\r\n")
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if report, ok := m.GetDeliveryReport(7); ok {
+			if report.Status != "delivered" || report.RecipientNumber != "+8613800000000" {
+				t.Fatalf("report = %+v", report)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("status report not stored")
+}
+
+func TestPushedStatusReportDuringCommand(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CSQ", "+CDS: 25\r\n"+testStatusReportPDU+"\r\n+CSQ: 20,99\r\nOK")
+	m, _ := newTestModem(t, script)
+	m.Name = t.Name()
+	_, events, cancel := ModemEvent.Subscribe(ModemEvent.Latest(), 16)
+	defer cancel()
+	go (&ModemService{}).watchPushedPDU(m)
+	time.Sleep(20 * time.Millisecond)
+
+	responses, err := m.SendCommand("AT+CSQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(responses, []string{"+CSQ: 20,99", "OK"}) {
+		t.Fatalf("responses = %q", responses)
+	}
+
+	// 状态报告保存后广播 delivery 事件，webhook 经由事件分发收到
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Type != EventDelivery || event.Port != m.Name {
+				continue
+			}
+			if report := event.Data.(models.DeliveryReport); report.Reference != 7 || report.Status != "delivered" {
+				t.Fatalf("event report = %+v", report)
+			}
+			if _, ok := m.GetDeliveryReport(7); !ok {
+				t.Fatal("status report not stored")
+			}
+			return
+		case <-timeout:
+			t.Fatal("no delivery event")
+		}
+	}
+}
+
+func TestMarkForwardedDistinguishesText(t *testing.T) {
+	port := t.Name()
+	a := models.ModemSMS{PhoneNumber: "10086", Time: "2026/10/16 12:00:00", Text: "first"}
+	b := a
+	b.Text = "second"
+
+	if !markForwarded(port, a) || !markForwarded(port, b) {
+		t.Fatal("direct sms with different text treated as duplicate")
+	}
+	if markForwarded(port, a) {
+		t.Fatal("duplicate sms forwarded twice")
+	}
+}
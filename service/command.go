@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rehiy/modem/at"
+	"github.com/rehiy/web-modem/metrics"
+	"golang.org/x/time/rate"
+)
+
+const (
+	atTimeout          = time.Second     // at 库默认的命令超时时间
+	maxCommandTimeout  = 5 * time.Minute // 单条命令允许的最长超时时间
+	defaultCommandRate = 20              // 默认每个端口每秒最多发送的命令数
+)
+
+// commandRate 每个端口每秒最多发送的命令数，由 MODEM_COMMAND_RATE 设置，为 0 时不限制
+var commandRate = sync.OnceValue(func() int64 {
+	if v, err := strconv.ParseInt(os.Getenv("MODEM_COMMAND_RATE"), 10, 64); err == nil && v >= 0 {
+		return v
+	}
+	return defaultCommandRate
+})
+
+// newCommandLimiter 按 commandRate 创建端口的命令限速器，不限制时返回 nil
+func newCommandLimiter() *rate.Limiter {
+	limit := commandRate()
+	if limit == 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(limit), int(limit))
+}
+
+// throttle 超出命令频率时等待，端口关闭时立即返回，仅由调度协程调用
+func (m *ModemInfo) throttle() {
+	if m.cmdLimiter == nil {
+		return
+	}
+	delay := m.cmdLimiter.Reserve().Delay()
+	if delay <= 0 {
+		return
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-m.closed():
+	}
+}
+
+var (
+	responseSet     = at.DefaultResponseSet()
+	notificationSet = modemNotificationSet()
+)
+
+// modemNotificationSet 在默认通知集基础上，将开机提示 RDY 视为通知
+// 否则模块重启后的 RDY 会混入下一条命令的响应
+func modemNotificationSet() *at.NotificationSet {
+	ns := at.DefaultNotificationSet()
+	ns.DeviceReady = "RDY"
+	return ns
+}
+
+// SendCommand 发送命令并等待最终响应
+// 无论是否已关闭回显，均去除响应中回显的命令行；
+// 模块处于数字结果码模式（ATV0）时，串口将结果码转换为文本结果码
+// 命令经端口命令队列按顺序执行
+func (m *ModemInfo) SendCommand(cmd string) ([]string, error) {
+	var responses []string
+	var err error
+	if qerr := m.exec(false, func() {
+		responses, err = m.sendCommand(cmd)
+	}); qerr != nil {
+		return nil, qerr
+	}
+	return responses, err
+}
+
+// sendCommand 直接发送命令，仅在命令队列的调度协程中调用
+func (m *ModemInfo) sendCommand(cmd string) ([]string, error) {
+	start := time.Now()
+	responses, err := m.roundTrip(cmd)
+	m.observeCommand(start, responses, err)
+	return responses, err
+}
+
+// SendCommandExpect 发送命令，响应中没有 expected 时返回错误，命令经端口命令队列执行
+func (m *ModemInfo) SendCommandExpect(cmd, expected string) error {
+	var err error
+	if qerr := m.exec(false, func() {
+		err = m.sendCommandExpect(cmd, expected)
+	}); qerr != nil {
+		return qerr
+	}
+	return err
+}
+
+// sendCommandExpect 直接发送命令，响应中没有 expected 时返回错误，仅在命令队列的调度协程中调用
+func (m *ModemInfo) sendCommandExpect(cmd, expected string) error {
+	responses, err := m.sendCommand(cmd)
+	if err != nil {
+		return err
+	}
+	if err := finalError(responses); err != nil {
+		return err
+	}
+	for _, line := range responses {
+		if strings.Contains(line, expected) {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected response %q not found in %v", expected, responses)
+}
+
+// roundTrip 发送命令并读取响应，处理回显
+// 数字结果码由串口转换为文本结果码后交给 at 库
+func (m *ModemInfo) roundTrip(cmd string) ([]string, error) {
+	responses, err := m.dev.SendCommand(cmd)
+	responses = m.stripEcho(cmd, responses)
+	if err == nil {
+		m.trackDataMode(responses)
+	}
+	return responses, err
+}
+
+// SendCommandWithTimeout 发送命令并在指定时间内等待最终响应，用于网络搜索、短信提交等耗时命令
+// at 库的超时固定为 1 秒，更长的超时通过截获串口输出自行等待最终响应
+func (m *ModemInfo) SendCommandWithTimeout(cmd string, timeout time.Duration) ([]string, error) {
+	return m.SendATCommand(context.Background(), cmd, timeout)
+}
+
+// SendATCommand 在请求上下文中发送命令，timeout 不超过 1 秒时使用 at 库的默认超时
+// 排队期间 ctx 结束则不再发送；命令开始执行后不会中断，避免打断进行中的串口交互
+func (m *ModemInfo) SendATCommand(ctx context.Context, cmd string, timeout time.Duration) ([]string, error) {
+	if timeout > maxCommandTimeout {
+		return nil, fmt.Errorf("%w: timeout must not exceed %s", ErrInvalid, maxCommandTimeout)
+	}
+
+	var responses []string
+	var err error
+	start := time.Now()
+	if qerr := m.execContext(ctx, false, func() {
+		responses, err = m.sendCommandWithTimeout(cmd, timeout)
+	}); qerr != nil {
+		slog.WarnContext(ctx, "at command cancelled before sending", slog.String("port", m.Name), slog.String("cmd", cmd), slog.Any("error", qerr))
+		return nil, qerr
+	}
+	slog.DebugContext(ctx, "at command", slog.String("port", m.Name), slog.String("cmd", cmd), slog.Duration("elapsed", time.Since(start)))
+	return responses, err
+}
+
+// sendCommandWithTimeout 直接发送命令并等待最终响应，仅在命令队列的调度协程中调用
+// 超过 at 库默认超时的命令截获串口输出自行解析，迟到的响应不会交给 at 库
+func (m *ModemInfo) sendCommandWithTimeout(cmd string, timeout time.Duration) (responses []string, err error) {
+	if timeout <= atTimeout {
+		return m.sendCommand(cmd)
+	}
+
+	start := time.Now()
+	defer func() { m.observeCommand(start, responses, err) }()
+
+	c := m.capture(cmd)
+	defer c.release()
+	if err := c.write(cmd); err != nil {
+		return nil, err
+	}
+	return c.await(timeout, false)
+}
+
+// capturedExchange 截获串口输出的一次命令交互
+type capturedExchange struct {
+	m       *ModemInfo
+	cmd     string // 用于区分响应和通知的命令
+	lines   <-chan string
+	release func()
+}
+
+// capture 开始截获串口输出，cmd 用于区分命令响应和通知
+// 通知（最终错误结果码除外）同时交给 at 库，照常分发给事件处理函数
+func (m *ModemInfo) capture(cmd string) *capturedExchange {
+	lines, release := m.port.Capture(func(line string) bool {
+		return notificationSet.IsNotification(line, cmd) && !isFinalError(line)
+	})
+	return &capturedExchange{m: m, cmd: cmd, lines: lines, release: release}
+}
+
+// write 写入数据，与 at 库一致，没有结束符时补充 CRLF
+func (c *capturedExchange) write(data string) error {
+	if !slices.ContainsFunc(at.Terminators, func(t string) bool { return strings.HasSuffix(data, t) }) {
+		data += at.Terminators[0]
+	}
+	slog.Debug("write cmd: "+strings.TrimSpace(data), slog.String("port", c.m.Name))
+	n, err := c.m.port.Write([]byte(data))
+	if err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+	if n != len(data) {
+		return fmt.Errorf("incomplete write: wrote %d of %d bytes", n, len(data))
+	}
+	return nil
+}
+
+// await 读取截获的响应行，直到最终响应或超时
+// prompt 为 true 时收到输入提示符也结束，提示符 ">" 作为最后一行返回
+func (c *capturedExchange) await(timeout time.Duration, prompt bool) ([]string, error) {
+	responses := []string{}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		select {
+		case line := <-c.lines:
+			if line == ">" && prompt {
+				return append(responses, line), nil
+			}
+			if notificationSet.IsNotification(line, c.cmd) {
+				// 错误结果码同时属于通知，也视为最终响应
+				if isFinalError(line) {
+					return append(responses, line), nil
+				}
+				continue
+			}
+			if len(responses) == 0 && isEcho(c.cmd, line) {
+				c.m.stripEcho(c.cmd, []string{line})
+				continue
+			}
+			responses = append(responses, line)
+			if responseSet.IsFinal(line) {
+				return responses, nil
+			}
+		case <-c.m.port.Done():
+			return responses, fmt.Errorf("device closed")
+		case <-deadline.C:
+			return responses, fmt.Errorf("command timeout")
+		}
+	}
+}
+
+// isFinalError 是否为错误最终响应
+func isFinalError(line string) bool {
+	return responseSet.IsFinal(line) && responseSet.IsError(line)
+}
+
+// stripEcho 去除响应开头回显的命令行，并记录回显次数
+// 部分模块忽略 ATE0 或在某些命令后重新开启回显
+func (m *ModemInfo) stripEcho(cmd string, responses []string) []string {
+	if len(responses) == 0 || !isEcho(cmd, responses[0]) {
+		return responses
+	}
+	if m.echoCount.Add(1) == 1 {
+		slog.Warn("command echo detected despite ATE0", slog.String("port", m.Name))
+	}
+	return responses[1:]
+}
+
+// isEcho 判断响应行是否为命令回显
+func isEcho(cmd, line string) bool {
+	return strings.EqualFold(strings.TrimSpace(line), strings.TrimSpace(cmd))
+}
+
+// observeCommand 记录命令耗时和结果指标
+// 结果为 ok、error（模块返回错误）、timeout 或 failed（串口错误）
+func (m *ModemInfo) observeCommand(start time.Time, responses []string, err error) {
+	status := "ok"
+	switch {
+	case err != nil && err.Error() == "command timeout":
+		status = "timeout"
+	case err != nil:
+		status = "failed"
+	case finalError(responses) != nil:
+		status = "error"
+	}
+	metrics.ATCommands.WithLabelValues(m.Name, status).Inc()
+	metrics.ATDuration.WithLabelValues(m.Name).Observe(time.Since(start).Seconds())
+}
+
+// finalError 检查最终响应是否为错误
+func finalError(responses []string) error {
+	if l := len(responses); l > 0 && responseSet.IsError(responses[l-1]) {
+		return fmt.Errorf("%s", responses[l-1])
+	}
+	return nil
+}
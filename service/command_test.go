@@ -0,0 +1,149 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSendCommandWithTimeoutWaitsForSlowModem(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+COPS=?", `+COPS: (2,"CMCC","CMCC","46000",7),,(0-4),(0-2)`+"\r\nOK")
+	script.delay("AT+COPS=?", 1500*time.Millisecond)
+	m, _ := newTestModem(t, script)
+
+	// at 库的默认超时为 1 秒，延长超时后应等到最终响应
+	responses, err := m.SendCommandWithTimeout("AT+COPS=?", 5*time.Second)
+	if err != nil {
+		t.Fatalf("SendCommandWithTimeout: %v", err)
+	}
+	if len(responses) != 2 || !strings.HasPrefix(responses[0], "+COPS:") || responses[1] != "OK" {
+		t.Fatalf("responses = %q", responses)
+	}
+}
+
+func TestCapturedResponseDoesNotLeakIntoNextCommand(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+COPS=?", "OK")
+	script.delay("AT+COPS=?", 1200*time.Millisecond)
+	script.reply("AT+CSQ", "+CSQ: 20,99\r\nOK")
+	m, _ := newTestModem(t, script)
+
+	if _, err := m.SendCommandWithTimeout("AT+COPS=?", 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	// 迟到的 OK 由截获处理，不会留给 at 库作为下一条命令的响应
+	responses, err := m.SendCommand("AT+CSQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 2 || responses[0] != "+CSQ: 20,99" {
+		t.Fatalf("responses = %q", responses)
+	}
+}
+
+func TestCapturedExchangeForwardsNotifications(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+COPS=?", "RING\r\nOK")
+	script.delay("AT+COPS=?", 1100*time.Millisecond)
+
+	var mu sync.Mutex
+	urcs := []string{}
+	m, _ := newTestModemWithURC(t, script, func(label string, _ map[int]string) {
+		mu.Lock()
+		urcs = append(urcs, label)
+		mu.Unlock()
+	})
+
+	responses, err := m.SendCommandWithTimeout("AT+COPS=?", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 1 || responses[0] != "OK" {
+		t.Fatalf("responses = %q", responses)
+	}
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(urcs) != 1 || urcs[0] != "RING" {
+		t.Fatalf("notifications = %q, want RING", urcs)
+	}
+}
+
+func TestSendCommandWithTimeoutReturnsFinalError(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+COPS=?", "+CME ERROR: 30")
+	script.delay("AT+COPS=?", 1100*time.Millisecond)
+	m, _ := newTestModem(t, script)
+
+	responses, err := m.SendCommandWithTimeout("AT+COPS=?", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if finalError(responses) == nil {
+		t.Fatalf("responses = %q, want error", responses)
+	}
+}
+
+func TestSendCommandWithTimeoutRejectsExcessiveTimeout(t *testing.T) {
+	script := &scriptedModem{}
+	m, _ := newTestModem(t, script)
+
+	if _, err := m.SendCommandWithTimeout("AT+COPS=?", maxCommandTimeout+time.Second); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("err = %v, want ErrInvalid", err)
+	}
+	if got := script.received(); len(got) != 0 {
+		t.Fatalf("sent %q, want nothing", got)
+	}
+}
+
+func TestSendCommandWithShortTimeoutUsesDefault(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CSQ", "+CSQ: 20,99\r\nOK")
+	m, _ := newTestModem(t, script)
+
+	// 不超过 at 库默认超时时按普通命令发送
+	responses, err := m.SendCommandWithTimeout("AT+CSQ", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 2 || responses[0] != "+CSQ: 20,99" {
+		t.Fatalf("responses = %q", responses)
+	}
+}
+
+func TestSendCommandExpectWaitsForQueue(t *testing.T) {
+	script := &scriptedModem{}
+	m, _ := newTestModem(t, script)
+	release := blockQueue(t, m)
+	defer release()
+
+	// 队列被占用（如正在提交短信 PDU）时，命令不能直接写入串口
+	done := make(chan error, 1)
+	go func() { done <- m.SendCommandExpect("ATE0", "OK") }()
+	time.Sleep(50 * time.Millisecond)
+	if cmds := script.received(); len(cmds) != 0 {
+		t.Fatalf("commands sent while queue busy: %q", cmds)
+	}
+
+	release()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if cmds := script.received(); len(cmds) != 1 || cmds[0] != "ATE0" {
+		t.Fatalf("commands = %q", cmds)
+	}
+}
+
+func TestSendCommandExpectFinalError(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply(`AT+CSCS="UCS2"`, "ERROR")
+	m, _ := newTestModem(t, script)
+
+	err := m.SendCommandExpect(`AT+CSCS="UCS2"`, "OK")
+	if err == nil || !strings.Contains(err.Error(), "ERROR") {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rehiy/web-modem/database"
+	"github.com/rehiy/web-modem/models"
+)
+
+// GetConfig 获取模块配置
+func (m *ModemInfo) GetConfig() (*models.ModemConfig, error) {
+	return database.GetModemConfig(m.Name)
+}
+
+// SaveConfig 校验并保存模块配置
+func (m *ModemInfo) SaveConfig(config *models.ModemConfig) error {
+	config.Name = m.Name
+	if err := validateAlias(m.Name, config.Alias, GetModemService().knownPorts()); err != nil {
+		return err
+	}
+	if config.EmptyRetry < 0 || config.EmptyRetry > maxEmptyRetry {
+		return fmt.Errorf("%w: empty_retry must be 0-%d", ErrInvalid, maxEmptyRetry)
+	}
+	if err := validateWatchdog(config); err != nil {
+		return err
+	}
+	for _, cmd := range config.AudioCommands {
+		if !strings.HasPrefix(strings.ToUpper(cmd), "AT") {
+			return fmt.Errorf("%w: audio command %q must start with AT", ErrInvalid, cmd)
+		}
+	}
+	for _, cmd := range config.DiagCommands {
+		if err := validateDiagCommand(cmd); err != nil {
+			return err
+		}
+	}
+	if err := database.SaveModemConfig(config); err != nil {
+		return err
+	}
+	GetModemService().setAlias(m.Name, config.Alias)
+	return nil
+}
+
+// applyAudioConfig 执行配置的厂商音频命令（如 AT+QAUDMOD、AT+CGAINS）
+// 仅负责模块侧的音频通道设置，主机侧的 USB 音频 / PCM 接入需由用户自行处理
+func (m *ModemInfo) applyAudioConfig() error {
+	config, err := m.GetConfig()
+	if err != nil {
+		return err
+	}
+	for _, cmd := range config.AudioCommands {
+		responses, err := m.SendCommand(cmd)
+		if err == nil {
+			err = finalError(responses)
+		}
+		if err != nil {
+			return fmt.Errorf("audio command %s failed: %v", cmd, err)
+		}
+	}
+	return nil
+}
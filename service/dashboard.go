@@ -0,0 +1,81 @@
+package service
+
+import (
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// GetIdentity 查询模块和 SIM 卡身份信息
+// 全部获取成功后缓存，后续直接返回缓存
+func (m *ModemInfo) GetIdentity() *models.Identity {
+	m.identityMu.Lock()
+	defer m.identityMu.Unlock()
+
+	if m.identity != nil {
+		return m.identity
+	}
+
+	id := &models.Identity{}
+	complete := true
+	for _, q := range []struct {
+		field *string
+		fn    func() (string, error)
+	}{
+		{&id.Manufacturer, m.GetManufacturer},
+		{&id.Model, m.GetModel},
+		{&id.Revision, m.GetRevision},
+		{&id.IMEI, m.GetSerialNumber},
+		{&id.IMSI, m.GetIMSI},
+		{&id.ICCID, m.GetICCID},
+	} {
+		v, err := q.fn()
+		if err != nil {
+			complete = false
+			continue
+		}
+		*q.field = v
+	}
+
+	if complete {
+		m.identity = id
+	}
+	return id
+}
+
+// GetDashboard 获取模块状态快照
+// 静态信息使用缓存，动态信息依次查询，避免与其他请求交错
+func (m *ModemInfo) GetDashboard() *models.Dashboard {
+	m.dashboardMu.Lock()
+	defer m.dashboardMu.Unlock()
+
+	d := &models.Dashboard{
+		Name:        m.Name,
+		Time:        time.Now(),
+		Identity:    m.GetIdentity(),
+		UnreadSMS:   int(m.unreadSMS.Load()),
+		ConnectedAt: m.ConnectedAt,
+		Uptime:      time.Since(m.ConnectedAt).Seconds(),
+		Errors:      map[string]string{},
+	}
+
+	var err error
+	if d.Signal, err = m.GetSignal(); err != nil {
+		d.Errors["signal"] = err.Error()
+	}
+	// 数据模式下仅信号质量可经其他端口查询
+	if d.DataMode = m.InDataMode(); d.DataMode {
+		return d
+	}
+	if d.Operator, err = m.GetOperatorInfo(); err != nil {
+		d.Errors["operator"] = err.Error()
+	}
+	if d.Registration, err = m.GetRegistration(); err != nil {
+		d.Errors["registration"] = err.Error()
+	}
+	if d.Storage, err = m.GetSMSStorage(); err != nil {
+		d.Errors["storage"] = err.Error()
+	}
+
+	return d
+}
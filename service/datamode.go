@@ -0,0 +1,32 @@
+package service
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// ErrDataMode 模块处于数据模式，命令端口不可用
+var ErrDataMode = errors.New("modem is in data mode")
+
+// InDataMode 模块是否处于数据模式（拨号返回 CONNECT 或串口被原始会话占用）
+// 数据模式下在命令端口发送 AT 命令会破坏数据会话
+func (m *ModemInfo) InDataMode() bool {
+	return m.dataMode.Load() || m.port.paused.Load()
+}
+
+// trackDataMode 根据最终响应更新数据模式状态
+func (m *ModemInfo) trackDataMode(responses []string) {
+	if l := len(responses); l > 0 && strings.HasPrefix(responses[l-1], "CONNECT") {
+		if !m.dataMode.Swap(true) {
+			slog.Info("entered data mode", slog.String("port", m.Name))
+		}
+	}
+}
+
+// leaveDataMode 收到 NO CARRIER 时退出数据模式
+func (m *ModemInfo) leaveDataMode() {
+	if m.dataMode.Swap(false) {
+		slog.Info("left data mode", slog.String("port", m.Name))
+	}
+}
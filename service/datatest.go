@@ -0,0 +1,112 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+const (
+	attachTimeout   = 75 * time.Second  // AT+CGATT 最长等待时间
+	activateTimeout = 150 * time.Second // AT+CGACT 最长等待时间
+)
+
+// TestDataConnection 测试数据连接：附着分组域、激活 PDP 上下文并确认分配到 IP 地址
+// 结束后恢复测试前的状态：仅去激活由测试激活的上下文，仅分离由测试附着的分组域
+func (m *ModemInfo) TestDataConnection(cid int) (*models.DataTestResult, error) {
+	if cid < 1 || cid > 24 {
+		return nil, fmt.Errorf("%w: cid must be 1-24", ErrInvalid)
+	}
+	if m.InDataMode() {
+		return nil, ErrDataMode
+	}
+
+	result := &models.DataTestResult{CID: cid, Steps: []models.DataTestStep{}}
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start).Milliseconds() }()
+
+	step := func(name, cmd string, timeout time.Duration) ([]string, bool) {
+		t := time.Now()
+		responses, err := m.SendCommandWithTimeout(cmd, timeout)
+		if err == nil {
+			err = finalError(responses)
+		}
+		s := models.DataTestStep{
+			Step:     name,
+			Command:  cmd,
+			Response: responses,
+			Duration: time.Since(t).Milliseconds(),
+		}
+		if s.Response == nil {
+			s.Response = []string{}
+		}
+		if err != nil {
+			s.Error = err.Error()
+		}
+		result.Steps = append(result.Steps, s)
+		return responses, err == nil
+	}
+
+	// 附着分组域
+	attached := m.packetAttached()
+	if !attached {
+		if _, ok := step("attach", "AT+CGATT=1", attachTimeout); !ok {
+			return result, nil
+		}
+		defer step("detach", "AT+CGATT=0", attachTimeout)
+	}
+
+	// 激活 PDP 上下文
+	if !m.contextActive(cid) {
+		if _, ok := step("activate", fmt.Sprintf("AT+CGACT=1,%d", cid), activateTimeout); !ok {
+			return result, nil
+		}
+		defer step("deactivate", fmt.Sprintf("AT+CGACT=0,%d", cid), activateTimeout)
+	}
+
+	// 查询分配的地址
+	// +CGPADDR: <cid>,<address>[,<ipv6 address>]
+	responses, ok := step("address", fmt.Sprintf("AT+CGPADDR=%d", cid), atTimeout)
+	if !ok {
+		return result, nil
+	}
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label == "+CGPADDR" && len(param) > 1 && param[0] == strconv.Itoa(cid) && param[1] != "" && param[1] != "0.0.0.0" {
+			result.IP = param[1]
+		}
+	}
+	result.Success = result.IP != ""
+	return result, nil
+}
+
+// packetAttached 查询是否已附着分组域（+CGATT: <state>）
+func (m *ModemInfo) packetAttached() bool {
+	responses, err := m.SendCommand("AT+CGATT?")
+	if err != nil {
+		return false
+	}
+	for _, line := range responses {
+		if label, param := splitParam(line); label == "+CGATT" && len(param) > 0 {
+			return param[0] == "1"
+		}
+	}
+	return false
+}
+
+// contextActive 查询 PDP 上下文是否已激活（+CGACT: <cid>,<state>）
+func (m *ModemInfo) contextActive(cid int) bool {
+	responses, err := m.SendCommand("AT+CGACT?")
+	if err != nil {
+		return false
+	}
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label == "+CGACT" && len(param) > 1 && param[0] == strconv.Itoa(cid) {
+			return param[1] == "1"
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rehiy/modem/sms"
+	"github.com/rehiy/modem/sms/pdumode"
+	"github.com/rehiy/modem/sms/tpdu"
+	"github.com/rehiy/web-modem/models"
+)
+
+// deliveryHistorySize 每个模块保留的状态报告数量
+const deliveryHistorySize = 256
+
+// deliveryReports 短信状态报告，按消息参考号保存最新一条
+type deliveryReports struct {
+	mu      sync.Mutex
+	reports map[int]models.DeliveryReport
+	order   []int // 参考号的接收顺序，用于淘汰旧报告
+}
+
+// store 保存状态报告
+func (d *deliveryReports) store(report models.DeliveryReport) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.reports == nil {
+		d.reports = map[int]models.DeliveryReport{}
+	}
+	if _, ok := d.reports[report.Reference]; !ok {
+		d.order = append(d.order, report.Reference)
+	}
+	d.reports[report.Reference] = report
+
+	for len(d.order) > deliveryHistorySize {
+		delete(d.reports, d.order[0])
+		d.order = d.order[1:]
+	}
+}
+
+// deliveryStatus 根据 TP-ST 判断投递结果（3GPP TS 23.040 9.2.3.15）
+func deliveryStatus(st byte) string {
+	switch {
+	case st <= 0x1f:
+		return "delivered"
+	case st <= 0x3f:
+		return "pending" // 临时错误，短信中心仍在重试
+	default:
+		return "failed"
+	}
+}
+
+// handleStatusReport 处理 +CDSI: <mem>,<index> 通知，读取并删除存储的状态报告
+func (m *ModemInfo) handleStatusReport(param map[int]string) {
+	index, err := strconv.Atoi(param[1])
+	if err != nil {
+		return
+	}
+
+	report, err := m.readStatusReport(param[0], index)
+	if err != nil {
+		slog.Error("failed to read status report", slog.String("port", m.Name), slog.String("mem", param[0]), slog.Int("index", index), slog.Any("error", err))
+		return
+	}
+
+	m.deliveries.store(*report)
+	ModemEvent.Publish(EventDelivery, m.Name, *report)
+}
+
+// handlePushedStatusReport 处理 +CDS: <length> 通知后一行的状态报告 PDU
+func (m *ModemInfo) handlePushedStatusReport(data string) {
+	report, err := parseStatusReportPDU(data)
+	if err != nil {
+		slog.Error("failed to parse status report", slog.String("port", m.Name), slog.Any("error", err))
+		return
+	}
+
+	m.deliveries.store(*report)
+	ModemEvent.Publish(EventDelivery, m.Name, *report)
+}
+
+// readStatusReport 从指定存储区读取状态报告，读取后删除并恢复原有存储区选择
+// 整个过程作为一个任务排队，避免其他命令在切换后的存储区上执行
+func (m *ModemInfo) readStatusReport(mem string, index int) (*models.DeliveryReport, error) {
+	var report *models.DeliveryReport
+	var err error
+	if qerr := m.exec(false, func() {
+		report, err = m.statusReportTask(mem, index)
+	}); qerr != nil {
+		return nil, qerr
+	}
+	return report, err
+}
+
+// statusReportTask 在命令队列中读取并删除状态报告
+func (m *ModemInfo) statusReportTask(mem string, index int) (*models.DeliveryReport, error) {
+	if mem != "" {
+		selected, err := m.smsStorageSelection()
+		if err != nil {
+			return nil, err
+		}
+		if selected[0] != mem {
+			responses, err := m.sendCommand(fmt.Sprintf(`AT+CPMS="%s"`, mem))
+			if err == nil {
+				err = finalError(responses)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("select %s storage: %w", mem, err)
+			}
+			defer m.restoreSMSStorage(selected)
+		}
+	}
+
+	responses, err := m.sendCommand(fmt.Sprintf("AT+CMGR=%d", index))
+	if err != nil {
+		return nil, err
+	}
+	if err := finalError(responses); err != nil {
+		return nil, err
+	}
+	defer m.sendCommand(fmt.Sprintf("AT+CMGD=%d", index))
+
+	// +CMGR: <stat>,[<alpha>],<length> 的下一行为 PDU
+	for i, line := range responses {
+		if label, _ := splitParam(line); label != "+CMGR" || i+1 >= len(responses) {
+			continue
+		}
+		return parseStatusReportPDU(responses[i+1])
+	}
+	return nil, fmt.Errorf("failed to parse status report")
+}
+
+// parseStatusReportPDU 解析十六进制 PDU 形式的状态报告
+func parseStatusReportPDU(data string) (*models.DeliveryReport, error) {
+	pdu, err := pdumode.UnmarshalHexString(strings.TrimSpace(data))
+	if err != nil {
+		return nil, err
+	}
+	t, err := sms.Unmarshal(pdu.TPDU)
+	if err != nil {
+		return nil, err
+	}
+	return parseStatusReport(t)
+}
+
+// parseStatusReport 转换 SMS-STATUS-REPORT
+func parseStatusReport(t *tpdu.TPDU) (*models.DeliveryReport, error) {
+	if t.SmsType() != tpdu.SmsStatusReport {
+		return nil, fmt.Errorf("not a status report")
+	}
+	return &models.DeliveryReport{
+		Reference:       int(t.MR),
+		RecipientNumber: t.RA.Number(),
+		SubmitTime:      t.SCTS.Time,
+		DischargeTime:   t.DT.Time,
+		StatusCode:      int(t.ST),
+		Status:          deliveryStatus(t.ST),
+	}, nil
+}
+
+// GetDeliveryReport 获取指定参考号的最新状态报告
+func (m *ModemInfo) GetDeliveryReport(ref int) (*models.DeliveryReport, bool) {
+	m.deliveries.mu.Lock()
+	defer m.deliveries.mu.Unlock()
+	report, ok := m.deliveries.reports[ref]
+	return &report, ok
+}
+
+// GetDeliveryReports 获取全部状态报告，最新的在前
+func (m *ModemInfo) GetDeliveryReports() []models.DeliveryReport {
+	m.deliveries.mu.Lock()
+	defer m.deliveries.mu.Unlock()
+
+	result := make([]models.DeliveryReport, 0, len(m.deliveries.order))
+	for i := len(m.deliveries.order) - 1; i >= 0; i-- {
+		result = append(result, m.deliveries.reports[m.deliveries.order[i]])
+	}
+	return result
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHandleStatusReportFromStorage(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CPMS?", `+CPMS: "SM",1,20,"SM",1,20,"SM",1,20`+"\r\nOK")
+	script.reply("AT+CMGR=3", "+CMGR: 0,,25\r\n"+testStatusReportPDU+"\r\nOK")
+	m, _ := newTestModem(t, script)
+
+	m.handleStatusReport(map[int]string{0: "SR", 1: "3"})
+	report, ok := m.GetDeliveryReport(7)
+	if !ok || report.Status != "delivered" {
+		t.Fatalf("report = %+v, %v", report, ok)
+	}
+
+	// 在状态报告存储区中读取并删除后才恢复原有选择
+	want := []string{"AT+CPMS?", `AT+CPMS="SR"`, "AT+CMGR=3", "AT+CMGD=3", `AT+CPMS="SM","SM","SM"`}
+	if cmds := script.received(); !slices.Equal(cmds, want) {
+		t.Fatalf("commands = %q, want %q", cmds, want)
+	}
+}
+
+func TestReadStatusReportFailsWhenStorageRejected(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CPMS?", `+CPMS: "SM",1,20,"SM",1,20,"SM",1,20`+"\r\nOK")
+	script.reply(`AT+CPMS="SR"`, "+CMS ERROR: 302")
+	m, _ := newTestModem(t, script)
+
+	if _, err := m.readStatusReport("SR", 3); err == nil {
+		t.Fatal("read from unselected storage succeeded")
+	}
+	if slices.Contains(script.received(), "AT+CMGR=3") {
+		t.Fatal("status report read from wrong storage")
+	}
+}
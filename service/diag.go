@@ -0,0 +1,93 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// DiagCommands 诊断报告执行的只读命令，可在启动前追加
+// 厂商服务小区命令和模块配置中的 diag_commands 会追加在其后
+var DiagCommands = []string{
+	"ATI",
+	"AT+CGMI",
+	"AT+CGMM",
+	"AT+CGMR",
+	"AT+CGSN",
+	"AT+CPIN?",
+	"AT+CIMI",
+	"AT+CCID",
+	"AT+CSQ",
+	"AT+CREG?",
+	"AT+CGREG?",
+	"AT+CEREG?",
+	"AT+COPS?",
+	"AT+CGDCONT?",
+	"AT+CGATT?",
+	"AT+CPMS?",
+	"AT+CEER",
+}
+
+// diagCommandRe 配置中允许的诊断命令，只能是查询（AT+X?）或测试（AT+X=?）形式
+// 设置和执行形式可能修改模块状态，一律拒绝；分号拼接的多条命令同样无法匹配
+var diagCommandRe = regexp.MustCompile(`^(?i)AT[+^$%#*][A-Z0-9]+=?\?$`)
+
+// validateDiagCommand 校验配置中的诊断命令是否为只读形式
+func validateDiagCommand(cmd string) error {
+	if !diagCommandRe.MatchString(cmd) {
+		return fmt.Errorf("%w: diag command %q must be a read (AT+X?) or test (AT+X=?) command", ErrInvalid, cmd)
+	}
+	return nil
+}
+
+// GetDiagReport 依次执行诊断命令，汇总原始输出
+// 单个命令失败不影响其他命令，错误记录在对应条目中
+func (m *ModemInfo) GetDiagReport() (*models.DiagReport, error) {
+	commands := append([]string{}, DiagCommands...)
+	if cmd, ok := vendorCommand("serving_cell", m.Vendor); ok {
+		commands = append(commands, cmd)
+	}
+	config, err := m.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	builtin := len(commands)
+	commands = append(commands, config.DiagCommands...)
+
+	report := &models.DiagReport{
+		Name:     m.Name,
+		Vendor:   m.Vendor,
+		Time:     time.Now(),
+		Commands: []models.DiagEntry{},
+	}
+	for i, cmd := range commands {
+		// 配置的命令保存时已校验，此处再次检查以跳过旧版本保存的非只读命令
+		if i >= builtin {
+			if err := validateDiagCommand(cmd); err != nil {
+				report.Commands = append(report.Commands, models.DiagEntry{Command: cmd, Response: []string{}, Error: err.Error()})
+				continue
+			}
+		}
+		start := time.Now()
+		responses, err := m.SendCommand(cmd)
+		if err == nil {
+			err = finalError(responses)
+		}
+		entry := models.DiagEntry{
+			Command:  cmd,
+			Response: responses,
+			Duration: time.Since(start).Milliseconds(),
+		}
+		if entry.Response == nil {
+			entry.Response = []string{}
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		report.Commands = append(report.Commands, entry)
+	}
+	report.EchoCount = m.echoCount.Load()
+	return report, nil
+}
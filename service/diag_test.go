@@ -0,0 +1,53 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/rehiy/web-modem/database"
+	"github.com/rehiy/web-modem/models"
+)
+
+func TestValidateDiagCommand(t *testing.T) {
+	for _, cmd := range []string{"AT+CSQ?", "AT+COPS=?", "at+cpms?", "AT^SYSINFOEX?", "AT+QCFG=?"} {
+		if err := validateDiagCommand(cmd); err != nil {
+			t.Errorf("validateDiagCommand(%q) = %v", cmd, err)
+		}
+	}
+	for _, cmd := range []string{"AT+CFUN=0", "AT+CFUN=1,1", "ATZ", "AT+CSQ", "AT+CSQ?;+CFUN=0", `AT+QCFG="usbnet"?`, "AT+CSQ? ", "+CSQ?"} {
+		if err := validateDiagCommand(cmd); !errors.Is(err, ErrInvalid) {
+			t.Errorf("validateDiagCommand(%q) = %v, want ErrInvalid", cmd, err)
+		}
+	}
+}
+
+func TestDiagReportSkipsWriteCommands(t *testing.T) {
+	initTestDB(t)
+	script := &scriptedModem{respond: func(cmd string) (string, bool) { return "OK", true }}
+	m, _ := newTestModem(t, script)
+
+	// 模拟旧版本保存的设置命令
+	config := &models.ModemConfig{Name: m.Name, DiagCommands: []string{"AT+QCFG=?", "AT+CFUN=0"}}
+	if err := database.SaveModemConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.SaveModemConfig(&models.ModemConfig{Name: m.Name}) })
+
+	report, err := m.GetDiagReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := script.received()
+	if !slices.Contains(sent, "AT+QCFG=?") || slices.Contains(sent, "AT+CFUN=0") {
+		t.Fatalf("sent = %q", sent)
+	}
+	last := report.Commands[len(report.Commands)-1]
+	if last.Command != "AT+CFUN=0" || last.Error == "" {
+		t.Fatalf("last entry = %+v", last)
+	}
+
+	if err := m.SaveConfig(config); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("SaveConfig err = %v, want ErrInvalid", err)
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// esimTimeout eSIM 命令的等待时间，配置文件切换需访问 eUICC，耗时较长
+const esimTimeout = 10 * time.Second
+
+// iccidRe ICCID 格式，部分 SIM 卡以 F 补齐
+var iccidRe = regexp.MustCompile(`^[0-9]{18,20}[fF]?$`)
+
+func init() {
+	RegisterParser("", `AT+QESIM="list"`, parseQuectelESIMProfiles)
+}
+
+// ListESIMProfiles 列出 eUICC 中已安装的配置文件
+// 仅支持提供 LPA 命令的模块，其他模块或固件不识别该命令（返回 ERROR）时返回 ErrUnsupported
+func (m *ModemInfo) ListESIMProfiles() ([]models.ESIMProfile, error) {
+	cmd, ok := vendorCommand("esim_list", m.Vendor)
+	if !ok {
+		return nil, fmt.Errorf("esim %w by %s modem", ErrUnsupported, m.Vendor)
+	}
+
+	responses, err := m.SendCommandWithTimeout(cmd, esimTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if l := len(responses); l > 0 && responses[l-1] == "ERROR" {
+		return nil, fmt.Errorf("esim %w by this modem firmware", ErrUnsupported)
+	}
+	if err := finalError(responses); err != nil {
+		return nil, err
+	}
+
+	fn := lookupParser(m.Vendor, cmd)
+	if fn == nil {
+		return nil, fmt.Errorf("no parser registered for %s", cmd)
+	}
+	v, err := fn(responses)
+	if err != nil {
+		return nil, err
+	}
+	profiles, ok := v.([]models.ESIMProfile)
+	if !ok {
+		return nil, fmt.Errorf("unexpected esim parser result %T", v)
+	}
+	return profiles, nil
+}
+
+// EnableESIMProfile 启用指定的配置文件，模块会切换到该配置文件
+func (m *ModemInfo) EnableESIMProfile(iccid string) error {
+	return m.esimAction("esim_enable", iccid)
+}
+
+// DisableESIMProfile 停用配置文件，iccid 为空时停用当前启用的配置文件
+func (m *ModemInfo) DisableESIMProfile(iccid string) error {
+	if iccid == "" {
+		profiles, err := m.ListESIMProfiles()
+		if err != nil {
+			return err
+		}
+		for _, p := range profiles {
+			if p.Enabled {
+				iccid = p.ICCID
+			}
+		}
+		if iccid == "" {
+			return fmt.Errorf("%w: no enabled esim profile", ErrInvalid)
+		}
+	}
+	return m.esimAction("esim_disable", iccid)
+}
+
+// DeleteESIMProfile 删除指定的配置文件，启用中的配置文件需先停用
+func (m *ModemInfo) DeleteESIMProfile(iccid string) error {
+	return m.esimAction("esim_delete", iccid)
+}
+
+// esimAction 执行配置文件操作
+func (m *ModemInfo) esimAction(feature, iccid string) error {
+	cmd, ok := vendorCommand(feature, m.Vendor)
+	if !ok {
+		return fmt.Errorf("esim %w by %s modem", ErrUnsupported, m.Vendor)
+	}
+	if !iccidRe.MatchString(iccid) {
+		return fmt.Errorf("%w: iccid %q", ErrInvalid, iccid)
+	}
+
+	responses, err := m.SendCommandWithTimeout(fmt.Sprintf(cmd, iccid), esimTimeout)
+	if err != nil {
+		return err
+	}
+	return finalError(responses)
+}
+
+// parseQuectelESIMProfiles 解析 +QESIM: "list" 返回的配置文件
+// 每行一个配置文件：+QESIM: "list",<iccid>,<state>[,<name>[,<provider>]]，state 为 1 表示启用
+func parseQuectelESIMProfiles(responses []string) (any, error) {
+	profiles := []models.ESIMProfile{}
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "+QESIM" {
+			continue
+		}
+
+		// 定位 ICCID 字段，兼容不同固件在其前后附加的字段
+		for i, v := range param {
+			if !iccidRe.MatchString(v) {
+				continue
+			}
+			profile := models.ESIMProfile{ICCID: v}
+			rest := param[i+1:]
+			if len(rest) > 0 {
+				state, err := strconv.Atoi(rest[0])
+				if err != nil {
+					return nil, fmt.Errorf("invalid esim profile state %q", rest[0])
+				}
+				profile.Enabled = state == 1
+				rest = rest[1:]
+			}
+			if len(rest) > 0 {
+				profile.Name = rest[0]
+			}
+			if len(rest) > 1 {
+				profile.Provider = rest[1]
+			}
+			profiles = append(profiles, profile)
+			break
+		}
+	}
+	return profiles, nil
+}
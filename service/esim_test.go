@@ -0,0 +1,45 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+func TestParseQuectelESIMProfiles(t *testing.T) {
+	v, err := parseQuectelESIMProfiles([]string{
+		`+QESIM: "list","89860012345678901234",1,"Travel","Carrier A"`,
+		`+QESIM: "list","8986001234567890123F",0`,
+		"OK",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles := v.([]models.ESIMProfile)
+	if len(profiles) != 2 || !profiles[0].Enabled || profiles[0].Name != "Travel" || profiles[0].Provider != "Carrier A" || profiles[1].Enabled {
+		t.Fatalf("profiles = %+v", profiles)
+	}
+
+	if _, err := parseQuectelESIMProfiles([]string{`+QESIM: "list","89860012345678901234",x`, "OK"}); err == nil {
+		t.Fatal("invalid state should be an error")
+	}
+}
+
+func TestListESIMProfilesErrors(t *testing.T) {
+	script := &scriptedModem{}
+	m, _ := newTestModem(t, script)
+	m.Vendor = VendorQuectel
+
+	// 固件不识别命令
+	script.reply(`AT+QESIM="list"`, "ERROR")
+	if _, err := m.ListESIMProfiles(); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("ERROR: err = %v, want ErrUnsupported", err)
+	}
+
+	// 卡片返回的具体错误原样返回，不视为不支持
+	script.reply(`AT+QESIM="list"`, "+CME ERROR: 10")
+	if _, err := m.ListESIMProfiles(); err == nil || errors.Is(err, ErrUnsupported) {
+		t.Fatalf("CME error: err = %v", err)
+	}
+}
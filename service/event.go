@@ -0,0 +1,310 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// eventHistorySize 保留用于补发的历史事件数量
+const eventHistorySize = 512
+
+// ModemEvent 模块事件广播中心
+var ModemEvent = NewEventHub(eventHistorySize)
+
+// 事件类型，端口状态变化以 PortReconnected / PortDisconnected 为类型
+const (
+	EventURC              = "urc"               // URCData
+	EventSMS              = "sms"               // *models.SMS
+	EventSignal           = "signal"            // models.SignalSample
+	EventCall             = "call"              // models.CallRecord
+	EventCallState        = "call_state"        // []models.CallState
+	EventUSSD             = "ussd"              // models.USSDResponse
+	EventRegistration     = "registration"      // models.RegistrationEvent
+	EventDelivery         = "delivery"          // models.DeliveryReport
+	EventModemReset       = "modem_reset"       // ResetData
+	EventConnect          = "connect"           // 无数据
+	EventDisconnect       = "disconnect"        // 无数据
+	EventOperatorReselect = "operator_reselect" // ReselectData
+)
+
+// Event 模块事件
+type Event struct {
+	Seq   uint64    `json:"seq"`
+	Type  string    `json:"type"`
+	Port  string    `json:"port,omitempty"`
+	Alias string    `json:"alias,omitempty"` // 端口别名
+	Time  time.Time `json:"time"`
+	Data  any       `json:"data,omitempty"`
+
+	encoded []byte // 发布时编码一次，所有订阅者共用
+}
+
+// eventJSON 去掉 MarshalJSON 方法的事件类型，用于编码
+type eventJSON Event
+
+// MarshalJSON 返回发布时编码的结果，数据为发布时的快照
+func (e Event) MarshalJSON() ([]byte, error) {
+	if e.encoded != nil {
+		return e.encoded, nil
+	}
+	return json.Marshal(eventJSON(e))
+}
+
+// JSON 返回事件的 JSON 编码
+func (e Event) JSON() []byte {
+	b, _ := e.MarshalJSON()
+	return b
+}
+
+// URCData 模块主动上报（URC）数据
+type URCData struct {
+	Label  string         `json:"label"`
+	Params map[int]string `json:"params"`
+}
+
+// ResetData 模块重启事件数据
+type ResetData struct {
+	Trigger string `json:"trigger"` // 触发检测的开机提示
+}
+
+// BackpressurePolicy 订阅通道已满时的处理方式
+type BackpressurePolicy int
+
+const (
+	BackpressureDrop       BackpressurePolicy = iota // 丢弃新事件
+	BackpressureDropOldest                           // 丢弃通道中最早的事件，保留新事件
+	BackpressureBlock                                // 事件先进入订阅的待发送队列，由独立协程等待订阅者读取
+)
+
+// maxBlockPending Block 策略待发送队列的长度，队列满时丢弃新事件
+// 发布方包括串口读取循环，不能等待卡住的订阅者
+const maxBlockPending = 1024
+
+// ParseBackpressure 解析 drop / oldest / block，为空时返回 def
+func ParseBackpressure(s string, def BackpressurePolicy) (BackpressurePolicy, error) {
+	switch s {
+	case "":
+		return def, nil
+	case "drop":
+		return BackpressureDrop, nil
+	case "oldest":
+		return BackpressureDropOldest, nil
+	case "block":
+		return BackpressureBlock, nil
+	}
+	return def, fmt.Errorf("%w: backpressure must be drop, oldest or block", ErrInvalid)
+}
+
+// subscription 事件订阅
+type subscription struct {
+	policy  BackpressurePolicy
+	pending chan Event    // Block 策略的待发送队列，其他策略为 nil
+	done    chan struct{} // 取消订阅时关闭，解除 Block 策略下的等待
+}
+
+// EventHub 事件广播中心
+// 每个事件分配单调递增的序号，并保留最近的事件供断线重连后补发
+type EventHub struct {
+	mu      sync.Mutex
+	seq     uint64
+	history []Event
+	next    int
+	full    bool
+	subs    map[chan Event]*subscription
+}
+
+// NewEventHub 创建事件广播中心
+func NewEventHub(size int) *EventHub {
+	return &EventHub{
+		history: make([]Event, size),
+		subs:    map[chan Event]*subscription{},
+	}
+}
+
+// Publish 广播事件，返回带序号的事件
+func (h *EventHub) Publish(typ, port string, data any) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	event := Event{
+		Seq:   h.seq,
+		Type:  typ,
+		Port:  port,
+		Alias: AliasOf(port),
+		Time:  time.Now(),
+		Data:  data,
+	}
+	if b, err := json.Marshal(eventJSON(event)); err == nil {
+		event.encoded = b
+	}
+
+	// 写入历史环形缓冲区
+	h.history[h.next] = event
+	h.next = (h.next + 1) % len(h.history)
+	if h.next == 0 {
+		h.full = true
+	}
+
+	// 分发给订阅者，通道满了按订阅的策略处理
+	for ch, sub := range h.subs {
+		sub.send(ch, event)
+	}
+
+	return event
+}
+
+// send 按背压策略写入订阅通道，调用方需持有锁，不会阻塞
+func (sub *subscription) send(ch chan Event, event Event) {
+	if sub.pending != nil {
+		select {
+		case sub.pending <- event:
+		default:
+		}
+		return
+	}
+
+	select {
+	case ch <- event:
+		return
+	default:
+	}
+
+	switch sub.policy {
+	case BackpressureDropOldest:
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// forward 将待发送队列中的事件按顺序写入订阅通道，在锁外等待订阅者读取
+// 待发送队列关闭或取消订阅后关闭订阅通道
+func (sub *subscription) forward(ch chan Event) {
+	defer close(ch)
+	for event := range sub.pending {
+		select {
+		case ch <- event:
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+// Latest 返回最新的事件序号
+func (h *EventHub) Latest() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.seq
+}
+
+// Subscribe 订阅事件，通道满时丢弃新事件
+// 返回序号大于 since 的历史事件、后续事件通道和取消订阅函数；
+// 历史事件与通道之间不会遗漏或重复
+func (h *EventHub) Subscribe(since uint64, buffer int) ([]Event, chan Event, func()) {
+	return h.SubscribeWithPolicy(since, buffer, BackpressureDrop)
+}
+
+// SubscribeWithPolicy 订阅事件，通道满时按 policy 处理
+func (h *EventHub) SubscribeWithPolicy(since uint64, buffer int, policy BackpressurePolicy) ([]Event, chan Event, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	replay := []Event{}
+	for _, event := range h.ordered() {
+		if event.Seq > since {
+			replay = append(replay, event)
+		}
+	}
+
+	ch := make(chan Event, buffer)
+	sub := &subscription{policy: policy, done: make(chan struct{})}
+	if policy == BackpressureBlock {
+		sub.pending = make(chan Event, maxBlockPending)
+		go sub.forward(ch)
+	}
+	h.subs[ch] = sub
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() { close(sub.done) })
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subs[ch]; ok {
+			delete(h.subs, ch)
+			// Block 策略的订阅通道由转发协程关闭
+			if sub.pending != nil {
+				close(sub.pending)
+			} else {
+				close(ch)
+			}
+		}
+	}
+
+	return replay, ch, cancel
+}
+
+// FilteredSubscription 订阅端口名满足 filter 的事件
+// 后台协程持续读取原始订阅通道并丢弃不匹配的事件，避免拖慢广播；
+// 订阅者读取不及时时，原始通道按 policy 处理
+func (h *EventHub) FilteredSubscription(since uint64, buffer int, policy BackpressurePolicy, filter func(string) bool) ([]Event, chan Event, func()) {
+	replay, events, cancel := h.SubscribeWithPolicy(since, buffer, policy)
+
+	matched := []Event{}
+	for _, event := range replay {
+		if filter(event.Port) {
+			matched = append(matched, event)
+		}
+	}
+
+	ch := make(chan Event, buffer)
+	done := make(chan struct{})
+	go func() {
+		defer close(ch)
+		for event := range events {
+			if !filter(event.Port) {
+				continue
+			}
+			select {
+			case ch <- event:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(done)
+			cancel()
+		})
+	}
+
+	return matched, ch, stop
+}
+
+// Oldest 返回缓冲区中最早的事件序号，无事件时返回 0
+func (h *EventHub) Oldest() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if events := h.ordered(); len(events) > 0 {
+		return events[0].Seq
+	}
+	return 0
+}
+
+// ordered 按序号返回历史事件，调用方需持有锁
+func (h *EventHub) ordered() []Event {
+	if !h.full {
+		return h.history[:h.next]
+	}
+	return append(append([]Event{}, h.history[h.next:]...), h.history[:h.next]...)
+}
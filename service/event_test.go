@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBlockSubscriberDoesNotStallPublish(t *testing.T) {
+	h := NewEventHub(16)
+	_, events, cancel := h.SubscribeWithPolicy(0, 1, BackpressureBlock)
+	defer cancel()
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		h.Publish(EventURC, "ttyTEST", i)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("publish waited %s for a slow subscriber", elapsed)
+	}
+
+	// 事件不丢失且保持顺序
+	for want := uint64(1); want <= 100; want++ {
+		select {
+		case event := <-events:
+			if event.Seq != want {
+				t.Fatalf("seq %d, want %d", event.Seq, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d not delivered", want)
+		}
+	}
+}
+
+func TestDropOldestKeepsNewest(t *testing.T) {
+	h := NewEventHub(16)
+	_, events, cancel := h.SubscribeWithPolicy(0, 2, BackpressureDropOldest)
+	defer cancel()
+
+	for i := 0; i < 5; i++ {
+		h.Publish(EventURC, "ttyTEST", i)
+	}
+	if a, b := <-events, <-events; a.Seq != 4 || b.Seq != 5 {
+		t.Fatalf("got seq %d, %d", a.Seq, b.Seq)
+	}
+}
+
+func TestCancelClosesBlockSubscription(t *testing.T) {
+	h := NewEventHub(16)
+	_, events, cancel := h.SubscribeWithPolicy(0, 0, BackpressureBlock)
+	h.Publish(EventURC, "ttyTEST", nil)
+	cancel()
+	h.Publish(EventURC, "ttyTEST", nil)
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("subscription channel not closed")
+		}
+	}
+}
+
+// BenchmarkPublishWithStalledSubscriber 订阅者不读取时发布事件的耗时
+func BenchmarkPublishWithStalledSubscriber(b *testing.B) {
+	for _, policy := range []struct {
+		name   string
+		policy BackpressurePolicy
+	}{{"drop", BackpressureDrop}, {"oldest", BackpressureDropOldest}, {"block", BackpressureBlock}} {
+		b.Run(policy.name, func(b *testing.B) {
+			h := NewEventHub(eventHistorySize)
+			_, _, cancel := h.SubscribeWithPolicy(0, 100, policy.policy)
+			defer cancel()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				h.Publish(EventURC, "ttyTEST", i)
+			}
+		})
+	}
+}
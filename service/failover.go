@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCircuitFailures = 3                // 默认熔断前允许的连续失败次数
+	defaultCircuitWindow   = 60 * time.Second // 默认失败统计窗口和熔断时长
+)
+
+// 熔断器状态
+const (
+	CircuitClosed   = "closed"    // 正常发送
+	CircuitOpen     = "open"      // 熔断中，直接跳过
+	CircuitHalfOpen = "half-open" // 熔断到期，允许一次试探
+)
+
+// circuitConfig 熔断阈值，由 CIRCUIT_FAILURES 和 CIRCUIT_WINDOW_SECONDS 设置
+var circuitConfig = sync.OnceValues(func() (int, time.Duration) {
+	failures, window := defaultCircuitFailures, defaultCircuitWindow
+	if v, err := strconv.Atoi(os.Getenv("CIRCUIT_FAILURES")); err == nil && v > 0 {
+		failures = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("CIRCUIT_WINDOW_SECONDS")); err == nil && v > 0 {
+		window = time.Duration(v) * time.Second
+	}
+	return failures, window
+})
+
+// circuitBreaker 单个端口的熔断器
+// 窗口内失败达到阈值后熔断，熔断一个窗口后进入半开状态，试探成功则恢复
+type circuitBreaker struct {
+	mu          sync.Mutex
+	state       string
+	failures    int
+	lastFailure time.Time
+	probing     bool // 半开状态下已有试探请求
+}
+
+// allow 是否允许发送，半开状态只放行一个试探请求
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, window := circuitConfig()
+	if b.state == CircuitOpen && now.Sub(b.lastFailure) >= window {
+		b.state, b.probing = CircuitHalfOpen, false
+	}
+	switch b.state {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+// record 记录发送结果
+func (b *circuitBreaker) record(err error, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.state, b.failures, b.probing = CircuitClosed, 0, false
+		return
+	}
+
+	threshold, window := circuitConfig()
+	if now.Sub(b.lastFailure) > window {
+		b.failures = 0
+	}
+	b.failures++
+	b.lastFailure = now
+	if b.state == CircuitHalfOpen || b.failures >= threshold {
+		b.state, b.probing = CircuitOpen, false
+	}
+}
+
+// release 结果与模块状态无关时（参数无效、请求取消）放弃本次试探，不计入结果
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// breaker 返回端口的熔断器，不存在时创建
+func (m *ModemService) breaker(name string) *circuitBreaker {
+	m.breakerMu.Lock()
+	defer m.breakerMu.Unlock()
+
+	b, ok := m.breakers[name]
+	if !ok {
+		b = &circuitBreaker{state: CircuitClosed}
+		m.breakers[name] = b
+	}
+	return b
+}
+
+// SendSMSWithFailover 按顺序尝试各端口发送短信，第一个成功即停止，返回使用的端口和实际尝试次数
+// 熔断中的端口直接跳过；全部失败时返回各端口错误的合并
+// 参数无效或已有 PDU 写入模块时不再尝试后续端口，避免重复发送
+func (m *ModemService) SendSMSWithFailover(ctx context.Context, number, message string, ports []string) (string, int, error) {
+	if len(ports) == 0 {
+		return "", 0, fmt.Errorf("%w: ports is required", ErrInvalid)
+	}
+
+	attempts := 0
+	errs := []error{}
+	for _, port := range ports {
+		name := portName(port)
+		conn, err := m.GetConnect(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := m.CheckLease(name, ""); err != nil {
+			errs = append(errs, fmt.Errorf("[%s] %w", name, err))
+			continue
+		}
+
+		b := m.breaker(name)
+		if !b.allow(time.Now()) {
+			errs = append(errs, fmt.Errorf("[%s] circuit open", name))
+			continue
+		}
+
+		attempts++
+		_, err = conn.SendSMS(ctx, number, message, SendOptions{})
+		if errors.Is(err, ErrInvalid) || ctx.Err() != nil {
+			b.release()
+			return name, attempts, err
+		}
+		b.record(err, time.Now())
+		if err == nil || errors.Is(err, ErrSMSSubmitted) {
+			return name, attempts, err
+		}
+		slog.WarnContext(ctx, "failover sms failed, trying next port", slog.String("port", name), slog.Any("error", err))
+		errs = append(errs, fmt.Errorf("[%s] %w", name, err))
+	}
+	return "", attempts, fmt.Errorf("%w: all ports failed: %w", ErrNoModem, errors.Join(errs...))
+}
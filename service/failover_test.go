@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// rejectPrompt 模拟 AT+CMGS 直接返回错误的模块，PDU 未写入
+func rejectPrompt() *scriptedModem {
+	return &scriptedModem{respond: func(cmd string) (string, bool) {
+		if strings.HasPrefix(cmd, "AT+CMGS=") {
+			return "+CMS ERROR: 500", true
+		}
+		return "", false
+	}}
+}
+
+// rejectSubmit 模拟写入 PDU 后提交失败的模块，failAt 为第几个分段开始失败（从 1 开始）
+func rejectSubmit(failAt int) *scriptedModem {
+	n := 0
+	return &scriptedModem{respond: func(cmd string) (string, bool) {
+		switch {
+		case strings.HasPrefix(cmd, "AT+CMGS="):
+			return ">", true
+		case !strings.HasPrefix(cmd, "AT"):
+			if n++; n >= failAt {
+				return "+CMS ERROR: 500", true
+			}
+			return "+CMGS: 1\r\nOK", true
+		}
+		return "", false
+	}}
+}
+
+// newSendTestService 创建包含 ttyA 和 ttyB 两个模块的连接池
+func newSendTestService(t *testing.T, a, b *scriptedModem) *ModemService {
+	s := &ModemService{pool: map[string]*ModemInfo{}, breakers: map[string]*circuitBreaker{}}
+	for name, script := range map[string]*scriptedModem{"ttyA": a, "ttyB": b} {
+		m, _ := newTestModem(t, script)
+		m.Name = name
+		s.pool[name] = m
+	}
+	return s
+}
+
+func TestFailoverTriesNextPortBeforeSubmit(t *testing.T) {
+	s := newSendTestService(t, rejectPrompt(), smsModem(1))
+
+	port, attempts, err := s.SendSMSWithFailover(context.Background(), "10086", "hello", []string{"ttyA", "ttyB"})
+	if err != nil || port != "ttyB" || attempts != 2 {
+		t.Fatalf("port %q, attempts %d, err %v", port, attempts, err)
+	}
+}
+
+func TestFailoverStopsAfterSubmit(t *testing.T) {
+	b := smsModem(1)
+	s := newSendTestService(t, rejectSubmit(1), b)
+
+	port, attempts, err := s.SendSMSWithFailover(context.Background(), "10086", "hello", []string{"ttyA", "ttyB"})
+	if !errors.Is(err, ErrSMSSubmitted) || port != "ttyA" || attempts != 1 {
+		t.Fatalf("port %q, attempts %d, err %v", port, attempts, err)
+	}
+	if len(b.received()) != 0 {
+		t.Fatalf("second port used after submit: %q", b.received())
+	}
+}
+
+func TestSendSMSInvalidOptionsIsInvalid(t *testing.T) {
+	s := newSendTestService(t, smsModem(1), smsModem(1))
+	class := 5
+	conn, _ := s.GetConnect("ttyA")
+	if _, err := conn.SendSMS(context.Background(), "10086", "hello", SendOptions{Class: &class}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("invalid class: %v", err)
+	}
+}
+
+func TestLongSMSFailureAfterFirstSegmentIsSubmitted(t *testing.T) {
+	// 第二个分段在提示符阶段失败，第一个分段已发出，没有收到参考号也不能换用其他模块
+	n := 0
+	script := &scriptedModem{respond: func(cmd string) (string, bool) {
+		switch {
+		case strings.HasPrefix(cmd, "AT+CMGS="):
+			if n++; n == 2 {
+				return "+CMS ERROR: 500", true
+			}
+			return ">", true
+		case !strings.HasPrefix(cmd, "AT"):
+			return "OK", true
+		}
+		return "", false
+	}}
+	m, _ := newTestModem(t, script)
+
+	refs, err := m.SendSMS(context.Background(), "10086", strings.Repeat("a", 200), SendOptions{})
+	if !errors.Is(err, ErrSMSSubmitted) {
+		t.Fatalf("err = %v", err)
+	}
+	if len(refs) != 0 {
+		t.Fatalf("refs = %v", refs)
+	}
+}
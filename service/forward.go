@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sync"
+
+	"github.com/rehiy/web-modem/database"
+	"github.com/rehiy/web-modem/models"
+)
+
+// compiledRule 已编译发送方匹配表达式的转发规则
+type compiledRule struct {
+	models.ForwardRule
+	from *regexp.Regexp
+}
+
+// RulesEngine 短信转发规则引擎
+// 规则保存在数据库中，首次使用和规则变更后重新加载
+type RulesEngine struct {
+	mu     sync.RWMutex
+	rules  []compiledRule
+	loaded bool
+}
+
+// ForwardRules 全局短信转发规则引擎
+var ForwardRules = &RulesEngine{}
+
+// ValidateForwardRule 校验转发规则
+func ValidateForwardRule(rule *models.ForwardRule) error {
+	if rule.FromPattern == "" {
+		return fmt.Errorf("%w: from_pattern is required", ErrInvalid)
+	}
+	if _, err := regexp.Compile(rule.FromPattern); err != nil {
+		return fmt.Errorf("%w: from_pattern: %v", ErrInvalid, err)
+	}
+	if rule.ToNumber == "" && rule.ToWebhook == "" {
+		return fmt.Errorf("%w: to_number or to_webhook is required", ErrInvalid)
+	}
+	if rule.ToNumber != "" && !dialNumberRe.MatchString(rule.ToNumber) {
+		return fmt.Errorf("%w: to_number %q", ErrInvalid, rule.ToNumber)
+	}
+	return nil
+}
+
+// Reload 从数据库重新加载规则，表达式无效的规则记录日志后跳过
+func (e *RulesEngine) Reload() error {
+	rules, err := database.GetForwardRules()
+	if err != nil {
+		return err
+	}
+
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.FromPattern)
+		if err != nil {
+			slog.Warn("forward: skip rule with invalid from_pattern", slog.Int("rule", rule.ID), slog.String("pattern", rule.FromPattern), slog.Any("error", err))
+			continue
+		}
+		compiled = append(compiled, compiledRule{ForwardRule: rule, from: re})
+	}
+
+	e.mu.Lock()
+	e.rules, e.loaded = compiled, true
+	e.mu.Unlock()
+	return nil
+}
+
+// match 返回匹配发送方号码的启用规则
+func (e *RulesEngine) match(number string) []compiledRule {
+	e.mu.RLock()
+	loaded := e.loaded
+	e.mu.RUnlock()
+	if !loaded {
+		if err := e.Reload(); err != nil {
+			slog.Error("forward: failed to load rules", slog.Any("error", err))
+			return nil
+		}
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	matched := []compiledRule{}
+	for _, rule := range e.rules {
+		if rule.Enabled && rule.from.MatchString(number) {
+			matched = append(matched, rule)
+		}
+	}
+	return matched
+}
+
+// Apply 按规则转发收到的短信：转发到号码时由接收短信的模块发送，转发到 webhook 时加入投递队列
+func (e *RulesEngine) Apply(conn *ModemInfo, sms *models.SMS) {
+	for _, rule := range e.match(sms.SendNumber) {
+		if rule.ToNumber != "" {
+			text := fmt.Sprintf("Fwd from %s: %s", sms.SendNumber, sms.Content)
+			if _, err := conn.SendSMS(context.Background(), rule.ToNumber, text, SendOptions{}); err != nil {
+				slog.Error("forward: rule failed", slog.Int("rule", rule.ID), slog.String("to", rule.ToNumber), slog.Any("error", err))
+			}
+		}
+		if rule.ToWebhook != "" {
+			webhook := &models.Webhook{Name: fmt.Sprintf("forward rule %d", rule.ID), URL: rule.ToWebhook}
+			payload, err := NewWebhookService().getDefaultPayload(smsEvent(sms))
+			if err != nil {
+				slog.Error("forward: failed to prepare payload", slog.Int("rule", rule.ID), slog.Any("error", err))
+				continue
+			}
+			enqueueWebhook(webhookJob{Webhook: *webhook, Payload: payload})
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHealthCheckInterval = 60 * time.Second // 默认健康检查间隔
+	healthCheckAttempts        = 2                // 连续失败该次数后视为模块失联
+	healthCheckTimeout         = 5 * time.Second  // 每次检查等待排队和响应的最长时间
+)
+
+// removeModem 从连接池移除指定连接并关闭，广播 disconnect 事件
+// 连接池中已是其他连接（如重新连接后）时不做处理，返回 false
+func (m *ModemService) removeModem(modem *ModemInfo) bool {
+	m.mu.Lock()
+	ok := m.pool[modem.Name] == modem
+	if ok {
+		delete(m.pool, modem.Name)
+	}
+	m.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	modem.Close()
+	modemInfoCache.Invalidate(modem.Name)
+	ModemEvent.Publish(EventDisconnect, modem.Name, nil)
+	return true
+}
+
+// Remove 关闭并移除端口的连接
+func (m *ModemService) Remove(name string) error {
+	n := portName(name)
+	m.mu.Lock()
+	modem, ok := m.pool[n]
+	m.mu.Unlock()
+	if !ok || !m.removeModem(modem) {
+		return fmt.Errorf("[%s] not connected", n)
+	}
+	slog.Info("modem removed", slog.String("port", n))
+	return nil
+}
+
+// HealthCheck 向每个已连接的模块发送 AT，返回各端口的检查结果，不移除任何连接
+// 数据模式下串口不接受 AT 命令，视为正常；正在重连的端口视为失败
+func (m *ModemService) HealthCheck(ctx context.Context) map[string]bool {
+	modems := m.GetModems()
+	errs := m.pingAll(ctx, modems)
+	result := make(map[string]bool, len(modems))
+	for i, modem := range modems {
+		result[modem.Name] = errs[i] == nil
+	}
+	return result
+}
+
+// checkHealth 执行一次健康检查并移除没有响应的模块，由后台定时调用
+// 正在重连的端口由重连流程处理，不在此移除
+func (m *ModemService) checkHealth() {
+	modems := m.GetModems()
+	errs := m.pingAll(context.Background(), modems)
+	for i, modem := range modems {
+		if errs[i] == nil || modem.port.Reconnecting() {
+			continue
+		}
+		slog.Warn("health check failed, removing modem", slog.String("port", modem.Name), slog.Any("error", errs[i]))
+		m.removeModem(modem)
+	}
+}
+
+// pingAll 并发检查各模块，返回与 modems 顺序一致的结果
+func (m *ModemService) pingAll(ctx context.Context, modems []*ModemInfo) []error {
+	errs := make([]error, len(modems))
+	var wg sync.WaitGroup
+	for i, modem := range modems {
+		wg.Add(1)
+		go func(i int, modem *ModemInfo) {
+			defer wg.Done()
+			errs[i] = modem.ping(ctx)
+		}(i, modem)
+	}
+	wg.Wait()
+	return errs
+}
+
+// ping 发送 AT 检查模块是否响应，失败时重试，避免偶发超时误判
+// 每次尝试最多等待 healthCheckTimeout，期间命令仍在排队说明模块正忙于其他命令，视为正常
+func (m *ModemInfo) ping(ctx context.Context) error {
+	var err error
+	for i := 0; i < healthCheckAttempts; i++ {
+		if !m.IsOpen() {
+			return fmt.Errorf("port closed")
+		}
+		if m.port.Reconnecting() {
+			return fmt.Errorf("port reconnecting")
+		}
+		if m.InDataMode() {
+			return nil
+		}
+		if err = m.pingOnce(ctx); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// pingOnce 发送一次 AT，排队超时视为模块忙碌
+func (m *ModemInfo) pingOnce(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	responses, err := m.SendATCommand(ctx, "AT", atTimeout)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return finalError(responses)
+}
+
+// StartHealthCheck 后台定期执行健康检查
+// 间隔由 HEALTH_CHECK_INTERVAL_SECONDS 设置，默认 60 秒，设为 0 时关闭
+func (m *ModemService) StartHealthCheck() {
+	interval := defaultHealthCheckInterval
+	if v := os.Getenv("HEALTH_CHECK_INTERVAL_SECONDS"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			slog.Warn("invalid HEALTH_CHECK_INTERVAL_SECONDS", slog.String("value", v), slog.Duration("using", interval))
+		} else {
+			interval = time.Duration(seconds) * time.Second
+		}
+	}
+	if interval == 0 {
+		slog.Info("health check disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			m.checkHealth()
+		}
+	}()
+}
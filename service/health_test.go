@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"testing"
+)
+
+// newHealthTestService 创建包含一个正常模块和一个没有响应的模块的连接池
+func newHealthTestService(t *testing.T) (*ModemService, *ModemInfo, *ModemInfo) {
+	good, _ := newTestModem(t, &scriptedModem{})
+	good.Name = "ttyGOOD"
+	script := &scriptedModem{}
+	dead, _ := newTestModem(t, script)
+	dead.Name = "ttyDEAD"
+	script.mu.Lock()
+	script.silent = true
+	script.mu.Unlock()
+	s := &ModemService{pool: map[string]*ModemInfo{good.Name: good, dead.Name: dead}}
+	return s, good, dead
+}
+
+func TestHealthCheckDoesNotRemove(t *testing.T) {
+	s, good, dead := newHealthTestService(t)
+
+	result := s.HealthCheck(context.Background())
+	if !result[good.Name] || result[dead.Name] {
+		t.Fatalf("result = %v", result)
+	}
+	if len(s.GetModems()) != 2 {
+		t.Fatal("HealthCheck removed a modem")
+	}
+	if !dead.IsOpen() {
+		t.Fatal("HealthCheck closed a modem")
+	}
+}
+
+func TestBackgroundHealthCheckRemovesDeadModem(t *testing.T) {
+	s, good, dead := newHealthTestService(t)
+
+	s.checkHealth()
+	modems := s.GetModems()
+	if len(modems) != 1 || modems[0] != good {
+		t.Fatalf("pool = %v", modems)
+	}
+	if dead.IsOpen() {
+		t.Fatal("removed modem was not closed")
+	}
+}
+
+func TestBackgroundHealthCheckSkipsReconnecting(t *testing.T) {
+	s, _, dead := newHealthTestService(t)
+	dead.port.reconnecting.Store(true)
+	defer dead.port.reconnecting.Store(false)
+
+	if s.HealthCheck(context.Background())[dead.Name] {
+		t.Fatal("reconnecting modem reported healthy")
+	}
+	s.checkHealth()
+	if len(s.GetModems()) != 2 {
+		t.Fatal("reconnecting modem was removed")
+	}
+}
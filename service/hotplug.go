@@ -0,0 +1,118 @@
+package service
+
+import (
+	"log/slog"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// hotplugSettle 设备节点出现后等待其余接口就绪再扫描
+const hotplugSettle = 2 * time.Second
+
+// hotplugPatterns 热插拔关注的串口设备名
+var hotplugPatterns = []string{"ttyUSB*", "ttyACM*"}
+
+// HotPlugWatcher 监听串口设备的插入和拔出，自动连接或移除模块
+type HotPlugWatcher struct {
+	ms *ModemService
+
+	mu      sync.Mutex
+	timer   *time.Timer     // 合并短时间内的多次插入事件
+	pending map[string]bool // 等待扫描的新增设备
+}
+
+// NewHotPlugWatcher 创建热插拔监听器
+func NewHotPlugWatcher(ms *ModemService) *HotPlugWatcher {
+	return &HotPlugWatcher{ms: ms}
+}
+
+// Start 在后台启动监听
+func (h *HotPlugWatcher) Start() {
+	go h.run()
+}
+
+// matchDevice 判断设备名是否为关注的串口
+func matchDevice(name string) bool {
+	for _, pattern := range hotplugPatterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// added 设备插入，等待同一模块的接口全部出现后只扫描新增的设备
+func (h *HotPlugWatcher) added(dev string) {
+	slog.Info("hotplug: device added", slog.String("device", dev))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.pending == nil {
+		h.pending = map[string]bool{}
+	}
+	h.pending[dev] = true
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	h.timer = time.AfterFunc(hotplugSettle, h.scanPending)
+}
+
+// scanPending 扫描合并后的新增设备
+func (h *HotPlugWatcher) scanPending() {
+	h.mu.Lock()
+	devs := make([]string, 0, len(h.pending))
+	for dev := range h.pending {
+		devs = append(devs, dev)
+	}
+	h.pending = nil
+	h.mu.Unlock()
+	if len(devs) == 0 {
+		return
+	}
+
+	sort.Strings(devs)
+	for _, probe := range h.ms.ScanModems(devs...) {
+		if probe.Connected {
+			slog.Info("hotplug: modem connected", slog.String("port", probe.Name))
+		}
+	}
+}
+
+// removed 设备拔出，关闭并移除对应的连接
+func (h *HotPlugWatcher) removed(dev string) {
+	h.ms.mu.Lock()
+	modem, ok := h.ms.pool[path.Base(dev)]
+	h.ms.mu.Unlock()
+	if !ok || !h.ms.removeModem(modem) {
+		return
+	}
+	slog.Info("hotplug: device removed", slog.String("device", dev))
+}
+
+// resync 以实际设备列表为准处理遗漏的插拔事件，返回最新的设备列表
+func (h *HotPlugWatcher) resync(known map[string]bool) map[string]bool {
+	current := listDevices()
+	for dev := range known {
+		if !current[dev] {
+			h.removed(dev)
+		}
+	}
+	for dev := range current {
+		if !known[dev] {
+			h.added(dev)
+		}
+	}
+	return current
+}
+
+// listDevices 列出当前存在的串口设备路径，Linux 为 /dev 下的 USB 串口，Windows 为 COM 口
+func listDevices() map[string]bool {
+	devs := map[string]bool{}
+	for _, dev := range discoverer.Discover(nil) {
+		devs[dev] = true
+	}
+	return devs
+}
@@ -0,0 +1,90 @@
+//go:build linux
+
+package service
+
+import (
+	"bytes"
+	"log/slog"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// run 使用 inotify 监听 /dev，出错后按退避时间重新初始化
+func (h *HotPlugWatcher) run() {
+	known := listDevices()
+	backoff := time.Second
+	for {
+		start := time.Now()
+		err := h.watch(known)
+		if time.Since(start) > time.Minute {
+			backoff = time.Second
+		}
+		slog.Warn("hotplug: inotify watcher failed", slog.Any("error", err), slog.Duration("restart_in", backoff))
+		time.Sleep(backoff)
+		backoff = min(backoff*2, time.Minute)
+	}
+}
+
+// watch 读取 inotify 事件直到出错，known 记录已知的设备
+func (h *HotPlugWatcher) watch(known map[string]bool) error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	if _, err := unix.InotifyAddWatch(fd, "/dev", unix.IN_CREATE|unix.IN_DELETE); err != nil {
+		return err
+	}
+
+	// 重新初始化期间可能有设备变化
+	h.resyncKnown(known)
+
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := unix.Read(fd, buf)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+			name := string(bytes.TrimRight(nameBytes, "\x00"))
+			offset += unix.SizeofInotifyEvent + int(event.Len)
+
+			// 事件队列溢出，以实际设备列表为准
+			if event.Mask&unix.IN_Q_OVERFLOW != 0 {
+				h.resyncKnown(known)
+				continue
+			}
+
+			if !matchDevice(name) {
+				continue
+			}
+			dev := "/dev/" + name
+			switch {
+			case event.Mask&unix.IN_CREATE != 0:
+				known[dev] = true
+				h.added(dev)
+			case event.Mask&unix.IN_DELETE != 0:
+				delete(known, dev)
+				h.removed(dev)
+			}
+		}
+	}
+}
+
+// resyncKnown 重新同步设备列表并原地更新 known
+func (h *HotPlugWatcher) resyncKnown(known map[string]bool) {
+	current := h.resync(known)
+	clear(known)
+	for dev := range current {
+		known[dev] = true
+	}
+}
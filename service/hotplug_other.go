@@ -0,0 +1,16 @@
+//go:build !linux
+
+package service
+
+import "time"
+
+// hotplugPollInterval 不支持 inotify 时轮询设备列表的间隔
+const hotplugPollInterval = 5 * time.Second
+
+// run 定期对比设备列表检测插拔，Windows 下设备列表来自注册表中的 COM 口
+func (h *HotPlugWatcher) run() {
+	known := listDevices()
+	for range time.Tick(hotplugPollInterval) {
+		known = h.resync(known)
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+func TestConnectedElsewhere(t *testing.T) {
+	s := &ModemService{pool: map[string]*ModemInfo{
+		"ttyUSB2": {Name: "ttyUSB2", USBPath: "/sys/devices/usb1/1-1"},
+	}}
+	group := func(usbPath string, names ...string) *interfaceGroup {
+		g := &interfaceGroup{usbPath: usbPath}
+		for _, name := range names {
+			g.interfaces = append(g.interfaces, models.ModemInterface{Device: "/dev/" + name, Name: name})
+		}
+		return g
+	}
+
+	if !s.connectedElsewhere(group("/sys/devices/usb1/1-1", "ttyUSB3")) {
+		t.Error("new interface of a connected modem would be probed")
+	}
+	if s.connectedElsewhere(group("/sys/devices/usb1/1-1", "ttyUSB2", "ttyUSB3")) {
+		t.Error("full rescan of a connected modem skipped")
+	}
+	if s.connectedElsewhere(group("/sys/devices/usb1/1-2", "ttyUSB5")) {
+		t.Error("other usb device skipped")
+	}
+	if s.connectedElsewhere(group("", "COM3")) {
+		t.Error("non-usb port skipped")
+	}
+}
+
+func TestHotPlugCoalescesAddedDevices(t *testing.T) {
+	h := NewHotPlugWatcher(&ModemService{pool: map[string]*ModemInfo{}})
+	h.added("/nonexistent/ttyUSB8")
+	h.added("/nonexistent/ttyUSB9")
+
+	h.mu.Lock()
+	h.timer.Stop()
+	pending := len(h.pending)
+	h.mu.Unlock()
+	if pending != 2 {
+		t.Fatalf("pending = %d", pending)
+	}
+
+	h.scanPending()
+	if h.pending != nil {
+		t.Fatalf("pending not cleared: %v", h.pending)
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// defaultModemInfoCacheTTL 默认基本信息缓存时间
+const defaultModemInfoCacheTTL = 60 * time.Second
+
+// modemInfoCacheTTL 基本信息缓存时间，由 MODEM_INFO_CACHE_TTL_SECONDS 设置，0 表示不缓存
+var modemInfoCacheTTL = sync.OnceValue(func() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("MODEM_INFO_CACHE_TTL_SECONDS")); err == nil && v >= 0 {
+		return time.Duration(v) * time.Second
+	}
+	return defaultModemInfoCacheTTL
+})
+
+// modemInfoEntry 单个端口的缓存，查询期间持有锁，同时到达的请求等待同一次查询结果
+type modemInfoEntry struct {
+	mu   sync.Mutex
+	info *models.ModemBasicInfo
+}
+
+// ModemInfoCache 按端口名缓存模块基本信息（厂商、型号、固件、IMEI、IMSI、手机号）
+type ModemInfoCache struct {
+	entries sync.Map // 端口名 -> *modemInfoEntry
+}
+
+// modemInfoCache 全局基本信息缓存
+var modemInfoCache = &ModemInfoCache{}
+
+// entry 返回端口的缓存项，不存在时创建
+func (c *ModemInfoCache) entry(name string) *modemInfoEntry {
+	e, _ := c.entries.LoadOrStore(name, &modemInfoEntry{})
+	return e.(*modemInfoEntry)
+}
+
+// Invalidate 清除端口的缓存，模块重启或重新连接后调用
+func (c *ModemInfoCache) Invalidate(name string) {
+	c.entries.Delete(name)
+}
+
+// GetBasicInfo 获取模块基本信息，force 为 true 时忽略缓存
+// 静态信息在缓存有效期内直接返回，全部获取成功时才写入缓存；运营商每次实时查询
+func (m *ModemInfo) GetBasicInfo(force bool) *models.ModemBasicInfo {
+	e := modemInfoCache.entry(m.Name)
+	e.mu.Lock()
+	if force || e.info == nil || time.Since(e.info.FetchedAt) >= modemInfoCacheTTL() {
+		info, complete := m.fetchBasicInfo()
+		e.info = nil
+		if complete && modemInfoCacheTTL() > 0 {
+			e.info = info
+		}
+		e.mu.Unlock()
+		return m.withOperator(*info)
+	}
+	info := *e.info
+	e.mu.Unlock()
+	return m.withOperator(info)
+}
+
+// fetchBasicInfo 依次查询静态信息，返回除手机号外是否全部获取成功
+func (m *ModemInfo) fetchBasicInfo() (*models.ModemBasicInfo, bool) {
+	info := &models.ModemBasicInfo{Name: m.Name, FetchedAt: time.Now()}
+	complete := true
+	for _, q := range []struct {
+		field *string
+		fn    func() (string, error)
+	}{
+		{&info.Manufacturer, m.GetManufacturer},
+		{&info.Model, m.GetModel},
+		{&info.Revision, m.GetRevision},
+		{&info.IMEI, m.GetSerialNumber},
+	} {
+		v, err := q.fn()
+		if err != nil {
+			complete = false
+			continue
+		}
+		*q.field = v
+	}
+
+	// SIM 卡初始化期间自动重试
+	err := m.RetrySIMBusy(func() error {
+		imsi, err := m.GetIMSI()
+		if err == nil {
+			info.IMSI = imsi
+		}
+		return err
+	})
+	complete = complete && err == nil
+	// 很多 SIM 卡未写入本机号码，查询失败不影响缓存
+	m.RetrySIMBusy(func() error {
+		phone, _, err := m.GetPhoneNumber()
+		if err == nil {
+			info.Phone = phone
+		}
+		return err
+	})
+	return info, complete
+}
+
+// withOperator 补充实时查询的运营商信息
+func (m *ModemInfo) withOperator(info models.ModemBasicInfo) *models.ModemBasicInfo {
+	if operator, err := m.GetOperatorInfo(); err == nil {
+		info.Operator = operator.Name
+		info.Act = &operator.Act
+	}
+	return &info
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// basicInfoModem 回应基本信息查询的模拟模块
+func basicInfoModem(t *testing.T) (*ModemInfo, *scriptedModem) {
+	t.Helper()
+	initTestDB(t)
+	script := &scriptedModem{}
+	script.reply("AT+CGMI", "Quectel\r\nOK")
+	script.reply("AT+CGMM", "EC25\r\nOK")
+	script.reply("AT+CGMR", "EC25EFAR06A06M4G\r\nOK")
+	script.reply("AT+CGSN", "861234567890123\r\nOK")
+	script.reply("AT+CIMI", "460001234567890\r\nOK")
+	script.reply("AT+CNUM", "ERROR")
+	script.reply("AT+COPS?", `+COPS: 0,0,"CHINA MOBILE",7`+"\r\nOK")
+	m, _ := newTestModem(t, script)
+	m.Name = t.Name()
+	t.Cleanup(func() { modemInfoCache.Invalidate(m.Name) })
+	return m, script
+}
+
+// setInfoCacheTTL 临时修改基本信息缓存时间
+func setInfoCacheTTL(t *testing.T, ttl time.Duration) {
+	old := modemInfoCacheTTL
+	modemInfoCacheTTL = func() time.Duration { return ttl }
+	t.Cleanup(func() { modemInfoCacheTTL = old })
+}
+
+// commandCount 统计模拟模块收到指定命令的次数
+func commandCount(script *scriptedModem, cmd string) int {
+	n := 0
+	for _, c := range script.received() {
+		if c == cmd {
+			n++
+		}
+	}
+	return n
+}
+
+func TestBasicInfoCache(t *testing.T) {
+	setInfoCacheTTL(t, time.Minute)
+	m, script := basicInfoModem(t)
+
+	info := m.GetBasicInfo(false)
+	if info.Manufacturer != "Quectel" || info.IMEI != "861234567890123" || info.IMSI != "460001234567890" || info.Operator != "CHINA MOBILE" {
+		t.Fatalf("info = %+v", info)
+	}
+
+	// 缓存命中时只实时查询运营商，手机号缺失不影响缓存
+	again := m.GetBasicInfo(false)
+	if n := commandCount(script, "AT+CGMI"); n != 1 {
+		t.Fatalf("AT+CGMI sent %d times, want 1", n)
+	}
+	if n := commandCount(script, "AT+COPS?"); n != 2 {
+		t.Fatalf("AT+COPS? sent %d times, want 2", n)
+	}
+	if !again.FetchedAt.Equal(info.FetchedAt) || again.Model != "EC25" {
+		t.Fatalf("cached info = %+v", again)
+	}
+
+	m.GetBasicInfo(true)
+	if n := commandCount(script, "AT+CGMI"); n != 2 {
+		t.Fatalf("force: AT+CGMI sent %d times, want 2", n)
+	}
+
+	// 模块重启或重新连接后重新查询
+	modemInfoCache.Invalidate(m.Name)
+	m.GetBasicInfo(false)
+	if n := commandCount(script, "AT+CGMI"); n != 3 {
+		t.Fatalf("invalidate: AT+CGMI sent %d times, want 3", n)
+	}
+}
+
+func TestBasicInfoCacheExpires(t *testing.T) {
+	setInfoCacheTTL(t, 50*time.Millisecond)
+	m, script := basicInfoModem(t)
+
+	m.GetBasicInfo(false)
+	m.GetBasicInfo(false)
+	if n := commandCount(script, "AT+CGMI"); n != 1 {
+		t.Fatalf("AT+CGMI sent %d times, want 1", n)
+	}
+	time.Sleep(60 * time.Millisecond)
+	m.GetBasicInfo(false)
+	if n := commandCount(script, "AT+CGMI"); n != 2 {
+		t.Fatalf("after ttl: AT+CGMI sent %d times, want 2", n)
+	}
+}
+
+func TestBasicInfoCacheSkipsIncomplete(t *testing.T) {
+	setInfoCacheTTL(t, time.Minute)
+	m, script := basicInfoModem(t)
+	script.reply("AT+CGSN", "ERROR")
+
+	if info := m.GetBasicInfo(false); info.IMEI != "" || info.Model != "EC25" {
+		t.Fatalf("info = %+v", info)
+	}
+	m.GetBasicInfo(false)
+	if n := commandCount(script, "AT+CGMI"); n != 2 {
+		t.Fatalf("incomplete info cached: AT+CGMI sent %d times, want 2", n)
+	}
+}
+
+func TestBasicInfoCacheDisabled(t *testing.T) {
+	setInfoCacheTTL(t, 0)
+	m, script := basicInfoModem(t)
+
+	m.GetBasicInfo(false)
+	m.GetBasicInfo(false)
+	if n := commandCount(script, "AT+CGMI"); n != 2 {
+		t.Fatalf("AT+CGMI sent %d times, want 2", n)
+	}
+}
+
+func TestGetPhoneNumber(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CNUM", `+CNUM: "","+8613800000000",145`+"\r\nOK")
+	m, _ := newTestModem(t, script)
+
+	number, typ, err := m.GetPhoneNumber()
+	if err != nil || number != "+8613800000000" || typ != 145 {
+		t.Fatalf("number = %q, type = %d, err = %v", number, typ, err)
+	}
+
+	script.reply("AT+CNUM", "OK")
+	if _, _, err := m.GetPhoneNumber(); err == nil {
+		t.Fatal("expected error without +CNUM line")
+	}
+}
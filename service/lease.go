@@ -0,0 +1,108 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+const (
+	defaultLeaseTTL = time.Minute // 默认租约时长
+	maxLeaseTTL     = time.Hour   // 最长租约时长
+)
+
+// ErrLocked 模块已被其他调用方租用
+var ErrLocked = errors.New("modem is leased")
+
+// modemLease 模块租约
+type modemLease struct {
+	token     string
+	expiresAt time.Time
+}
+
+var (
+	leaseMu sync.Mutex
+	leases  = map[string]*modemLease{}
+)
+
+// AcquireLease 租用模块，携带当前租约令牌时续期
+// 租约到期未续期自动释放；ttl 为 0 时使用默认时长
+func (m *ModemService) AcquireLease(name, token string, ttl time.Duration) (*models.Lease, error) {
+	if ttl == 0 {
+		ttl = defaultLeaseTTL
+	}
+	if ttl < 0 || ttl > maxLeaseTTL {
+		return nil, fmt.Errorf("%w: ttl must be 1-%d seconds", ErrInvalid, int(maxLeaseTTL.Seconds()))
+	}
+
+	n := portName(name)
+	leaseMu.Lock()
+	defer leaseMu.Unlock()
+
+	lease := activeLease(n)
+	if lease != nil && lease.token != token {
+		return nil, fmt.Errorf("%w until %s", ErrLocked, lease.expiresAt.Format(time.RFC3339))
+	}
+	if lease == nil {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		lease = &modemLease{token: hex.EncodeToString(buf)}
+		leases[n] = lease
+	}
+	lease.expiresAt = time.Now().Add(ttl)
+
+	return &models.Lease{
+		Name:      n,
+		Token:     lease.token,
+		TTL:       int(ttl.Seconds()),
+		ExpiresAt: lease.expiresAt,
+	}, nil
+}
+
+// ReleaseLease 释放租约，令牌不匹配时返回 ErrLocked
+func (m *ModemService) ReleaseLease(name, token string) error {
+	n := portName(name)
+	leaseMu.Lock()
+	defer leaseMu.Unlock()
+
+	lease := activeLease(n)
+	if lease == nil {
+		return nil
+	}
+	if lease.token != token {
+		return ErrLocked
+	}
+	delete(leases, n)
+	return nil
+}
+
+// CheckLease 检查调用方能否使用模块：未被租用或令牌匹配
+func (m *ModemService) CheckLease(name, token string) error {
+	leaseMu.Lock()
+	defer leaseMu.Unlock()
+
+	if lease := activeLease(portName(name)); lease != nil && lease.token != token {
+		return ErrLocked
+	}
+	return nil
+}
+
+// activeLease 返回未过期的租约，过期的租约直接清除，调用方需持有锁
+func activeLease(name string) *modemLease {
+	lease, ok := leases[name]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(lease.expiresAt) {
+		delete(leases, name)
+		return nil
+	}
+	return lease
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// ErrLocationAcquiring 尚未获得定位
+var ErrLocationAcquiring = errors.New("location acquiring")
+
+// locationTimeout 定位查询的等待时间，超过 at 库默认超时以便识别未定位的错误码
+const locationTimeout = 3 * time.Second
+
+func init() {
+	RegisterParser("", "AT+QGPSLOC=2", parseQuectelLocation)
+	RegisterParser("", "AT+CGPSINFO", parseSimcomLocation)
+}
+
+// GetLocation 查询当前定位
+// 实时定位不可用且 allowStale 为真时，返回最近一次成功的定位并标记为过期
+func (m *ModemInfo) GetLocation(allowStale bool) (*models.Location, error) {
+	cmd, ok := vendorCommand("location", m.Vendor)
+	if !ok {
+		return nil, fmt.Errorf("location %w by %s modem", ErrUnsupported, m.Vendor)
+	}
+
+	m.locationMu.Lock()
+	defer m.locationMu.Unlock()
+
+	v, err := m.queryWithTimeout(cmd, locationTimeout)
+	if err == nil {
+		location, ok := v.(*models.Location)
+		if !ok {
+			return nil, fmt.Errorf("unexpected location parser result %T", v)
+		}
+		m.location, m.locationAt = location, time.Now()
+		result := *location
+		return &result, nil
+	}
+
+	// 仅在尚未定位时回退到最近一次定位，通信失败或 GNSS 未开启等错误直接返回
+	if !errors.Is(err, ErrLocationAcquiring) || !allowStale || m.location == nil {
+		return nil, err
+	}
+
+	result := *m.location
+	result.Stale = true
+	result.Age = time.Since(m.locationAt).Seconds()
+	return &result, nil
+}
+
+// quectelNotFixed Quectel 尚未定位时返回的错误码（Not fixed now）
+const quectelNotFixed = "516"
+
+// parseQuectelLocation 解析 +QGPSLOC: <UTC>,<lat>,<lon>,<hdop>,<alt>,<fix>,<cog>,<spkm>,<spkn>,<date>,<nsat>
+// 未定位时模块返回 +CME ERROR: 516
+func parseQuectelLocation(responses []string) (any, error) {
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label == "+CME ERROR" && len(param) > 0 && param[0] == quectelNotFixed {
+			return nil, ErrLocationAcquiring
+		}
+		if label != "+QGPSLOC" || len(param) < 11 {
+			continue
+		}
+		lat, err := strconv.ParseFloat(param[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latitude %q", param[1])
+		}
+		lon, err := strconv.ParseFloat(param[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid longitude %q", param[2])
+		}
+		alt, err := strconv.ParseFloat(param[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid altitude %q", param[4])
+		}
+		nsat, err := strconv.Atoi(param[10])
+		if err != nil {
+			return nil, fmt.Errorf("invalid satellite count %q", param[10])
+		}
+		fixTime, err := parseFixTime(param[9], param[0])
+		if err != nil {
+			return nil, err
+		}
+		return &models.Location{
+			Latitude:   lat,
+			Longitude:  lon,
+			Altitude:   alt,
+			Satellites: nsat,
+			FixTime:    fixTime,
+		}, nil
+	}
+	if err := finalError(responses); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("failed to parse location")
+}
+
+// parseSimcomLocation 解析 +CGPSINFO: <lat>,<N/S>,<lon>,<E/W>,<date>,<UTC>,<alt>,<speed>,<course>
+// 未定位时各字段为空
+func parseSimcomLocation(responses []string) (any, error) {
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "+CGPSINFO" || len(param) < 7 {
+			continue
+		}
+		if param[0] == "" || param[2] == "" {
+			return nil, ErrLocationAcquiring
+		}
+		lat, err := parseNMEACoord(param[0], param[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid latitude %q", param[0])
+		}
+		lon, err := parseNMEACoord(param[2], param[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid longitude %q", param[2])
+		}
+		alt, err := strconv.ParseFloat(param[6], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid altitude %q", param[6])
+		}
+		fixTime, err := parseFixTime(param[4], param[5])
+		if err != nil {
+			return nil, err
+		}
+		return &models.Location{
+			Latitude:  lat,
+			Longitude: lon,
+			Altitude:  alt,
+			FixTime:   fixTime,
+		}, nil
+	}
+	if err := finalError(responses); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("failed to parse location")
+}
+
+// parseNMEACoord 将 ddmm.mmmm 格式的坐标转换为十进制度数
+func parseNMEACoord(value, hemisphere string) (float64, error) {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	deg := float64(int(v / 100))
+	deg += (v - deg*100) / 60
+	if hemisphere == "S" || hemisphere == "W" {
+		deg = -deg
+	}
+	return deg, nil
+}
+
+// parseFixTime 解析 ddmmyy 格式的日期和 hhmmss.s 格式的 UTC 时间
+func parseFixTime(date, utc string) (time.Time, error) {
+	if i := strings.Index(utc, "."); i >= 0 {
+		utc = utc[:i]
+	}
+	t, err := time.Parse("020106150405", date+utc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid fix time %q %q", date, utc)
+	}
+	return t, nil
+}
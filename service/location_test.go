@@ -0,0 +1,75 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+func TestParseQuectelLocation(t *testing.T) {
+	v, err := parseQuectelLocation([]string{"+QGPSLOC: 061951.000,31.84537,117.19882,0.7,62.2,2,0.00,0.0,0.0,110513,09", "OK"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := v.(*models.Location)
+	want := time.Date(2013, 5, 11, 6, 19, 51, 0, time.UTC)
+	if loc.Latitude != 31.84537 || loc.Longitude != 117.19882 || loc.Satellites != 9 || !loc.FixTime.Equal(want) {
+		t.Fatalf("location = %+v", loc)
+	}
+
+	if _, err := parseQuectelLocation([]string{"+CME ERROR: 516"}); !errors.Is(err, ErrLocationAcquiring) {
+		t.Fatalf("not fixed: err = %v, want ErrLocationAcquiring", err)
+	}
+	// GNSS 未开启等错误原样返回，不视为定位中
+	if _, err := parseQuectelLocation([]string{"+CME ERROR: 505"}); err == nil || errors.Is(err, ErrLocationAcquiring) {
+		t.Fatalf("session not active: err = %v", err)
+	}
+	if _, err := parseQuectelLocation([]string{"+QGPSLOC: 061951.000,31.84537,117.19882,0.7,,2,0.00,0.0,0.0,110513,09", "OK"}); err == nil {
+		t.Fatal("empty altitude should be an error")
+	}
+}
+
+func TestParseSimcomLocation(t *testing.T) {
+	v, err := parseSimcomLocation([]string{"+CGPSINFO: 3113.343286,N,12121.234064,E,250311,072809.3,44.1,0.0,0", "OK"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := v.(*models.Location)
+	if loc.Latitude < 31.22 || loc.Latitude > 31.23 || loc.Longitude < 121.35 || loc.Longitude > 121.36 || loc.Altitude != 44.1 {
+		t.Fatalf("location = %+v", loc)
+	}
+
+	if _, err := parseSimcomLocation([]string{"+CGPSINFO: ,,,,,,,,", "OK"}); !errors.Is(err, ErrLocationAcquiring) {
+		t.Fatalf("not fixed: err = %v, want ErrLocationAcquiring", err)
+	}
+	if _, err := parseSimcomLocation([]string{"ERROR"}); err == nil || errors.Is(err, ErrLocationAcquiring) {
+		t.Fatalf("gps off: err = %v", err)
+	}
+}
+
+func TestGetLocationFallsBackOnlyWhileAcquiring(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+QGPSLOC=2", "+QGPSLOC: 061951.000,31.84537,117.19882,0.7,62.2,2,0.00,0.0,0.0,110513,09\r\nOK")
+	m, _ := newTestModem(t, script)
+	m.Vendor = VendorQuectel
+
+	if _, err := m.GetLocation(true); err != nil {
+		t.Fatal(err)
+	}
+
+	script.reply("AT+QGPSLOC=2", "+CME ERROR: 516")
+	loc, err := m.GetLocation(true)
+	if err != nil || !loc.Stale {
+		t.Fatalf("acquiring: loc = %+v, err = %v", loc, err)
+	}
+	if _, err := m.GetLocation(false); !errors.Is(err, ErrLocationAcquiring) {
+		t.Fatalf("without stale: err = %v", err)
+	}
+
+	script.reply("AT+QGPSLOC=2", "+CME ERROR: 505")
+	if _, err := m.GetLocation(true); err == nil || errors.Is(err, ErrLocationAcquiring) {
+		t.Fatalf("session not active: err = %v", err)
+	}
+}
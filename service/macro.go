@@ -0,0 +1,70 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+const (
+	maxMacroCommands = 50    // 单个宏最多包含的命令数
+	maxMacroDelayMS  = 60000 // 相邻命令之间的最长等待时间
+)
+
+// ValidateMacro 校验 AT 命令宏
+func ValidateMacro(macro *models.ATMacro) error {
+	macro.Name = strings.TrimSpace(macro.Name)
+	if macro.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if len(macro.Commands) == 0 || len(macro.Commands) > maxMacroCommands {
+		return fmt.Errorf("%w: 1-%d commands are required", ErrInvalid, maxMacroCommands)
+	}
+	for i, cmd := range macro.Commands {
+		cmd = strings.TrimSpace(cmd)
+		if !strings.HasPrefix(strings.ToUpper(cmd), "AT") {
+			return fmt.Errorf("%w: command %d %q must start with AT", ErrInvalid, i+1, cmd)
+		}
+		macro.Commands[i] = cmd
+	}
+	if macro.DelayBetweenMS < 0 || macro.DelayBetweenMS > maxMacroDelayMS {
+		return fmt.Errorf("%w: delay_between_ms must be 0-%d", ErrInvalid, maxMacroDelayMS)
+	}
+	return nil
+}
+
+// RunMacro 依次执行宏中的命令并记录每条命令的响应和耗时
+// 命令出错或返回 ERROR 时停止执行，返回已执行部分的结果
+func (m *ModemInfo) RunMacro(macro models.ATMacro) *models.MacroResult {
+	result := &models.MacroResult{Macro: macro.Name, Steps: []models.MacroStep{}}
+	for i, cmd := range macro.Commands {
+		if i > 0 && macro.DelayBetweenMS > 0 {
+			time.Sleep(time.Duration(macro.DelayBetweenMS) * time.Millisecond)
+		}
+
+		start := time.Now()
+		responses, err := m.SendCommand(cmd)
+		if err == nil {
+			err = finalError(responses)
+		}
+		step := models.MacroStep{
+			Command:    cmd,
+			Responses:  responses,
+			DurationMS: time.Since(start).Milliseconds(),
+		}
+		if step.Responses == nil {
+			step.Responses = []string{}
+		}
+		if err != nil {
+			step.Error = err.Error()
+			result.Failed = true
+		}
+		result.Steps = append(result.Steps, step)
+		if result.Failed {
+			break
+		}
+	}
+	return result
+}
@@ -1,46 +1,98 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path"
-	"path/filepath"
-	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rehiy/modem/at"
+	"github.com/rehiy/web-modem/metrics"
+	"github.com/rehiy/web-modem/models"
 	"github.com/tarm/serial"
+	"golang.org/x/time/rate"
 )
 
 var (
 	modemOnce     sync.Once
 	modemInstance *ModemService
-	ModemEvent    = make(chan string, 100)
 )
 
 // ModemInfo 端口信息
 type ModemInfo struct {
-	Name        string `json:"name"`
-	PhoneNumber string `json:"phoneNumber"`
-	Connected   bool   `json:"connected"`
-	*at.Device  `json:"-"`
+	Name        string                  `json:"name"`
+	Alias       string                  `json:"alias,omitempty"`
+	PhoneNumber string                  `json:"phoneNumber"`
+	Vendor      string                  `json:"vendor"`
+	Baud        int                     `json:"baud"`
+	Connected   bool                    `json:"connected"`
+	SIMStatus   string                  `json:"simStatus,omitempty"` // 最近一次查询的 SIM 卡锁定状态
+	ConnectedAt time.Time               `json:"connectedAt"`
+	USBPath     string                  `json:"usbPath,omitempty"`    // 所属 USB 设备的 sysfs 路径
+	Interfaces  []models.ModemInterface `json:"interfaces,omitempty"` // 同一模块的全部串口
+	dev         *at.Device              // at 库设备，命令须经队列发送，不直接调用其方法
+
+	stateMu sync.RWMutex // 保护连接后仍会修改的导出字段，序列化时加读锁
+
+	port       *serialPort   // 串口包装
+	queue      *cmdQueue     // 命令队列
+	cmdLimiter *rate.Limiter // 命令限速，仅由调度协程使用，不限制时为 nil
+
+	imei      string       // 连接时读取的 IMEI，用于识别同一模块的多个端口
+	simBusyAt atomic.Int64 // 最近一次 SIM 卡忙通知的时间（UnixNano）
+	unreadSMS atomic.Int64 // 用户上次读取短信后收到的 +CMTI 数量
+	dataMode  atomic.Bool  // 拨号进入数据模式
+	resetAt   atomic.Int64 // 最近一次检测到模块重启的时间（UnixNano）
+	echoCount atomic.Int64 // 关闭回显后仍收到命令回显的次数
+	smsBusy   atomic.Bool  // 正在发送短信
+	scanning  atomic.Bool  // 正在搜索网络（AT+COPS=?）
+
+	identityMu  sync.Mutex
+	identity    *models.Identity // 缓存的身份信息
+	dashboardMu sync.Mutex
+
+	calls    callLog       // 通话记录
+	ussd     ussdSession   // USSD 会话
+	signals  signalHistory // 信号采样历史
+	smsCache smsListCache  // 已解析的短信列表
+
+	deliveries deliveryReports // 短信状态报告
+
+	operatorMu   sync.Mutex
+	operatorScan *models.OperatorScan // 最近一次网络搜索结果
+
+	regMu     sync.Mutex
+	regStates map[string]int // 各注册域最近一次上报的状态码
+
+	locationMu sync.Mutex
+	location   *models.Location // 最近一次成功的定位结果
+	locationAt time.Time        // 获取该定位结果的本地时间
 }
 
 // ModemService 管理多个串口连接
 type ModemService struct {
-	pool map[string]*ModemInfo
-	mu   sync.Mutex
+	pool    map[string]*ModemInfo
+	mu      sync.Mutex
+	balance atomic.Uint64 // 负载均衡发送的轮询计数
+
+	breakerMu sync.Mutex
+	breakers  map[string]*circuitBreaker // 端口名 -> 故障转移发送的熔断器
 }
 
 // GetModemService 返回单例实例
 func GetModemService() *ModemService {
 	modemOnce.Do(func() {
 		modemInstance = &ModemService{
-			pool: map[string]*ModemInfo{},
+			pool:     map[string]*ModemInfo{},
+			breakers: map[string]*circuitBreaker{},
 		}
 	})
 	return modemInstance
@@ -51,15 +103,15 @@ func (m *ModemService) GetModems() []*ModemInfo {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	var modems []*ModemInfo
+	modems := []*ModemInfo{}
 	for _, model := range m.pool {
 		modems = append(modems, model)
 	}
 	return modems
 }
 
-// ScanModems 扫描可用的调制解调器并连接到它们
-func (m *ModemService) ScanModems(devs ...string) {
+// ScanModems 扫描可用的调制解调器并连接到它们，返回每个设备的连接结果
+func (m *ModemService) ScanModems(devs ...string) []models.DeviceProbe {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -72,32 +124,100 @@ func (m *ModemService) ScanModems(devs ...string) {
 	}
 
 	// 查找潜在设备
-	switch runtime.GOOS {
-	case "windows":
-		if len(devs) == 0 {
-			devs = []string{"COM1", "COM2", "COM3", "COM4", "COM5"}
+	devs = discoverer.Discover(devs)
+
+	// 按物理模块分组，每个模块只连接一个 AT 端口
+	probes := []models.DeviceProbe{}
+	for _, group := range groupInterfaces(devs) {
+		// 只扫描了模块的部分接口（如热插拔新增的端口），模块已通过其他接口连接
+		if m.connectedElsewhere(group) {
+			continue
+		}
+		try, skip := group.candidates()
+		try = m.preferConnected(try)
+		for _, iface := range try {
+			probe := models.DeviceProbe{Device: iface.Device, Name: iface.Name, FriendlyName: iface.FriendlyName, Role: iface.Role}
+			modem, err := m.makeConnect(iface.Device)
+			if err != nil {
+				probe.Error = err.Error()
+				probe.Hint = probeHint(err)
+				probes = append(probes, probe)
+				continue
+			}
+			modem.USBPath, modem.Interfaces = group.usbPath, group.withRole(iface.Name, RoleAT)
+			probe.Connected = true
+			probe.Vendor = modem.Vendor
+			probe.Baud = modem.Baud
+			probe.PhoneNumber = modem.PhoneNumber
+			probes = append(probes, probe)
+			break
 		}
-	default:
-		if len(devs) == 0 {
-			devs = []string{"/dev/ttyUSB*", "/dev/ttyACM*"}
+		for _, iface := range skip {
+			probes = append(probes, models.DeviceProbe{
+				Device:       iface.Device,
+				Name:         iface.Name,
+				FriendlyName: iface.FriendlyName,
+				Role:         iface.Role,
+				Error:        "skipped, not an AT command interface",
+			})
 		}
-		pps := []string{}
-		for _, p := range devs {
-			matches, _ := filepath.Glob(p)
-			pps = append(pps, matches...)
+	}
+	return probes
+}
+
+// connectedElsewhere 同一 USB 设备已通过不在本组中的接口连接，调用方需持有锁
+func (m *ModemService) connectedElsewhere(group *interfaceGroup) bool {
+	if group.usbPath == "" {
+		return false
+	}
+	for name, modem := range m.pool {
+		if modem.USBPath == group.usbPath && !slices.ContainsFunc(group.interfaces, func(iface models.ModemInterface) bool {
+			return iface.Name == name
+		}) {
+			return true
 		}
-		devs = pps
 	}
+	return false
+}
 
-	// 尝试连接到新设备
-	for _, u := range devs {
-		m.makeConnect(u)
+// preferConnected 将已连接的接口排在最前，避免重新扫描时切换到同一模块的其他端口
+func (m *ModemService) preferConnected(ifaces []models.ModemInterface) []models.ModemInterface {
+	for i, iface := range ifaces {
+		if _, ok := m.pool[iface.Name]; ok {
+			return append([]models.ModemInterface{iface}, append(ifaces[:i:i], ifaces[i+1:]...)...)
+		}
 	}
+	return ifaces
+}
+
+// CloseAll 从连接池移除全部模块，等待排队的命令执行完成后关闭，用于服务退出
+// ctx 结束后不再等待，直接关闭剩余连接
+func (m *ModemService) CloseAll(ctx context.Context) {
+	m.mu.Lock()
+	modems := make([]*ModemInfo, 0, len(m.pool))
+	for n, modem := range m.pool {
+		modems = append(modems, modem)
+		delete(m.pool, n)
+	}
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, modem := range modems {
+		wg.Add(1)
+		go func(modem *ModemInfo) {
+			defer wg.Done()
+			if err := modem.drain(ctx); err != nil {
+				slog.Warn("closing with pending commands", slog.String("port", modem.Name), slog.Any("error", err))
+			}
+			modem.Close()
+		}(modem)
+	}
+	wg.Wait()
 }
 
 // GetConnect 返回给定端口名称的 AT 接口
 func (m *ModemService) GetConnect(u string) (*ModemInfo, error) {
-	n := path.Base(u)
+	n := portName(u)
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -113,63 +233,82 @@ func (m *ModemService) GetConnect(u string) (*ModemInfo, error) {
 func (m *ModemService) handleIncomingSMS(portName string, smsIndex int, webhookService *WebhookService) {
 	conn, err := m.GetConnect(portName)
 	if err != nil {
-		log.Printf("[%s] Failed to get connection for incoming SMS: %v", portName, err)
+		slog.Error("failed to get connection for incoming sms", slog.String("port", portName), slog.Any("error", err))
 		return
 	}
 
 	// 获取短信列表（只获取新短信）
-	smsList, err := conn.ListSMSPdu(4)
+	smsList, err := conn.ListSMS(context.Background(), 4)
 	if err != nil {
-		log.Printf("[%s] Failed to list SMS: %v", portName, err)
+		slog.Error("failed to list sms", slog.String("port", portName), slog.Any("error", err))
 		return
 	}
 
 	// 处理每条短信
 	for _, sms := range smsList {
-		hasNewSMS := false
-		for _, idx := range sms.Indices {
-			if idx == smsIndex {
-				hasNewSMS = true
-				break
-			}
+		if slices.Contains(sms.Indices, smsIndex) {
+			go m.deliverSMS(conn, sms, webhookService)
 		}
-		if !hasNewSMS {
-			continue
-		}
-		go func(smsData at.SMS) {
-			modelSMS := atSMSToModelSMS(smsData, conn.PhoneNumber)
-			if err := webhookService.HandleIncomingSMS(modelSMS); err != nil {
-				log.Printf("[%s] Failed to handle incoming SMS: %v", portName, err)
-			}
-			log.Printf("[%s] New SMS from %s: %s", portName, smsData.PhoneNumber, smsData.Text)
-		}(sms)
 	}
 }
 
 // makeConnect 添加新的 AT 接口
-func (m *ModemService) makeConnect(u string) error {
+func (m *ModemService) makeConnect(u string) (*ModemInfo, error) {
 	n := path.Base(u)
 
-	// 创建日志函数
+	// at 库的收发日志，LOG_LEVEL=debug 时输出
 	pf := func(s string, v ...any) {
-		log.Printf(fmt.Sprintf("[%s] %s", n, s), v...)
+		slog.Debug(fmt.Sprintf(s, v...), slog.String("port", n))
 	}
 
 	// 检查是否已连接
 	if conn, ok := m.pool[n]; ok {
-		if conn.Test() == nil {
-			pf("already connected")
-			return nil
+		if conn.SendCommandExpect("AT", "OK") == nil {
+			slog.Info("already connected", slog.String("port", n))
+			return conn, nil
 		}
 		conn.Close()
 		delete(m.pool, n)
 	}
 
+	// 预先创建连接信息，供事件处理函数使用
+	modem := &ModemInfo{
+		Name:        n,
+		Alias:       AliasOf(n),
+		PhoneNumber: "unkown",
+		Vendor:      VendorGeneric,
+		Baud:        115200,
+		Connected:   true,
+	}
+	modem.ussd.results = make(chan *models.USSDResponse, 1)
+
 	// 创建事件处理函数，写入 ModemEvent 并处理短信
 	hf := func(l string, p map[int]string) {
-		ModemEvent <- fmt.Sprintf("[%s] urc:%s %v", n, l, p)
+		ModemEvent.Publish(EventURC, n, URCData{Label: l, Params: p})
+		// 记录 SIM 卡忙等临时错误
+		if l == "+CME ERROR" {
+			modem.markSIMBusy(p[0])
+		}
+		// 模块意外重启，重新初始化的命令需经命令队列，不能阻塞读取协程
+		if bootURCs[l] {
+			go modem.handleBootURC(l)
+		}
+		// 数据连接断开
+		if l == "NO CARRIER" {
+			modem.leaveDataMode()
+		}
+		// 记录来电和通话结束
+		modem.handleCallURC(l, p)
+		// 跟踪网络注册状态变化
+		modem.handleRegistrationURC(l, p)
+		// 处理短信状态报告，读取报告的命令需经命令队列，不能阻塞读取协程
+		if l == "+CDSI" {
+			go modem.handleStatusReport(p)
+		}
 		// 处理收到的短信通知
 		if l == "+CMTI" && len(p) > 0 {
+			modem.unreadSMS.Add(1)
+			modem.smsCache.invalidate()
 			if indexStr, ok := p[1]; ok {
 				if index, err := strconv.Atoi(indexStr); err == nil {
 					w := NewWebhookService()
@@ -179,46 +318,163 @@ func (m *ModemService) makeConnect(u string) error {
 		}
 	}
 
-	// 打开串口
-	pf("connecting")
-	port, err := serial.OpenPort(&serial.Config{
-		Name:        u,      // 串口完整路径
-		Baud:        115200, // 波特率
-		ReadTimeout: 1 * time.Second,
-	})
+	// 依次尝试各波特率，使用第一个通过 AT 测试的
+	slog.Info("connecting", slog.String("port", n))
+	var err error
+	for _, baud := range scanBauds() {
+		if modem.dev, modem.port, err = openAT(u, baud, hf, pf); err == nil {
+			modem.Baud = baud
+			break
+		}
+		if !strings.HasPrefix(err.Error(), "at test failed") {
+			return nil, err // 串口无法打开，换波特率也无济于事
+		}
+	}
 	if err != nil {
-		pf("connect failed: %v", err)
-		return err
+		return nil, err
 	}
 
-	// 创建新的连接
-	conn := at.New(port, hf, &at.Config{Printf: pf})
-	if err := conn.Test(); err != nil {
-		pf("at test failed: %v", err)
-		conn.Close()
-		return err
-	}
+	// 启动命令队列
+	modem.startQueue()
 
 	// 设置默认参数
-	conn.EchoOff()     // 关闭回显
-	conn.SetSMSMode(0) // PDU 模式
+	modem.initialize()
 
-	// 添加到连接池
-	modem := &ModemInfo{
-		Name:        n,
-		PhoneNumber: "unkown",
-		Connected:   true,
-		Device:      conn,
+	// 串口重新打开后恢复会话，重连失败时移除连接
+	modem.port.onState = func(state string) {
+		ModemEvent.Publish(state, n, nil)
+		if state == PortReconnected {
+			modem.initialize()
+			return
+		}
+		if !m.removeModem(modem) {
+			modem.Close()
+		}
 	}
 
-	// 获取手机号，用于接收号码
-	if phoneNum, _, err := modem.GetPhoneNumber(); err == nil {
-		modem.PhoneNumber = phoneNum
+	// 识别厂商，用于选择响应解析器
+	if manufacturer, err := modem.GetManufacturer(); err == nil {
+		modem.Vendor = detectVendor(manufacturer)
 	}
+	modem.imei, _ = modem.GetSerialNumber()
+
+	// 获取手机号，用于接收号码
+	modem.RetrySIMBusy(func() error {
+		phoneNum, _, err := modem.GetPhoneNumber()
+		if err == nil {
+			modem.PhoneNumber = phoneNum
+		}
+		return err
+	})
 
 	// 获取并显示手机号
-	pf("connected, phone number: %s", modem.PhoneNumber)
+	slog.Info("connected", slog.String("port", n), slog.Int("baud", modem.Baud), slog.String("phone", modem.PhoneNumber))
+	modem.ConnectedAt = time.Now()
+	modemInfoCache.Invalidate(n)
 	m.pool[n] = modem
+	metrics.ModemConnected.WithLabelValues(n).Set(1)
+	ModemEvent.Publish(EventConnect, n, nil)
+
+	// 弱信号看门狗，按模块配置开启
+	go modem.runWatchdog()
+	go modem.pollSignal()
+	go modem.pollRegistration()
+	// 接收 USSD 响应
+	go modem.watchUSSD()
+	// 接收直接推送的短信（+CMT）和状态报告（+CDS）
+	go m.watchPushedPDU(modem)
+
+	return modem, nil
+}
+
+// defaultBauds 自动检测时依次尝试的波特率
+var defaultBauds = []int{115200, 9600, 57600, 230400}
+
+// scanBauds 自动检测的波特率列表，可通过 MODEM_BAUD 指定（逗号分隔）
+func scanBauds() []int {
+	bauds := []int{}
+	for _, s := range strings.Split(os.Getenv("MODEM_BAUD"), ",") {
+		if baud, err := strconv.Atoi(strings.TrimSpace(s)); err == nil && baud > 0 {
+			bauds = append(bauds, baud)
+		}
+	}
+	if len(bauds) == 0 {
+		return defaultBauds
+	}
+	return bauds
+}
+
+// openAT 以指定波特率打开串口并测试 AT 命令
+func openAT(u string, baud int, hf func(string, map[int]string), pf func(string, ...any)) (*at.Device, *serialPort, error) {
+	port, err := openSerialPort(&serial.Config{
+		Name:        u,    // 串口完整路径
+		Baud:        baud, // 波特率
+		ReadTimeout: 1 * time.Second,
+	})
+	if err != nil {
+		slog.Warn("connect failed", slog.String("port", path.Base(u)), slog.Int("baud", baud), slog.Any("error", err))
+		return nil, nil, err
+	}
+
+	conn := at.New(port, hf, &at.Config{Printf: pf, NotificationSet: notificationSet})
+	conn.SendCommand("ATQ0V1") // 确保返回文本结果码，否则无法识别最终响应
+	if err := conn.Test(); err != nil {
+		slog.Warn("at test failed", slog.String("port", path.Base(u)), slog.Int("baud", baud), slog.Any("error", err))
+		conn.Close()
+		return nil, nil, fmt.Errorf("at test failed: %v", err)
+	}
+	return conn, port, nil
+}
+
+// PauseReadLoop 暂停读取循环，使调用方可以独占串口进行原始读写
+func (m *ModemInfo) PauseReadLoop() error {
+	return m.port.Pause()
+}
+
+// ResumeReadLoop 恢复读取循环和通知处理
+func (m *ModemInfo) ResumeReadLoop() error {
+	return m.port.Resume()
+}
+
+// RawRead 读取循环暂停期间直接读取串口
+func (m *ModemInfo) RawRead(buf []byte) (int, error) {
+	return m.port.RawRead(buf)
+}
+
+// RawWrite 读取循环暂停期间直接写入串口
+func (m *ModemInfo) RawWrite(data []byte) (int, error) {
+	return m.port.RawWrite(data)
+}
+
+// MarshalJSON 在读锁下序列化端口信息，避免与波特率等字段的修改并发
+func (m *ModemInfo) MarshalJSON() ([]byte, error) {
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	type plain ModemInfo
+	return json.Marshal((*plain)(m))
+}
+
+// setBaud 记录切换后的波特率
+func (m *ModemInfo) setBaud(baud int) {
+	m.stateMu.Lock()
+	m.Baud = baud
+	m.stateMu.Unlock()
+}
+
+// setSIMStatus 记录最近一次查询的 SIM 卡状态
+func (m *ModemInfo) setSIMStatus(status string) {
+	m.stateMu.Lock()
+	m.SIMStatus = status
+	m.stateMu.Unlock()
+}
+
+// IsOpen 连接是否仍然打开
+func (m *ModemInfo) IsOpen() bool {
+	return m.dev.IsOpen()
+}
 
-	return nil
+// Close 关闭连接，并将连接状态指标置为 0
+func (m *ModemInfo) Close() error {
+	metrics.ModemConnected.WithLabelValues(m.Name).Set(0)
+	return m.dev.Close()
 }
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rehiy/modem/at"
+	"github.com/tarm/serial"
+)
+
+// scriptedModem 按命令返回预设响应的模拟模块，未设置的命令返回 OK
+// 响应 ">" 模拟没有换行的短信输入提示符
+type scriptedModem struct {
+	mu       sync.Mutex
+	replies  map[string]string        // 命令 -> 响应（不含结尾换行）
+	delays   map[string]time.Duration // 命令 -> 响应延迟
+	commands []string                 // 收到的命令
+	silent   bool                     // 不响应任何命令
+	raw      bool                     // 响应原样输出，不添加换行，用于数字结果码格式
+
+	// respond 不为空时优先调用，ok 为 false 时按 replies 响应
+	respond func(cmd string) (resp string, ok bool)
+}
+
+// reply 设置命令的响应，多行用 \r\n 分隔
+func (s *scriptedModem) reply(cmd, resp string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.replies == nil {
+		s.replies = map[string]string{}
+	}
+	s.replies[cmd] = resp
+}
+
+// delay 设置命令的响应延迟
+func (s *scriptedModem) delay(cmd string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.delays == nil {
+		s.delays = map[string]time.Duration{}
+	}
+	s.delays[cmd] = d
+}
+
+// received 返回收到的命令
+func (s *scriptedModem) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.commands...)
+}
+
+// attach 将模拟模块连接到串口，每条以 \r 结尾的命令异步返回响应
+func (s *scriptedModem) attach(f *fakeSerial) {
+	var pending strings.Builder
+	f.onWrite = func(f *fakeSerial, b []byte) {
+		pending.Write(b)
+		for {
+			data := pending.String()
+			i := strings.IndexAny(data, "\r\x1a")
+			if i < 0 {
+				return
+			}
+			cmd := strings.TrimSpace(data[:i])
+			pending.Reset()
+			pending.WriteString(strings.TrimLeft(data[i+1:], "\n"))
+			if cmd == "" {
+				continue
+			}
+
+			s.mu.Lock()
+			s.commands = append(s.commands, cmd)
+			resp, ok := s.replies[cmd]
+			delay := s.delays[cmd]
+			silent, raw, respond := s.silent, s.raw, s.respond
+			s.mu.Unlock()
+			if silent {
+				continue
+			}
+			if respond != nil {
+				if r, handled := respond(cmd); handled {
+					resp, ok = r, true
+				}
+			}
+			if !ok {
+				resp = "OK"
+			}
+			switch {
+			case resp == "":
+			case raw:
+				go f.push(resp)
+			case resp == ">":
+				go f.push("\r\n> ")
+			default:
+				go func() {
+					time.Sleep(delay)
+					f.push("\r\n" + strings.ReplaceAll(resp, "\r\n", "\r\n\r\n") + "\r\n")
+				}()
+			}
+		}
+	}
+}
+
+// newTestModem 创建连接到模拟串口的 ModemInfo，命令队列已启动，测试结束时关闭
+func newTestModem(t testing.TB, script *scriptedModem) (*ModemInfo, *fakeSerial) {
+	return newTestModemWithURC(t, script, nil)
+}
+
+// newTestModemWithURC 与 newTestModem 相同，at 库分发的通知交给 hf
+func newTestModemWithURC(t testing.TB, script *scriptedModem, hf func(string, map[int]string)) (*ModemInfo, *fakeSerial) {
+	t.Helper()
+	f := newFakeSerial()
+	script.attach(f)
+
+	config := &serial.Config{Name: "/dev/ttyFAKE0", Baud: 115200, ReadTimeout: 20 * time.Millisecond}
+	p := newSerialPort(config, f, func(*serial.Config) (at.Port, error) { return f, nil })
+	m := &ModemInfo{Name: "ttyFAKE0", Vendor: VendorGeneric, Baud: 115200, port: p}
+	m.dev = at.New(p, hf, &at.Config{Printf: func(string, ...any) {}, NotificationSet: notificationSet})
+	// at 库在发送第一条命令前收到任何行都会出错，与 openAT 一样先发送一条命令
+	if err := m.dev.Test(); err != nil {
+		t.Fatalf("at test: %v", err)
+	}
+	script.mu.Lock()
+	script.commands = nil
+	script.mu.Unlock()
+	m.startQueue()
+	t.Cleanup(func() { m.Close() })
+	return m, f
+}
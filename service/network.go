@@ -0,0 +1,323 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// defaultRegistrationInterval 默认注册状态轮询间隔
+const defaultRegistrationInterval = 60 * time.Second
+
+// registrationInterval 注册状态轮询间隔，由 REGISTRATION_POLL_INTERVAL_SECONDS 设置，为 0 时关闭轮询
+var registrationInterval = sync.OnceValue(func() time.Duration {
+	v := os.Getenv("REGISTRATION_POLL_INTERVAL_SECONDS")
+	if v == "" {
+		return defaultRegistrationInterval
+	}
+	seconds, err := strconv.Atoi(v)
+	if err != nil || seconds < 0 {
+		slog.Warn("invalid REGISTRATION_POLL_INTERVAL_SECONDS", slog.String("value", v), slog.Duration("using", defaultRegistrationInterval))
+		return defaultRegistrationInterval
+	}
+	return time.Duration(seconds) * time.Second
+})
+
+// registrationStatus 网络注册状态码描述（3GPP TS 27.007 +CREG）
+var registrationStatus = map[int]string{
+	0:  "not registered",
+	1:  "registered, home network",
+	2:  "searching",
+	3:  "registration denied",
+	4:  "unknown",
+	5:  "registered, roaming",
+	6:  "registered for SMS only, home network",
+	7:  "registered for SMS only, roaming",
+	8:  "emergency services only",
+	11: "attached for emergency bearer services only",
+}
+
+// registrationDomains 注册状态通知对应的注册域
+var registrationDomains = map[string]string{
+	"+CREG":  "cs",
+	"+CGREG": "ps",
+	"+CEREG": "eps",
+}
+
+// registrationURCCommands 连接时开启注册状态通知的命令
+var registrationURCCommands = []string{"AT+CREG=2", "AT+CGREG=2", "AT+CEREG=2"}
+
+// GetRegistration 查询网络注册状态，并比较服务网络与 SIM 卡归属网络判断是否漫游
+// 部分模块的 +CREG 漫游状态（stat=5）不可靠，因此以 PLMN 比较结果为准
+func (m *ModemInfo) GetRegistration() (*models.Registration, error) {
+	_, stat, err := m.GetNetworkStatus()
+	if err != nil {
+		return nil, err
+	}
+	reg := newRegistration(stat)
+	if stat != 1 && stat != 5 {
+		return reg, nil
+	}
+
+	reg.ServingPLMN = m.servingPLMN()
+	if imsi, err := m.GetIMSI(); err == nil {
+		reg.HomePLMN = homePLMN(imsi, m.mncLength(), len(reg.ServingPLMN))
+	}
+
+	roaming := stat == 5
+	if reg.ServingPLMN != "" && reg.HomePLMN != "" {
+		roaming = reg.ServingPLMN != reg.HomePLMN
+	}
+	reg.Roaming = &roaming
+	return reg, nil
+}
+
+// servingPLMN 以数字格式查询当前服务网络，查询后恢复原有格式
+func (m *ModemInfo) servingPLMN() string {
+	operator, err := m.GetOperatorInfo()
+	if err != nil {
+		return ""
+	}
+	if operator.Format != 2 {
+		if _, err := m.SendCommand("AT+COPS=3,2"); err != nil {
+			return ""
+		}
+		defer m.SendCommand(fmt.Sprintf("AT+COPS=3,%d", operator.Format))
+		if operator, err = m.GetOperatorInfo(); err != nil {
+			return ""
+		}
+	}
+	if !isDigits(operator.Name) || len(operator.Name) < 5 || len(operator.Name) > 6 {
+		return ""
+	}
+	return operator.Name
+}
+
+// mncLength 从 SIM 卡 EF_AD（6FAD）第 4 字节读取 MNC 长度，读取失败时返回 0
+// +CRSM: <sw1>,<sw2>,<response>
+func (m *ModemInfo) mncLength() int {
+	responses, err := m.SendCommand("AT+CRSM=176,28589,0,0,4")
+	if err != nil {
+		return 0
+	}
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "+CRSM" || len(param) < 3 || param[0] != "144" || len(param[2]) < 8 {
+			continue
+		}
+		if n, err := strconv.ParseUint(param[2][6:8], 16, 8); err == nil && (n&0x0f == 2 || n&0x0f == 3) {
+			return int(n & 0x0f)
+		}
+	}
+	return 0
+}
+
+// homePLMN 根据 IMSI 获取归属网络 MCC+MNC
+// MNC 长度未知时参考服务网络的长度，仍未知时按 2 位处理
+func homePLMN(imsi string, mncLen, servingLen int) string {
+	if !isDigits(imsi) {
+		return ""
+	}
+	if mncLen == 0 && servingLen > 3 {
+		mncLen = servingLen - 3
+	}
+	if mncLen == 0 {
+		mncLen = 2
+	}
+	if len(imsi) < 3+mncLen {
+		return ""
+	}
+	return imsi[:3+mncLen]
+}
+
+// newRegistration 根据状态码创建注册状态
+func newRegistration(stat int) *models.Registration {
+	status, ok := registrationStatus[stat]
+	if !ok {
+		status = "unknown"
+	}
+	return &models.Registration{Stat: stat, Status: status}
+}
+
+// GetNetworkRegistration 分别查询电路域、分组域和 LTE 的注册状态
+// 查询结果与通知共用状态记录，不支持主动上报的模块也能通过查询发现变化
+func (m *ModemInfo) GetNetworkRegistration() (*models.NetworkRegistration, error) {
+	result := &models.NetworkRegistration{}
+	found := false
+	for _, label := range []string{"+CREG", "+CGREG", "+CEREG"} {
+		responses, err := m.SendCommand("AT" + label + "?")
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range responses {
+			// 查询响应格式：<n>,<stat>[,<lac/tac>,<ci>[,<act>]]
+			l, param := splitParam(line)
+			if l != label || len(param) < 2 {
+				continue
+			}
+			for len(param) < 5 {
+				param = append(param, "")
+			}
+			reg := m.recordRegistration(label, param[1], param[2], param[3], param[4])
+			if reg == nil {
+				continue
+			}
+			found = true
+			switch label {
+			case "+CREG":
+				result.CS = reg
+			case "+CGREG":
+				result.PS = reg
+			case "+CEREG":
+				result.EPS = reg
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("failed to parse network registration")
+	}
+	return result, nil
+}
+
+// pollRegistration 连接期间定期查询注册状态，补充不支持或丢失的 +CREG 等通知，状态变化时广播 registration 事件
+// 发送短信和数据模式期间跳过
+func (m *ModemInfo) pollRegistration() {
+	interval := registrationInterval()
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !m.IsOpen() {
+			return
+		}
+		if m.smsBusy.Load() || m.InDataMode() {
+			continue
+		}
+		if _, err := m.GetNetworkRegistration(); err != nil {
+			slog.Debug("registration poll failed", slog.String("port", m.Name), slog.Any("error", err))
+		}
+	}
+}
+
+// accessTechnologies 注册状态中 <AcT> 的取值（3GPP TS 27.007 +CREG）
+var accessTechnologies = map[int]string{
+	0:  "GSM",
+	1:  "GSM Compact",
+	2:  "UTRAN",
+	3:  "GSM/EGPRS",
+	4:  "UTRAN/HSDPA",
+	5:  "UTRAN/HSUPA",
+	6:  "UTRAN/HSDPA/HSUPA",
+	7:  "LTE",
+	8:  "EC-GSM-IoT",
+	9:  "LTE Cat-NB1",
+	10: "E-UTRA/5GCN",
+	11: "NR/5GCN",
+	12: "NG-RAN",
+	13: "E-UTRA/NR",
+}
+
+// GetCellInfo 查询当前服务小区的 LAC、小区标识和 MCC/MNC
+// 连接时已通过 AT+CREG=2 等开启带位置信息的注册状态，优先使用 LTE、其次分组域和电路域的结果
+func (m *ModemInfo) GetCellInfo() (*models.CellInfo, error) {
+	regs, err := m.GetNetworkRegistration()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &models.CellInfo{}
+	for _, d := range []struct {
+		name string
+		reg  *models.DomainRegistration
+	}{{"eps", regs.EPS}, {"ps", regs.PS}, {"cs", regs.CS}} {
+		if d.reg == nil || !d.reg.Registered || d.reg.CellID == "" {
+			continue
+		}
+		info.Domain, info.LAC, info.CellID = d.name, d.reg.Lac, d.reg.CellID
+		if d.reg.AcT != nil {
+			info.NetworkType = accessTechnologies[*d.reg.AcT]
+		}
+		break
+	}
+	if info.Domain == "" {
+		return nil, fmt.Errorf("not registered or cell location unavailable")
+	}
+
+	if plmn := m.servingPLMN(); plmn != "" {
+		mncLen := m.mncLength()
+		if mncLen == 0 || 3+mncLen != len(plmn) {
+			mncLen = len(plmn) - 3
+		}
+		info.MCC, info.MNC = plmn[:3], plmn[3:3+mncLen]
+	}
+	return info, nil
+}
+
+// handleRegistrationURC 处理 +CREG / +CGREG / +CEREG 通知，状态变化时广播 registration 事件
+// 通知格式：<stat>[,<lac/tac>,<ci>[,<act>]]
+func (m *ModemInfo) handleRegistrationURC(label string, param map[int]string) {
+	if _, ok := registrationDomains[label]; ok {
+		m.recordRegistration(label, param[0], param[1], param[2], param[3])
+	}
+}
+
+// recordRegistration 记录注册状态，状态变化时广播 registration 事件
+func (m *ModemInfo) recordRegistration(label, statStr, lac, ci, actStr string) *models.DomainRegistration {
+	domain := registrationDomains[label]
+	stat, err := strconv.Atoi(statStr)
+	if err != nil {
+		return nil
+	}
+
+	reg := models.DomainRegistration{
+		Registration: *newRegistration(stat),
+		Registered:   stat == 1 || stat == 5,
+		Lac:          lac,
+		CellID:       ci,
+	}
+	if act, err := strconv.Atoi(actStr); err == nil {
+		reg.AcT = &act
+	}
+
+	m.regMu.Lock()
+	if m.regStates == nil {
+		m.regStates = map[string]int{}
+	}
+	previous, seen := m.regStates[domain]
+	m.regStates[domain] = stat
+	m.regMu.Unlock()
+	if seen && previous == stat {
+		return &reg
+	}
+
+	event := models.RegistrationEvent{DomainRegistration: reg, Domain: domain}
+	if seen {
+		event.Previous = &previous
+	}
+
+	slog.Info("registration changed", slog.String("port", m.Name), slog.String("domain", domain), slog.String("status", event.Status))
+	ModemEvent.Publish(EventRegistration, m.Name, event)
+	return &reg
+}
+
+// isDigits 检查字符串是否全部为数字
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
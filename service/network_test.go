@@ -0,0 +1,65 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+func TestPollRegistrationPublishesChange(t *testing.T) {
+	old := registrationInterval
+	registrationInterval = func() time.Duration { return 20 * time.Millisecond }
+	t.Cleanup(func() { registrationInterval = old })
+
+	script := &scriptedModem{}
+	script.reply("AT+CREG?", "+CREG: 2,2\r\nOK")
+	m, _ := newTestModem(t, script)
+	m.Name = t.Name()
+	_, events, cancel := ModemEvent.Subscribe(ModemEvent.Latest(), 16)
+	defer cancel()
+	go m.pollRegistration()
+
+	next := func() models.RegistrationEvent {
+		t.Helper()
+		timeout := time.After(2 * time.Second)
+		for {
+			select {
+			case event := <-events:
+				if event.Type == EventRegistration && event.Port == m.Name {
+					return event.Data.(models.RegistrationEvent)
+				}
+			case <-timeout:
+				t.Fatal("no registration event")
+			}
+		}
+	}
+
+	if reg := next(); reg.Domain != "cs" || reg.Stat != 2 {
+		t.Fatalf("first event = %+v", reg)
+	}
+	script.reply("AT+CREG?", `+CREG: 2,1,"1A2B","0001ABCD",7`+"\r\nOK")
+	reg := next()
+	if reg.Stat != 1 || reg.Previous == nil || *reg.Previous != 2 || reg.CellID != "0001ABCD" {
+		t.Fatalf("change event = %+v", reg)
+	}
+}
+
+func TestGetRegistrationQueued(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CREG?", `+CREG: 2,2,"1A2B","0001ABCD",7`+"\r\nOK")
+	m, _ := newTestModem(t, script)
+
+	reg, err := m.GetRegistration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reg.Stat != 2 || reg.Roaming != nil {
+		t.Fatalf("reg = %+v", reg)
+	}
+
+	script.reply("AT+CREG?", "ERROR")
+	if _, err := m.GetRegistration(); err == nil {
+		t.Fatal("expected error")
+	}
+}
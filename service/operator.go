@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+const (
+	operatorScanTimeout   = 3 * time.Minute // 网络搜索需逐个频段扫描，耗时很长
+	operatorSelectTimeout = 2 * time.Minute // 手动选网等待注册结果的时间
+)
+
+// operatorStatus AT+COPS=? 中 <stat> 的取值
+var operatorStatus = map[string]string{
+	"0": "unknown",
+	"1": "available",
+	"2": "current",
+	"3": "forbidden",
+}
+
+// ErrScanInProgress 正在搜索网络
+var ErrScanInProgress = errors.New("operator scan in progress")
+
+// ScanOperators 通过 AT+COPS=? 搜索可用网络，并缓存结果
+// 搜索期间独占命令队列长达数分钟，挂断、接听等紧急命令也无法执行，因此通话中拒绝搜索
+func (m *ModemInfo) ScanOperators() (*models.OperatorScan, error) {
+	if !m.scanning.CompareAndSwap(false, true) {
+		return nil, ErrScanInProgress
+	}
+	defer m.scanning.Store(false)
+	if m.inCall() {
+		return nil, fmt.Errorf("%w: operator scan not allowed during a call", ErrCallInProgress)
+	}
+
+	responses, err := m.SendCommandWithTimeout("AT+COPS=?", operatorScanTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := finalError(responses); err != nil {
+		return nil, err
+	}
+
+	for _, line := range responses {
+		if label, _, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(label) == "+COPS" {
+			scan := &models.OperatorScan{
+				Operators: parseOperatorList(line),
+				ScannedAt: time.Now(),
+			}
+			m.operatorMu.Lock()
+			m.operatorScan = scan
+			m.operatorMu.Unlock()
+			return scan, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to parse operator list")
+}
+
+// LastOperatorScan 返回最近一次网络搜索结果，未搜索过时返回 nil
+func (m *ModemInfo) LastOperatorScan() *models.OperatorScan {
+	m.operatorMu.Lock()
+	defer m.operatorMu.Unlock()
+	return m.operatorScan
+}
+
+// parseOperatorList 解析 +COPS: (<stat>,"<long>","<short>","<numeric>"[,<AcT>]),...,,(<modes>),(<formats>)
+// 空括号组之后为支持的模式和格式列表，不属于网络
+func parseOperatorList(line string) []models.NetworkOperator {
+	operators := []models.NetworkOperator{}
+	_, rest, _ := strings.Cut(line, ":")
+	for {
+		open := strings.Index(rest, "(")
+		if open < 0 {
+			break
+		}
+		end := strings.Index(rest[open:], ")")
+		if end < 0 {
+			break
+		}
+		group := rest[open+1 : open+end]
+		rest = rest[open+end+1:]
+
+		fields := strings.Split(group, ",")
+		if len(fields) < 4 || !strings.HasPrefix(strings.TrimSpace(fields[1]), `"`) {
+			break
+		}
+		for i := range fields {
+			fields[i] = strings.Trim(strings.TrimSpace(fields[i]), `"`)
+		}
+
+		op := models.NetworkOperator{
+			Status:      operatorStatus[fields[0]],
+			LongName:    fields[1],
+			ShortName:   fields[2],
+			NumericCode: fields[3],
+		}
+		if op.Status == "" {
+			op.Status = "unknown"
+		}
+		if len(fields) > 4 {
+			if act, err := strconv.Atoi(fields[4]); err == nil {
+				op.AccessTechnology = accessTechnologies[act]
+			}
+		}
+		operators = append(operators, op)
+	}
+	return operators
+}
+
+// SelectOperator 手动选择网络，mode 为 1（手动）或 4（手动失败后自动）
+func (m *ModemInfo) SelectOperator(code string, mode int) error {
+	if !isDigits(code) || len(code) < 5 || len(code) > 6 {
+		return fmt.Errorf("%w: operator code must be 5-6 digit MCC+MNC", ErrInvalid)
+	}
+	if mode != 1 && mode != 4 {
+		return fmt.Errorf("%w: mode must be 1 (manual) or 4 (manual/automatic)", ErrInvalid)
+	}
+	return m.setOperator(fmt.Sprintf(`AT+COPS=%d,2,"%s"`, mode, code))
+}
+
+// SetAutoOperator 恢复自动选网
+func (m *ModemInfo) SetAutoOperator() error {
+	return m.setOperator("AT+COPS=0")
+}
+
+// setOperator 发送选网命令并等待注册结果
+func (m *ModemInfo) setOperator(cmd string) error {
+	responses, err := m.SendCommandWithTimeout(cmd, operatorSelectTimeout)
+	if err != nil {
+		return err
+	}
+	return finalError(responses)
+}
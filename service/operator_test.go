@@ -0,0 +1,64 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+func TestScanOperatorsRejectedDuringCall(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+COPS=?", `+COPS: (2,"CMCC","CMCC","46000",7),,(0-4),(0-2)`+"\r\nOK")
+	m, _ := newTestModem(t, script)
+
+	m.calls.mu.Lock()
+	m.calls.current = &models.CallRecord{Direction: "in", Status: CallRinging}
+	m.calls.mu.Unlock()
+
+	if _, err := m.ScanOperators(); !errors.Is(err, ErrCallInProgress) {
+		t.Fatalf("err = %v, want ErrCallInProgress", err)
+	}
+	if m.scanning.Load() {
+		t.Fatal("scanning flag left set")
+	}
+
+	m.calls.mu.Lock()
+	m.calls.current = nil
+	m.calls.mu.Unlock()
+
+	scan, err := m.ScanOperators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scan.Operators) != 1 || scan.Operators[0].NumericCode != "46000" {
+		t.Fatalf("operators = %+v", scan.Operators)
+	}
+}
+
+func TestDialRejectedDuringScan(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+COPS=?", `+COPS: (2,"CMCC","CMCC","46000",7),,(0-4),(0-2)`+"\r\nOK")
+	script.delay("AT+COPS=?", 1200*time.Millisecond)
+	m, _ := newTestModem(t, script)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.ScanOperators()
+		done <- err
+	}()
+	for !m.scanning.Load() {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := m.ScanOperators(); !errors.Is(err, ErrScanInProgress) {
+		t.Fatalf("second scan err = %v, want ErrScanInProgress", err)
+	}
+	if err := m.Dial("10086"); !errors.Is(err, ErrScanInProgress) {
+		t.Fatalf("dial err = %v, want ErrScanInProgress", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
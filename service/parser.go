@@ -0,0 +1,184 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// ParseFunc 命令响应解析函数
+type ParseFunc func(responses []string) (any, error)
+
+var (
+	parserMu sync.RWMutex
+	parsers  = map[string]ParseFunc{}
+)
+
+func init() {
+	RegisterParser("", "AT+CSQ", parseSignal)
+	RegisterParser("", "AT+COPS?", parseOperator)
+}
+
+// RegisterParser 注册命令响应解析器
+// vendor 为空表示通用解析器，厂商解析器优先于通用解析器
+func RegisterParser(vendor, command string, fn ParseFunc) {
+	parserMu.Lock()
+	defer parserMu.Unlock()
+	parsers[vendor+"|"+command] = fn
+}
+
+// lookupParser 查找命令响应解析器
+func lookupParser(vendor, command string) ParseFunc {
+	parserMu.RLock()
+	defer parserMu.RUnlock()
+	if fn, ok := parsers[vendor+"|"+command]; ok {
+		return fn
+	}
+	return parsers["|"+command]
+}
+
+// Query 发送命令并使用已注册的解析器解析响应
+func (m *ModemInfo) Query(command string) (any, error) {
+	fn := lookupParser(m.Vendor, command)
+	if fn == nil {
+		return nil, fmt.Errorf("no parser registered for %s", command)
+	}
+
+	responses, err := m.SendCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	return fn(responses)
+}
+
+// queryWithTimeout 与 Query 相同，但在指定时间内等待最终响应
+// 超过 at 库默认超时的命令经截获解析，+CME ERROR 等错误结果码会交给解析器而不是表现为超时
+func (m *ModemInfo) queryWithTimeout(command string, timeout time.Duration) (any, error) {
+	fn := lookupParser(m.Vendor, command)
+	if fn == nil {
+		return nil, fmt.Errorf("no parser registered for %s", command)
+	}
+
+	responses, err := m.SendCommandWithTimeout(command, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return fn(responses)
+}
+
+// GetSignal 查询信号质量，数据模式下端口被 PPP 占用，返回 ErrDataMode
+func (m *ModemInfo) GetSignal() (*models.Signal, error) {
+	if m.InDataMode() {
+		return nil, ErrDataMode
+	}
+
+	v, err := m.Query("AT+CSQ")
+	if err != nil {
+		return nil, err
+	}
+	signal, ok := v.(*models.Signal)
+	if !ok {
+		return nil, fmt.Errorf("unexpected signal parser result %T", v)
+	}
+	return signal, nil
+}
+
+// GetOperatorInfo 查询运营商信息
+func (m *ModemInfo) GetOperatorInfo() (*models.Operator, error) {
+	v, err := m.Query("AT+COPS?")
+	if err != nil {
+		return nil, err
+	}
+	operator, ok := v.(*models.Operator)
+	if !ok {
+		return nil, fmt.Errorf("unexpected operator parser result %T", v)
+	}
+	return operator, nil
+}
+
+// ===== 通用解析器 =====
+
+// parseSignal 解析 +CSQ: <rssi>,<ber>
+func parseSignal(responses []string) (any, error) {
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "+CSQ" || len(param) < 2 {
+			continue
+		}
+		rssi, err := strconv.Atoi(param[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rssi %q", param[0])
+		}
+		ber, _ := strconv.Atoi(param[1])
+		return newSignal(rssi, ber), nil
+	}
+	return nil, fmt.Errorf("failed to parse signal quality")
+}
+
+// parseOperator 解析 +COPS: <mode>[,<format>,<oper>[,<act>]]
+func parseOperator(responses []string) (any, error) {
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "+COPS" || len(param) < 1 {
+			continue
+		}
+		operator := &models.Operator{Act: -1}
+		operator.Mode, _ = strconv.Atoi(param[0])
+		if len(param) >= 3 {
+			operator.Format, _ = strconv.Atoi(param[1])
+			operator.Name = param[2]
+		}
+		if len(param) >= 4 {
+			operator.Act, _ = strconv.Atoi(param[3])
+		}
+		return operator, nil
+	}
+	return nil, fmt.Errorf("failed to parse operator info")
+}
+
+// newSignal 根据 RSSI 计算信号等级和 dBm
+func newSignal(rssi, ber int) *models.Signal {
+	signal := &models.Signal{RSSI: rssi, BER: ber}
+
+	// 99 表示未知或不可检测
+	if rssi == 99 {
+		return signal
+	}
+
+	// 计算信号等级
+	switch {
+	case rssi >= 20:
+		signal.Level = 5
+	case rssi >= 15:
+		signal.Level = 4
+	case rssi >= 10:
+		signal.Level = 3
+	case rssi >= 5:
+		signal.Level = 2
+	case rssi >= 1:
+		signal.Level = 1
+	}
+
+	// 将 RSSI 转换为 dBm: dBm = -113 + (rssi * 2)
+	signal.DBM = -113 + (rssi * 2)
+	return signal
+}
+
+// splitParam 拆分响应行的标签和参数，兼容冒号后有无空格的写法
+func splitParam(line string) (string, []string) {
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) != 2 {
+		return strings.TrimSpace(line), nil
+	}
+
+	label := strings.TrimSpace(parts[0])
+	param := []string{}
+	for _, v := range strings.Split(parts[1], ",") {
+		param = append(param, strings.Trim(strings.TrimSpace(v), `"'`))
+	}
+	return label, param
+}
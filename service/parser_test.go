@@ -0,0 +1,96 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+func TestQueryPrefersVendorParser(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CSQ", "+CSQ: 20,99\r\nOK")
+	m, _ := newTestModem(t, script)
+
+	v, err := m.Query("AT+CSQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signal := v.(*models.Signal); signal.RSSI != 20 || signal.DBM != -73 || signal.Level != 5 {
+		t.Fatalf("generic signal = %+v", signal)
+	}
+
+	RegisterParser(VendorGeneric, "AT+CSQ", func(responses []string) (any, error) { return "vendor", nil })
+	t.Cleanup(func() {
+		parserMu.Lock()
+		delete(parsers, VendorGeneric+"|AT+CSQ")
+		parserMu.Unlock()
+	})
+	if v, err := m.Query("AT+CSQ"); err != nil || v != "vendor" {
+		t.Fatalf("vendor parser: v = %v, err = %v", v, err)
+	}
+
+	if _, err := m.Query("AT+UNKNOWN"); err == nil {
+		t.Fatal("query without parser should fail")
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	cases := []struct {
+		line        string
+		rssi, level int
+		dbm         int
+	}{
+		{"+CSQ: 31,0", 31, 5, -51},
+		{"+CSQ: 12,99", 12, 3, -89},
+		{"+CSQ:1,99", 1, 1, -111},
+		{"+CSQ: 99,99", 99, 0, 0},
+	}
+	for _, c := range cases {
+		v, err := parseSignal([]string{c.line, "OK"})
+		if err != nil {
+			t.Fatalf("%s: %v", c.line, err)
+		}
+		signal := v.(*models.Signal)
+		if signal.RSSI != c.rssi || signal.Level != c.level || signal.DBM != c.dbm {
+			t.Errorf("%s: signal = %+v", c.line, signal)
+		}
+	}
+	if _, err := parseSignal([]string{"OK"}); err == nil {
+		t.Error("missing +CSQ should fail")
+	}
+}
+
+func TestParseOperator(t *testing.T) {
+	v, err := parseOperator([]string{`+COPS: 0,0,"CHINA MOBILE",7`, "OK"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op := v.(*models.Operator); op.Mode != 0 || op.Name != "CHINA MOBILE" || op.Act != 7 {
+		t.Fatalf("operator = %+v", op)
+	}
+
+	// 未注册时只有模式
+	v, err = parseOperator([]string{"+COPS: 0", "OK"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op := v.(*models.Operator); op.Name != "" || op.Act != -1 {
+		t.Fatalf("unregistered operator = %+v", op)
+	}
+}
+
+func TestDetectVendor(t *testing.T) {
+	cases := map[string]string{
+		"Quectel":                VendorQuectel,
+		"huawei technologies":    VendorHuawei,
+		"SIMCOM INCORPORATED":    VendorSimcom,
+		"Fibocom Wireless Inc.":  VendorFibocom,
+		"Sierra Wireless, Inc.":  VendorSierra,
+		"Some Other Modem Maker": VendorGeneric,
+	}
+	for manufacturer, want := range cases {
+		if got := detectVendor(manufacturer); got != want {
+			t.Errorf("detectVendor(%q) = %q, want %q", manufacturer, got, want)
+		}
+	}
+}
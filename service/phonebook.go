@@ -0,0 +1,170 @@
+package service
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rehiy/modem/sms/ucs2"
+	"github.com/rehiy/web-modem/models"
+)
+
+// phonebookTimeout 读取整个 SIM 卡电话簿的等待时间
+const phonebookTimeout = 30 * time.Second
+
+var (
+	// cpbrRe 解析 +CPBR: <index>,"<number>",<type>,"<text>"
+	cpbrRe = regexp.MustCompile(`^\+CPBR:\s*(\d+),"([^"]*)",(\d+),"([^"]*)"`)
+	// cpbrRangeRe 解析 +CPBR: (<first>-<last>),<nlength>,<tlength>
+	cpbrRangeRe = regexp.MustCompile(`^\+CPBR:\s*\((\d+)-(\d+)\)`)
+	// phoneNumberRe 电话簿号码允许的字符
+	phoneNumberRe = regexp.MustCompile(`^\+?[0-9*#]+$`)
+)
+
+// ListContacts 读取 SIM 卡电话簿
+// 读取期间临时切换为 UCS2 字符集，以正确获取中文等非 ASCII 姓名，完成后恢复原字符集
+func (m *ModemInfo) ListContacts() ([]models.Contact, error) {
+	contacts := []models.Contact{}
+	err := m.withPhonebook(func() error {
+		first, last := 1, 250
+		responses, err := m.sendCommand("AT+CPBR=?")
+		if err != nil {
+			return err
+		}
+		for _, line := range responses {
+			if match := cpbrRangeRe.FindStringSubmatch(line); match != nil {
+				first, _ = strconv.Atoi(match[1])
+				last, _ = strconv.Atoi(match[2])
+			}
+		}
+
+		responses, err = m.sendCommandWithTimeout(fmt.Sprintf("AT+CPBR=%d,%d", first, last), phonebookTimeout)
+		if err != nil {
+			return err
+		}
+		// 电话簿为空时部分模块返回 +CME ERROR: not found
+		if finalError(responses) != nil {
+			return nil
+		}
+		for _, line := range responses {
+			match := cpbrRe.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			index, _ := strconv.Atoi(match[1])
+			contacts = append(contacts, models.Contact{
+				Index:  index,
+				Number: decodePhonebookNumber(match[2]),
+				Name:   decodeUCS2Hex(match[4]),
+			})
+		}
+		return nil
+	})
+	return contacts, err
+}
+
+// WriteContact 写入电话簿条目，姓名按 UCS2 编码，支持中文和 emoji
+func (m *ModemInfo) WriteContact(contact models.Contact) error {
+	if contact.Index <= 0 {
+		return fmt.Errorf("%w: index must be positive", ErrInvalid)
+	}
+	if !phoneNumberRe.MatchString(contact.Number) {
+		return fmt.Errorf("%w: invalid number %q", ErrInvalid, contact.Number)
+	}
+
+	numberType := 129
+	if strings.HasPrefix(contact.Number, "+") {
+		numberType = 145
+	}
+	name := strings.ToUpper(hex.EncodeToString(ucs2.Encode([]rune(contact.Name))))
+	cmd := fmt.Sprintf(`AT+CPBW=%d,"%s",%d,"%s"`, contact.Index, contact.Number, numberType, name)
+
+	return m.withPhonebook(func() error {
+		responses, err := m.sendCommand(cmd)
+		if err != nil {
+			return err
+		}
+		return finalError(responses)
+	})
+}
+
+// DeleteContact 删除电话簿条目
+func (m *ModemInfo) DeleteContact(index int) error {
+	if index <= 0 {
+		return fmt.Errorf("%w: index must be positive", ErrInvalid)
+	}
+	return m.withPhonebook(func() error {
+		responses, err := m.sendCommand(fmt.Sprintf("AT+CPBW=%d", index))
+		if err != nil {
+			return err
+		}
+		return finalError(responses)
+	})
+}
+
+// withPhonebook 选择 SIM 卡电话簿并切换为 UCS2 字符集后执行 fn，完成后恢复原字符集
+// 整个过程作为一个任务排队，避免其他命令在 UCS2 字符集下执行
+func (m *ModemInfo) withPhonebook(fn func() error) error {
+	var err error
+	if qerr := m.exec(false, func() {
+		err = m.phonebookTask(fn)
+	}); qerr != nil {
+		return qerr
+	}
+	return err
+}
+
+// phonebookTask 在命令队列中执行电话簿操作
+func (m *ModemInfo) phonebookTask(fn func() error) error {
+	responses, err := m.sendCommand(`AT+CPBS="SM"`)
+	if err != nil {
+		return err
+	}
+	if err := finalError(responses); err != nil {
+		return fmt.Errorf("select sim phonebook: %w", err)
+	}
+
+	charset := ""
+	if responses, err := m.sendCommand("AT+CSCS?"); err == nil {
+		for _, line := range responses {
+			if label, param := splitParam(line); label == "+CSCS" && len(param) > 0 {
+				charset = param[0]
+			}
+		}
+	}
+	if responses, err := m.sendCommand(`AT+CSCS="UCS2"`); err != nil || finalError(responses) != nil {
+		return fmt.Errorf("%w: modem does not support UCS2 charset", ErrUnsupported)
+	}
+	if charset != "" && charset != "UCS2" {
+		defer m.sendCommand(fmt.Sprintf(`AT+CSCS="%s"`, charset))
+	}
+
+	return fn()
+}
+
+// decodeUCS2Hex 解码 UCS2 十六进制字符串，无法解码时原样返回
+func decodeUCS2Hex(s string) string {
+	if len(s)%4 != 0 {
+		return s
+	}
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return s
+	}
+	runes, err := ucs2.Decode(data)
+	if err != nil {
+		return s
+	}
+	return string(runes)
+}
+
+// decodePhonebookNumber 号码按规范不受字符集影响，部分模块仍以 UCS2 返回，解码后为合法号码时才采用
+func decodePhonebookNumber(s string) string {
+	if decoded := decodeUCS2Hex(s); decoded != s && phoneNumberRe.MatchString(decoded) {
+		return decoded
+	}
+	return s
+}
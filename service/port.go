@@ -0,0 +1,559 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/rehiy/modem/at"
+	"github.com/tarm/serial"
+)
+
+// errPortUnavailable 串口已关闭或正在重连
+var errPortUnavailable = errors.New("port unavailable")
+
+// serialPort 串口包装，实现 at.Port 接口
+// 暂停期间阻塞读取循环和常规写入，仅允许原始读写
+type serialPort struct {
+	open func(*serial.Config) (at.Port, error) // 打开串口，重连时使用
+
+	mu     sync.Mutex     // 保护 config 和 port
+	config *serial.Config // 当前串口配置
+	port   at.Port        // 当前打开的串口，重连期间为 nil
+
+	readGate     sync.Mutex // 读取循环闸门
+	writeGate    sync.Mutex // 常规写入闸门
+	paused       atomic.Bool
+	closed       atomic.Bool
+	done         chan struct{} // 串口关闭时关闭
+	closeOnce    sync.Once
+	reconnecting atomic.Bool
+
+	readErrors int                // 连续读取错误次数，仅在读取循环中访问
+	onState    func(state string) // 重连状态回调：reconnected / disconnected
+
+	tapMu    sync.Mutex
+	taps     map[chan string]struct{} // 行监听通道
+	capture  *lineCapture             // 截获中的命令交互
+	partial  []byte                   // 未结束的行
+	prompted bool                     // 未结束的行为已分发的提示符
+	pduNext  bool                     // 上一行为 +CMT/+CDS 通知，下一行为 PDU
+	numeric  atomic.Bool              // 模块使用数字结果码（ATV0）
+	out      []byte                   // 待交给 at 库的完整行，仅在读取循环中访问
+}
+
+// lineCapture 截获读取到的行，用于自行解析响应的命令交互
+type lineCapture struct {
+	lines   chan string
+	forward func(line string) bool // 返回 true 的行（如通知）同时交给 at 库
+}
+
+const (
+	captureBuffer   = 256                   // 截获通道的缓冲行数
+	maxPartialLine  = 4096                  // 未结束行的最大缓存长度
+	writeChunkSize  = 256                   // 单次写入的最大长度，超出部分分块写入
+	writeChunkDelay = 10 * time.Millisecond // 分块写入间隔，留出模块处理输入缓冲区的时间
+
+	maxReadErrors         = 5                // 连续读取错误达到该次数后重新打开串口
+	reconnectBaseDelay    = time.Second      // 首次重连等待时间
+	reconnectMaxDelay     = 60 * time.Second // 最长重连等待时间
+	defaultReconnectTries = 10               // 默认最大重连次数
+)
+
+// 串口重连状态
+const (
+	PortReconnected  = "reconnected"
+	PortDisconnected = "disconnected"
+)
+
+// openSerialPort 打开串口
+func openSerialPort(config *serial.Config) (*serialPort, error) {
+	open := func(c *serial.Config) (at.Port, error) {
+		return serial.OpenPort(c)
+	}
+	port, err := open(config)
+	if err != nil {
+		return nil, err
+	}
+	return newSerialPort(config, port, open), nil
+}
+
+// newSerialPort 包装已打开的串口，open 用于重连和切换波特率时重新打开
+func newSerialPort(config *serial.Config, port at.Port, open func(*serial.Config) (at.Port, error)) *serialPort {
+	return &serialPort{
+		open:   open,
+		config: config,
+		port:   port,
+		done:   make(chan struct{}),
+		taps:   map[chan string]struct{}{},
+	}
+}
+
+// current 返回当前打开的串口，已关闭或正在重连时为 nil
+func (p *serialPort) current() at.Port {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.port
+}
+
+// Read 按行读取数据交给 at 库，暂停期间阻塞
+// 截获期间的行不交给 at 库，因此只返回完整的行
+// 读取超时（io.EOF）时继续等待而不返回，at 库每次读取出错后会暂停半个超时周期，期间到达的响应会被延迟；
+// USB 模块被拔出后读取不再等待超时而是立即返回 io.EOF，按读取错误计数；
+// 设备消失（ENXIO / ENODEV / EIO）时立即重新打开串口，连续读取出错时也尝试重新打开
+func (p *serialPort) Read(buf []byte) (int, error) {
+	for {
+		p.readGate.Lock()
+		if len(p.out) > 0 {
+			n := copy(buf, p.out)
+			p.out = p.out[n:]
+			p.readGate.Unlock()
+			return n, nil
+		}
+		port := p.current()
+		if port == nil {
+			p.readGate.Unlock()
+			time.Sleep(p.readTimeout())
+			return 0, errPortUnavailable
+		}
+		start := time.Now()
+		n, err := port.Read(buf)
+		if n > 0 {
+			p.feed(buf[:n])
+		}
+		p.readGate.Unlock()
+
+		switch {
+		case p.closed.Load():
+			return 0, io.EOF
+		case err == nil || n > 0:
+			// 读取到的数据已放入待交付缓冲区或被截获，继续读取
+			p.readErrors = 0
+			if err == nil {
+				continue
+			}
+		case isDeviceGone(err):
+			slog.Warn("device gone", slog.String("port", p.name()), slog.Any("error", err))
+			p.readErrors = 0
+			p.reconnect()
+		case err == io.EOF && time.Since(start) >= p.readTimeout()/2:
+			// 空闲时仍未结束的行可能是数字结果码，由此发现模块已切换为数字格式
+			p.readErrors = 0
+			p.tapMu.Lock()
+			p.flushNumeric()
+			p.tapMu.Unlock()
+			continue
+		default:
+			if p.readErrors++; p.readErrors >= maxReadErrors {
+				p.readErrors = 0
+				p.reconnect()
+			}
+		}
+		return 0, err
+	}
+}
+
+// isDeviceGone 读写错误是否表示设备已不存在
+func isDeviceGone(err error) bool {
+	return errors.Is(err, syscall.ENXIO) || errors.Is(err, syscall.ENODEV) || errors.Is(err, syscall.EIO)
+}
+
+// readTimeout 串口读取超时时间
+func (p *serialPort) readTimeout() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config.ReadTimeout > 0 {
+		return p.config.ReadTimeout
+	}
+	return time.Second
+}
+
+// name 串口名
+func (p *serialPort) name() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return path.Base(p.config.Name)
+}
+
+// reconnect 关闭串口后按指数退避重新打开
+// 等待期间不持有读写闸门，命令直接返回 errPortUnavailable 而不是阻塞到重连结束
+// 重试次数由 MAX_RECONNECT_ATTEMPTS 设置，全部失败后通知断开
+func (p *serialPort) reconnect() {
+	if !p.reconnecting.CompareAndSwap(false, true) {
+		return
+	}
+	defer p.reconnecting.Store(false)
+
+	attempts := defaultReconnectTries
+	if v, err := strconv.Atoi(os.Getenv("MAX_RECONNECT_ATTEMPTS")); err == nil && v > 0 {
+		attempts = v
+	}
+
+	p.mu.Lock()
+	if p.port != nil {
+		p.port.Close()
+		p.port = nil
+	}
+	config := *p.config
+	p.mu.Unlock()
+
+	name := path.Base(config.Name)
+	delay := reconnectBaseDelay
+	for i := 1; i <= attempts; i++ {
+		select {
+		case <-time.After(delay):
+		case <-p.done:
+			return
+		}
+		port, err := p.open(&config)
+		if err == nil {
+			p.mu.Lock()
+			if p.closed.Load() {
+				p.mu.Unlock()
+				port.Close()
+				return
+			}
+			p.port = port
+			p.mu.Unlock()
+
+			slog.Info("port reopened", slog.String("port", name), slog.Int("attempts", i))
+			p.tapMu.Lock()
+			p.partial, p.prompted, p.pduNext = nil, false, false
+			p.tapMu.Unlock()
+			p.notify(PortReconnected)
+			return
+		}
+		slog.Warn("port reopen failed", slog.String("port", name), slog.Int("attempt", i), slog.Any("error", err))
+		delay = min(delay*2, reconnectMaxDelay)
+	}
+	p.notify(PortDisconnected)
+}
+
+// Reconnecting 是否正在重新打开串口
+func (p *serialPort) Reconnecting() bool {
+	return p.reconnecting.Load()
+}
+
+// notify 异步通知重连状态，回调中可能需要读写串口
+func (p *serialPort) notify(state string) {
+	if p.onState != nil {
+		go p.onState(state)
+	}
+}
+
+// Write 写入数据，暂停期间阻塞
+// 较长的命令（如长短信 PDU）分块写入，并处理部分写入，避免模块输入缓冲区溢出导致截断
+func (p *serialPort) Write(data []byte) (int, error) {
+	p.writeGate.Lock()
+	defer p.writeGate.Unlock()
+	port := p.current()
+	if port == nil {
+		return 0, errPortUnavailable
+	}
+	return writeChunked(port, data)
+}
+
+// writeChunked 按块写入全部数据，返回已写入的长度
+func writeChunked(w io.Writer, data []byte) (int, error) {
+	written := 0
+	for written < len(data) {
+		if written > 0 {
+			time.Sleep(writeChunkDelay)
+		}
+		end := min(written+writeChunkSize, len(data))
+		for written < end {
+			n, err := w.Write(data[written:end])
+			written += n
+			if err != nil {
+				return written, err
+			}
+			if n == 0 {
+				return written, io.ErrShortWrite
+			}
+		}
+	}
+	return written, nil
+}
+
+// Flush 清空缓冲区
+func (p *serialPort) Flush() error {
+	port := p.current()
+	if port == nil {
+		return errPortUnavailable
+	}
+	return port.Flush()
+}
+
+// Close 关闭串口，同时结束正在进行的重连
+func (p *serialPort) Close() error {
+	p.closed.Store(true)
+	p.closeOnce.Do(func() { close(p.done) })
+
+	p.mu.Lock()
+	port := p.port
+	p.port = nil
+	p.mu.Unlock()
+	if port == nil {
+		return nil
+	}
+	return port.Close()
+}
+
+// Done 返回串口关闭时关闭的通道
+func (p *serialPort) Done() <-chan struct{} {
+	return p.done
+}
+
+// Pause 暂停读取循环和常规写入
+// 会等待正在进行的读取返回，未读取的数据保留在串口缓冲区中
+func (p *serialPort) Pause() error {
+	if !p.paused.CompareAndSwap(false, true) {
+		return fmt.Errorf("read loop already paused")
+	}
+	p.writeGate.Lock()
+	p.readGate.Lock()
+	return nil
+}
+
+// Resume 恢复读取循环和常规写入
+func (p *serialPort) Resume() error {
+	if !p.paused.CompareAndSwap(true, false) {
+		return fmt.Errorf("read loop not paused")
+	}
+	p.readGate.Unlock()
+	p.writeGate.Unlock()
+	return nil
+}
+
+// RawRead 暂停期间直接读取串口
+func (p *serialPort) RawRead(buf []byte) (int, error) {
+	if !p.paused.Load() {
+		return 0, fmt.Errorf("read loop not paused")
+	}
+	port := p.current()
+	if port == nil {
+		return 0, errPortUnavailable
+	}
+	return port.Read(buf)
+}
+
+// RawWrite 暂停期间直接写入串口
+func (p *serialPort) RawWrite(data []byte) (int, error) {
+	if !p.paused.Load() {
+		return 0, fmt.Errorf("read loop not paused")
+	}
+	port := p.current()
+	if port == nil {
+		return 0, errPortUnavailable
+	}
+	return port.Write(data)
+}
+
+// Reopen 以新的波特率重新打开串口，期间阻塞读写
+// 打开失败时尝试按原配置重新打开，原配置也无法打开时串口保持关闭，返回的错误包含两次失败的原因
+func (p *serialPort) Reopen(baud int) error {
+	if p.paused.Load() {
+		return fmt.Errorf("read loop paused")
+	}
+
+	p.writeGate.Lock()
+	defer p.writeGate.Unlock()
+	p.readGate.Lock()
+	defer p.readGate.Unlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.port == nil {
+		return errPortUnavailable
+	}
+
+	config := *p.config
+	config.Baud = baud
+
+	p.port.Close()
+	port, err := p.open(&config)
+	if err != nil {
+		p.port = nil
+		restored, rerr := p.open(p.config)
+		if rerr != nil {
+			return fmt.Errorf("%v; reopen at %d: %v", err, p.config.Baud, rerr)
+		}
+		p.port = restored
+		return err
+	}
+
+	p.port, p.config = port, &config
+	p.tapMu.Lock()
+	p.partial, p.prompted, p.pduNext = nil, false, false
+	p.tapMu.Unlock()
+	return nil
+}
+
+// Baud 当前波特率
+func (p *serialPort) Baud() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.config.Baud
+}
+
+// Tap 监听读取到的每一行（去除空白，忽略空行）
+// 与读取循环并行工作，不影响 at 库的响应和通知分发
+func (p *serialPort) Tap(buffer int) (chan string, func()) {
+	ch := make(chan string, buffer)
+
+	p.tapMu.Lock()
+	p.taps[ch] = struct{}{}
+	p.tapMu.Unlock()
+
+	return ch, func() {
+		p.tapMu.Lock()
+		delete(p.taps, ch)
+		p.tapMu.Unlock()
+	}
+}
+
+// Capture 截获之后读取到的行，直到调用释放函数
+// 截获的行发送到返回的通道而不交给 at 库，at 库超时后迟到的响应因此不会混入下一条命令；
+// forward 返回 true 的行（如截获期间收到的通知）同时交给 at 库分发；
+// 没有换行的短信输入提示符 "> " 作为单独的一行 ">" 发送
+// 同一时间只能有一个截获，由命令队列保证
+func (p *serialPort) Capture(forward func(line string) bool) (<-chan string, func()) {
+	c := &lineCapture{lines: make(chan string, captureBuffer), forward: forward}
+
+	p.tapMu.Lock()
+	p.capture = c
+	p.tapMu.Unlock()
+
+	return c.lines, func() {
+		p.tapMu.Lock()
+		if p.capture == c {
+			p.capture = nil
+		}
+		p.tapMu.Unlock()
+	}
+}
+
+// feed 按行拆分读取到的数据，分发给监听者和截获者，需要交给 at 库的行放入待交付缓冲区
+func (p *serialPort) feed(data []byte) {
+	p.tapMu.Lock()
+	defer p.tapMu.Unlock()
+
+	p.partial = append(p.partial, data...)
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			break
+		}
+		raw := p.partial[:i+1]
+		p.partial = p.partial[i+1:]
+		line := strings.TrimSpace(string(raw))
+		if line == "OK" || line == "ERROR" {
+			p.numeric.Store(false)
+		}
+		if p.dispatch(line) {
+			p.out = append(p.out, raw...)
+		}
+	}
+	if p.numeric.Load() {
+		p.flushNumeric()
+	}
+
+	// 提示符之后没有换行，不等待行结束直接分发
+	if !p.prompted && strings.TrimSpace(string(p.partial)) == ">" {
+		p.prompted = true
+		p.deliver(">")
+	}
+
+	// 避免异常数据导致缓存无限增长
+	if len(p.partial) > maxPartialLine {
+		p.partial = nil
+	}
+}
+
+// flushNumeric 未结束的行为数字结果码时转换为文本结果码分发，返回是否已转换，调用方需持有 tapMu
+// 数字格式下结果码为 <code><CR>，没有 LF，而信息响应仍以 CRLF 结尾，因此只转换以 CR 结尾的未结束行，
+// 内容为 0 的数据行不受影响
+func (p *serialPort) flushNumeric() bool {
+	line := string(p.partial)
+	if !strings.HasSuffix(line, "\r") {
+		return false
+	}
+	result, ok := numericResults[strings.TrimSpace(line)]
+	if !ok {
+		return false
+	}
+	p.partial = nil
+	p.numeric.Store(true)
+	if p.dispatch(result) {
+		p.out = append(p.out, result+"\r\n"...)
+	}
+	return true
+}
+
+// SetNumeric 设置模块是否使用数字结果码，切换结果码格式前调用，新格式的第一个结果码即可识别
+func (p *serialPort) SetNumeric(numeric bool) {
+	p.numeric.Store(numeric)
+}
+
+// dispatch 分发一个完整的行，返回是否交给 at 库，调用方需持有 tapMu
+func (p *serialPort) dispatch(line string) bool {
+	prompted := p.prompted
+	p.prompted = false
+	if line == "" {
+		return true
+	}
+	if prompted && line == ">" {
+		// 提示符已分发过
+		return p.capture == nil
+	}
+	if p.pduNext {
+		// 通知后的 PDU 行不属于任何命令的响应，只交给监听者
+		p.pduNext = false
+		p.broadcast(line)
+		return false
+	}
+	p.pduNext = pushedPDU(line)
+	p.deliver(line)
+	if c := p.capture; c != nil {
+		return c.forward != nil && c.forward(line)
+	}
+	return true
+}
+
+// pushedPDU 是否为下一行携带 PDU 的通知（+CMT: [<alpha>],<length> 或 +CDS: <length>）
+func pushedPDU(line string) bool {
+	return strings.HasPrefix(line, "+CMT:") || strings.HasPrefix(line, "+CDS:")
+}
+
+// broadcast 将行发送给监听者，处理不及时的行被丢弃，调用方需持有 tapMu
+func (p *serialPort) broadcast(line string) {
+	for ch := range p.taps {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// deliver 将行发送给监听者和截获者，调用方需持有 tapMu
+// 截获通道满时丢弃，避免阻塞读取循环
+func (p *serialPort) deliver(line string) {
+	p.broadcast(line)
+	if c := p.capture; c != nil {
+		select {
+		case c.lines <- line:
+		default:
+			slog.Warn("captured line dropped", slog.String("line", line))
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rehiy/modem/at"
+	"github.com/tarm/serial"
+)
+
+// fakeSerial 模拟串口：Read 返回 push 写入的数据，没有数据时按读取超时返回 io.EOF
+type fakeSerial struct {
+	mu      sync.Mutex
+	rx      []byte        // 待读取的数据
+	ready   chan struct{} // 有新数据或已关闭
+	written []byte        // 已写入的全部数据
+	readErr error         // 不为空时 Read 立即返回该错误
+	closed  atomic.Bool
+
+	timeout time.Duration                 // 没有数据时 Read 的等待时间
+	onWrite func(f *fakeSerial, b []byte) // 每次 Write 后调用
+}
+
+func newFakeSerial() *fakeSerial {
+	return &fakeSerial{ready: make(chan struct{}, 1), timeout: 20 * time.Millisecond}
+}
+
+func (f *fakeSerial) Read(b []byte) (int, error) {
+	f.mu.Lock()
+	if f.readErr != nil {
+		err := f.readErr
+		f.mu.Unlock()
+		return 0, err
+	}
+	deadline := time.After(f.timeout)
+	for len(f.rx) == 0 {
+		f.mu.Unlock()
+		select {
+		case <-f.ready:
+		case <-deadline:
+			return 0, io.EOF
+		}
+		f.mu.Lock()
+	}
+	n := copy(b, f.rx)
+	f.rx = f.rx[n:]
+	f.mu.Unlock()
+	return n, nil
+}
+
+func (f *fakeSerial) Write(b []byte) (int, error) {
+	if f.closed.Load() {
+		return 0, io.ErrClosedPipe
+	}
+	f.mu.Lock()
+	f.written = append(f.written, b...)
+	onWrite := f.onWrite
+	f.mu.Unlock()
+	if onWrite != nil {
+		onWrite(f, b)
+	}
+	return len(b), nil
+}
+
+func (f *fakeSerial) Flush() error { return nil }
+
+func (f *fakeSerial) Close() error {
+	f.closed.Store(true)
+	return nil
+}
+
+// push 模拟模块输出
+func (f *fakeSerial) push(s string) {
+	f.mu.Lock()
+	f.rx = append(f.rx, s...)
+	f.mu.Unlock()
+	select {
+	case f.ready <- struct{}{}:
+	default:
+	}
+}
+
+// setReadErr 设置 Read 返回的错误
+func (f *fakeSerial) setReadErr(err error) {
+	f.mu.Lock()
+	f.readErr = err
+	f.mu.Unlock()
+}
+
+// writes 返回已写入的全部数据
+func (f *fakeSerial) writes() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return string(f.written)
+}
+
+func TestSerialPortReconnectsWhenDeviceGone(t *testing.T) {
+	t.Setenv("MAX_RECONNECT_ATTEMPTS", "2")
+
+	first, second := newFakeSerial(), newFakeSerial()
+	var opens atomic.Int32
+	p := newSerialPort(&serial.Config{Name: "/dev/ttyFAKE0", ReadTimeout: 20 * time.Millisecond}, first, func(*serial.Config) (at.Port, error) {
+		opens.Add(1)
+		return second, nil
+	})
+	states := make(chan string, 2)
+	p.onState = func(state string) { states <- state }
+
+	first.setReadErr(syscall.ENXIO)
+	go func() {
+		buf := make([]byte, 16)
+		for !p.closed.Load() {
+			p.Read(buf)
+		}
+	}()
+
+	// 重连等待期间写入立即失败，不阻塞
+	time.Sleep(100 * time.Millisecond)
+	if !p.Reconnecting() {
+		t.Fatal("expected port to be reconnecting")
+	}
+	if _, err := p.Write([]byte("AT\r\n")); err != errPortUnavailable {
+		t.Fatalf("write during reconnect: got %v, want errPortUnavailable", err)
+	}
+
+	select {
+	case state := <-states:
+		if state != PortReconnected {
+			t.Fatalf("state = %q, want %q", state, PortReconnected)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("port was not reopened")
+	}
+	if !first.closed.Load() {
+		t.Error("old port was not closed")
+	}
+	if _, err := p.Write([]byte("AT\r\n")); err != nil || second.writes() != "AT\r\n" {
+		t.Errorf("write after reconnect: err=%v written=%q", err, second.writes())
+	}
+	p.Close()
+	if !second.closed.Load() {
+		t.Error("reopened port was not closed")
+	}
+	if opens.Load() != 1 {
+		t.Errorf("opened %d times, want 1", opens.Load())
+	}
+}
+
+func TestSerialPortImmediateEOFCountsAsError(t *testing.T) {
+	t.Setenv("MAX_RECONNECT_ATTEMPTS", "1")
+
+	first := newFakeSerial()
+	first.setReadErr(io.EOF) // 立即返回 EOF，与拔出 USB 设备后的表现一致
+	p := newSerialPort(&serial.Config{Name: "/dev/ttyFAKE0", ReadTimeout: time.Second}, first, func(*serial.Config) (at.Port, error) {
+		return nil, syscall.ENOENT
+	})
+	states := make(chan string, 1)
+	p.onState = func(state string) { states <- state }
+
+	buf := make([]byte, 16)
+	go func() {
+		for !p.closed.Load() {
+			if _, err := p.Read(buf); err == errPortUnavailable {
+				return
+			}
+		}
+	}()
+	select {
+	case state := <-states:
+		if state != PortDisconnected {
+			t.Fatalf("state = %q, want %q", state, PortDisconnected)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("immediate EOF did not trigger reconnect")
+	}
+	p.Close()
+}
+
+func TestSerialPortTimeoutEOFIsIdle(t *testing.T) {
+	f := newFakeSerial()
+	p := newSerialPort(&serial.Config{Name: "/dev/ttyFAKE0", ReadTimeout: 20 * time.Millisecond}, f, func(*serial.Config) (at.Port, error) {
+		t.Error("idle port must not be reopened")
+		return nil, syscall.ENOENT
+	})
+
+	// 读取超时不返回，收到完整的行后返回
+	result := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 16)
+		n, err := p.Read(buf)
+		if err != nil {
+			result <- err.Error()
+			return
+		}
+		result <- string(buf[:n])
+	}()
+	time.Sleep(time.Duration(maxReadErrors*3) * 20 * time.Millisecond)
+	if p.Reconnecting() {
+		t.Fatal("idle port is reconnecting")
+	}
+	f.push("OK\r\n")
+	select {
+	case got := <-result:
+		if got != "OK\r\n" {
+			t.Fatalf("read %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("read did not return")
+	}
+}
+
+func TestSerialPortCloseStopsReconnect(t *testing.T) {
+	t.Setenv("MAX_RECONNECT_ATTEMPTS", "5")
+
+	first := newFakeSerial()
+	first.setReadErr(syscall.EIO)
+	p := newSerialPort(&serial.Config{Name: "/dev/ttyFAKE0", ReadTimeout: 20 * time.Millisecond}, first, func(*serial.Config) (at.Port, error) {
+		return newFakeSerial(), nil
+	})
+	p.onState = func(state string) { t.Errorf("unexpected state %q after close", state) }
+
+	done := make(chan struct{})
+	go func() {
+		p.Read(make([]byte, 16))
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	p.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("reconnect did not stop after close")
+	}
+	if p.current() != nil {
+		t.Fatal("port reopened after close")
+	}
+}
+
+// chunkWriter 记录每次写入的长度，每次最多接受 limit 字节，写入 failAt 字节后返回错误
+type chunkWriter struct {
+	limit  int
+	failAt int
+	writes []int
+	data   []byte
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	n := min(len(p), w.limit)
+	if w.failAt > 0 && len(w.data)+n > w.failAt {
+		n = w.failAt - len(w.data)
+		w.writes = append(w.writes, n)
+		w.data = append(w.data, p[:n]...)
+		return n, syscall.EIO
+	}
+	w.writes = append(w.writes, n)
+	w.data = append(w.data, p[:n]...)
+	return n, nil
+}
+
+func TestWriteChunkedSplitsLongData(t *testing.T) {
+	data := make([]byte, writeChunkSize*2+10)
+	for i := range data {
+		data[i] = byte('0' + i%10)
+	}
+	w := &chunkWriter{limit: len(data)}
+	n, err := writeChunked(w, data)
+	if err != nil || n != len(data) || string(w.data) != string(data) {
+		t.Fatalf("n = %d, err = %v", n, err)
+	}
+	if want := []int{writeChunkSize, writeChunkSize, 10}; !slices.Equal(w.writes, want) {
+		t.Fatalf("writes = %v, want %v", w.writes, want)
+	}
+}
+
+func TestWriteChunkedRetriesPartialWrites(t *testing.T) {
+	data := []byte("AT+CMGS=152\r")
+	w := &chunkWriter{limit: 5}
+	n, err := writeChunked(w, data)
+	if err != nil || n != len(data) || string(w.data) != string(data) {
+		t.Fatalf("n = %d, err = %v, data = %q", n, err, w.data)
+	}
+	if want := []int{5, 5, 2}; !slices.Equal(w.writes, want) {
+		t.Fatalf("writes = %v, want %v", w.writes, want)
+	}
+}
+
+func TestWriteChunkedStopsOnError(t *testing.T) {
+	w := &chunkWriter{limit: writeChunkSize, failAt: 300}
+	n, err := writeChunked(w, make([]byte, 600))
+	if err == nil || n != 300 {
+		t.Fatalf("n = %d, err = %v, want 300 and an error", n, err)
+	}
+
+	if n, err := writeChunked(&chunkWriter{limit: 0}, []byte("AT")); err != io.ErrShortWrite || n != 0 {
+		t.Fatalf("zero write: n = %d, err = %v", n, err)
+	}
+}
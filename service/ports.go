@@ -0,0 +1,43 @@
+package service
+
+import (
+	"path"
+	"path/filepath"
+)
+
+// portDiscoverer 查找候选串口设备，各平台分别实现
+type portDiscoverer interface {
+	// Discover 返回候选设备，devs 为用户指定的设备或通配符，为空时使用平台默认值
+	Discover(devs []string) []string
+	// FriendlyName 返回设备的描述名称，未知时为空
+	FriendlyName(dev string) string
+}
+
+// discoverer 当前平台的串口查找实现
+var discoverer = newPortDiscoverer()
+
+// globDiscoverer 通过通配符匹配 /dev 下的串口设备
+type globDiscoverer struct{}
+
+// Discover 展开通配符，默认匹配 USB 串口和 ACM 设备
+func (globDiscoverer) Discover(devs []string) []string {
+	if len(devs) == 0 {
+		devs = []string{"/dev/ttyUSB*", "/dev/ttyACM*"}
+	}
+	pps := []string{}
+	for _, p := range devs {
+		matches, _ := filepath.Glob(p)
+		pps = append(pps, matches...)
+	}
+	return pps
+}
+
+// FriendlyName 读取所属 USB 设备的产品名称
+func (globDiscoverer) FriendlyName(dev string) string {
+	usbPath, _, _ := usbParent(path.Base(dev))
+	if usbPath == "" {
+		return ""
+	}
+	product, _ := readSysfs(usbPath, "product")
+	return product
+}
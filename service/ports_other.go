@@ -0,0 +1,8 @@
+//go:build !windows
+
+package service
+
+// newPortDiscoverer 非 Windows 平台使用通配符查找串口
+func newPortDiscoverer() portDiscoverer {
+	return globDiscoverer{}
+}
@@ -0,0 +1,140 @@
+//go:build windows
+
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// maxCOMPort 注册表不可用时逐个探测的最大 COM 口编号
+const maxCOMPort = 256
+
+// comDiscoverer 通过注册表和 CreateFile 探测查找 COM 口
+type comDiscoverer struct{}
+
+// newPortDiscoverer Windows 平台查找 COM 口
+func newPortDiscoverer() portDiscoverer {
+	return comDiscoverer{}
+}
+
+// Discover 优先读取 HARDWARE\DEVICEMAP\SERIALCOMM，失败时探测 COM1 - COM256
+func (comDiscoverer) Discover(devs []string) []string {
+	if len(devs) > 0 {
+		return devs
+	}
+	if ports, err := registryPorts(); err == nil {
+		return ports
+	}
+	ports := []string{}
+	for i := 1; i <= maxCOMPort; i++ {
+		if name := fmt.Sprintf("COM%d", i); comExists(name) {
+			ports = append(ports, name)
+		}
+	}
+	return ports
+}
+
+// FriendlyName 在 USB 设备枚举信息中查找端口对应的设备描述
+func (comDiscoverer) FriendlyName(dev string) string {
+	return usbFriendlyNames()[strings.ToUpper(dev)]
+}
+
+// registryPorts 读取系统当前存在的 COM 口列表
+func registryPorts() ([]string, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `HARDWARE\DEVICEMAP\SERIALCOMM`, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Close()
+
+	names, err := key.ReadValueNames(0)
+	if err != nil {
+		return nil, err
+	}
+	ports := []string{}
+	for _, name := range names {
+		if port, _, err := key.GetStringValue(name); err == nil && port != "" {
+			ports = append(ports, port)
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		return comNumber(ports[i]) < comNumber(ports[j])
+	})
+	return ports, nil
+}
+
+// comNumber 返回 COM 口编号，用于排序
+func comNumber(port string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(port), "COM"))
+	return n
+}
+
+// comExists 以零访问权限打开设备判断端口是否存在，不会占用串口
+// 端口已被其他程序占用时返回拒绝访问，同样视为存在
+func comExists(name string) bool {
+	path, err := windows.UTF16PtrFromString(`\\.\` + name)
+	if err != nil {
+		return false
+	}
+	h, err := windows.CreateFile(path, 0, 0, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return err == windows.ERROR_ACCESS_DENIED || err == windows.ERROR_SHARING_VIOLATION
+	}
+	windows.CloseHandle(h)
+	return true
+}
+
+// usbFriendlyNames 遍历 SYSTEM\CurrentControlSet\Enum\USB，返回 COM 口到设备描述的映射
+func usbFriendlyNames() map[string]string {
+	names := map[string]string{}
+	root, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Enum\USB`, registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		return names
+	}
+	defer root.Close()
+
+	devices, _ := root.ReadSubKeyNames(0)
+	for _, device := range devices {
+		dk, err := registry.OpenKey(root, device, registry.ENUMERATE_SUB_KEYS)
+		if err != nil {
+			continue
+		}
+		instances, _ := dk.ReadSubKeyNames(0)
+		dk.Close()
+
+		for _, instance := range instances {
+			base := device + `\` + instance
+			params, err := registry.OpenKey(root, base+`\Device Parameters`, registry.QUERY_VALUE)
+			if err != nil {
+				continue
+			}
+			port, _, err := params.GetStringValue("PortName")
+			params.Close()
+			if err != nil || port == "" {
+				continue
+			}
+
+			ik, err := registry.OpenKey(root, base, registry.QUERY_VALUE)
+			if err != nil {
+				continue
+			}
+			name, _, err := ik.GetStringValue("FriendlyName")
+			if err != nil {
+				name, _, _ = ik.GetStringValue("DeviceDesc")
+			}
+			ik.Close()
+			// DeviceDesc 形如 @oem.inf,%desc%;Description
+			if i := strings.LastIndex(name, ";"); i >= 0 {
+				name = name[i+1:]
+			}
+			names[strings.ToUpper(port)] = name
+		}
+	}
+	return names
+}
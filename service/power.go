@@ -0,0 +1,180 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// tauUnits T3412 扩展定时器单位（秒），-1 表示停用
+var tauUnits = map[string]int{
+	"000": 600, "001": 3600, "010": 36000, "011": 2,
+	"100": 30, "101": 60, "110": 1152000, "111": -1,
+}
+
+// activeTimeUnits T3324 定时器单位（秒），-1 表示停用
+var activeTimeUnits = map[string]int{
+	"000": 2, "001": 60, "010": 360, "111": -1,
+}
+
+// edrxCycles E-UTRAN eDRX 周期（秒），按 4 位取值索引
+var edrxCycles = []float64{
+	5.12, 10.24, 20.48, 40.96, 61.44, 81.92, 102.4, 122.88,
+	143.36, 163.84, 327.68, 655.36, 1310.72, 2621.44, 5242.88, 10485.76,
+}
+
+// GetPowerSaving 查询 PSM / eDRX 配置及网络分配的定时器
+func (m *ModemInfo) GetPowerSaving() (*models.PowerSaving, error) {
+	ps := &models.PowerSaving{EDRX: []models.EDRX{}}
+
+	// 请求的 PSM 参数 +CPSMS: <mode>,[<rau>],[<gprs_ready>],[<tau>],[<active_time>]
+	responses, err := m.SendCommand("AT+CPSMS?")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "+CPSMS" || len(param) < 1 {
+			continue
+		}
+		ps.PSMEnabled = param[0] == "1"
+		if len(param) >= 5 {
+			ps.RequestedTAU, ps.RequestedActiveTime = param[3], param[4]
+		}
+	}
+
+	// 网络分配的 PSM 定时器，需要 +CEREG 结果码格式 4
+	ps.NetworkActiveTime, ps.NetworkTAU = m.networkPSMTimers()
+
+	// 网络分配的 eDRX 参数
+	// +CEDRXRDP: <act>,<requested>,<network>,<paging_window>
+	if responses, err := m.SendCommand("AT+CEDRXRDP"); err == nil {
+		for _, line := range responses {
+			label, param := splitParam(line)
+			if label != "+CEDRXRDP" || len(param) < 2 {
+				continue
+			}
+			edrx := models.EDRX{AcT: parseRetry(param[0]), Requested: param[1]}
+			if len(param) >= 4 {
+				edrx.Network, edrx.PagingWindow = param[2], param[3]
+				edrx.Cycle = edrxCycle(param[2])
+			}
+			if edrx.AcT > 0 {
+				ps.EDRX = append(ps.EDRX, edrx)
+			}
+		}
+	}
+
+	return ps, nil
+}
+
+// networkPSMTimers 通过 +CEREG 格式 4 获取网络分配的 T3324 和 T3412 定时器
+// +CEREG: 4,<stat>,[<tac>],[<ci>],[<act>],[<cause_type>],[<reject_cause>],[<active_time>],[<tau>]
+func (m *ModemInfo) networkPSMTimers() (*models.Timer, *models.Timer) {
+	// 记录当前格式，查询后恢复
+	n := -1
+	if responses, err := m.SendCommand("AT+CEREG?"); err == nil {
+		for _, line := range responses {
+			if label, param := splitParam(line); label == "+CEREG" && len(param) > 0 {
+				n = parseRetry(param[0])
+			}
+		}
+	}
+	if n < 0 {
+		return nil, nil
+	}
+	if n != 4 {
+		if _, err := m.SendCommand("AT+CEREG=4"); err != nil {
+			return nil, nil
+		}
+		defer m.SendCommand(fmt.Sprintf("AT+CEREG=%d", n))
+	}
+
+	responses, err := m.SendCommand("AT+CEREG?")
+	if err != nil {
+		return nil, nil
+	}
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "+CEREG" || len(param) < 9 {
+			continue
+		}
+		return decodeTimer(param[7], activeTimeUnits), decodeTimer(param[8], tauUnits)
+	}
+	return nil, nil
+}
+
+// SetPowerSaving 设置 PSM，tau 和 activeTime 为 8 位定时器位串（3GPP TS 24.008）
+func (m *ModemInfo) SetPowerSaving(psm bool, tau, activeTime string) error {
+	if !psm {
+		return m.SendCommandExpect("AT+CPSMS=0", "OK")
+	}
+	if err := validateTimerBits(tau, tauUnits); err != nil {
+		return fmt.Errorf("%w: invalid tau: %v", ErrInvalid, err)
+	}
+	if err := validateTimerBits(activeTime, activeTimeUnits); err != nil {
+		return fmt.Errorf("%w: invalid activeTime: %v", ErrInvalid, err)
+	}
+	return m.SendCommandExpect(fmt.Sprintf(`AT+CPSMS=1,,,"%s","%s"`, tau, activeTime), "OK")
+}
+
+// SetEDRX 设置 eDRX，act 为接入技术类型（4: E-UTRAN，5: NB-IoT），cycle 为 4 位 eDRX 值位串
+func (m *ModemInfo) SetEDRX(enable bool, act int, cycle string) error {
+	if act != 4 && act != 5 {
+		return fmt.Errorf("%w: invalid act %d, must be 4 (E-UTRAN) or 5 (NB-IoT)", ErrInvalid, act)
+	}
+	if !enable {
+		return m.SendCommandExpect(fmt.Sprintf("AT+CEDRXS=0,%d", act), "OK")
+	}
+	if !isBits(cycle, 4) {
+		return fmt.Errorf("%w: invalid cycle %q, must be 4 bits", ErrInvalid, cycle)
+	}
+	return m.SendCommandExpect(fmt.Sprintf(`AT+CEDRXS=1,%d,"%s"`, act, cycle), "OK")
+}
+
+// validateTimerBits 校验 8 位定时器位串及其单位
+func validateTimerBits(bits string, units map[string]int) error {
+	if !isBits(bits, 8) {
+		return fmt.Errorf("%q must be 8 bits", bits)
+	}
+	if _, ok := units[bits[:3]]; !ok {
+		return fmt.Errorf("%q has invalid unit %s", bits, bits[:3])
+	}
+	return nil
+}
+
+// decodeTimer 解析 8 位定时器位串，无效时返回 nil
+func decodeTimer(bits string, units map[string]int) *models.Timer {
+	if validateTimerBits(bits, units) != nil {
+		return nil
+	}
+	unit := units[bits[:3]]
+	if unit < 0 {
+		return &models.Timer{Bits: bits, Seconds: -1}
+	}
+	value, _ := strconv.ParseInt(bits[3:], 2, 64)
+	return &models.Timer{Bits: bits, Seconds: unit * int(value)}
+}
+
+// edrxCycle 转换 4 位 eDRX 值位串为周期秒数，无效时返回 0
+func edrxCycle(bits string) float64 {
+	if !isBits(bits, 4) {
+		return 0
+	}
+	i, _ := strconv.ParseInt(bits, 2, 64)
+	return edrxCycles[i]
+}
+
+// isBits 检查是否为指定长度的二进制位串
+func isBits(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if c != '0' && c != '1' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetPowerSavingErrors(t *testing.T) {
+	script := &scriptedModem{}
+	m, _ := newTestModem(t, script)
+
+	// 定时器位串和接入技术无效属于请求错误，不发送命令
+	for name, err := range map[string]error{
+		"tau":        m.SetPowerSaving(true, "0010001", "00100001"),
+		"activeTime": m.SetPowerSaving(true, "00100001", "01100001"),
+		"act":        m.SetEDRX(true, 7, "0101"),
+		"cycle":      m.SetEDRX(true, 4, "01012"),
+	} {
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: err = %v, want ErrInvalid", name, err)
+		}
+	}
+	if cmds := script.received(); len(cmds) != 0 {
+		t.Fatalf("commands sent for invalid input: %q", cmds)
+	}
+
+	// 模块拒绝设置不是请求错误
+	script.reply(`AT+CPSMS=1,,,"00100001","00100001"`, "ERROR")
+	if err := m.SetPowerSaving(true, "00100001", "00100001"); err == nil || errors.Is(err, ErrInvalid) {
+		t.Fatalf("modem error: err = %v", err)
+	}
+	if err := m.SetEDRX(true, 4, "0101"); err != nil {
+		t.Fatal(err)
+	}
+	if cmds := script.received(); cmds[len(cmds)-1] != `AT+CEDRXS=1,4,"0101"` {
+		t.Fatalf("commands = %q", cmds)
+	}
+}
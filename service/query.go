@@ -0,0 +1,156 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+const (
+	maxEmptyRetry   = 3                      // 空响应最大重试次数
+	emptyRetryDelay = 200 * time.Millisecond // 空响应重试间隔
+)
+
+// queryInfo 查询单行信息，返回第一个数据行
+// 部分模块偶尔只返回回显或 OK 而没有数据行，按模块配置的 empty_retry 重试；
+// 错误响应不重试
+func (m *ModemInfo) queryInfo(cmd string) (string, error) {
+	retries := 0
+	if config, err := m.GetConfig(); err == nil {
+		retries = config.EmptyRetry
+	}
+
+	for i := 0; ; i++ {
+		responses, err := m.SendCommand(cmd)
+		if err != nil {
+			return "", err
+		}
+		if err := finalError(responses); err != nil {
+			return "", err
+		}
+		if line := dataLine(responses); line != "" {
+			return line, nil
+		}
+		if i >= retries {
+			return "", fmt.Errorf("no info found for %s", cmd)
+		}
+		time.Sleep(emptyRetryDelay)
+	}
+}
+
+// dataLine 返回第一个非回显、非结果码的行
+func dataLine(responses []string) string {
+	for _, line := range responses {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "AT") || responseSet.IsFinal(line) {
+			continue
+		}
+		return line
+	}
+	return ""
+}
+
+// GetManufacturer 查询制造商信息
+func (m *ModemInfo) GetManufacturer() (string, error) {
+	return m.queryInfo("AT+CGMI")
+}
+
+// GetModel 查询型号信息
+func (m *ModemInfo) GetModel() (string, error) {
+	return m.queryInfo("AT+CGMM")
+}
+
+// GetRevision 查询版本信息
+func (m *ModemInfo) GetRevision() (string, error) {
+	return m.queryInfo("AT+CGMR")
+}
+
+// GetSerialNumber 查询序列号（IMEI）
+func (m *ModemInfo) GetSerialNumber() (string, error) {
+	return m.queryInfo("AT+CGSN")
+}
+
+// GetIMSI 查询 IMSI
+func (m *ModemInfo) GetIMSI() (string, error) {
+	return m.queryInfo("AT+CIMI")
+}
+
+// GetPhoneNumber 查询本机号码（AT+CNUM），返回号码及号码类型
+func (m *ModemInfo) GetPhoneNumber() (string, int, error) {
+	responses, err := m.SendCommand("AT+CNUM")
+	if err != nil {
+		return "", 0, err
+	}
+	if err := finalError(responses); err != nil {
+		return "", 0, err
+	}
+	for _, line := range responses {
+		// +CNUM: [<alpha>],<number>,<type>
+		label, param := splitParam(line)
+		if label == "+CNUM" && len(param) >= 2 && param[1] != "" {
+			typ := 0
+			if len(param) >= 3 {
+				typ, _ = strconv.Atoi(param[2])
+			}
+			return param[1], typ, nil
+		}
+	}
+	return "", 0, fmt.Errorf("no phone number found")
+}
+
+// GetNetworkStatus 查询 CS 域注册状态（AT+CREG?），返回通知模式和注册状态
+func (m *ModemInfo) GetNetworkStatus() (int, int, error) {
+	responses, err := m.SendCommand("AT+CREG?")
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := finalError(responses); err != nil {
+		return 0, 0, err
+	}
+	for _, line := range responses {
+		// +CREG: <n>,<stat>[,<lac>,<ci>[,<AcT>]]
+		label, param := splitParam(line)
+		if label != "+CREG" || len(param) < 2 {
+			continue
+		}
+		n, err1 := strconv.Atoi(param[0])
+		stat, err2 := strconv.Atoi(param[1])
+		if err1 == nil && err2 == nil {
+			return n, stat, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("failed to parse network status")
+}
+
+// GetICCID 查询 ICCID
+func (m *ModemInfo) GetICCID() (string, error) {
+	return m.queryInfo("AT+CCID")
+}
+
+// clacTimeout AT+CLAC 输出可达数百行，等待时间长于普通命令
+const clacTimeout = 10 * time.Second
+
+// GetCapabilities 通过 AT+CLAC 查询支持的命令列表
+// 模块不支持 AT+CLAC 时返回空列表而不是错误
+func (m *ModemInfo) GetCapabilities() (*models.ModemCapabilities, error) {
+	caps := &models.ModemCapabilities{SupportedCommands: []string{}}
+	responses, err := m.SendCommandWithTimeout("AT+CLAC", clacTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if finalError(responses) != nil {
+		return caps, nil
+	}
+
+	for _, line := range responses {
+		line = strings.TrimSpace(line)
+		if line == "" || responseSet.IsFinal(line) || strings.EqualFold(line, "AT+CLAC") {
+			continue
+		}
+		caps.SupportedCommands = append(caps.SupportedCommands, line)
+	}
+	return caps, nil
+}
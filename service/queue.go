@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// cmdQueueSize 每个端口排队等待的命令数上限，超出后调用方阻塞
+const cmdQueueSize = 64
+
+// errModemClosed 端口已关闭，排队的命令不再执行
+var errModemClosed = errors.New("device closed")
+
+// 命令请求状态
+const (
+	cmdQueued    int32 = iota // 排队中
+	cmdRunning                // 已开始执行
+	cmdAbandoned              // 调用方已放弃等待，不再执行
+)
+
+// cmdRequest 排队执行的命令，run 在调度协程中独占端口执行
+type cmdRequest struct {
+	ctx   context.Context
+	run   func()
+	done  chan struct{}
+	state atomic.Int32
+	err   error // 未执行的原因，close(done) 前写入
+}
+
+// cmdQueue 端口命令队列，由单个调度协程按顺序执行，紧急命令优先
+type cmdQueue struct {
+	normal chan *cmdRequest
+	urgent chan *cmdRequest
+}
+
+// startQueue 创建命令队列并启动调度协程，端口关闭后退出
+func (m *ModemInfo) startQueue() {
+	m.queue = &cmdQueue{
+		normal: make(chan *cmdRequest, cmdQueueSize),
+		urgent: make(chan *cmdRequest, cmdQueueSize),
+	}
+	m.cmdLimiter = newCommandLimiter()
+	go m.dispatch()
+}
+
+// closed 返回端口关闭时关闭的通道
+func (m *ModemInfo) closed() <-chan struct{} {
+	if m.port == nil {
+		return nil
+	}
+	return m.port.Done()
+}
+
+// dispatch 依次执行队列中的命令，每次先检查紧急队列
+// 端口关闭后退出，并让仍在排队的命令返回 errModemClosed
+func (m *ModemInfo) dispatch() {
+	q := m.queue
+	closed := m.closed()
+	idle := time.NewTicker(time.Second)
+	defer idle.Stop()
+	defer m.failPending()
+
+	for {
+		var req *cmdRequest
+		select {
+		case req = <-q.urgent:
+		default:
+			select {
+			case req = <-q.urgent:
+			case req = <-q.normal:
+			case <-closed:
+				return
+			case <-idle.C:
+				if !m.IsOpen() {
+					return
+				}
+				continue
+			}
+		}
+		if !req.state.CompareAndSwap(cmdQueued, cmdRunning) {
+			close(req.done)
+			continue
+		}
+		select {
+		case <-closed:
+			req.err = errModemClosed
+			close(req.done)
+			return
+		default:
+		}
+		if err := req.ctx.Err(); err != nil {
+			req.err = err
+			close(req.done)
+			continue
+		}
+		m.throttle()
+		req.run()
+		close(req.done)
+	}
+}
+
+// failPending 让队列中剩余的命令返回 errModemClosed
+func (m *ModemInfo) failPending() {
+	q := m.queue
+	for {
+		var req *cmdRequest
+		select {
+		case req = <-q.urgent:
+		case req = <-q.normal:
+		default:
+			return
+		}
+		req.err = errModemClosed
+		close(req.done)
+	}
+}
+
+// exec 将操作放入命令队列并等待执行完成
+// 队列未启动时直接执行，由 at 库返回相应错误；端口已关闭或在排队期间关闭时返回 errModemClosed，不执行
+// 注意：run 中只能使用不经过队列的发送函数，否则会阻塞调度协程；返回错误时调用方不应再读取 run 写入的结果
+func (m *ModemInfo) exec(urgent bool, run func()) error {
+	return m.execContext(context.Background(), urgent, run)
+}
+
+// execContext 与 exec 相同，但排队期间 ctx 结束（如 HTTP 客户端断开）时不再执行并返回 ctx 的错误
+// 已开始执行的操作不会被中断，但 ctx 结束或端口关闭时不再等待其完成
+func (m *ModemInfo) execContext(ctx context.Context, urgent bool, run func()) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if m.queue == nil {
+		run()
+		return nil
+	}
+	closed := m.closed()
+	if !m.IsOpen() {
+		return errModemClosed
+	}
+
+	req := &cmdRequest{ctx: ctx, run: run, done: make(chan struct{})}
+	queue := m.queue.normal
+	if urgent {
+		queue = m.queue.urgent
+	}
+	select {
+	case queue <- req:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-closed:
+		return errModemClosed
+	}
+
+	select {
+	case <-req.done:
+		return req.err
+	case <-ctx.Done():
+		req.state.CompareAndSwap(cmdQueued, cmdAbandoned)
+		return ctx.Err()
+	case <-closed:
+		req.state.CompareAndSwap(cmdQueued, cmdAbandoned)
+		return errModemClosed
+	}
+}
+
+// drain 等待已排队的命令全部执行完成，ctx 结束时提前返回
+func (m *ModemInfo) drain(ctx context.Context) error {
+	if m.queue == nil || !m.IsOpen() {
+		return nil
+	}
+	err := m.execContext(ctx, false, func() {})
+	if errors.Is(err, errModemClosed) {
+		return nil
+	}
+	return err
+}
+
+// SendUrgentCommand 发送命令，插队到当前命令之后立即执行，用于挂断等需要及时响应的操作
+func (m *ModemInfo) SendUrgentCommand(cmd string) ([]string, error) {
+	var responses []string
+	var err error
+	if qerr := m.exec(true, func() {
+		responses, err = m.sendCommand(cmd)
+	}); qerr != nil {
+		return nil, qerr
+	}
+	return responses, err
+}
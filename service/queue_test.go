@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// noThrottle 测试期间取消命令速率限制
+func noThrottle(t testing.TB) {
+	old := commandRate
+	commandRate = func() int64 { return 0 }
+	t.Cleanup(func() { commandRate = old })
+}
+
+// blockQueue 在队列中放入一个阻塞的任务，返回释放函数
+func blockQueue(t testing.TB, m *ModemInfo) func() {
+	started, release := make(chan struct{}), make(chan struct{})
+	go m.exec(false, func() {
+		close(started)
+		<-release
+	})
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("blocking task did not start")
+	}
+	var once sync.Once
+	return func() { once.Do(func() { close(release) }) }
+}
+
+func TestExecReturnsWhenClosedWhileQueued(t *testing.T) {
+	m, _ := newTestModem(t, &scriptedModem{})
+	release := blockQueue(t, m)
+	defer release()
+
+	result := make(chan error, 1)
+	ran := false
+	go func() {
+		result <- m.exec(false, func() { ran = true })
+	}()
+	time.Sleep(20 * time.Millisecond)
+	m.Close()
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, errModemClosed) {
+			t.Fatalf("exec: %v, want errModemClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("exec did not return after close")
+	}
+	release()
+	time.Sleep(20 * time.Millisecond)
+	if ran {
+		t.Fatal("queued task ran after close")
+	}
+}
+
+func TestExecContextCancelledWhileQueued(t *testing.T) {
+	m, _ := newTestModem(t, &scriptedModem{})
+	release := blockQueue(t, m)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	var mu sync.Mutex
+	ran := false
+	go func() {
+		result <- m.execContext(ctx, false, func() {
+			mu.Lock()
+			ran = true
+			mu.Unlock()
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("execContext: %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("execContext did not return after cancel")
+	}
+
+	// 放弃的任务不再执行，后续命令正常执行
+	release()
+	if _, err := m.SendCommand("AT"); err != nil {
+		t.Fatalf("SendCommand after cancel: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if ran {
+		t.Fatal("abandoned task ran")
+	}
+}
+
+func TestDispatchFailsPendingOnClose(t *testing.T) {
+	m, _ := newTestModem(t, &scriptedModem{})
+	release := blockQueue(t, m)
+
+	// 直接放入队列，不经过 execContext 的关闭检查
+	reqs := make([]*cmdRequest, 3)
+	for i := range reqs {
+		reqs[i] = &cmdRequest{ctx: context.Background(), run: func() { t.Error("pending task ran") }, done: make(chan struct{})}
+		m.queue.normal <- reqs[i]
+	}
+	m.Close()
+	release()
+
+	for i, req := range reqs {
+		select {
+		case <-req.done:
+			if !errors.Is(req.err, errModemClosed) {
+				t.Fatalf("request %d: %v, want errModemClosed", i, req.err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("request %d still pending after close", i)
+		}
+	}
+}
+
+func TestExecAfterCloseFails(t *testing.T) {
+	m, _ := newTestModem(t, &scriptedModem{})
+	m.Close()
+	if _, err := m.SendCommand("AT"); !errors.Is(err, errModemClosed) {
+		t.Fatalf("SendCommand after close: %v", err)
+	}
+}
+
+func TestUrgentCommandRunsFirst(t *testing.T) {
+	noThrottle(t)
+	m, _ := newTestModem(t, &scriptedModem{})
+	release := blockQueue(t, m)
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	for _, task := range []struct {
+		name   string
+		urgent bool
+	}{{"normal", false}, {"urgent", true}} {
+		wg.Add(1)
+		go func(name string, urgent bool) {
+			defer wg.Done()
+			m.exec(urgent, func() {
+				mu.Lock()
+				order = append(order, name)
+				mu.Unlock()
+			})
+		}(task.name, task.urgent)
+		time.Sleep(10 * time.Millisecond)
+	}
+	release()
+	wg.Wait()
+	if strings.Join(order, ",") != "urgent,normal" {
+		t.Fatalf("order %v", order)
+	}
+}
+
+func TestConcurrentSendSMSGetsOwnReference(t *testing.T) {
+	noThrottle(t)
+	// 参考号取 PDU 长度，不同长度的短信各自只能取到自己的参考号
+	script := &scriptedModem{respond: func(cmd string) (string, bool) {
+		if strings.HasPrefix(cmd, "AT+CMGS=") {
+			return ">", true
+		}
+		if !strings.HasPrefix(cmd, "AT") {
+			return "+CMGS: " + strconv.Itoa(len(cmd)) + "\r\nOK", true
+		}
+		return "", false
+	}}
+	m, _ := newTestModem(t, script)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 8; i++ {
+		wg.Add(1)
+		go func(text string) {
+			defer wg.Done()
+			tpdus, err := buildTPDUs("10086", text, SendOptions{})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			b, _ := tpdus[0].MarshalBinary()
+			want := 2 + 2*len(b) // 空 SMSC 加十六进制 TPDU
+			refs, err := m.SendSMS(context.Background(), "10086", text, SendOptions{Verify: true})
+			if err != nil {
+				t.Errorf("send %q: %v", text, err)
+				return
+			}
+			if len(refs) != 1 || refs[0] != want {
+				t.Errorf("send %q: refs %v, want [%d]", text, refs, want)
+			}
+		}(strings.Repeat("a", i*3))
+	}
+	wg.Wait()
+}
+
+// BenchmarkSendCommandQueued 并发发送命令，经命令队列按顺序执行
+func BenchmarkSendCommandQueued(b *testing.B) {
+	noThrottle(b)
+	m, _ := newTestModem(b, &scriptedModem{})
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := m.SendCommand("AT"); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
+
+// BenchmarkSendCommandMutex 并发发送命令，用互斥锁串行化，作为队列的对照
+func BenchmarkSendCommandMutex(b *testing.B) {
+	noThrottle(b)
+	m, _ := newTestModem(b, &scriptedModem{})
+	var mu sync.Mutex
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			mu.Lock()
+			_, err := m.sendCommand("AT")
+			mu.Unlock()
+			if err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
+
+// BenchmarkUrgentCommandUnderLoad 普通命令持续排队时紧急命令的等待时间
+func BenchmarkUrgentCommandUnderLoad(b *testing.B) {
+	noThrottle(b)
+	script := &scriptedModem{}
+	script.delay("AT+CSQ", time.Millisecond)
+	m, _ := newTestModem(b, script)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				m.SendCommand("AT+CSQ")
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := m.SendUrgentCommand("AT"); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	cancel()
+	wg.Wait()
+}
+
+func TestThrottleLimitsRate(t *testing.T) {
+	m := &ModemInfo{cmdLimiter: rate.NewLimiter(20, 1)}
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		m.throttle()
+	}
+	if elapsed := time.Since(start); elapsed < 120*time.Millisecond {
+		t.Fatalf("4 commands at 20/s took %s", elapsed)
+	}
+}
+
+func TestThrottleReturnsWhenClosed(t *testing.T) {
+	m, _ := newTestModem(t, &scriptedModem{})
+	m.Close()
+	m.cmdLimiter = rate.NewLimiter(0.01, 1)
+	m.throttle()
+
+	start := time.Now()
+	m.throttle()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("throttle waited %s on a closed port", elapsed)
+	}
+}
+
+func TestDrainWaitsForQueuedCommands(t *testing.T) {
+	m, _ := newTestModem(t, &scriptedModem{})
+	release := blockQueue(t, m)
+	defer release()
+
+	ran := make(chan struct{})
+	go m.exec(false, func() { close(ran) })
+	time.Sleep(20 * time.Millisecond)
+
+	drained := make(chan error, 1)
+	go func() { drained <- m.drain(context.Background()) }()
+	select {
+	case err := <-drained:
+		t.Fatalf("drain returned before queued commands ran: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("drain did not return after queue emptied")
+	}
+	select {
+	case <-ran:
+	default:
+		t.Fatal("queued command did not run before drain returned")
+	}
+}
+
+func TestDrainStopsAtDeadline(t *testing.T) {
+	m, _ := newTestModem(t, &scriptedModem{})
+	release := blockQueue(t, m)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := m.drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("drain: %v, want DeadlineExceeded", err)
+	}
+
+	// 已关闭的模块没有待执行的命令
+	release()
+	m.Close()
+	if err := m.drain(context.Background()); err != nil {
+		t.Fatalf("drain after close: %v", err)
+	}
+}
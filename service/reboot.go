@@ -0,0 +1,113 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"time"
+)
+
+// 重启方式
+const (
+	ResetSoft    = "soft"    // AT+CFUN=1,1 重启模块
+	ResetMin     = "min"     // AT+CFUN=0 最小功能模式，关闭射频
+	ResetFactory = "factory" // AT&F 恢复出厂设置后重启
+)
+
+const (
+	rebootSettle  = 5 * time.Second  // 重启命令发出后等待端口消失的时间
+	rebootTimeout = 30 * time.Second // 等待模块重新出现的最长时间
+)
+
+// MinFunctional 进入最小功能模式（AT+CFUN=0），射频关闭，串口保持可用
+func (m *ModemInfo) MinFunctional() error {
+	return m.sendCheck("AT+CFUN=0", cfunTimeout)
+}
+
+// softReset 发送重启命令，factory 为 true 时先恢复出厂设置并保存
+func (m *ModemInfo) softReset(factory bool) error {
+	if factory {
+		if err := m.sendCheck("AT&F", atTimeout); err != nil {
+			return fmt.Errorf("factory reset: %w", err)
+		}
+		m.SendCommand("AT&W") // 保存为用户配置，不支持时忽略
+	}
+	return m.sendCheck("AT+CFUN=1,1", cfunTimeout)
+}
+
+// ResetModem 按指定方式重启模块
+// 重启后 USB 端口会短暂消失，因此关闭连接并在后台重新探测，直至同一模块（IMEI 相同）重新连接或超时
+func (m *ModemService) ResetModem(name, typ string) error {
+	conn, err := m.GetConnect(name)
+	if err != nil {
+		return err
+	}
+
+	switch typ {
+	case ResetMin:
+		return conn.MinFunctional()
+	case ResetSoft, ResetFactory:
+	default:
+		return fmt.Errorf("%w: reset type must be one of soft, min, factory", ErrInvalid)
+	}
+
+	if err := conn.softReset(typ == ResetFactory); err != nil {
+		return err
+	}
+	modemInfoCache.Invalidate(conn.Name)
+
+	m.mu.Lock()
+	if m.pool[conn.Name] == conn {
+		delete(m.pool, conn.Name)
+	}
+	m.mu.Unlock()
+	conn.Close()
+	ModemEvent.Publish(EventModemReset, conn.Name, ResetData{Trigger: typ})
+
+	go m.reconnectAfterReset(conn.Name, conn.devicePath(), conn.USBPath, conn.imei)
+	return nil
+}
+
+// devicePath 返回连接使用的串口设备路径
+func (m *ModemInfo) devicePath() string {
+	for _, iface := range m.Interfaces {
+		if iface.Name == m.Name {
+			return iface.Device
+		}
+	}
+	return m.Name
+}
+
+// resetCandidates 返回重启后可能重新出现的设备：原设备，以及同一 USB 设备下重新枚举的串口
+func resetCandidates(device, usbPath string) []string {
+	devs := []string{device}
+	if usbPath == "" {
+		return devs
+	}
+	for _, dev := range discoverer.Discover(nil) {
+		if parent, _, _ := usbParent(path.Base(dev)); parent == usbPath && dev != device {
+			devs = append(devs, dev)
+		}
+	}
+	return devs
+}
+
+// reconnectAfterReset 等待模块重启后每秒探测一次重启的模块，直到找到同一模块
+// 只探测原设备和同一 USB 设备下的串口，不重新扫描其他模块
+func (m *ModemService) reconnectAfterReset(name, device, usbPath, imei string) {
+	time.Sleep(rebootSettle)
+	deadline := time.Now().Add(rebootTimeout)
+	for time.Now().Before(deadline) {
+		m.ScanModems(resetCandidates(device, usbPath)...)
+		for _, modem := range m.GetModems() {
+			if modem.Name == name || (imei != "" && modem.imei == imei) {
+				slog.Info("reconnected after reset", slog.String("port", name), slog.String("as", modem.Name))
+				ModemEvent.Publish(PortReconnected, modem.Name, nil)
+				return
+			}
+		}
+		time.Sleep(time.Second)
+	}
+	slog.Warn("modem did not come back after reset", slog.String("port", name), slog.Duration("waited", rebootSettle+rebootTimeout))
+	ModemEvent.Publish(PortDisconnected, name, nil)
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+func TestDevicePath(t *testing.T) {
+	m := &ModemInfo{Name: "ttyUSB2", Interfaces: []models.ModemInterface{
+		{Device: "/dev/ttyUSB1", Name: "ttyUSB1"},
+		{Device: "/dev/ttyUSB2", Name: "ttyUSB2"},
+	}}
+	if got := m.devicePath(); got != "/dev/ttyUSB2" {
+		t.Fatalf("devicePath = %q", got)
+	}
+	if got := (&ModemInfo{Name: "COM3"}).devicePath(); got != "COM3" {
+		t.Fatalf("devicePath without interfaces = %q", got)
+	}
+}
+
+func TestResetCandidatesOnlyResetPort(t *testing.T) {
+	// 没有 USB 路径时只探测原设备，不扫描其他串口
+	if got := resetCandidates("/dev/ttyUSB2", ""); !slices.Equal(got, []string{"/dev/ttyUSB2"}) {
+		t.Fatalf("candidates = %q", got)
+	}
+	// 其他 USB 设备下的串口不在候选中
+	for _, dev := range resetCandidates("/dev/ttyUSB2", "/sys/devices/nonexistent/1-9") {
+		if dev != "/dev/ttyUSB2" {
+			t.Fatalf("unrelated device %q probed", dev)
+		}
+	}
+}
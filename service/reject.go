@@ -0,0 +1,223 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// ErrRejectRuleNotFound 删除不存在的拒接规则
+var ErrRejectRuleNotFound = errors.New("reject rule not found")
+
+const (
+	defaultRejectListFile       = "reject_list.json"
+	rejectListFileCheckInterval = 10 * time.Second // 检查拒接列表文件变更的间隔
+)
+
+// compiledRejectRule 已编译号码表达式的拒接规则
+type compiledRejectRule struct {
+	models.RejectRule
+	number *regexp.Regexp
+}
+
+// RejectListStore 来电拒接列表，保存在 REJECT_LIST_FILE 指向的 JSON 文件中（默认 reject_list.json）
+// 文件被外部修改后自动重新加载，通过接口修改时直接写回文件
+type RejectListStore struct {
+	mu      sync.RWMutex
+	rules   []compiledRejectRule
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// RejectList 全局来电拒接列表
+var RejectList = &RejectListStore{}
+
+// ValidateRejectRule 校验拒接规则
+func ValidateRejectRule(rule *models.RejectRule) error {
+	if rule.Pattern == "" {
+		return fmt.Errorf("%w: pattern is required", ErrInvalid)
+	}
+	if _, err := regexp.Compile(rule.Pattern); err != nil {
+		return fmt.Errorf("%w: pattern: %v", ErrInvalid, err)
+	}
+	return nil
+}
+
+// LoadRejectList 加载拒接列表并启动文件变更检查，启动时调用
+func LoadRejectList() error {
+	s := RejectList
+	s.mu.Lock()
+	s.path = os.Getenv("REJECT_LIST_FILE")
+	if s.path == "" {
+		s.path = defaultRejectListFile
+	}
+	s.mu.Unlock()
+
+	err := s.reloadFile()
+	go s.watchFile()
+	return err
+}
+
+// reloadFile 文件大小或修改时间变化时重新加载，文件不存在时视为空列表
+func (s *RejectListStore) reloadFile() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		if len(s.rules) > 0 {
+			slog.Info("reject list file removed, list cleared", slog.String("file", s.path))
+		}
+		s.rules, s.modTime, s.size = nil, time.Time{}, 0
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if st.ModTime().Equal(s.modTime) && st.Size() == s.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var rules []models.RejectRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("parse %s: %w", s.path, err)
+	}
+
+	compiled := make([]compiledRejectRule, 0, len(rules))
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil || rule.Pattern == "" {
+			slog.Warn("reject: skip rule with invalid pattern", slog.Int("rule", rule.ID), slog.String("pattern", rule.Pattern), slog.Any("error", err))
+			continue
+		}
+		compiled = append(compiled, compiledRejectRule{RejectRule: rule, number: re})
+	}
+
+	s.rules = compiled
+	s.modTime, s.size = st.ModTime(), st.Size()
+	slog.Info("reject list loaded", slog.String("file", s.path), slog.Int("rules", len(compiled)))
+	return nil
+}
+
+// watchFile 定期检查拒接列表文件，加载失败时保留原有列表
+func (s *RejectListStore) watchFile() {
+	for range time.Tick(rejectListFileCheckInterval) {
+		if err := s.reloadFile(); err != nil {
+			slog.Error("failed to reload reject list", slog.String("file", s.path), slog.Any("error", err))
+		}
+	}
+}
+
+// save 写回拒接列表文件，先写临时文件再替换，调用方需持有写锁
+func (s *RejectListStore) save(rules []compiledRejectRule) error {
+	list := make([]models.RejectRule, 0, len(rules))
+	for _, rule := range rules {
+		list = append(list, rule.RejectRule)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+
+	// 记录写入后的文件状态，避免变更检查重复加载
+	if st, err := os.Stat(s.path); err == nil {
+		s.modTime, s.size = st.ModTime(), st.Size()
+	}
+	s.rules = rules
+	return nil
+}
+
+// List 列出全部拒接规则
+func (s *RejectListStore) List() []models.RejectRule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]models.RejectRule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		list = append(list, rule.RejectRule)
+	}
+	return list
+}
+
+// Add 添加拒接规则，编号自动分配
+func (s *RejectListStore) Add(rule *models.RejectRule) error {
+	if err := ValidateRejectRule(rule); err != nil {
+		return err
+	}
+	re := regexp.MustCompile(rule.Pattern)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rule.ID = 1
+	for _, r := range s.rules {
+		if r.ID >= rule.ID {
+			rule.ID = r.ID + 1
+		}
+	}
+	rules := append(append([]compiledRejectRule{}, s.rules...), compiledRejectRule{RejectRule: *rule, number: re})
+	return s.save(rules)
+}
+
+// Delete 删除拒接规则
+func (s *RejectListStore) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules := make([]compiledRejectRule, 0, len(s.rules))
+	for _, r := range s.rules {
+		if r.ID != id {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == len(s.rules) {
+		return ErrRejectRuleNotFound
+	}
+	return s.save(rules)
+}
+
+// Match 返回匹配来电号码的第一条规则
+func (s *RejectListStore) Match(number string) (*models.RejectRule, bool) {
+	if number == "" {
+		return nil, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, rule := range s.rules {
+		if rule.number.MatchString(number) {
+			r := rule.RejectRule
+			return &r, true
+		}
+	}
+	return nil, false
+}
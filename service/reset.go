@@ -0,0 +1,70 @@
+package service
+
+import (
+	"log/slog"
+	"time"
+)
+
+const (
+	resetDebounce = 10 * time.Second // 同一次重启的多个开机提示只处理一次
+	resetSettle   = time.Second      // 开机提示后等待模块就绪的时间
+)
+
+// bootURCs 模块开机时主动上报的提示
+var bootURCs = map[string]bool{
+	"RDY":   true,
+	"+BOOT": true,
+}
+
+// initialize 设置会话参数，连接时和模块重启后执行
+func (m *ModemInfo) initialize() {
+	// SIM 卡需要 PIN 码时自动解锁
+	m.autoUnlockPIN()
+
+	m.SendCommandExpect("ATE0", "OK")      // 关闭回显
+	m.SendCommandExpect("AT+CMGF=0", "OK") // PDU 模式
+	m.SendCommand("AT+CMEE=1")             // 数字错误码
+	if smsDirectMode() {
+		// 服务等级 1 要求对每条直接推送的短信回复 AT+CNMA，否则模块会停止推送；等级 0 不需要确认
+		m.SendCommand("AT+CSMS=0")
+		m.SendCommand("AT+CNMI=2,2,0,0,0") // 新短信通过 +CMT 直接推送，不保存到存储
+		m.SendCommand("AT+CNMI=2,2,0,2,0") // 状态报告通过 +CDSI 通知，不支持时保留上一条设置
+	} else {
+		m.SendCommand("AT+CNMI=2,1,0,0,0") // 新短信通过 +CMTI 通知
+		m.SendCommand("AT+CNMI=2,1,0,2,0") // 状态报告通过 +CDSI 通知，不支持时保留上一条设置
+	}
+	m.SendCommand("AT+CLIP=1") // 来电显示号码（+CLIP）
+
+	// 开启网络注册状态主动上报（含位置信息），不支持时忽略
+	for _, cmd := range registrationURCCommands {
+		m.SendCommand(cmd)
+	}
+}
+
+// handleBootURC 收到开机提示时视为模块意外重启，重新初始化会话并广播 modem_reset 事件
+func (m *ModemInfo) handleBootURC(label string) {
+	if !bootURCs[label] || m.dev == nil {
+		return
+	}
+	now := time.Now().UnixNano()
+	if last := m.resetAt.Load(); now-last < int64(resetDebounce) || !m.resetAt.CompareAndSwap(last, now) {
+		return
+	}
+
+	slog.Warn("modem reset detected, reinitializing", slog.String("port", m.Name), slog.String("urc", label))
+	time.Sleep(resetSettle)
+
+	// 清除重启前的状态
+	m.identityMu.Lock()
+	m.identity = nil
+	m.identityMu.Unlock()
+	modemInfoCache.Invalidate(m.Name)
+	m.regMu.Lock()
+	m.regStates = nil
+	m.regMu.Unlock()
+	m.leaveDataMode()
+	m.endCall("")
+
+	m.initialize()
+	ModemEvent.Publish(EventModemReset, m.Name, ResetData{Trigger: label})
+}
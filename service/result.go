@@ -0,0 +1,59 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// numericResults 数字结果码与文本结果码的对应关系（ITU-T V.250）
+var numericResults = map[string]string{
+	"0": "OK",
+	"1": "CONNECT",
+	"2": "RING",
+	"3": "NO CARRIER",
+	"4": "ERROR",
+	"6": "NO DIALTONE",
+	"7": "BUSY",
+	"8": "NO ANSWER",
+}
+
+// GetResultFormat 通过 AT 命令的响应判断结果码格式
+// 串口识别到数字结果码时为数字格式，无响应为静默模式
+func (m *ModemInfo) GetResultFormat() (*models.ResultFormat, error) {
+	responses, err := m.SendCommand("AT")
+	if err != nil {
+		if err.Error() == "command timeout" && len(responses) == 0 {
+			return &models.ResultFormat{Verbose: true, Quiet: true}, nil
+		}
+		return nil, err
+	}
+	if l := len(responses); l == 0 || responses[l-1] != "OK" {
+		return nil, fmt.Errorf("unexpected response: %v", responses)
+	}
+	return &models.ResultFormat{Verbose: !m.port.numeric.Load()}, nil
+}
+
+// SetResultFormat 设置结果码格式（ATQ<n>V<n>）
+// 发送前通知串口新的格式，模块以新格式返回的结果码即可识别；静默模式下每条命令都需等待超时，仅用于调试
+func (m *ModemInfo) SetResultFormat(verbose, quiet bool) error {
+	q, v := 0, 0
+	if quiet {
+		q = 1
+	}
+	if verbose {
+		v = 1
+	}
+
+	m.port.SetNumeric(!verbose)
+	responses, err := m.SendCommand("ATQ" + strconv.Itoa(q) + "V" + strconv.Itoa(v))
+	if quiet && err != nil && err.Error() == "command timeout" && len(responses) == 0 {
+		// 静默模式下没有结果码
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return finalError(responses)
+}
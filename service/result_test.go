@@ -0,0 +1,95 @@
+package service
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// numericModem 以数字结果码格式（<code><CR>）响应的模拟模块
+func numericModem(t *testing.T) (*ModemInfo, *scriptedModem) {
+	script := &scriptedModem{}
+	m, _ := newTestModem(t, script)
+	script.mu.Lock()
+	script.raw = true
+	script.mu.Unlock()
+	script.reply("ATQ0V0", "0\r")
+	script.reply("AT", "0\r")
+	script.reply("AT+CSQ", "\r\n+CSQ: 20,99\r\n0\r")
+	script.reply("AT+CFUN=9", "4\r")
+	return m, script
+}
+
+func TestNumericResultCodes(t *testing.T) {
+	m, _ := numericModem(t)
+	if err := m.SetResultFormat(false, false); err != nil {
+		t.Fatalf("SetResultFormat: %v", err)
+	}
+
+	start := time.Now()
+	responses, err := m.SendCommand("AT+CSQ")
+	if err != nil {
+		t.Fatalf("SendCommand: %v", err)
+	}
+	if !slices.Equal(responses, []string{"+CSQ: 20,99", "OK"}) {
+		t.Fatalf("responses = %q", responses)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("numeric result recognised after %s", elapsed)
+	}
+
+	responses, err = m.SendCommand("AT+CFUN=9")
+	if err != nil || finalError(responses) == nil {
+		t.Fatalf("numeric error: responses %q, err %v", responses, err)
+	}
+
+	// 直接调用 at 库的命令同样可以识别
+	if err := m.dev.Test(); err != nil {
+		t.Fatalf("library command: %v", err)
+	}
+
+	format, err := m.GetResultFormat()
+	if err != nil || format.Verbose || format.Quiet {
+		t.Fatalf("GetResultFormat = %+v, %v", format, err)
+	}
+}
+
+func TestNumericModeDetectedFromResponse(t *testing.T) {
+	// 未通过 SetResultFormat 切换（如原始命令发送 ATV0），空闲时识别结果码
+	m, _ := numericModem(t)
+	responses, err := m.SendCommand("AT")
+	if err != nil || !slices.Equal(responses, []string{"OK"}) {
+		t.Fatalf("responses %q, err %v", responses, err)
+	}
+	if !m.port.numeric.Load() {
+		t.Fatal("numeric mode not detected")
+	}
+}
+
+func TestVerboseDataLineNotConverted(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CGATT?", "0\r\nOK")
+	m, _ := newTestModem(t, script)
+
+	responses, err := m.SendCommand("AT+CGATT?")
+	if err != nil || !slices.Equal(responses, []string{"0", "OK"}) {
+		t.Fatalf("responses %q, err %v", responses, err)
+	}
+}
+
+func TestSetResultFormatReturnsError(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("ATQ0V0", "ERROR")
+	script.reply("ATQ1V1", "ERROR")
+	m, _ := newTestModem(t, script)
+
+	if err := m.SetResultFormat(false, false); err == nil {
+		t.Fatal("SetResultFormat ignored ERROR")
+	}
+	if m.port.numeric.Load() {
+		t.Fatal("numeric mode kept after verbose ERROR")
+	}
+	if err := m.SetResultFormat(true, true); err == nil {
+		t.Fatal("SetResultFormat ignored ERROR in quiet mode")
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rehiy/web-modem/database"
+	"github.com/rehiy/web-modem/models"
+)
+
+// scheduleInterval 检查到期定时短信的间隔
+const scheduleInterval = 30 * time.Second
+
+// ScheduleSMS 校验并保存定时短信
+func ScheduleSMS(port, number, message string, sendAt time.Time) (*models.ScheduledSMS, error) {
+	if strings.TrimSpace(port) == "" || strings.TrimSpace(number) == "" || message == "" {
+		return nil, fmt.Errorf("%w: name, number and message are required", ErrInvalid)
+	}
+	if sendAt.IsZero() {
+		return nil, fmt.Errorf("%w: send_at is required", ErrInvalid)
+	}
+	if _, err := buildTPDUs(number, message, SendOptions{}); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	sms := &models.ScheduledSMS{
+		ID:      hex.EncodeToString(buf),
+		Port:    path.Base(port),
+		Number:  number,
+		Message: message,
+		SendAt:  sendAt,
+		Status:  models.ScheduledPending,
+	}
+	if err := database.CreateScheduledSMS(sms); err != nil {
+		return nil, err
+	}
+	return sms, nil
+}
+
+// StartScheduler 后台定期发送到期的定时短信
+// 任务保存在数据库中，重启后启动时立即补发停机期间到期的短信
+func (m *ModemService) StartScheduler() {
+	go func() {
+		ticker := time.NewTicker(scheduleInterval)
+		defer ticker.Stop()
+		for {
+			m.sendDueSMS()
+			<-ticker.C
+		}
+	}()
+}
+
+// sendDueSMS 发送所有到期的定时短信，模块未连接时保留等待下次检查
+func (m *ModemService) sendDueSMS() {
+	due, err := database.DueScheduledSMS(time.Now())
+	if err != nil {
+		slog.Error("scheduler failed", slog.Any("error", err))
+		return
+	}
+
+	for _, sms := range due {
+		conn, err := m.GetConnect(sms.Port)
+		if err != nil {
+			continue
+		}
+
+		status, errMsg := models.ScheduledSent, ""
+		if _, err := conn.SendSMS(context.Background(), sms.Number, sms.Message, SendOptions{}); err != nil {
+			status, errMsg = models.ScheduledFailed, err.Error()
+			slog.Warn("scheduled sms failed", slog.String("port", sms.Port), slog.String("id", sms.ID), slog.Any("error", err))
+		}
+
+		now := time.Now()
+		if err := database.UpdateScheduledSMS(sms.ID, status, errMsg, &now); err != nil {
+			slog.Error("scheduler failed", slog.Any("error", err))
+		}
+	}
+}
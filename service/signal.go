@@ -0,0 +1,101 @@
+package service
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rehiy/web-modem/metrics"
+	"github.com/rehiy/web-modem/models"
+)
+
+const (
+	signalHistorySize     = 100              // 每个模块保留的信号采样数量
+	defaultSignalInterval = 10 * time.Second // 默认信号采样间隔
+)
+
+// signalInterval 信号采样间隔，由 SIGNAL_POLL_INTERVAL_SECONDS 设置，为 0 时关闭采样
+var signalInterval = sync.OnceValue(func() time.Duration {
+	v := os.Getenv("SIGNAL_POLL_INTERVAL_SECONDS")
+	if v == "" {
+		return defaultSignalInterval
+	}
+	seconds, err := strconv.Atoi(v)
+	if err != nil || seconds < 0 {
+		slog.Warn("invalid SIGNAL_POLL_INTERVAL_SECONDS", slog.String("value", v), slog.Duration("using", defaultSignalInterval))
+		return defaultSignalInterval
+	}
+	return time.Duration(seconds) * time.Second
+})
+
+// signalHistory 信号采样环形缓冲区
+type signalHistory struct {
+	mu      sync.Mutex
+	samples [signalHistorySize]models.SignalSample
+	next    int
+	full    bool
+}
+
+// add 记录一次采样
+func (h *signalHistory) add(sample models.SignalSample) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.samples[h.next] = sample
+	h.next = (h.next + 1) % signalHistorySize
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// latest 按时间顺序返回最近 limit 条采样，limit <= 0 表示全部
+func (h *signalHistory) latest(limit int) []models.SignalSample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	samples := append([]models.SignalSample{}, h.samples[:h.next]...)
+	if h.full {
+		samples = append(append([]models.SignalSample{}, h.samples[h.next:]...), samples...)
+	}
+	if limit > 0 && len(samples) > limit {
+		samples = samples[len(samples)-limit:]
+	}
+	return samples
+}
+
+// pollSignal 连接期间定期采样信号强度，记录历史并广播 signal 事件
+// 发送短信期间跳过，避免打断长短信的分段提交
+func (m *ModemInfo) pollSignal() {
+	interval := signalInterval()
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !m.IsOpen() {
+			return
+		}
+		if m.smsBusy.Load() {
+			continue
+		}
+
+		signal, err := m.GetSignal()
+		if err != nil {
+			continue
+		}
+		sample := models.SignalSample{Signal: *signal, Time: time.Now()}
+		m.signals.add(sample)
+		metrics.SignalRSSI.WithLabelValues(m.Name).Set(float64(signal.RSSI))
+		ModemEvent.Publish(EventSignal, m.Name, sample)
+	}
+}
+
+// GetSignalHistory 获取最近的信号采样，按时间从早到晚排列
+func (m *ModemInfo) GetSignalHistory(limit int) []models.SignalSample {
+	return m.signals.latest(limit)
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// simBusyCodes SIM 卡初始化期间返回的临时错误（+CME ERROR），稍后重试即可恢复
+// 262（SIM blocked）需要 PUK 解锁，重试无效，不在此列
+var simBusyCodes = map[string]bool{
+	"14":            true, // SIM busy
+	"515":           true, // Quectel: please wait, init or command is processing
+	"sim busy":      true,
+	"sim not ready": true,
+}
+
+const (
+	simBusyWindow  = 10 * time.Second       // SIM 卡忙时的最长重试时间
+	simBusyBackoff = 500 * time.Millisecond // 首次重试间隔，之后逐次加倍
+)
+
+func init() {
+	RegisterParser("", "AT+QPINC?", parseQuectelPINRetries)
+	RegisterParser("", "AT^CPIN?", parseHuaweiPINRetries)
+	RegisterParser("", "AT+CPINR", parsePINRetries)
+}
+
+// markSIMBusy 记录 SIM 卡忙的 +CME ERROR 通知
+func (m *ModemInfo) markSIMBusy(code string) {
+	if simBusyCodes[strings.ToLower(code)] {
+		m.simBusyAt.Store(time.Now().UnixNano())
+	}
+}
+
+// RetrySIMBusy 执行操作，期间收到 SIM 卡忙错误时按退避重试
+// 仅处理 SIM 卡初始化的临时错误，其他错误直接返回
+func (m *ModemInfo) RetrySIMBusy(fn func() error) error {
+	deadline := time.Now().Add(simBusyWindow)
+	backoff := simBusyBackoff
+	for {
+		start := time.Now().UnixNano()
+		err := fn()
+		if err == nil || m.simBusyAt.Load() < start {
+			return err
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("sim busy: %w", err)
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// GetSIMStatus 查询 SIM 卡状态（+CPIN: READY / SIM PIN / SIM PUK 等）
+func (m *ModemInfo) GetSIMStatus() (string, error) {
+	responses, err := m.SendCommand("AT+CPIN?")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range responses {
+		if label, param := splitParam(line); label == "+CPIN" && len(param) > 0 {
+			return param[0], nil
+		}
+	}
+	return "", fmt.Errorf("failed to parse sim status")
+}
+
+// PIN / PUK 码格式
+var (
+	pinRe = regexp.MustCompile(`^[0-9]{4,8}$`)
+	pukRe = regexp.MustCompile(`^[0-9]{8}$`)
+)
+
+// GetPINStatus 查询 SIM 卡锁定状态和剩余尝试次数
+func (m *ModemInfo) GetPINStatus() (*models.PINStatus, error) {
+	status, err := m.GetSIMStatus()
+	if err != nil {
+		return nil, err
+	}
+	m.setSIMStatus(status)
+
+	retries, err := m.GetPINRetries()
+	if err != nil {
+		retries = unknownPINRetries()
+	}
+	return &models.PINStatus{Status: status, Retries: retries}, nil
+}
+
+// UnlockPIN 输入 PIN 码解锁 SIM 卡；SIM 卡被 PUK 锁定时需提供 PUK 码，pin 作为新的 PIN 码
+func (m *ModemInfo) UnlockPIN(pin, puk string) error {
+	if !pinRe.MatchString(pin) {
+		return fmt.Errorf("%w: pin must be 4-8 digits", ErrInvalid)
+	}
+	if puk != "" && !pukRe.MatchString(puk) {
+		return fmt.Errorf("%w: puk must be 8 digits", ErrInvalid)
+	}
+
+	status, err := m.GetSIMStatus()
+	if err != nil {
+		return err
+	}
+
+	var cmd string
+	switch status {
+	case "READY":
+		return nil
+	case "SIM PIN":
+		cmd = fmt.Sprintf(`AT+CPIN="%s"`, pin)
+	case "SIM PUK":
+		if puk == "" {
+			return fmt.Errorf("%w: sim is puk locked, puk is required", ErrInvalid)
+		}
+		cmd = fmt.Sprintf(`AT+CPIN="%s","%s"`, puk, pin)
+	default:
+		return fmt.Errorf("%w: cannot unlock sim in state %q", ErrUnsupported, status)
+	}
+
+	responses, err := m.SendCommandWithTimeout(cmd, 10*time.Second)
+	if err == nil {
+		err = finalError(responses)
+	}
+	if err != nil {
+		return fmt.Errorf("unlock failed: %w", err)
+	}
+	m.setSIMStatus("READY")
+	return nil
+}
+
+// autoUnlockPIN SIM 卡需要 PIN 码时使用 MODEM_PIN 自动解锁
+// 只剩最后一次机会时不自动尝试，避免 PIN 码错误导致 SIM 卡被锁定
+func (m *ModemInfo) autoUnlockPIN() {
+	status, err := m.GetPINStatus()
+	if err != nil || status.Status != "SIM PIN" {
+		return
+	}
+
+	pin := os.Getenv("MODEM_PIN")
+	if pin == "" {
+		slog.Warn("sim is pin locked, set MODEM_PIN to unlock automatically", slog.String("port", m.Name))
+		return
+	}
+	if status.Retries.PIN == 1 {
+		slog.Warn("sim pin has only 1 attempt left, skip automatic unlock", slog.String("port", m.Name))
+		return
+	}
+	if err := m.UnlockPIN(pin, ""); err != nil {
+		slog.Error("automatic pin unlock failed", slog.String("port", m.Name), slog.Any("error", err))
+		return
+	}
+	slog.Info("sim unlocked with MODEM_PIN", slog.String("port", m.Name))
+}
+
+// GetPINRetries 查询 PIN/PUK 剩余尝试次数
+// 模块不支持查询时返回全部未知，而不是错误
+func (m *ModemInfo) GetPINRetries() (*models.PINRetries, error) {
+	cmd, ok := vendorCommand("pin_retries", m.Vendor)
+	if !ok {
+		return unknownPINRetries(), nil
+	}
+
+	v, err := m.Query(cmd)
+	if err != nil {
+		if m.Vendor == VendorGeneric {
+			return unknownPINRetries(), nil
+		}
+		return nil, err
+	}
+
+	retries, ok := v.(*models.PINRetries)
+	if !ok {
+		return nil, fmt.Errorf("unexpected pin retries parser result %T", v)
+	}
+	return retries, nil
+}
+
+// unknownPINRetries 返回未知的尝试次数
+func unknownPINRetries() *models.PINRetries {
+	return &models.PINRetries{PIN: -1, PIN2: -1, PUK: -1, PUK2: -1}
+}
+
+// parseRetry 解析尝试次数，无法解析时返回 -1
+func parseRetry(s string) int {
+	if v, err := strconv.Atoi(s); err == nil {
+		return v
+	}
+	return -1
+}
+
+// parseQuectelPINRetries 解析 +QPINC: "SC",<pin>,<puk> 和 +QPINC: "P2",<pin2>,<puk2>
+func parseQuectelPINRetries(responses []string) (any, error) {
+	retries := unknownPINRetries()
+	found := false
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "+QPINC" || len(param) < 3 {
+			continue
+		}
+		switch param[0] {
+		case "SC":
+			retries.PIN, retries.PUK = parseRetry(param[1]), parseRetry(param[2])
+			found = true
+		case "P2":
+			retries.PIN2, retries.PUK2 = parseRetry(param[1]), parseRetry(param[2])
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("failed to parse pin retries")
+	}
+	return retries, nil
+}
+
+// parseHuaweiPINRetries 解析 ^CPIN: <code>,[<times>],<puk>,<pin>,<puk2>,<pin2>
+func parseHuaweiPINRetries(responses []string) (any, error) {
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "^CPIN" || len(param) < 6 {
+			continue
+		}
+		return &models.PINRetries{
+			PUK:  parseRetry(param[2]),
+			PIN:  parseRetry(param[3]),
+			PUK2: parseRetry(param[4]),
+			PIN2: parseRetry(param[5]),
+		}, nil
+	}
+	return nil, fmt.Errorf("failed to parse pin retries")
+}
+
+// parsePINRetries 解析 3GPP 标准的 +CPINR: <code>,<retries>[,<default>]
+func parsePINRetries(responses []string) (any, error) {
+	retries := unknownPINRetries()
+	found := false
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "+CPINR" || len(param) < 2 {
+			continue
+		}
+		found = true
+		switch strings.ToUpper(param[0]) {
+		case "SIM PIN":
+			retries.PIN = parseRetry(param[1])
+		case "SIM PIN2":
+			retries.PIN2 = parseRetry(param[1])
+		case "SIM PUK":
+			retries.PUK = parseRetry(param[1])
+		case "SIM PUK2":
+			retries.PUK2 = parseRetry(param[1])
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("failed to parse pin retries")
+	}
+	return retries, nil
+}
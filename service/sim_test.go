@@ -0,0 +1,40 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestGetPINStatusRecordsSIMStatus(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CPIN?", "+CPIN: SIM PIN\r\nOK")
+	script.reply("AT+CPINR", "+CPINR: SIM PIN,3,10\r\nOK")
+	m, _ := newTestModem(t, script)
+
+	// 与序列化并发执行，-race 下可发现未加锁的写入
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if _, err := json.Marshal(m); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	if _, err := m.GetPINStatus(); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"simStatus":"SIM PIN"`) {
+		t.Fatalf("json = %s", data)
+	}
+}
@@ -0,0 +1,596 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rehiy/modem/sms"
+	"github.com/rehiy/modem/sms/gsm7/charset"
+	"github.com/rehiy/modem/sms/pdumode"
+	"github.com/rehiy/modem/sms/tpdu"
+	"github.com/rehiy/web-modem/metrics"
+	"github.com/rehiy/web-modem/models"
+)
+
+const (
+	smsPromptTimeout = 5 * time.Second  // 等待 AT+CMGS 输入提示符的最长时间
+	smsSubmitTimeout = 60 * time.Second // 等待短信提交最终响应的最长时间
+)
+
+// ErrSMSSubmitted 发送失败时已有 PDU 写入模块，短信可能已全部或部分发出，不应换用其他模块重发
+var ErrSMSSubmitted = errors.New("sms may have been submitted")
+
+func init() {
+	// gsm7 字符表在首次使用时才生成，并发编解码时存在数据竞争，启动时预先生成
+	charset.DefaultEncoder()
+	charset.DefaultExtEncoder()
+	charset.DefaultDecoder()
+}
+
+// udhNames 常见用户数据头信息单元标识（3GPP TS 23.040 9.2.3.24）
+var udhNames = map[byte]string{
+	0x00: "concat8",
+	0x01: "special_sms",
+	0x04: "port8",
+	0x05: "port16",
+	0x06: "smsc_control",
+	0x07: "udh_source",
+	0x08: "concat16",
+	0x09: "wcmp",
+	0x0a: "text_formatting",
+	0x0b: "predefined_sound",
+	0x0c: "user_defined_sound",
+	0x0d: "predefined_animation",
+	0x10: "small_picture",
+	0x14: "extended_object",
+	0x16: "compression_control",
+	0x22: "hyperlink",
+	0x24: "single_shift",
+	0x25: "locking_shift",
+}
+
+// SendOptions 短信发送选项
+type SendOptions struct {
+	Verify      bool // 要求模块对每个分段返回 +CMGS 参考号，否则视为发送失败
+	Class       *int // 消息类别 0-3，为空时不设置
+	ReplaceType int  // 替换类型 1-7（TP-PID 0x41-0x47），0 表示普通短信
+
+	StatusReport bool // 请求状态报告（TP-SRR），通过 +CDSI 或 +CDS 通知接收
+}
+
+// Validate 校验发送选项
+func (o SendOptions) Validate() error {
+	if o.Class != nil && (*o.Class < 0 || *o.Class > 3) {
+		return fmt.Errorf("%w: invalid class %d, must be 0-3", ErrInvalid, *o.Class)
+	}
+	if o.ReplaceType < 0 || o.ReplaceType > 7 {
+		return fmt.Errorf("%w: invalid replaceType %d, must be 0-7", ErrInvalid, o.ReplaceType)
+	}
+	return nil
+}
+
+// SendSMS 发送短信
+// 返回模块确认的各分段参考号（未要求确认时可能不完整）；已有分段写入模块后失败时返回的错误包含 ErrSMSSubmitted
+// ctx 结束时不再开始发送；已开始的长短信会发完全部分段，避免对方收到不完整的短信
+func (m *ModemInfo) SendSMS(ctx context.Context, number, message string, opts SendOptions) ([]int, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	tpdus, err := buildTPDUs(number, message, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 标记发送中，后台采样期间暂停
+	m.smsBusy.Store(true)
+	defer m.smsBusy.Store(false)
+	defer m.smsCache.invalidate()
+
+	refs := []int{}
+	for i, t := range tpdus {
+		ref, err := m.sendTPDU(t, opts.Verify)
+		if err != nil {
+			if i > 0 && !errors.Is(err, ErrSMSSubmitted) {
+				err = fmt.Errorf("%w: %d of %d segments submitted: %w", ErrSMSSubmitted, i, len(tpdus), err)
+			}
+			metrics.SMSSent.WithLabelValues(m.Name, "error").Inc()
+			slog.WarnContext(ctx, "sms send failed", slog.String("port", m.Name), slog.String("to", number), slog.Any("error", err))
+			return refs, err
+		}
+		if ref >= 0 {
+			refs = append(refs, ref)
+		}
+	}
+
+	metrics.SMSSent.WithLabelValues(m.Name, "ok").Inc()
+	slog.InfoContext(ctx, "sms sent", slog.String("port", m.Name), slog.String("to", number), slog.Int("parts", len(tpdus)))
+	return refs, nil
+}
+
+// buildTPDUs 编码短信，并按选项设置消息类别和协议标识，错误均包含 ErrInvalid
+func buildTPDUs(number, message string, opts SendOptions) ([]tpdu.TPDU, error) {
+	tpdus, err := sms.Encode([]byte(message), sms.To(number), randomConcatRef{})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	for i := range tpdus {
+		if opts.Class != nil {
+			dcs, err := tpdus[i].DCS.WithClass(tpdu.MessageClass(*opts.Class))
+			if err != nil {
+				return nil, fmt.Errorf("%w: set message class: %v", ErrInvalid, err)
+			}
+			tpdus[i].SetDCS(byte(dcs))
+		}
+		if opts.ReplaceType > 0 {
+			tpdus[i].SetPID(byte(0x40 + opts.ReplaceType))
+		}
+		if opts.StatusReport {
+			tpdus[i].FirstOctet |= tpdu.FoSRR
+		}
+	}
+
+	return tpdus, nil
+}
+
+// randomConcatRef 长短信拼接参考号（3GPP TS 23.040 9.2.3.24.1）
+// 编码器默认每次从 1 开始计数，同一号码先后发出的长短信参考号相同，接收方可能拼接错乱，因此每条长短信随机选取
+type randomConcatRef struct{}
+
+// Count 返回 1-255 的随机参考号
+func (randomConcatRef) Count() int {
+	return 1 + rand.Intn(255)
+}
+
+// ApplyEncoderOption 作为编码选项设置拼接参考号生成器
+func (r randomConcatRef) ApplyEncoderOption(e *sms.Encoder) {
+	e.ConcatRef = r
+}
+
+// EstimateSMS 估算短信编码方式和分段数量
+// 使用与发送相同的编码和分段逻辑，GSM7 扩展字符（如 €{}[]~^|\）占用两个 septet，且不会被拆分到两个分段
+func EstimateSMS(message string) (*models.SMSEstimate, error) {
+	estimate := &models.SMSEstimate{Encoding: "GSM7", PerSegment: 160}
+	if message == "" {
+		return estimate, nil
+	}
+
+	tpdus, err := sms.Encode([]byte(message))
+	if err != nil {
+		return nil, err
+	}
+
+	// UCS2 按 UTF-16 码元计数，每个码元 2 字节
+	unit := 1
+	if alpha, _ := tpdus[0].Alphabet(); alpha == tpdu.AlphaUCS2 {
+		estimate.Encoding = "UCS2"
+		unit = 2
+	}
+
+	for _, t := range tpdus {
+		estimate.Units += len(t.UD) / unit
+	}
+	last := tpdus[len(tpdus)-1]
+	estimate.Segments = len(tpdus)
+	estimate.PerSegment = last.UDBlockSize() / unit
+	estimate.Remaining = estimate.PerSegment - len(last.UD)/unit
+	return estimate, nil
+}
+
+// ValidatePDU 按发送时的方式编码短信，再将各分段 PDU 解码拼接，检查能否还原原文
+// 用于在发送前发现编码问题，例如一个 emoji 使整条短信改用 UCS2 而分段数翻倍
+func ValidatePDU(number, message string, opts SendOptions) (*models.PDUValidation, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if message == "" {
+		return nil, fmt.Errorf("%w: message is empty", ErrInvalid)
+	}
+
+	tpdus, err := buildTPDUs(number, message, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &models.PDUValidation{
+		Encoding:        "GSM7",
+		PartsCount:      len(tpdus),
+		ActualCharCount: utf8.RuneCountInString(message),
+		PDUs:            []string{},
+	}
+	if alpha, _ := tpdus[0].Alphabet(); alpha == tpdu.AlphaUCS2 {
+		v.Encoding = "UCS2"
+	}
+
+	segments := []*tpdu.TPDU{}
+	for _, t := range tpdus {
+		tpduBytes, err := t.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		pduHex, err := (&pdumode.PDU{TPDU: tpduBytes}).MarshalHexString()
+		if err != nil {
+			return nil, err
+		}
+		v.EncodedLength += len(tpduBytes)
+		v.PDUs = append(v.PDUs, pduHex)
+
+		pdu, err := pdumode.UnmarshalHexString(pduHex)
+		if err != nil {
+			return nil, err
+		}
+		decoded, err := sms.Unmarshal(pdu.TPDU, sms.AsMO)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, decoded)
+	}
+
+	text, err := sms.Decode(segments)
+	if err != nil {
+		v.Diff = "decode failed: " + err.Error()
+		return v, nil
+	}
+	v.DecodedText = string(text)
+	v.RoundTrip = v.DecodedText == message
+	v.WillBeTruncated = truncated(message, v.DecodedText)
+	if !v.RoundTrip {
+		v.Diff = textDiff(message, v.DecodedText)
+	}
+	return v, nil
+}
+
+// truncated 解码结果的字符数是否少于原文，按字符而非字节比较，替换为单字节字符不算截断
+func truncated(want, got string) bool {
+	return utf8.RuneCountInString(got) < utf8.RuneCountInString(want)
+}
+
+// textDiff 描述两段文本第一处不一致的字符位置和前后内容
+func textDiff(want, got string) string {
+	w, g := []rune(want), []rune(got)
+	i := 0
+	for i < len(w) && i < len(g) && w[i] == g[i] {
+		i++
+	}
+	snippet := func(r []rune) string {
+		return string(r[i:min(i+10, len(r))])
+	}
+	return fmt.Sprintf("at char %d: expected %q, got %q", i, snippet(w), snippet(g))
+}
+
+// sendTPDU 通过 AT+CMGS 提交单个分段，返回参考号（未收到时为 -1）
+func (m *ModemInfo) sendTPDU(t tpdu.TPDU, verify bool) (int, error) {
+	tpduBytes, err := t.MarshalBinary()
+	if err != nil {
+		return -1, err
+	}
+
+	pdu := &pdumode.PDU{TPDU: tpduBytes}
+	pduHex, err := pdu.MarshalHexString()
+	if err != nil {
+		return -1, err
+	}
+
+	// TPDU 长度不包含 SMSC 部分，提示符、PDU 和提交结果作为一个整体排队，避免其他命令插入
+	ref := -1
+	if qerr := m.exec(false, func() {
+		ref, err = m.submitPDU(len(tpduBytes), pduHex)
+	}); qerr != nil {
+		return -1, qerr
+	}
+	if err != nil {
+		return -1, err
+	}
+	if ref < 0 && verify {
+		return -1, fmt.Errorf("%w: send not confirmed: no reference received", ErrSMSSubmitted)
+	}
+	return ref, nil
+}
+
+// submitPDU 发送 AT+CMGS，收到输入提示符后写入 PDU，并从同一段截获的输出中读取 +CMGS 参考号和最终响应
+// 仅在命令队列的调度协程中调用；参考号不经过事件处理函数，并发发送时不会取到其他短信的参考号
+// 开始写入 PDU 后的错误包含 ErrSMSSubmitted
+func (m *ModemInfo) submitPDU(length int, pduHex string) (ref int, err error) {
+	cmd := fmt.Sprintf("AT+CMGS=%d", length)
+	start := time.Now()
+	var responses []string
+	defer func() { m.observeCommand(start, responses, err) }()
+
+	c := m.capture(cmd)
+	defer c.release()
+	if err = c.write(cmd); err != nil {
+		return -1, err
+	}
+	responses, err = c.await(smsPromptTimeout, true)
+	if err != nil {
+		c.write("\x1b") // 取消输入，避免后续命令被当作 PDU
+		return -1, fmt.Errorf("waiting for prompt: %w", err)
+	}
+	if l := len(responses); l == 0 || responses[l-1] != ">" {
+		if err = finalError(responses); err != nil {
+			return -1, err
+		}
+		return -1, fmt.Errorf("expected prompt, got %q", responses)
+	}
+
+	if err = c.write(pduHex + "\x1a"); err != nil {
+		return -1, fmt.Errorf("%w: %w", ErrSMSSubmitted, err)
+	}
+	responses, err = c.await(smsSubmitTimeout, false)
+	if err != nil {
+		return -1, fmt.Errorf("%w: %w", ErrSMSSubmitted, err)
+	}
+	if err = finalError(responses); err != nil {
+		return -1, fmt.Errorf("%w: %w", ErrSMSSubmitted, err)
+	}
+	for _, line := range responses {
+		if label, param := splitParam(line); label == "+CMGS" && len(param) > 0 {
+			if ref, err := strconv.Atoi(param[0]); err == nil {
+				return ref, nil
+			}
+		}
+	}
+	return -1, nil
+}
+
+// ListSMS 获取短信列表
+// 与 at.ListSMSPdu 一致地合并长短信，并保留拼接以外的用户数据头信息单元
+func (m *ModemInfo) ListSMS(ctx context.Context, stat int) ([]models.ModemSMS, error) {
+	responses, err := m.SendATCommand(ctx, fmt.Sprintf("AT+CMGL=%d", stat), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.ModemSMS{}
+	indices := map[int][]int{}
+	headers := map[int][]models.UDHElement{}
+	collector := sms.NewCollector()
+	defer collector.Close()
+
+	for i, l := 0, len(responses); i < l; {
+		label, param := splitParam(responses[i])
+		i++
+
+		if label != "+CMGL" || len(param) < 2 {
+			continue
+		}
+
+		// 无下一行，退出
+		if i >= l {
+			break
+		}
+
+		// 解析十六进制 PDU
+		pdu, err := pdumode.UnmarshalHexString(responses[i])
+		i++
+		if err != nil {
+			continue
+		}
+
+		// 从 PDU 中解析 TPDU
+		tpduMsg, err := sms.Unmarshal(pdu.TPDU)
+		if err != nil {
+			continue
+		}
+
+		// 记录索引、引用号和信息单元
+		index, _ := strconv.Atoi(param[0])
+		_, _, mref, _ := tpduMsg.ConcatInfo()
+		if mref == 0 {
+			mref = index
+		}
+		indices[mref] = append(indices[mref], index)
+		headers[mref] = append(headers[mref], udhElements(tpduMsg.UDH)...)
+
+		// 收集短信（长短信自动合并）
+		segments, err := collector.Collect(*tpduMsg)
+		if err != nil || len(segments) == 0 {
+			continue
+		}
+
+		msgBytes, err := sms.Decode(segments)
+		if err != nil {
+			continue
+		}
+
+		result = append(result, models.ModemSMS{
+			PhoneNumber: segments[0].OA.Number(),
+			Text:        string(msgBytes),
+			Time:        segments[0].SCTS.Time.Format("2006/01/02 15:04:05"),
+			ReceivedAt:  segments[0].SCTS.Time,
+			Index:       indices[mref][0],
+			Indices:     indices[mref],
+			Status:      param[1],
+			Headers:     headers[mref],
+		})
+		delete(indices, mref)
+		delete(headers, mref)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Index > result[j].Index
+	})
+	return result, nil
+}
+
+// smsDeleteFlags 按状态批量删除时 AT+CMGD=1,<delflag> 的取值
+// delflag 是累加的：删除已发送短信时也会删除已读短信，依此类推
+var smsDeleteFlags = map[string]int{
+	"REC READ":   1, // 已读
+	"STO SENT":   2, // 已读和已发送
+	"STO UNSENT": 3, // 已读、已发送和未发送
+	"ALL":        4, // 全部（包括未读）
+}
+
+// DeleteSMSByStatus 按状态批量删除短信，status 为 REC READ / STO SENT / STO UNSENT / ALL
+func (m *ModemInfo) DeleteSMSByStatus(status string) error {
+	flag, ok := smsDeleteFlags[strings.ToUpper(strings.TrimSpace(status))]
+	if !ok {
+		return fmt.Errorf("%w: status %q, allowed: REC READ, STO SENT, STO UNSENT, ALL", ErrInvalid, status)
+	}
+
+	// 可能需要逐条擦除存储区，耗时较长
+	defer m.smsCache.invalidate()
+	responses, err := m.SendCommandWithTimeout(fmt.Sprintf("AT+CMGD=1,%d", flag), 25*time.Second)
+	if err != nil {
+		return err
+	}
+	return finalError(responses)
+}
+
+// ClearUnreadSMS 用户读取短信后清零未读计数
+func (m *ModemInfo) ClearUnreadSMS() {
+	m.unreadSMS.Store(0)
+}
+
+// smsStores 分别查询的短信存储区：SIM 卡和模块内存
+var smsStores = []string{"SM", "ME"}
+
+// smsStorageRoles AT+CPMS 三个存储区参数的用途
+var smsStorageRoles = []string{"read", "write", "receive"}
+
+// GetSMSStorage 分别查询 SIM 卡（SM）和模块内存（ME）的短信存储使用情况
+// 逐个选择存储区读取容量，完成后恢复原有选择；不支持的存储区会被跳过
+func (m *ModemInfo) GetSMSStorage() ([]models.SMSStorage, error) {
+	var storage []models.SMSStorage
+	var err error
+	if qerr := m.exec(false, func() {
+		storage, err = m.smsStorageTask()
+	}); qerr != nil {
+		return nil, qerr
+	}
+	return storage, err
+}
+
+// smsStorageTask 在命令队列中查询各存储区容量，切换和恢复之间不会插入其他命令
+func (m *ModemInfo) smsStorageTask() ([]models.SMSStorage, error) {
+	selected, err := m.smsStorageSelection()
+	if err != nil {
+		return nil, err
+	}
+
+	stores := append([]string{}, smsStores...)
+	for _, mem := range selected {
+		if !slices.Contains(stores, mem) {
+			stores = append(stores, mem)
+		}
+	}
+
+	storage := []models.SMSStorage{}
+	defer m.restoreSMSStorage(selected)
+	for _, mem := range stores {
+		// +CPMS: <used1>,<total1>,<used2>,<total2>,<used3>,<total3>
+		responses, err := m.sendCommand(fmt.Sprintf(`AT+CPMS="%s"`, mem))
+		if err != nil || finalError(responses) != nil {
+			continue
+		}
+		for _, line := range responses {
+			label, param := splitParam(line)
+			if label != "+CPMS" || len(param) < 2 {
+				continue
+			}
+			used, _ := strconv.Atoi(param[0])
+			total, _ := strconv.Atoi(param[1])
+			item := models.SMSStorage{Memory: mem, Used: used, Total: total}
+			for i, s := range selected {
+				if s == mem {
+					item.Selected = append(item.Selected, smsStorageRoles[i])
+				}
+			}
+			storage = append(storage, item)
+		}
+	}
+	return storage, nil
+}
+
+// restoreSMSStorage 恢复原有存储区选择，仅在命令队列的调度协程中调用
+func (m *ModemInfo) restoreSMSStorage(selected []string) {
+	responses, err := m.sendCommand(fmt.Sprintf(`AT+CPMS="%s"`, strings.Join(selected, `","`)))
+	if err == nil {
+		err = finalError(responses)
+	}
+	if err != nil {
+		slog.Warn("failed to restore sms storage", slog.String("port", m.Name), slog.Any("selected", selected), slog.Any("error", err))
+	}
+}
+
+// smsMemories AT+CPMS 可选的存储区（3GPP TS 27.005）
+var smsMemories = []string{"SM", "ME", "MT", "BM", "SR", "TA"}
+
+// SetSMSStorage 选择读取、写入和接收短信使用的存储区，可只指定前一个或两个
+func (m *ModemInfo) SetSMSStorage(mems ...string) error {
+	if len(mems) == 0 || len(mems) > len(smsStorageRoles) {
+		return fmt.Errorf("%w: 1-%d storage names are required", ErrInvalid, len(smsStorageRoles))
+	}
+	for i, mem := range mems {
+		mems[i] = strings.ToUpper(strings.TrimSpace(mem))
+		if !slices.Contains(smsMemories, mems[i]) {
+			return fmt.Errorf("%w: unknown sms storage %q, must be one of %s", ErrInvalid, mem, strings.Join(smsMemories, ", "))
+		}
+	}
+
+	defer m.smsCache.invalidate()
+	responses, err := m.SendCommand(fmt.Sprintf(`AT+CPMS="%s"`, strings.Join(mems, `","`)))
+	if err != nil {
+		return err
+	}
+	return finalError(responses)
+}
+
+// smsStorageSelection 查询当前选择的存储区，仅在命令队列的调度协程中调用
+// 解析 +CPMS: <mem1>,<used1>,<total1>,<mem2>,<used2>,<total2>,<mem3>,<used3>,<total3>
+func (m *ModemInfo) smsStorageSelection() ([]string, error) {
+	responses, err := m.sendCommand("AT+CPMS?")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range responses {
+		label, param := splitParam(line)
+		if label != "+CPMS" {
+			continue
+		}
+		selected := []string{}
+		for i := 0; i+2 < len(param) && len(selected) < len(smsStorageRoles); i += 3 {
+			selected = append(selected, param[i])
+		}
+		if len(selected) > 0 {
+			return selected, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to parse sms storage")
+}
+
+// udhElements 提取拼接信息以外的用户数据头信息单元
+func udhElements(udh tpdu.UserDataHeader) []models.UDHElement {
+	elements := []models.UDHElement{}
+	for _, ie := range udh {
+		if ie.ID == 0x00 || ie.ID == 0x08 {
+			continue
+		}
+		name, ok := udhNames[ie.ID]
+		if !ok {
+			name = "unknown"
+		}
+		elements = append(elements, models.UDHElement{
+			ID:   int(ie.ID),
+			Name: name,
+			Data: hex.EncodeToString(ie.Data),
+		})
+	}
+	return elements
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smsModem 模拟短信提交：AT+CMGS 返回提示符，PDU 返回递增的参考号
+func smsModem(firstRef int) *scriptedModem {
+	ref := firstRef
+	return &scriptedModem{respond: func(cmd string) (string, bool) {
+		switch {
+		case strings.HasPrefix(cmd, "AT+CMGS="):
+			return ">", true
+		case !strings.HasPrefix(cmd, "AT"):
+			ref++
+			return "+CMGS: " + strconv.Itoa(ref-1) + "\r\nOK", true
+		}
+		return "", false
+	}}
+}
+
+func TestSendSMSReadsReferenceFromCapturedOutput(t *testing.T) {
+	m, f := newTestModem(t, smsModem(7))
+
+	start := time.Now()
+	refs, err := m.SendSMS(context.Background(), "+8613800000000", strings.Repeat("a", 200), SendOptions{Verify: true})
+	if err != nil {
+		t.Fatalf("SendSMS: %v", err)
+	}
+	if len(refs) != 2 || refs[0] != 7 || refs[1] != 8 {
+		t.Fatalf("refs = %v, want [7 8]", refs)
+	}
+	// 不再为每个分段固定等待提交结果
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("sending 2 segments took %s", elapsed)
+	}
+	if strings.Count(f.writes(), "\x1a") != 2 {
+		t.Errorf("written = %q", f.writes())
+	}
+}
+
+func TestSendSMSFailsWithoutPrompt(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CMGS=19", "+CMS ERROR: 330")
+	m, f := newTestModem(t, script)
+
+	_, err := m.SendSMS(context.Background(), "+8613800000000", "hello", SendOptions{})
+	if err == nil || !strings.Contains(err.Error(), "+CMS ERROR: 330") {
+		t.Fatalf("SendSMS: %v", err)
+	}
+	if strings.Contains(f.writes(), "\x1a") {
+		t.Fatal("pdu written without prompt")
+	}
+}
+
+func TestSendSMSVerifyRequiresReference(t *testing.T) {
+	script := &scriptedModem{respond: func(cmd string) (string, bool) {
+		if strings.HasPrefix(cmd, "AT+CMGS=") {
+			return ">", true
+		}
+		return "", false
+	}}
+	m, _ := newTestModem(t, script)
+
+	if _, err := m.SendSMS(context.Background(), "+8613800000000", "hello", SendOptions{}); err != nil {
+		t.Fatalf("unverified send: %v", err)
+	}
+	if _, err := m.SendSMS(context.Background(), "+8613800000000", "hello", SendOptions{Verify: true}); err == nil {
+		t.Fatal("verified send without reference succeeded")
+	}
+}
+
+func TestGetSMSStorageRunsAsOneTask(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CPMS?", `+CPMS: "SR",1,10,"SM",2,20,"SM",2,20`+"\r\nOK")
+	script.reply(`AT+CPMS="SM"`, "+CPMS: 2,20,2,20,2,20\r\nOK")
+	script.reply(`AT+CPMS="ME"`, "+CPMS: 0,100,2,20,2,20\r\nOK")
+	script.reply(`AT+CPMS="SR"`, "+CPMS: 1,10,2,20,2,20\r\nOK")
+	for _, mem := range []string{"SM", "ME", "SR"} {
+		script.delay(`AT+CPMS="`+mem+`"`, 5*time.Millisecond)
+	}
+	m, _ := newTestModem(t, script)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				m.SendCommand("AT+CSQ")
+			}
+		}
+	}()
+	storage, err := m.GetSMSStorage()
+	close(done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(storage) != 3 || storage[2].Memory != "SR" || storage[2].Used != 1 {
+		t.Fatalf("storage = %+v", storage)
+	}
+
+	// 切换存储区到恢复原有选择之间不能插入其他命令
+	cmds := script.received()
+	first := slices.IndexFunc(cmds, func(c string) bool { return strings.HasPrefix(c, `AT+CPMS="`) })
+	restore := slices.Index(cmds, `AT+CPMS="SR","SM","SM"`)
+	if first < 0 || restore < first {
+		t.Fatalf("commands = %q", cmds)
+	}
+	if i := slices.Index(cmds[first:restore], "AT+CSQ"); i >= 0 {
+		t.Fatalf("command interleaved with storage switch: %q", cmds[first:restore+1])
+	}
+}
+
+func TestBuildTPDUsMessageClass(t *testing.T) {
+	class0, class1 := 0, 1
+	cases := []struct {
+		name    string
+		message string
+		class   *int
+		dcs     byte
+	}{
+		{"gsm7 default", "hello", nil, 0x00},
+		{"gsm7 flash", "hello", &class0, 0x10},
+		{"gsm7 class 1", "hello", &class1, 0x11},
+		{"ucs2 flash", "你好", &class0, 0x18},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tpdus, err := buildTPDUs("+8613800138000", c.message, SendOptions{Class: c.class})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range tpdus {
+				if byte(p.DCS) != c.dcs {
+					t.Errorf("DCS = %#02x, want %#02x", byte(p.DCS), c.dcs)
+				}
+			}
+		})
+	}
+}
+
+func TestTruncatedCountsRunes(t *testing.T) {
+	cases := []struct {
+		want, got string
+		truncated bool
+	}{
+		{"hello", "hello", false},
+		{"hello", "hell", true},
+		{"café", "cafe", false}, // 字节数变少但字符数相同
+		{"你好", "你", true},
+	}
+	for _, c := range cases {
+		if got := truncated(c.want, c.got); got != c.truncated {
+			t.Errorf("truncated(%q, %q) = %v, want %v", c.want, c.got, got, c.truncated)
+		}
+	}
+}
+
+func TestValidatePDURoundTrip(t *testing.T) {
+	v, err := ValidatePDU("+8613800138000", "你好，世界", SendOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.RoundTrip || v.WillBeTruncated || v.Encoding != "UCS2" {
+		t.Fatalf("validation = %+v", v)
+	}
+}
+
+func TestEstimateSMS(t *testing.T) {
+	cases := []struct {
+		name     string
+		message  string
+		encoding string
+		units    int
+		segments int
+		per      int
+		remain   int
+	}{
+		{"empty", "", "GSM7", 0, 0, 160, 0},
+		{"ascii", "hello", "GSM7", 5, 1, 160, 155},
+		{"extension chars", "€{}", "GSM7", 6, 1, 160, 154},
+		{"single segment limit", strings.Repeat("a", 160), "GSM7", 160, 1, 160, 0},
+		{"two segments", strings.Repeat("a", 161), "GSM7", 161, 2, 153, 145},
+		// 扩展字符的两个 septet 不拆分到两个分段
+		{"extension at boundary", strings.Repeat("a", 152) + "€" + strings.Repeat("a", 10), "GSM7", 164, 2, 153, 141},
+		{"ucs2", "你好", "UCS2", 2, 1, 70, 68},
+		{"ucs2 two segments", strings.Repeat("你", 71), "UCS2", 71, 2, 67, 63},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e, err := EstimateSMS(c.message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Encoding != c.encoding || e.Units != c.units || e.Segments != c.segments || e.PerSegment != c.per || e.Remaining != c.remain {
+				t.Errorf("estimate = %+v", e)
+			}
+		})
+	}
+}
+
+func TestDeleteSMSByStatus(t *testing.T) {
+	script := &scriptedModem{respond: func(cmd string) (string, bool) {
+		if strings.HasPrefix(cmd, "AT+CMGD=") {
+			return "OK", true
+		}
+		return "", false
+	}}
+	m, _ := newTestModem(t, script)
+
+	for _, status := range []string{"rec read", "STO SENT", " STO UNSENT ", "ALL"} {
+		if err := m.DeleteSMSByStatus(status); err != nil {
+			t.Fatalf("DeleteSMSByStatus(%q): %v", status, err)
+		}
+	}
+	want := []string{"AT+CMGD=1,1", "AT+CMGD=1,2", "AT+CMGD=1,3", "AT+CMGD=1,4"}
+	if got := script.received(); !slices.Equal(got, want) {
+		t.Fatalf("sent %q, want %q", got, want)
+	}
+
+	// delflag 没有只删除未读短信的取值
+	if err := m.DeleteSMSByStatus("REC UNREAD"); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("REC UNREAD: err = %v, want ErrInvalid", err)
+	}
+}
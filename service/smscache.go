@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rehiy/web-modem/database"
+	"github.com/rehiy/web-modem/models"
+)
+
+// smsCacheTTL 已解析短信列表的缓存有效期
+const smsCacheTTL = 30 * time.Second
+
+// smsListCache 按存储区和状态缓存已解析的短信列表，分页查询时避免重复读取和解析 PDU
+type smsListCache struct {
+	mu      sync.RWMutex
+	gen     uint64 // 每次失效加一，防止失效前开始的查询写回旧数据
+	entries map[smsCacheKey]smsCacheEntry
+}
+
+// smsCacheKey 读取短信的存储区和状态
+type smsCacheKey struct {
+	mem  string
+	stat int
+}
+
+type smsCacheEntry struct {
+	list []models.ModemSMS
+	at   time.Time
+}
+
+// get 返回未过期的缓存列表和当前代数
+func (c *smsListCache) get(key smsCacheKey) ([]models.ModemSMS, uint64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Since(entry.at) > smsCacheTTL {
+		return nil, c.gen, false
+	}
+	return entry.list, c.gen, true
+}
+
+// put 写入缓存，期间缓存已失效时放弃
+func (c *smsListCache) put(key smsCacheKey, gen uint64, list []models.ModemSMS) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	if c.entries == nil {
+		c.entries = map[smsCacheKey]smsCacheEntry{}
+	}
+	c.entries[key] = smsCacheEntry{list: list, at: time.Now()}
+}
+
+// invalidate 清空缓存
+func (c *smsListCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	clear(c.entries)
+}
+
+// ListSMSPaged 分页获取短信，返回本页短信和筛选后的总数
+// 合并长短信仍需读取全部短信，解析结果按当前读取存储区缓存 30 秒，发送、删除、切换存储区或收到新短信时失效
+func (m *ModemInfo) ListSMSPaged(ctx context.Context, stat int, filter models.ModemSMSFilter, offset, limit int) ([]models.ModemSMS, int, error) {
+	key := smsCacheKey{stat: stat}
+	var selected []string
+	if qerr := m.execContext(ctx, false, func() {
+		selected, _ = m.smsStorageSelection()
+	}); qerr != nil {
+		return nil, 0, qerr
+	}
+	if len(selected) > 0 {
+		key.mem = selected[0]
+	}
+
+	list, gen, ok := m.smsCache.get(key)
+	if !ok {
+		var err error
+		if list, err = m.ListSMS(ctx, stat); err != nil {
+			return nil, 0, err
+		}
+		m.smsCache.put(key, gen, list)
+	}
+
+	list = FilterSMS(list, filter)
+	total := len(list)
+	if offset >= total {
+		return []models.ModemSMS{}, total, nil
+	}
+	return list[offset:min(offset+limit, total)], total, nil
+}
+
+// ListStoredSMS 分页获取数据库中保存的端口接收短信，按接收时间倒序，返回本页短信和总数
+// name 可以是端口名、设备路径或别名，模块离线时同样可以查询
+func ListStoredSMS(name string, offset, limit int) ([]models.ModemSMS, int, error) {
+	stored, total, err := database.GetSMSList(&models.SMSFilter{Modem: portName(name), Direction: "in", Limit: limit, Offset: offset})
+	if err != nil {
+		return nil, 0, err
+	}
+	list := make([]models.ModemSMS, 0, len(stored))
+	for _, sms := range stored {
+		list = append(list, storedToModemSMS(sms))
+	}
+	return list, total, nil
+}
+
+// DeleteSMS 按索引删除短信
+func (m *ModemInfo) DeleteSMS(indices []int) error {
+	defer m.smsCache.invalidate()
+	var err error
+	if qerr := m.exec(false, func() {
+		err = m.dev.DeleteSMS(indices)
+	}); qerr != nil {
+		return qerr
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rehiy/web-modem/database"
+	"github.com/rehiy/web-modem/models"
+)
+
+var testDBOnce sync.Once
+
+// initTestDB 在临时目录中初始化数据库，同一测试进程内共享
+func initTestDB(t *testing.T) {
+	t.Helper()
+	var err error
+	testDBOnce.Do(func() {
+		var dir string
+		if dir, err = os.MkdirTemp("", "web-modem-test"); err != nil {
+			return
+		}
+		os.Setenv("DB_PATH", filepath.Join(dir, "data.db"))
+		err = database.InitDB()
+	})
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+}
+
+func TestListSMSPagedCachesPerStorage(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CPMS?", `+CPMS: "SM",1,20,"SM",1,20,"SM",1,20`+"\r\nOK")
+	script.reply("AT+CMGL=4", "+CMGL: 1,1,,24\r\n"+testDeliverPDU+"\r\nOK")
+	m, _ := newTestModem(t, script)
+
+	list, total, err := m.ListSMSPaged(context.Background(), 4, models.ModemSMSFilter{}, 0, 10)
+	if err != nil || total != 1 || list[0].Index != 1 {
+		t.Fatalf("SM: %+v, %d, %v", list, total, err)
+	}
+
+	// 存储区在外部（如原始 AT 命令）被切换后不能返回上一个存储区的缓存
+	script.reply("AT+CPMS?", `+CPMS: "ME",0,100,"SM",1,20,"SM",1,20`+"\r\nOK")
+	script.reply("AT+CMGL=4", "OK")
+	if _, total, err = m.ListSMSPaged(context.Background(), 4, models.ModemSMSFilter{}, 0, 10); err != nil || total != 0 {
+		t.Fatalf("ME: total %d, %v", total, err)
+	}
+}
+
+func TestSMSCacheInvalidated(t *testing.T) {
+	m, _ := newTestModem(t, &scriptedModem{})
+	key := smsCacheKey{mem: "SM", stat: 4}
+	fill := func() {
+		_, gen, _ := m.smsCache.get(key)
+		m.smsCache.put(key, gen, []models.ModemSMS{{Index: 1}})
+	}
+
+	fill()
+	if err := m.SetSMSStorage("ME"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := m.smsCache.get(key); ok {
+		t.Fatal("cache kept after storage change")
+	}
+
+	fill()
+	if err := m.DeleteSMS([]int{1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := m.smsCache.get(key); ok {
+		t.Fatal("cache kept after delete")
+	}
+}
+
+func TestListStoredSMS(t *testing.T) {
+	initTestDB(t)
+	port := "ttyLIST0"
+	aliasMu.Lock()
+	aliases[port] = "stored-sim"
+	aliasMu.Unlock()
+	t.Cleanup(func() {
+		aliasMu.Lock()
+		delete(aliases, port)
+		aliasMu.Unlock()
+	})
+
+	base := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	for i, text := range []string{"older", "newer"} {
+		sms := &models.SMS{Modem: port, Content: text, SMSIDs: "3,4", SendNumber: "10086", Direction: "in", ReceiveTime: base.Add(time.Duration(i) * time.Hour)}
+		if _, err := database.SaveIncomingSMS(sms); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, total, err := ListStoredSMS("stored-sim", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(list) != 1 {
+		t.Fatalf("total %d, list %+v", total, list)
+	}
+	got := list[0]
+	if got.Text != "newer" || got.PhoneNumber != "10086" || got.Index != 3 || len(got.Indices) != 2 || got.Status != "REC UNREAD" {
+		t.Fatalf("sms = %+v", got)
+	}
+}
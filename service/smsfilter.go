@@ -0,0 +1,62 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// smsListStats AT+CMGL 状态参数（PDU 模式）
+var smsListStats = map[string]int{
+	"REC UNREAD": 0,
+	"REC READ":   1,
+	"STO UNSENT": 2,
+	"STO SENT":   3,
+	"ALL":        4,
+}
+
+// ParseSMSStat 解析短信状态（REC UNREAD 等或 0-4），为空时返回 4（全部）
+func ParseSMSStat(status string) (int, error) {
+	status = strings.ToUpper(strings.TrimSpace(status))
+	if status == "" {
+		return 4, nil
+	}
+	if stat, ok := smsListStats[status]; ok {
+		return stat, nil
+	}
+	if stat, err := strconv.Atoi(status); err == nil && stat >= 0 && stat <= 4 {
+		return stat, nil
+	}
+	return 0, fmt.Errorf("%w: status %q, allowed: REC UNREAD, REC READ, STO UNSENT, STO SENT, ALL or 0-4", ErrInvalid, status)
+}
+
+// FilterSMS 按号码和内容筛选已解码的短信
+// 号码为部分匹配，内容不区分大小写；条件为空时不筛选
+func FilterSMS(messages []models.ModemSMS, f models.ModemSMSFilter) []models.ModemSMS {
+	contains := strings.ToLower(f.Contains)
+	result := []models.ModemSMS{}
+	for _, sms := range messages {
+		if f.Number != "" && !strings.Contains(sms.PhoneNumber, f.Number) {
+			continue
+		}
+		if contains != "" && !strings.Contains(strings.ToLower(sms.Text), contains) {
+			continue
+		}
+		result = append(result, sms)
+	}
+	return result
+}
+
+// SMSSince 返回服务中心时间戳晚于 since 的短信
+func SMSSince(messages []models.ModemSMS, since time.Time) []models.ModemSMS {
+	result := []models.ModemSMS{}
+	for _, sms := range messages {
+		if sms.ReceivedAt.After(since) {
+			result = append(result, sms)
+		}
+	}
+	return result
+}
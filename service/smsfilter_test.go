@@ -0,0 +1,50 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+func TestParseSMSStat(t *testing.T) {
+	cases := map[string]int{"": 4, "rec unread": 0, " REC READ ": 1, "STO SENT": 3, "2": 2}
+	for status, want := range cases {
+		if got, err := ParseSMSStat(status); err != nil || got != want {
+			t.Errorf("ParseSMSStat(%q) = %d, %v, want %d", status, got, err, want)
+		}
+	}
+	for _, status := range []string{"5", "-1", "UNREAD"} {
+		if _, err := ParseSMSStat(status); !errors.Is(err, ErrInvalid) {
+			t.Errorf("ParseSMSStat(%q) err = %v, want ErrInvalid", status, err)
+		}
+	}
+}
+
+func TestFilterSMS(t *testing.T) {
+	messages := []models.ModemSMS{
+		{PhoneNumber: "+8613800138000", Text: "Your code is 1234"},
+		{PhoneNumber: "10086", Text: "话费余额 12.5 元"},
+		{PhoneNumber: "+8613900139000", Text: "See you"},
+	}
+	cases := []struct {
+		filter models.ModemSMSFilter
+		want   []string
+	}{
+		{models.ModemSMSFilter{}, []string{"+8613800138000", "10086", "+8613900139000"}},
+		{models.ModemSMSFilter{Number: "+86138"}, []string{"+8613800138000"}},
+		{models.ModemSMSFilter{Contains: "CODE"}, []string{"+8613800138000"}},
+		{models.ModemSMSFilter{Number: "+86", Contains: "you"}, []string{"+8613800138000", "+8613900139000"}},
+		{models.ModemSMSFilter{Contains: "余额"}, []string{"10086"}},
+	}
+	for _, c := range cases {
+		got := []string{}
+		for _, sms := range FilterSMS(messages, c.filter) {
+			got = append(got, sms.PhoneNumber)
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("FilterSMS(%+v) = %q, want %q", c.filter, got, c.want)
+		}
+	}
+}
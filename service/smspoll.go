@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rehiy/web-modem/metrics"
+	"github.com/rehiy/web-modem/models"
+)
+
+// defaultSMSPollInterval 默认短信轮询间隔
+const defaultSMSPollInterval = 30 * time.Second
+
+const (
+	forwardedTTL = 24 * time.Hour // 已转发短信标识的保留时间，重复通知只会在短时间内出现
+	maxForwarded = 1000           // 每个端口最多保留的已转发短信标识，超出时丢弃最早的
+)
+
+var (
+	forwardedMu sync.Mutex
+	forwarded   = map[string]map[string]time.Time{} // 端口 -> 已转发短信标识 -> 记录时间
+)
+
+// StartSMSPoller 后台定期查询各模块的未读短信，补充可能丢失的 +CMTI 通知
+// 间隔由 SMS_POLL_INTERVAL_SECONDS 设置，默认 30 秒，设为 0 时关闭
+func (m *ModemService) StartSMSPoller() {
+	interval := defaultSMSPollInterval
+	if v := os.Getenv("SMS_POLL_INTERVAL_SECONDS"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			slog.Warn("invalid SMS_POLL_INTERVAL_SECONDS", slog.String("value", v), slog.Duration("using", interval))
+		} else {
+			interval = time.Duration(seconds) * time.Second
+		}
+	}
+	if interval == 0 {
+		slog.Info("sms poller disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			for _, modem := range m.GetModems() {
+				m.pollSMS(modem)
+			}
+		}
+	}()
+}
+
+// pollSMS 查询未读短信（AT+CMGL=0）并转发尚未处理的短信
+func (m *ModemService) pollSMS(conn *ModemInfo) {
+	if conn.InDataMode() {
+		return
+	}
+
+	smsList, err := conn.ListSMS(context.Background(), 0)
+	if err != nil {
+		slog.Warn("failed to poll sms", slog.String("port", conn.Name), slog.Any("error", err))
+		return
+	}
+
+	w := NewWebhookService()
+	for _, sms := range smsList {
+		m.deliverSMS(conn, sms, w)
+	}
+}
+
+// deliverSMS 广播 sms 事件，保存短信并触发 webhook
+// 同一条短信只处理一次，避免 +CMTI 通知和轮询重复转发
+func (m *ModemService) deliverSMS(conn *ModemInfo, sms models.ModemSMS, w *WebhookService) {
+	if !markForwarded(conn.Name, sms) {
+		return
+	}
+
+	metrics.SMSReceived.WithLabelValues(conn.Name).Inc()
+	modelSMS := atSMSToModelSMS(sms, conn.Name, conn.PhoneNumber)
+	ModemEvent.Publish(EventSMS, conn.Name, modelSMS)
+	if err := w.HandleIncomingSMS(modelSMS); err != nil {
+		slog.Error("failed to handle incoming sms", slog.String("port", conn.Name), slog.Any("error", err))
+	}
+	ForwardRules.Apply(conn, modelSMS)
+	slog.Info("new sms", slog.String("port", conn.Name), slog.String("from", sms.PhoneNumber), slog.String("text", sms.Text))
+}
+
+// markForwarded 记录已转发的短信，已记录时返回 false
+// 存储索引在短信删除后会被复用，因此同时使用发送方和时间标识短信；
+// 直接推送的短信没有索引，同一秒内同一发送方的多条短信靠内容区分
+func markForwarded(port string, sms models.ModemSMS) bool {
+	sum := sha256.Sum256([]byte(sms.Text))
+	key := fmt.Sprintf("%v|%s|%s|%x", sms.Indices, sms.PhoneNumber, sms.Time, sum[:8])
+
+	forwardedMu.Lock()
+	defer forwardedMu.Unlock()
+
+	seen := forwarded[port]
+	if seen == nil {
+		seen = map[string]time.Time{}
+		forwarded[port] = seen
+	}
+	now := time.Now()
+	if at, ok := seen[key]; ok && now.Sub(at) < forwardedTTL {
+		return false
+	}
+	if len(seen) >= maxForwarded {
+		pruneForwarded(seen, now)
+	}
+	seen[key] = now
+	return true
+}
+
+// pruneForwarded 删除过期的标识，仍然超出上限时删除最早的，调用方需持有锁
+func pruneForwarded(seen map[string]time.Time, now time.Time) {
+	oldestKey, oldest := "", now
+	for key, at := range seen {
+		if now.Sub(at) >= forwardedTTL {
+			delete(seen, key)
+			continue
+		}
+		if at.Before(oldest) {
+			oldestKey, oldest = key, at
+		}
+	}
+	if len(seen) >= maxForwarded {
+		delete(seen, oldestKey)
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rehiy/web-modem/database"
+	"github.com/rehiy/web-modem/models"
+)
+
+// testDeliverPDU2 与 testDeliverPDU 发送方相同、时间不同的另一条短信
+const testDeliverPDU2 = "07911326040000F0040B911346610089F60000208062917315080CC8F71D14969741F977FD07"
+
+// webhookReceiver 启动记录请求次数的 webhook 接收端，并注册为只接收 port 短信的 webhook
+func webhookReceiver(t *testing.T, port string) *atomic.Int64 {
+	t.Helper()
+	initTestDB(t)
+	var posts atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			posts.Add(1)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	webhook := &models.Webhook{Name: port, URL: srv.URL, Enabled: true, Type: models.WebhookSMS, Modem: port}
+	if err := database.Create(webhook); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.SetWebhookEnabled(true); err != nil {
+		t.Fatal(err)
+	}
+	resetWebhookCache := func() {
+		webhookCacheMux.Lock()
+		webhookCache = nil
+		webhookCacheMux.Unlock()
+	}
+	resetWebhookCache()
+	t.Cleanup(func() {
+		database.Delete(webhook.ID)
+		database.SetWebhookEnabled(false)
+		resetWebhookCache()
+	})
+	return &posts
+}
+
+// waitPosts 等待 webhook 请求数达到 want，并确认之后没有多余的请求
+func waitPosts(t *testing.T, posts *atomic.Int64, want int64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for posts.Load() < want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if got := posts.Load(); got != want {
+		t.Fatalf("webhook posts = %d, want %d", got, want)
+	}
+}
+
+func TestPollSMSForwardsEachMessageOnce(t *testing.T) {
+	script := &scriptedModem{}
+	script.reply("AT+CMGL=0", "+CMGL: 1,0,,24\r\n"+testDeliverPDU+"\r\nOK")
+	m, _ := newTestModem(t, script)
+	m.Name = t.Name()
+	t.Cleanup(func() {
+		forwardedMu.Lock()
+		delete(forwarded, m.Name)
+		forwardedMu.Unlock()
+	})
+	posts := webhookReceiver(t, m.Name)
+	ms := &ModemService{}
+
+	ms.pollSMS(m)
+	waitPosts(t, posts, 1)
+
+	// 第二次轮询多了一条新短信，只转发新短信
+	script.reply("AT+CMGL=0", "+CMGL: 1,0,,24\r\n"+testDeliverPDU+"\r\n+CMGL: 2,0,,24\r\n"+testDeliverPDU2+"\r\nOK")
+	ms.pollSMS(m)
+	waitPosts(t, posts, 2)
+
+	// 重复轮询不再转发
+	ms.pollSMS(m)
+	waitPosts(t, posts, 2)
+}
+
+func TestMarkForwardedBounded(t *testing.T) {
+	port := t.Name()
+	defer func() {
+		forwardedMu.Lock()
+		delete(forwarded, port)
+		forwardedMu.Unlock()
+	}()
+
+	first := models.ModemSMS{PhoneNumber: "10086", Time: "2026/10/16 12:00:00", Text: "0"}
+	markForwarded(port, first)
+	for i := 1; i < maxForwarded+50; i++ {
+		markForwarded(port, models.ModemSMS{PhoneNumber: "10086", Time: "2026/10/16 12:00:00", Text: fmt.Sprint(i)})
+	}
+
+	forwardedMu.Lock()
+	n := len(forwarded[port])
+	forwardedMu.Unlock()
+	if n > maxForwarded {
+		t.Fatalf("%d entries kept, limit %d", n, maxForwarded)
+	}
+	if !markForwarded(port, first) {
+		t.Fatal("oldest entry not evicted")
+	}
+}
+
+func TestMarkForwardedExpires(t *testing.T) {
+	port := t.Name()
+	defer func() {
+		forwardedMu.Lock()
+		delete(forwarded, port)
+		forwardedMu.Unlock()
+	}()
+
+	sms := models.ModemSMS{Indices: []int{3}, PhoneNumber: "10086", Time: "2026/10/16 12:00:00", Text: "hi"}
+	if !markForwarded(port, sms) || markForwarded(port, sms) {
+		t.Fatal("duplicate not detected")
+	}
+
+	forwardedMu.Lock()
+	for key := range forwarded[port] {
+		forwarded[port][key] = time.Now().Add(-forwardedTTL)
+	}
+	forwardedMu.Unlock()
+	if !markForwarded(port, sms) {
+		t.Fatal("expired entry still suppresses forwarding")
+	}
+}
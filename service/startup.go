@@ -0,0 +1,106 @@
+package service
+
+import (
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+var (
+	startupMu     sync.RWMutex
+	startupReport *models.StartupReport
+)
+
+// StartupCheck 扫描并连接设备，收集每个设备的身份和错误信息，输出汇总日志
+func (m *ModemService) StartupCheck() *models.StartupReport {
+	report := &models.StartupReport{
+		Time:    time.Now(),
+		Devices: m.ScanModems(),
+	}
+
+	for i := range report.Devices {
+		probe := &report.Devices[i]
+		if !probe.Connected {
+			continue
+		}
+		report.Connected++
+		modem, err := m.GetConnect(probe.Name)
+		if err != nil {
+			continue
+		}
+		probe.Manufacturer, _ = modem.GetManufacturer()
+		probe.Model, _ = modem.GetModel()
+		err = modem.RetrySIMBusy(func() (err error) {
+			probe.SIMStatus, err = modem.GetSIMStatus()
+			return err
+		})
+		if err != nil {
+			probe.Hint = "SIM not detected, check that the card is inserted"
+			continue
+		}
+		if probe.SIMStatus != "READY" {
+			probe.Hint = "SIM is locked or not ready, unlock it with the PIN/PUK"
+			continue
+		}
+		modem.RetrySIMBusy(func() (err error) {
+			probe.IMSI, err = modem.GetIMSI()
+			return err
+		})
+	}
+
+	if len(report.Devices) == 0 {
+		report.Hint = "no candidate devices found, check the modem is plugged in or set MODEM_PORT"
+	} else if report.Connected == 0 {
+		report.Hint = "no modem connected, see per-device hints"
+	}
+
+	// 输出汇总日志
+	slog.Info("startup check", slog.Int("probed", len(report.Devices)), slog.Int("connected", report.Connected))
+	for _, d := range report.Devices {
+		if d.Connected {
+			slog.Info("startup check: device connected", slog.String("device", d.Device), slog.String("vendor", d.Vendor),
+				slog.String("model", d.Model), slog.String("imsi", d.IMSI), slog.String("sim", d.SIMStatus))
+		} else {
+			slog.Warn("startup check: device not connected", slog.String("device", d.Device), slog.String("error", d.Error), slog.String("hint", d.Hint))
+		}
+	}
+	if report.Hint != "" {
+		slog.Warn("startup check: " + report.Hint)
+	}
+
+	startupMu.Lock()
+	startupReport = report
+	startupMu.Unlock()
+
+	return report
+}
+
+// StartupReport 返回最近一次启动自检报告，未执行时返回 nil
+func (m *ModemService) StartupReport() *models.StartupReport {
+	startupMu.RLock()
+	defer startupMu.RUnlock()
+	return startupReport
+}
+
+// probeHint 根据连接错误给出排查建议
+func probeHint(err error) string {
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "permission denied") || strings.Contains(msg, "access is denied"):
+		if runtime.GOOS == "windows" {
+			return "port is in use or access denied, close other programs using it"
+		}
+		return "permission denied, add the user to the dialout group: sudo usermod -aG dialout $USER"
+	case strings.Contains(msg, "busy"):
+		return "port is busy, stop ModemManager or other programs using it"
+	case strings.Contains(msg, "no such file") || strings.Contains(msg, "cannot find"):
+		return "device not found, check the port name or MODEM_PORT"
+	case strings.Contains(msg, "at test failed"):
+		return "no AT response, this may be a diagnostic/NMEA interface rather than the AT port"
+	}
+	return ""
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// maxSMSTemplateBody 模板内容的最大长度
+const maxSMSTemplateBody = 4096
+
+// maxSMSTemplateOutput 渲染结果的最大长度，约为 255 个分段的 UCS2 长短信
+const maxSMSTemplateOutput = 255 * 134
+
+// smsTemplateFuncs 模板中允许使用的内置函数，其余函数（如 call，以及可指定任意宽度的 printf）一律拒绝
+var smsTemplateFuncs = map[string]bool{
+	"and": true, "or": true, "not": true, "len": true, "index": true, "print": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+}
+
+// errTemplateOutputTooLong 渲染结果超过 maxSMSTemplateOutput
+var errTemplateOutputTooLong = fmt.Errorf("rendered message exceeds %d bytes", maxSMSTemplateOutput)
+
+// limitedWriter 超过长度上限时返回错误，模板执行随之终止
+type limitedWriter struct {
+	sb    strings.Builder
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.sb.Len()+len(p) > w.limit {
+		return 0, errTemplateOutputTooLong
+	}
+	return w.sb.Write(p)
+}
+
+// parseSMSTemplate 解析模板并检查只使用了变量、条件和允许的函数
+func parseSMSTemplate(body string) (*template.Template, error) {
+	if len(body) > maxSMSTemplateBody {
+		return nil, fmt.Errorf("%w: body must be at most %d bytes", ErrInvalid, maxSMSTemplateBody)
+	}
+
+	t, err := template.New("sms").Option("missingkey=error").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: body: %v", ErrInvalid, err)
+	}
+	if len(t.Templates()) > 1 {
+		return nil, fmt.Errorf("%w: body: define and block are not allowed", ErrInvalid)
+	}
+	if t.Tree != nil {
+		if err := checkTemplateNode(t.Tree.Root); err != nil {
+			return nil, fmt.Errorf("%w: body: %v", ErrInvalid, err)
+		}
+	}
+	return t, nil
+}
+
+// checkTemplateNode 遍历语法树，拒绝嵌套模板、range 循环和未允许的函数
+func checkTemplateNode(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, c := range n.Nodes {
+			if err := checkTemplateNode(c); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return checkTemplateNode(n.Pipe)
+	case *parse.IfNode:
+		return checkTemplateBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		return fmt.Errorf("range is not allowed")
+	case *parse.WithNode:
+		return checkTemplateBranch(&n.BranchNode)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			if err := checkTemplateNode(cmd); err != nil {
+				return err
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if err := checkTemplateNode(arg); err != nil {
+				return err
+			}
+		}
+	case *parse.ChainNode:
+		return checkTemplateNode(n.Node)
+	case *parse.IdentifierNode:
+		if !smsTemplateFuncs[n.Ident] {
+			return fmt.Errorf("function %q is not allowed", n.Ident)
+		}
+	case *parse.TemplateNode:
+		return fmt.Errorf("template %q is not allowed", n.Name)
+	}
+	return nil
+}
+
+// checkTemplateBranch 检查 if / with 的条件以及两个分支
+func checkTemplateBranch(n *parse.BranchNode) error {
+	for _, c := range []parse.Node{n.Pipe, n.List, n.ElseList} {
+		if err := checkTemplateNode(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateSMSTemplate 校验短信模板
+func ValidateSMSTemplate(tpl *models.SMSTemplate) error {
+	tpl.Name = strings.TrimSpace(tpl.Name)
+	if tpl.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if tpl.Body == "" {
+		return fmt.Errorf("%w: body is required", ErrInvalid)
+	}
+	_, err := parseSMSTemplate(tpl.Body)
+	return err
+}
+
+// RenderSMSTemplate 使用变量渲染模板，缺少变量或结果过长时返回错误
+func RenderSMSTemplate(body string, vars map[string]string) (text string, err error) {
+	t, err := parseSMSTemplate(body)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			text, err = "", fmt.Errorf("%w: render template: %v", ErrInvalid, r)
+		}
+	}()
+
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	w := &limitedWriter{limit: maxSMSTemplateOutput}
+	if err := t.Execute(w, vars); err != nil {
+		return "", fmt.Errorf("%w: render template: %v", ErrInvalid, err)
+	}
+	if strings.TrimSpace(w.sb.String()) == "" {
+		return "", fmt.Errorf("%w: rendered message is empty", ErrInvalid)
+	}
+	return w.sb.String(), nil
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRenderSMSTemplate(t *testing.T) {
+	text, err := RenderSMSTemplate(`Code {{.code}}{{if .name}} for {{.name}}{{end}}`, map[string]string{"code": "1234", "name": "Alice"})
+	if err != nil || text != "Code 1234 for Alice" {
+		t.Fatalf("text %q, err %v", text, err)
+	}
+	if _, err := RenderSMSTemplate(`Code {{.code}}`, nil); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("missing variable: %v", err)
+	}
+}
+
+func TestSMSTemplateRejectsUnsafeConstructs(t *testing.T) {
+	for _, body := range []string{
+		`{{call .fn}}`,
+		`{{printf "%0999999999d" 1}}`,
+		`{{range $k, $v := .}}{{$v}}{{end}}`,
+		`{{define "x"}}x{{end}}{{template "x"}}`,
+		`{{html .code}}`,
+	} {
+		if _, err := parseSMSTemplate(body); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: err = %v", body, err)
+		}
+	}
+}
+
+func TestRenderSMSTemplateOutputLimit(t *testing.T) {
+	big := strings.Repeat("x", maxSMSTemplateBody/4)
+	body := strings.Repeat("{{.v}}{{.v}}{{.v}}{{.v}}", 100)
+	_, err := RenderSMSTemplate(body, map[string]string{"v": big})
+	if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+// 接口角色
+const (
+	RoleAT      = "at"      // AT 命令端口
+	RoleData    = "data"    // 数据 / PPP 端口
+	RoleGNSS    = "gnss"    // NMEA 定位输出端口
+	RoleDiag    = "diag"    // 厂商诊断端口
+	RoleAudio   = "audio"   // 语音 PCM 端口
+	RoleUnknown = "unknown" // 未知，按顺序尝试 AT
+)
+
+// usbInterfaceRoles 常见模块 USB 接口编号与角色的对应关系，按 USB 厂商 ID 组织
+var usbInterfaceRoles = map[string]map[int]string{
+	// Quectel EC2x / EG2x / EM05 等
+	"2c7c": {0: RoleDiag, 1: RoleGNSS, 2: RoleAT, 3: RoleData},
+	// SIMCom SIM7600 / SIM7500 等
+	"1e0e": {0: RoleDiag, 1: RoleGNSS, 2: RoleAT, 3: RoleData, 4: RoleAudio},
+}
+
+// interfaceGroup 同一物理模块的一组串口
+type interfaceGroup struct {
+	usbPath    string // USB 设备在 sysfs 中的路径，非 USB 设备为空
+	interfaces []models.ModemInterface
+}
+
+// groupInterfaces 按 sysfs 中的 USB 父设备对串口分组，并按接口编号标注角色
+// 无法识别 USB 父设备的串口（如 Windows COM 口）各自单独成组
+func groupInterfaces(devs []string) []*interfaceGroup {
+	groups := []*interfaceGroup{}
+	byUSB := map[string]*interfaceGroup{}
+
+	for _, dev := range devs {
+		iface := models.ModemInterface{Device: dev, Name: path.Base(dev), Interface: -1, Role: RoleUnknown}
+		iface.FriendlyName = discoverer.FriendlyName(dev)
+		usbPath, vendor, number := usbParent(iface.Name)
+		if usbPath == "" {
+			groups = append(groups, &interfaceGroup{interfaces: []models.ModemInterface{iface}})
+			continue
+		}
+
+		iface.Interface = number
+		if role, ok := usbInterfaceRoles[vendor][number]; ok {
+			iface.Role = role
+		}
+		group, ok := byUSB[usbPath]
+		if !ok {
+			group = &interfaceGroup{usbPath: usbPath}
+			byUSB[usbPath] = group
+			groups = append(groups, group)
+		}
+		group.interfaces = append(group.interfaces, iface)
+	}
+
+	for _, group := range groups {
+		sort.SliceStable(group.interfaces, func(i, j int) bool {
+			return group.interfaces[i].Interface < group.interfaces[j].Interface
+		})
+	}
+	return groups
+}
+
+// usbParent 通过 /sys/class/tty/<name>/device 查找所属 USB 接口和设备
+// 返回 USB 设备路径、厂商 ID 和接口编号
+func usbParent(name string) (string, string, int) {
+	dir, err := filepath.EvalSymlinks(filepath.Join("/sys/class/tty", name, "device"))
+	if err != nil {
+		return "", "", -1
+	}
+
+	// ttyACM 直接指向 USB 接口，ttyUSB 指向接口下的 usb-serial 端口
+	for ; dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+		number, err := readSysfs(dir, "bInterfaceNumber")
+		if err != nil {
+			continue
+		}
+		n, err := strconv.ParseInt(number, 16, 32)
+		if err != nil {
+			return "", "", -1
+		}
+		usbPath := filepath.Dir(dir)
+		vendor, _ := readSysfs(usbPath, "idVendor")
+		return usbPath, vendor, int(n)
+	}
+	return "", "", -1
+}
+
+// readSysfs 读取 sysfs 属性文件
+func readSysfs(dir, name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// candidates 返回尝试 AT 连接的接口顺序：已识别的 AT 端口优先，其次未知和数据端口
+// 定位、诊断和音频端口不会响应 AT 命令，直接跳过
+func (g *interfaceGroup) candidates() ([]models.ModemInterface, []models.ModemInterface) {
+	var try, skip []models.ModemInterface
+	for _, role := range []string{RoleAT, RoleUnknown, RoleData} {
+		for _, iface := range g.interfaces {
+			if iface.Role == role {
+				try = append(try, iface)
+			}
+		}
+	}
+	for _, iface := range g.interfaces {
+		if iface.Role == RoleGNSS || iface.Role == RoleDiag || iface.Role == RoleAudio {
+			skip = append(skip, iface)
+		}
+	}
+	return try, skip
+}
+
+// withRole 返回接口列表副本，并将指定接口标记为给定角色
+func (g *interfaceGroup) withRole(name, role string) []models.ModemInterface {
+	ifaces := append([]models.ModemInterface{}, g.interfaces...)
+	for i := range ifaces {
+		if ifaces[i].Name == name {
+			ifaces[i].Role = role
+		}
+	}
+	return ifaces
+}
+
+// InterfaceFor 返回同一模块中指定角色的串口设备路径，不存在时返回空
+func (m *ModemInfo) InterfaceFor(role string) string {
+	for _, iface := range m.Interfaces {
+		if iface.Role == role {
+			return iface.Device
+		}
+	}
+	return ""
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rehiy/modem/sms/gsm7"
+	"github.com/rehiy/modem/sms/tpdu"
+	"github.com/rehiy/modem/sms/ucs2"
+	"github.com/rehiy/web-modem/models"
+)
+
+const (
+	ussdTimeout   = 30 * time.Second // 等待 +CUSD 响应的最长时间
+	ussdTapBuffer = 16
+)
+
+// ussdStatus +CUSD <m> 描述
+var ussdStatus = map[int]string{
+	0: "no further action required",
+	1: "further user action required",
+	2: "terminated by network",
+	3: "other local client has responded",
+	4: "operation not supported",
+	5: "network time out",
+}
+
+// ussdCodeRe 允许发送的 USSD 字符串
+var ussdCodeRe = regexp.MustCompile(`^[0-9*#+]{1,160}$`)
+
+// ussdSession USSD 会话状态
+type ussdSession struct {
+	mu      sync.Mutex // 串行化 USSD 请求
+	stateMu sync.Mutex
+	active  bool                 // 网络是否等待用户回复
+	dcs     int                  // 最近一次响应的编码方案
+	last    *models.USSDResponse // 最近一次响应，包括网络主动发起的 USSD
+	results chan *models.USSDResponse
+}
+
+// watchUSSD 监听串口上的 +CUSD 行
+// 使用原始行而不是 URC 参数，避免响应文本中的逗号被拆分
+func (m *ModemInfo) watchUSSD() {
+	lines, stop := m.port.Tap(ussdTapBuffer)
+	defer stop()
+
+	for {
+		select {
+		case line := <-lines:
+			if !strings.HasPrefix(line, "+CUSD:") {
+				continue
+			}
+			resp, err := parseCUSD(line)
+			if err != nil {
+				continue
+			}
+			m.recordUSSD(resp)
+		case <-time.After(5 * time.Second):
+			if !m.IsOpen() {
+				return
+			}
+		}
+	}
+}
+
+// recordUSSD 更新会话状态并通知等待中的请求
+func (m *ModemInfo) recordUSSD(resp *models.USSDResponse) {
+	s := &m.ussd
+	s.stateMu.Lock()
+	s.active = resp.SessionActive
+	s.dcs = resp.DCS
+	s.last = resp
+	s.stateMu.Unlock()
+
+	ModemEvent.Publish(EventUSSD, m.Name, *resp)
+	select {
+	case s.results <- resp:
+	default:
+	}
+}
+
+// SendUSSD 发起 USSD 请求（如 *100#），等待网络响应
+func (m *ModemInfo) SendUSSD(code string) (*models.USSDResponse, error) {
+	if !ussdCodeRe.MatchString(code) {
+		return nil, fmt.Errorf("%w: ussd code %q", ErrInvalid, code)
+	}
+	return m.ussdRequest(code)
+}
+
+// ContinueUSSD 在网络等待回复时发送用户输入（如菜单选项）
+func (m *ModemInfo) ContinueUSSD(reply string) (*models.USSDResponse, error) {
+	if reply == "" || len(reply) > 160 || strings.ContainsAny(reply, "\"\r\n") {
+		return nil, fmt.Errorf("%w: ussd reply %q", ErrInvalid, reply)
+	}
+	m.ussd.stateMu.Lock()
+	active := m.ussd.active
+	m.ussd.stateMu.Unlock()
+	if !active {
+		return nil, fmt.Errorf("%w: no active ussd session", ErrInvalid)
+	}
+	return m.ussdRequest(reply)
+}
+
+// CancelUSSD 结束当前 USSD 会话
+func (m *ModemInfo) CancelUSSD() error {
+	m.ussd.stateMu.Lock()
+	m.ussd.active = false
+	m.ussd.stateMu.Unlock()
+
+	responses, err := m.SendCommand("AT+CUSD=2")
+	if err != nil {
+		return err
+	}
+	return finalError(responses)
+}
+
+// LastUSSD 返回最近一次 USSD 响应，没有时返回 nil
+func (m *ModemInfo) LastUSSD() *models.USSDResponse {
+	m.ussd.stateMu.Lock()
+	defer m.ussd.stateMu.Unlock()
+	return m.ussd.last
+}
+
+// ussdRequest 发送 AT+CUSD=1,"<str>",15 并等待 +CUSD 响应
+// 响应可能在 OK 之前或之后到达，均由 watchUSSD 统一接收
+func (m *ModemInfo) ussdRequest(str string) (*models.USSDResponse, error) {
+	s := &m.ussd
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.results) > 0 {
+		<-s.results
+	}
+
+	responses, err := m.SendCommand(fmt.Sprintf(`AT+CUSD=1,"%s",15`, str))
+	if err != nil {
+		return nil, err
+	}
+	if err := finalError(responses); err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-s.results:
+		if resp.Status == 4 {
+			return resp, fmt.Errorf("ussd %w by network", ErrUnsupported)
+		}
+		return resp, nil
+	case <-time.After(ussdTimeout):
+		return nil, fmt.Errorf("ussd response timeout")
+	}
+}
+
+// parseCUSD 解析 +CUSD: <m>[,"<str>"[,<dcs>]]，文本中可能包含逗号
+func parseCUSD(line string) (*models.USSDResponse, error) {
+	body := strings.TrimSpace(strings.TrimPrefix(line, "+CUSD:"))
+	head, rest, _ := strings.Cut(body, ",")
+	status, err := strconv.Atoi(strings.TrimSpace(head))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ussd status %q", head)
+	}
+
+	resp := &models.USSDResponse{
+		Status:        status,
+		StatusText:    ussdStatus[status],
+		SessionActive: status == 1,
+		Time:          time.Now(),
+	}
+
+	// 文本位于第一个和最后一个引号之间，其后为 dcs
+	if i, j := strings.Index(rest, `"`), strings.LastIndex(rest, `"`); i >= 0 && j > i {
+		resp.Text = rest[i+1 : j]
+		if _, dcs, ok := strings.Cut(rest[j+1:], ","); ok {
+			resp.DCS, _ = strconv.Atoi(strings.TrimSpace(dcs))
+		}
+	}
+	resp.Text = decodeUSSD(resp.Text, resp.DCS)
+	return resp, nil
+}
+
+// decodeUSSD 按编码方案（3GPP TS 23.038 第 5 节）解码 USSD 文本
+// 模块通常直接返回字符；UCS2 编码或 TE 字符集为 UCS2 时返回十六进制，
+// 部分模块（如华为）对 GSM7 编码返回打包后的七位组十六进制
+// 只有十六进制内容能解码为可打印文本时才采用解码结果，否则原样返回
+func decodeUSSD(text string, dcs int) string {
+	switch ussdAlphabet(dcs) {
+	case tpdu.AlphaUCS2:
+		hexText := text
+		if dcs == 0x11 && len(hexText) > 4 {
+			hexText = hexText[4:] // 前两个八位组为 GSM7 编码的语言代码
+		}
+		if decoded, ok := decodeUSSDUCS2(hexText); ok {
+			return decoded
+		}
+	case tpdu.Alpha7Bit:
+		if decoded, ok := decodeUSSDHex(text); ok {
+			text = decoded
+		}
+		if dcs == 0x10 && utf8.RuneCountInString(text) > 3 {
+			text = string([]rune(text)[3:]) // 前三个字符为语言代码和 CR
+		}
+	}
+	return text
+}
+
+// decodeUSSDHex 解码 GSM7 编码下模块返回的十六进制文本
+// TE 字符集为 UCS2 时解码结果应仍在 GSM7 字符范围内，借此与打包的七位组区分
+func decodeUSSDHex(text string) (string, bool) {
+	if decoded, ok := decodeUSSDUCS2(text); ok {
+		if _, err := gsm7.Encode([]byte(decoded)); err == nil {
+			return decoded, true
+		}
+	}
+	return decodeUSSDGSM7(text)
+}
+
+// ussdAlphabet 按 CBS 编码方案判断字母表，未定义的取值按 GSM7 处理
+func ussdAlphabet(dcs int) tpdu.Alphabet {
+	switch {
+	case dcs == 0x11:
+		return tpdu.AlphaUCS2
+	case dcs&0xc0 == 0x40, dcs&0xf0 == 0x90:
+		// 通用编码和带用户数据头的消息，bit 3-2 为字母表
+		switch dcs & 0x0c {
+		case 0x04:
+			return tpdu.Alpha8Bit
+		case 0x08:
+			return tpdu.AlphaUCS2
+		}
+	case dcs&0xf0 == 0xf0:
+		if dcs&0x04 != 0 {
+			return tpdu.Alpha8Bit
+		}
+	}
+	return tpdu.Alpha7Bit
+}
+
+// decodeUSSDUCS2 解码 UCS2 十六进制文本
+func decodeUSSDUCS2(text string) (string, bool) {
+	if !isHexText(text) || len(text)%4 != 0 {
+		return "", false
+	}
+	data, err := hex.DecodeString(text)
+	if err != nil {
+		return "", false
+	}
+	runes, err := ucs2.Decode(data)
+	if err != nil {
+		return "", false
+	}
+	return string(runes), isPrintableText(string(runes))
+}
+
+// decodeUSSDGSM7 解码打包后的 GSM7 七位组十六进制文本
+func decodeUSSDGSM7(text string) (string, bool) {
+	if !isHexText(text) {
+		return "", false
+	}
+	data, err := hex.DecodeString(text)
+	if err != nil {
+		return "", false
+	}
+	decoded, err := gsm7.Decode(gsm7.Unpack7BitUSSD(data, 0))
+	if err != nil {
+		return "", false
+	}
+	return string(decoded), isPrintableText(string(decoded))
+}
+
+// isHexText 是否为偶数长度的十六进制文本
+func isHexText(s string) bool {
+	if len(s) < 2 || len(s)%2 != 0 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// isPrintableText 是否只包含可打印字符和换行
+func isPrintableText(s string) bool {
+	for _, r := range s {
+		if !unicode.IsPrint(r) && r != '\n' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/rehiy/modem/sms/gsm7"
+	"github.com/rehiy/modem/sms/ucs2"
+)
+
+// packedUSSD 生成打包后的 GSM7 七位组十六进制文本
+func packedUSSD(t *testing.T, text string) string {
+	t.Helper()
+	septets, err := gsm7.Encode([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.ToUpper(hex.EncodeToString(gsm7.Pack7BitUSSD(septets, 0)))
+}
+
+// ucs2Hex 生成 UCS2 十六进制文本
+func ucs2Hex(text string) string {
+	return strings.ToUpper(hex.EncodeToString(ucs2.Encode([]rune(text))))
+}
+
+func TestDecodeUSSD(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		dcs  int
+		want string
+	}{
+		{"plain gsm7", "Balance: 12.50 USD", 15, "Balance: 12.50 USD"},
+		{"packed gsm7", packedUSSD(t, "Your balance is 10"), 15, "Your balance is 10"},
+		{"packed gsm7 dcs 0", packedUSSD(t, "Menu"), 0, "Menu"},
+		{"ucs2 te charset", ucs2Hex("Balance 5"), 15, "Balance 5"},
+		{"ucs2 dcs 72", ucs2Hex("余额 10 元"), 72, "余额 10 元"},
+		{"ucs2 general", ucs2Hex("余额"), 0x48 | 0x10, "余额"},
+		{"ucs2 with language", "0000" + ucs2Hex("余额"), 0x11, "余额"},
+		{"gsm7 with language", "en\rHello", 0x10, "Hello"},
+		{"packed gsm7 with language", packedUSSD(t, "en\rHello"), 0x10, "Hello"},
+		{"8bit untouched", "48656C6C6F", 0x44, "48656C6C6F"},
+		{"invalid ucs2 untouched", "ABC", 72, "ABC"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := decodeUSSD(c.text, c.dcs); got != c.want {
+				t.Errorf("decodeUSSD(%q, %d) = %q, want %q", c.text, c.dcs, got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseCUSD(t *testing.T) {
+	resp, err := parseCUSD(`+CUSD: 1,"` + ucs2Hex("1. 查询, 2. 充值") + `",72`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != 1 || !resp.SessionActive || resp.DCS != 72 || resp.Text != "1. 查询, 2. 充值" {
+		t.Fatalf("resp = %+v", resp)
+	}
+
+	resp, err = parseCUSD(`+CUSD: 0,"Balance: 1,234.00",15`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "Balance: 1,234.00" || resp.DCS != 15 {
+		t.Fatalf("resp = %+v", resp)
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrUnsupported 当前模块不支持该功能
+	ErrUnsupported = errors.New("not supported")
+	// ErrInvalid 请求参数无效
+	ErrInvalid = errors.New("invalid argument")
+)
+
+// 厂商标识
+const (
+	VendorGeneric = "generic"
+	VendorQuectel = "quectel"
+	VendorHuawei  = "huawei"
+	VendorSimcom  = "simcom"
+	VendorFibocom = "fibocom"
+	VendorZTE     = "zte"
+	VendorSierra  = "sierra"
+)
+
+// vendorKeywords 制造商信息关键字与厂商标识的对应关系
+var vendorKeywords = map[string]string{
+	"quectel": VendorQuectel,
+	"huawei":  VendorHuawei,
+	"simcom":  VendorSimcom,
+	"fibocom": VendorFibocom,
+	"zte":     VendorZTE,
+	"sierra":  VendorSierra,
+}
+
+// detectVendor 根据 AT+CGMI 返回的制造商信息识别厂商
+func detectVendor(manufacturer string) string {
+	s := strings.ToLower(manufacturer)
+	for keyword, vendor := range vendorKeywords {
+		if strings.Contains(s, keyword) {
+			return vendor
+		}
+	}
+	return VendorGeneric
+}
+
+// vendorCommands 厂商专有命令，按 功能 -> 厂商 组织，空厂商表示通用命令
+var vendorCommands = map[string]map[string]string{
+	"pin_retries": {
+		VendorQuectel: "AT+QPINC?",
+		VendorHuawei:  "AT^CPIN?",
+		"":            "AT+CPINR",
+	},
+	"location": {
+		VendorQuectel: "AT+QGPSLOC=2",
+		VendorSimcom:  "AT+CGPSINFO",
+	},
+	"active_band": {
+		VendorQuectel: `AT+QENG="servingcell"`,
+		VendorHuawei:  "AT^HFREQINFO?",
+		VendorSimcom:  "AT+CPSI?",
+	},
+	"serving_cell": {
+		VendorQuectel: `AT+QENG="servingcell"`,
+		VendorHuawei:  "AT^MONSC",
+		VendorSimcom:  "AT+CPSI?",
+		VendorFibocom: "AT+GTCCINFO?",
+	},
+	"cell_lock": {
+		VendorQuectel: `AT+QNWLOCK="common/4g"`,
+	},
+	"cell_lock_set": {
+		VendorQuectel: `AT+QNWLOCK="common/4g",1,%d,%d`,
+	},
+	"cell_lock_clear": {
+		VendorQuectel: `AT+QNWLOCK="common/4g",0`,
+	},
+	"esim_list": {
+		VendorQuectel: `AT+QESIM="list"`,
+	},
+	"esim_enable": {
+		VendorQuectel: `AT+QESIM="enable","%s"`,
+	},
+	"esim_disable": {
+		VendorQuectel: `AT+QESIM="disable","%s"`,
+	},
+	"esim_delete": {
+		VendorQuectel: `AT+QESIM="delete","%s"`,
+	},
+	"sms_bearer": {
+		VendorQuectel: `AT+QCFG="ims"`,
+	},
+	"sms_bearer_set": {
+		VendorQuectel: `AT+QCFG="ims",%d`,
+	},
+}
+
+// vendorCommand 获取指定功能在当前厂商下使用的命令
+func vendorCommand(feature, vendor string) (string, bool) {
+	commands, ok := vendorCommands[feature]
+	if !ok {
+		return "", false
+	}
+	if cmd, ok := commands[vendor]; ok {
+		return cmd, true
+	}
+	cmd, ok := commands[""]
+	return cmd, ok
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rehiy/web-modem/models"
+)
+
+const (
+	watchdogInterval        = 30 * time.Second  // 信号检查间隔
+	watchdogDefaultMinDBM   = -105              // 默认信号阈值（dBm）
+	watchdogDefaultDuration = 600               // 默认持续时长（秒）
+	watchdogMinDuration     = 60                // 最短持续时长（秒），避免频繁重选
+	copsTimeout             = 180 * time.Second // 运营商重选最长等待时间
+	cfunTimeout             = 15 * time.Second  // 射频开关最长等待时间
+)
+
+// 重选方式
+const (
+	WatchdogCOPS = "cops" // AT+COPS=0 自动重选运营商
+	WatchdogCFUN = "cfun" // AT+CFUN=0/1 重启射频后重新注册
+)
+
+// ReselectData 运营商重选事件数据
+type ReselectData struct {
+	Action   string `json:"action"`
+	DBM      int    `json:"dbm"`
+	WeakFor  int    `json:"weakFor"` // 信号低于阈值的时长（秒）
+	Error    string `json:"error,omitempty"`
+	Operator string `json:"operator,omitempty"` // 重选后的运营商
+}
+
+// validateWatchdog 校验看门狗配置
+func validateWatchdog(config *models.ModemConfig) error {
+	if config.WatchdogMinDBM != 0 && (config.WatchdogMinDBM < -120 || config.WatchdogMinDBM > -50) {
+		return fmt.Errorf("%w: watchdog_min_dbm must be -120 to -50", ErrInvalid)
+	}
+	if config.WatchdogDuration != 0 && config.WatchdogDuration < watchdogMinDuration {
+		return fmt.Errorf("%w: watchdog_duration must be at least %d seconds", ErrInvalid, watchdogMinDuration)
+	}
+	switch config.WatchdogAction {
+	case "", WatchdogCOPS, WatchdogCFUN:
+	default:
+		return fmt.Errorf("%w: watchdog_action must be cops or cfun", ErrInvalid)
+	}
+	return nil
+}
+
+// runWatchdog 弱信号看门狗，连接期间在后台运行
+// 仅在模块配置开启时检查；数据模式和通话期间跳过，避免打断会话
+func (m *ModemInfo) runWatchdog() {
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+
+	var weakSince time.Time
+	for range ticker.C {
+		if !m.IsOpen() {
+			return
+		}
+
+		config, err := m.GetConfig()
+		if err != nil || !config.Watchdog || m.InDataMode() || m.inCall() {
+			weakSince = time.Time{}
+			continue
+		}
+
+		signal, err := m.GetSignal()
+		if err != nil {
+			continue
+		}
+		minDBM := config.WatchdogMinDBM
+		if minDBM == 0 {
+			minDBM = watchdogDefaultMinDBM
+		}
+		// RSSI 为 99 表示无信号，同样视为弱信号
+		if signal.RSSI != 99 && signal.DBM >= minDBM {
+			weakSince = time.Time{}
+			continue
+		}
+		if weakSince.IsZero() {
+			weakSince = time.Now()
+			continue
+		}
+
+		duration := config.WatchdogDuration
+		if duration == 0 {
+			duration = watchdogDefaultDuration
+		}
+		weakFor := time.Since(weakSince)
+		if weakFor < time.Duration(duration)*time.Second {
+			continue
+		}
+
+		action := config.WatchdogAction
+		if action == "" {
+			action = WatchdogCOPS
+		}
+		m.reselect(action, signal.DBM, weakFor)
+		weakSince = time.Time{}
+	}
+}
+
+// reselect 执行运营商重选并广播 operator_reselect 事件
+func (m *ModemInfo) reselect(action string, dbm int, weakFor time.Duration) {
+	slog.Warn("weak signal, reselecting operator", slog.String("port", m.Name), slog.Int("dbm", dbm), slog.Duration("weak_for", weakFor.Round(time.Second)), slog.String("action", action))
+
+	var err error
+	switch action {
+	case WatchdogCFUN:
+		if err = m.sendCheck("AT+CFUN=0", cfunTimeout); err == nil {
+			err = m.sendCheck("AT+CFUN=1", cfunTimeout)
+		}
+	default:
+		err = m.sendCheck("AT+COPS=0", copsTimeout)
+	}
+
+	data := ReselectData{Action: action, DBM: dbm, WeakFor: int(weakFor.Seconds())}
+	if err != nil {
+		data.Error = err.Error()
+		slog.Error("operator reselection failed", slog.String("port", m.Name), slog.Any("error", err))
+	} else if operator, err := m.GetOperatorInfo(); err == nil {
+		data.Operator = operator.Name
+	}
+	ModemEvent.Publish(EventOperatorReselect, m.Name, data)
+}
+
+// sendCheck 发送命令并检查最终响应
+func (m *ModemInfo) sendCheck(cmd string, timeout time.Duration) error {
+	responses, err := m.SendCommandWithTimeout(cmd, timeout)
+	if err != nil {
+		return err
+	}
+	return finalError(responses)
+}
+
+// inCall 是否有进行中的通话
+func (m *ModemInfo) inCall() bool {
+	m.calls.mu.Lock()
+	defer m.calls.mu.Unlock()
+	return m.calls.current != nil
+}
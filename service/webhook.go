@@ -2,16 +2,18 @@ package service
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/rehiy/modem/at"
 	"github.com/rehiy/web-modem/database"
 	"github.com/rehiy/web-modem/models"
 )
@@ -26,9 +28,10 @@ var (
 	cacheTTL         = 30 * time.Second // 缓存30秒
 )
 
-// atSMSToModelSMS 将 at.SMS 转换为 models.SMS
-func atSMSToModelSMS(smsData at.SMS, receiveNumber string) *models.SMS {
+// atSMSToModelSMS 将模块短信转换为 models.SMS
+func atSMSToModelSMS(smsData models.ModemSMS, modem, receiveNumber string) *models.SMS {
 	return &models.SMS{
+		Modem:         modem,
 		Content:       smsData.Text,
 		SMSIDs:        database.IntArrayToString(smsData.Indices),
 		ReceiveTime:   parseSMSTime(smsData.Time),
@@ -38,6 +41,33 @@ func atSMSToModelSMS(smsData at.SMS, receiveNumber string) *models.SMS {
 	}
 }
 
+// storedToModemSMS 将数据库中的接收短信转换为模块短信格式，已读状态取自数据库
+func storedToModemSMS(sms models.SMS) models.ModemSMS {
+	status := "REC UNREAD"
+	if sms.Read {
+		status = "REC READ"
+	}
+	indices := []int{}
+	for _, s := range strings.Split(sms.SMSIDs, ",") {
+		if i, err := strconv.Atoi(s); err == nil {
+			indices = append(indices, i)
+		}
+	}
+	index := 0
+	if len(indices) > 0 {
+		index = indices[0]
+	}
+	return models.ModemSMS{
+		PhoneNumber: sms.SendNumber,
+		Text:        sms.Content,
+		Time:        sms.ReceiveTime.Format("2006/01/02 15:04:05"),
+		ReceivedAt:  sms.ReceiveTime,
+		Index:       index,
+		Indices:     indices,
+		Status:      status,
+	}
+}
+
 // parseSMSTime 解析短信时间字符串
 func parseSMSTime(timeStr string) time.Time {
 	if timeStr == "" {
@@ -101,132 +131,299 @@ func (w *WebhookService) TriggerWebhooks(sms *models.SMS) error {
 		return fmt.Errorf("failed to get enabled webhooks: %w", err)
 	}
 
+	webhooks = routeWebhooks(filterWebhooks(webhooks, models.WebhookSMS), sms.Modem)
 	if len(webhooks) == 0 {
-		log.Printf("[Webhook] No enabled webhooks found")
+		slog.Debug("webhook: no enabled webhooks found")
 		return nil
 	}
 
-	// 使用并发控制触发webhook
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, 5) // 限制并发数为5
-
+	// 加入投递队列，由后台任务发送和重试
 	for _, webhook := range webhooks {
-		wg.Add(1)
-		semaphore <- struct{}{} // 获取信号量
+		w.triggerWebhook(&webhook, sms)
+	}
+	slog.Info("webhook: queued for sms", slog.Int("count", len(webhooks)))
 
-		go func(wh models.Webhook) {
-			defer wg.Done()
-			defer func() { <-semaphore }() // 释放信号量
+	return nil
+}
 
-			w.triggerWebhook(&wh, sms)
-		}(webhook)
+// TriggerCallWebhooks 通话状态变化时触发类型为 call 的 webhook
+func (w *WebhookService) TriggerCallWebhooks(modem string, record models.CallRecord) error {
+	if !database.IsWebhookEnabled() {
+		return nil
 	}
 
-	wg.Wait()
-	log.Printf("[Webhook] Successfully triggered %d webhooks for SMS", len(webhooks))
+	webhooks, err := w.getCachedWebhooks()
+	if err != nil {
+		return fmt.Errorf("failed to get enabled webhooks: %w", err)
+	}
 
+	event := callEvent(modem, record)
+	for _, webhook := range routeWebhooks(filterWebhooks(webhooks, models.WebhookCall), modem) {
+		w.sendEvent(&webhook, event)
+	}
 	return nil
 }
 
-// triggerWebhook 触发单个webhook，支持重试机制
-func (w *WebhookService) triggerWebhook(webhook *models.Webhook, sms *models.SMS) error {
-	maxRetries := 3
-	retryDelay := 2 * time.Second
-
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		if attempt > 0 {
-			log.Printf("[Webhook] Retry attempt %d for webhook %s", attempt, webhook.Name)
-			time.Sleep(retryDelay)
-			retryDelay *= 2 // 指数退避
+// webhookEventTypes webhook 可订阅的事件类型
+var webhookEventTypes = map[string]bool{
+	models.WebhookSMS: true, models.WebhookCall: true, models.WebhookAll: true,
+	EventCallState: true, EventSignal: true, EventUSSD: true, EventRegistration: true,
+	EventDelivery: true, EventModemReset: true, EventConnect: true, EventDisconnect: true,
+	EventOperatorReselect: true, EventURC: true, PortReconnected: true, PortDisconnected: true,
+}
+
+// ValidWebhookType 是否为 webhook 可订阅的事件类型
+func ValidWebhookType(typ string) bool {
+	return webhookEventTypes[typ]
+}
+
+// StartEventDispatch 订阅模块事件，投递给订阅该事件类型的 webhook
+// sms 和 call 事件由 TriggerWebhooks / TriggerCallWebhooks 投递，这里跳过以免重复
+func (w *WebhookService) StartEventDispatch() {
+	_, events, _ := ModemEvent.SubscribeWithPolicy(ModemEvent.Latest(), 256, BackpressureDropOldest)
+	go func() {
+		for event := range events {
+			if event.Type == EventSMS || event.Type == EventCall {
+				continue
+			}
+			if err := w.DispatchEvent(event); err != nil {
+				slog.Error("webhook: failed to dispatch event", slog.String("type", event.Type), slog.Any("error", err))
+			}
 		}
+	}()
+}
 
-		// 准备payload
-		payload, err := w.preparePayload(webhook, sms)
-		if err != nil {
-			log.Printf("[Webhook] Failed to prepare payload for %s: %v", webhook.Name, err)
-			return err // 模板错误不重试
+// DispatchEvent 将模块事件投递给类型匹配（或为 *）的 webhook
+func (w *WebhookService) DispatchEvent(e Event) error {
+	if !database.IsWebhookEnabled() {
+		return nil
+	}
+
+	webhooks, err := w.getCachedWebhooks()
+	if err != nil {
+		return fmt.Errorf("failed to get enabled webhooks: %w", err)
+	}
+
+	event := hubEvent(e)
+	for _, webhook := range routeWebhooks(filterWebhooks(webhooks, e.Type), e.Port) {
+		w.sendEvent(&webhook, event)
+	}
+	return nil
+}
+
+// filterWebhooks 按事件类型筛选 webhook，未设置类型的视为 sms，类型为 * 的接收全部事件
+func filterWebhooks(webhooks []models.Webhook, typ string) []models.Webhook {
+	result := []models.Webhook{}
+	for _, wh := range webhooks {
+		if wh.Type == typ || wh.Type == models.WebhookAll || (wh.Type == "" && typ == models.WebhookSMS) {
+			result = append(result, wh)
 		}
+	}
+	return result
+}
 
-		// 发送请求
-		client := &http.Client{
-			Timeout: 30 * time.Second,
+// routeWebhooks 按短信来源模块选择 webhook
+// 优先使用绑定该模块（端口名或 IMEI）的 webhook，没有则使用全局 webhook
+func routeWebhooks(webhooks []models.Webhook, modem string) []models.Webhook {
+	keys := map[string]bool{}
+	if modem != "" {
+		keys[modem] = true
+		if conn, err := GetModemService().GetConnect(modem); err == nil {
+			if imei := conn.GetIdentity().IMEI; imei != "" {
+				keys[imei] = true
+			}
 		}
+	}
 
-		req, err := http.NewRequest("POST", webhook.URL, bytes.NewBuffer(payload))
-		if err != nil {
-			log.Printf("[Webhook] Failed to create request for %s: %v", webhook.Name, err)
-			continue // 请求创建错误重试
+	matched, global := []models.Webhook{}, []models.Webhook{}
+	for _, wh := range webhooks {
+		switch {
+		case wh.Modem == "":
+			global = append(global, wh)
+		case keys[wh.Modem]:
+			matched = append(matched, wh)
 		}
+	}
 
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("User-Agent", "Web-Modem/1.0")
+	if len(matched) > 0 {
+		return matched
+	}
+	return global
+}
 
-		start := time.Now()
-		resp, err := client.Do(req)
-		duration := time.Since(start)
+// triggerWebhook 触发单个webhook，失败时由投递队列重试
+func (w *WebhookService) triggerWebhook(webhook *models.Webhook, sms *models.SMS) error {
+	return w.sendEvent(webhook, smsEvent(sms))
+}
 
-		if err != nil {
-			log.Printf("[Webhook] Failed to send request to %s (attempt %d): %v", webhook.Name, attempt+1, err)
-			continue // 网络错误重试
-		}
+// sendEvent 按模板生成 payload 并加入投递队列
+func (w *WebhookService) sendEvent(webhook *models.Webhook, event webhookEvent) error {
+	payload, err := w.preparePayload(webhook, event)
+	if err != nil {
+		slog.Error("webhook: failed to prepare payload", slog.String("webhook", webhook.Name), slog.Any("error", err))
+		return err // 模板错误不重试
+	}
 
-		defer resp.Body.Close()
+	enqueueWebhook(webhookJob{Webhook: *webhook, Payload: payload})
+	return nil
+}
 
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			log.Printf("[Webhook] Successfully triggered %s (status: %d, duration: %v)",
-				webhook.Name, resp.StatusCode, duration)
-			return nil
-		} else {
-			log.Printf("[Webhook] Failed to trigger %s (status: %d, attempt %d)",
-				webhook.Name, resp.StatusCode, attempt+1)
+// WebhookSignatureHeader webhook 签名请求头，格式与 GitHub webhook 相同：sha256=<hex>
+const WebhookSignatureHeader = "X-Modem-Signature"
 
-			// 如果是服务器错误(5xx)，重试
-			if resp.StatusCode >= 500 && resp.StatusCode < 600 {
-				continue
-			}
-			// 如果是客户端错误(4xx)，不重试
-			break
-		}
+// SignWebhook 计算请求体的 HMAC-SHA256 签名，签名覆盖实际发送的原始字节
+func SignWebhook(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature 校验 X-Modem-Signature 签名，供接收方验证请求来源
+func VerifyWebhookSignature(body []byte, signature, secret string) bool {
+	return hmac.Equal([]byte(signature), []byte(SignWebhook(body, secret)))
+}
+
+// postWebhook 发送一次 webhook 请求，retry 表示失败后是否值得重试
+func postWebhook(webhook *models.Webhook, payload []byte) (retry bool, err error) {
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewBuffer(payload))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Web-Modem/1.0")
+	if webhook.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(payload, webhook.Secret))
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	duration := time.Since(start)
+
+	if err != nil {
+		return true, err // 网络错误重试
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		slog.Info("webhook: triggered", slog.String("webhook", webhook.Name),
+			slog.Int("status", resp.StatusCode), slog.Duration("elapsed", duration))
+		return false, nil
+	}
+
+	// 服务器错误(5xx)重试，客户端错误(4xx)不重试
+	retry = resp.StatusCode >= 500 && resp.StatusCode < 600
+	return retry, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+// webhookEvent webhook 事件
+type webhookEvent struct {
+	Name string            // 事件名称
+	Data map[string]any    // 默认 payload 数据
+	Vars map[string]string // 模板变量
+}
+
+// smsEvent 收到短信事件
+func smsEvent(sms *models.SMS) webhookEvent {
+	return webhookEvent{
+		Name: "sms_received",
+		Data: map[string]any{
+			"id":             sms.ID,
+			"content":        sms.Content,
+			"sms_ids":        sms.SMSIDs,
+			"receive_time":   sms.ReceiveTime.Format(time.RFC3339),
+			"receive_number": sms.ReceiveNumber,
+			"send_number":    sms.SendNumber,
+			"direction":      sms.Direction,
+			"modem":          sms.Modem,
+		},
+		Vars: map[string]string{
+			"{{content}}":        sms.Content,
+			"{{sms_ids}}":        sms.SMSIDs,
+			"{{receive_time}}":   sms.ReceiveTime.Format(time.RFC3339),
+			"{{receive_number}}": sms.ReceiveNumber,
+			"{{send_number}}":    sms.SendNumber,
+			"{{direction}}":      sms.Direction,
+			"{{modem}}":          sms.Modem,
+		},
 	}
+}
 
-	log.Printf("[Webhook] All %d attempts failed for webhook %s", maxRetries, webhook.Name)
-	return fmt.Errorf("failed to trigger webhook %s after %d attempts", webhook.Name, maxRetries)
+// callEvent 通话状态变化事件
+func callEvent(modem string, record models.CallRecord) webhookEvent {
+	return webhookEvent{
+		Name: "call",
+		Data: map[string]any{
+			"id":         record.ID,
+			"number":     record.Number,
+			"direction":  record.Direction,
+			"status":     record.Status,
+			"rings":      record.Rings,
+			"started_at": record.StartedAt.Format(time.RFC3339),
+			"modem":      modem,
+		},
+		Vars: map[string]string{
+			"{{number}}":     record.Number,
+			"{{direction}}":  record.Direction,
+			"{{status}}":     record.Status,
+			"{{started_at}}": record.StartedAt.Format(time.RFC3339),
+			"{{modem}}":      modem,
+		},
+	}
+}
+
+// hubEvent 其他模块事件，事件数据原样放入 payload
+func hubEvent(e Event) webhookEvent {
+	data, _ := json.Marshal(e.Data)
+	return webhookEvent{
+		Name: e.Type,
+		Data: map[string]any{
+			"seq":     e.Seq,
+			"modem":   e.Port,
+			"alias":   e.Alias,
+			"time":    e.Time.Format(time.RFC3339),
+			"payload": e.Data,
+		},
+		Vars: map[string]string{
+			"{{event}}": e.Type,
+			"{{modem}}": e.Port,
+			"{{alias}}": e.Alias,
+			"{{time}}":  e.Time.Format(time.RFC3339),
+			"{{data}}":  string(data),
+		},
+	}
 }
 
 // preparePayload 准备webhook payload
-func (w *WebhookService) preparePayload(webhook *models.Webhook, sms *models.SMS) ([]byte, error) {
+func (w *WebhookService) preparePayload(webhook *models.Webhook, event webhookEvent) ([]byte, error) {
 	// 如果template为空或不是有效的JSON，使用默认模板
 	if webhook.Template == "" || webhook.Template == "{}" {
-		return w.getDefaultPayload(sms)
+		return w.getDefaultPayload(event)
 	}
 
 	// 尝试解析模板
 	var template map[string]interface{}
 	if err := json.Unmarshal([]byte(webhook.Template), &template); err != nil {
 		// 如果模板解析失败，使用默认模板
-		log.Printf("[Webhook] Invalid template for %s, using default: %v", webhook.Name, err)
-		return w.getDefaultPayload(sms)
+		slog.Warn("webhook: invalid template, using default", slog.String("webhook", webhook.Name), slog.Any("error", err))
+		return w.getDefaultPayload(event)
 	}
 
 	// 替换模板中的变量
-	payload := w.replaceTemplateVariables(template, sms)
+	payload := w.replaceTemplateVariables(template, event.Vars)
 
 	return json.Marshal(payload)
 }
 
 // getDefaultPayload 获取默认payload
-func (w *WebhookService) getDefaultPayload(sms *models.SMS) ([]byte, error) {
+func (w *WebhookService) getDefaultPayload(event webhookEvent) ([]byte, error) {
 	payload := map[string]interface{}{
-		"event": "sms_received",
-		"data": map[string]interface{}{
-			"id":             sms.ID,
-			"content":        sms.Content,
-			"sms_ids":        sms.SMSIDs,
-			"receive_time":   sms.ReceiveTime.Format(time.RFC3339),
-			"receive_number": sms.ReceiveNumber,
-			"send_number":    sms.SendNumber,
-			"direction":      sms.Direction,
-		},
+		"event":     event.Name,
+		"data":      event.Data,
 		"timestamp": time.Now().Unix(),
 	}
 
@@ -234,15 +431,15 @@ func (w *WebhookService) getDefaultPayload(sms *models.SMS) ([]byte, error) {
 }
 
 // replaceTemplateVariables 替换模板中的变量
-func (w *WebhookService) replaceTemplateVariables(template map[string]interface{}, sms *models.SMS) map[string]interface{} {
+func (w *WebhookService) replaceTemplateVariables(template map[string]interface{}, vars map[string]string) map[string]interface{} {
 	result := make(map[string]interface{})
 
 	for key, value := range template {
 		switch v := value.(type) {
 		case string:
-			result[key] = w.replaceStringVariables(v, sms)
+			result[key] = w.replaceStringVariables(v, vars)
 		case map[string]interface{}:
-			result[key] = w.replaceTemplateVariables(v, sms)
+			result[key] = w.replaceTemplateVariables(v, vars)
 		default:
 			result[key] = value
 		}
@@ -252,26 +449,17 @@ func (w *WebhookService) replaceTemplateVariables(template map[string]interface{
 }
 
 // replaceStringVariables 替换字符串中的变量
-func (w *WebhookService) replaceStringVariables(s string, sms *models.SMS) string {
-	replacements := map[string]string{
-		"{{content}}":        sms.Content,
-		"{{sms_ids}}":        sms.SMSIDs,
-		"{{receive_time}}":   sms.ReceiveTime.Format(time.RFC3339),
-		"{{receive_number}}": sms.ReceiveNumber,
-		"{{send_number}}":    sms.SendNumber,
-		"{{direction}}":      sms.Direction,
-	}
-
-	for old, new := range replacements {
+func (w *WebhookService) replaceStringVariables(s string, vars map[string]string) string {
+	for old, new := range vars {
 		s = strings.ReplaceAll(s, old, new)
 	}
 
 	return s
 }
 
-// Test 测试webhook
+// Test 测试webhook，直接发送一次，不经过投递队列
 func (w *WebhookService) Test(webhook *models.Webhook) error {
-	testSMS := &models.SMS{
+	event := smsEvent(&models.SMS{
 		ID:            0,
 		Content:       "Test webhook message",
 		SMSIDs:        "1,2,3",
@@ -279,9 +467,28 @@ func (w *WebhookService) Test(webhook *models.Webhook) error {
 		ReceiveNumber: "+8613800138000",
 		SendNumber:    "+8613800138001",
 		Direction:     "in",
+	})
+	switch webhook.Type {
+	case "", models.WebhookSMS, models.WebhookAll:
+	case models.WebhookCall:
+		event = callEvent("test", models.CallRecord{
+			Number:    "+8613800138001",
+			Direction: "in",
+			Status:    CallRinging,
+			StartedAt: time.Now(),
+		})
+	default:
+		event = hubEvent(Event{Type: webhook.Type, Port: "test", Time: time.Now()})
 	}
 
-	return w.triggerWebhook(webhook, testSMS)
+	payload, err := w.preparePayload(webhook, event)
+	if err != nil {
+		return err
+	}
+	if _, err := postWebhook(webhook, payload); err != nil {
+		return fmt.Errorf("failed to trigger webhook %s: %w", webhook.Name, err)
+	}
+	return nil
 }
 
 // HandleIncomingSMS 处理接收到的短信，保存到数据库并异步触发webhook
@@ -290,14 +497,14 @@ func (w *WebhookService) HandleIncomingSMS(smsData *models.SMS) error {
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				log.Printf("[SMS] Panic recovered in HandleIncomingSMS: %v", r)
+				slog.Error("sms: panic recovered in HandleIncomingSMS", slog.Any("panic", r))
 			}
 		}()
 
 		// 保存到数据库
 		sms, err := database.SaveIncomingSMS(smsData)
 		if err != nil {
-			log.Printf("[SMS] Failed to save incoming SMS: %v", err)
+			slog.Error("sms: failed to save incoming sms", slog.Any("error", err))
 		}
 
 		// 如果webhook启用，触发webhook
@@ -309,7 +516,7 @@ func (w *WebhookService) HandleIncomingSMS(smsData *models.SMS) error {
 				// 如果保存失败或未启用，尝试查询
 				smsList, err := database.GetsmsdbBodyBySMSIDs(parseSMSIDs(smsData.SMSIDs))
 				if err != nil {
-					log.Printf("[SMS] Failed to get saved SMS: %v", err)
+					slog.Error("sms: failed to get saved sms", slog.Any("error", err))
 					return
 				}
 				if len(smsList) > 0 {
@@ -323,7 +530,7 @@ func (w *WebhookService) HandleIncomingSMS(smsData *models.SMS) error {
 			// 异步触发webhook，不阻塞主流程
 			go func() {
 				if err := w.TriggerWebhooks(smsForWebhook); err != nil {
-					log.Printf("[Webhook] Failed to trigger webhooks: %v", err)
+					slog.Error("webhook: failed to trigger", slog.Any("error", err))
 				}
 			}()
 		}
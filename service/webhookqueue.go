@@ -0,0 +1,203 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rehiy/web-modem/database"
+	"github.com/rehiy/web-modem/models"
+)
+
+const (
+	webhookQueueSize      = 256             // 投递队列容量
+	webhookWorkers        = 5               // 并发投递数量
+	webhookBaseDelay      = 2 * time.Second // 首次重试延迟
+	webhookMaxDelay       = 5 * time.Minute // 最大重试延迟
+	defaultWebhookRetries = 5               // 默认最大重试次数
+	webhookDLQFile        = "webhook_dlq.jsonl"
+)
+
+// webhookJob 待投递的 webhook
+type webhookJob struct {
+	Webhook models.Webhook
+	Payload []byte
+	Attempt int // 已失败次数
+}
+
+var (
+	webhookJobs    = make(chan webhookJob, webhookQueueSize)
+	webhookStart   sync.Once
+	webhookRetries = defaultWebhookRetries
+	dlqMu          sync.Mutex
+)
+
+// enqueueWebhook 加入投递队列，首次调用时启动投递任务
+// 队列已满时直接写入死信文件，避免阻塞事件处理
+func enqueueWebhook(job webhookJob) {
+	webhookStart.Do(startWebhookWorkers)
+
+	select {
+	case webhookJobs <- job:
+	default:
+		writeDeadLetter(job, "queue full")
+	}
+}
+
+// startWebhookWorkers 读取重试配置并启动投递任务
+// 最大重试次数由 WEBHOOK_MAX_RETRIES 设置，默认 5 次
+func startWebhookWorkers() {
+	if v := os.Getenv("WEBHOOK_MAX_RETRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			webhookRetries = n
+		} else {
+			slog.Warn("invalid WEBHOOK_MAX_RETRIES", slog.String("value", v), slog.Int("using", webhookRetries))
+		}
+	}
+
+	for i := 0; i < webhookWorkers; i++ {
+		go func() {
+			for job := range webhookJobs {
+				deliverWebhook(job)
+			}
+		}()
+	}
+}
+
+// deliverWebhook 投递一次，失败时按指数退避安排重试，重试耗尽后写入死信文件
+func deliverWebhook(job webhookJob) {
+	retry, err := postWebhook(&job.Webhook, job.Payload)
+	if err == nil {
+		return
+	}
+
+	retries := webhookRetries
+	if job.Webhook.MaxRetries != nil {
+		retries = *job.Webhook.MaxRetries
+	}
+
+	job.Attempt++
+	if !retry || job.Attempt > retries {
+		slog.Error("webhook: giving up", slog.String("webhook", job.Webhook.Name), slog.Int("attempts", job.Attempt), slog.Any("error", err))
+		writeDeadLetter(job, err.Error())
+		return
+	}
+
+	delay := webhookBackoff(job.Attempt)
+	slog.Warn("webhook: trigger failed", slog.String("webhook", job.Webhook.Name), slog.Int("attempt", job.Attempt), slog.Any("error", err), slog.Duration("retry_in", delay))
+	time.AfterFunc(delay, func() { enqueueWebhook(job) })
+}
+
+// webhookBackoff 第 attempt 次失败后的重试延迟：min(2^(attempt-1) * base, max)
+func webhookBackoff(attempt int) time.Duration {
+	delay := webhookBaseDelay
+	for i := 1; i < attempt && delay < webhookMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookMaxDelay)
+}
+
+// dlqPath 死信文件路径，与数据库文件位于同一目录
+func dlqPath() string {
+	return filepath.Join(database.DataDir(), webhookDLQFile)
+}
+
+// writeDeadLetter 追加一条死信记录
+func writeDeadLetter(job webhookJob, reason string) {
+	line, err := json.Marshal(models.WebhookDeadLetter{
+		WebhookID: job.Webhook.ID,
+		Name:      job.Webhook.Name,
+		URL:       job.Webhook.URL,
+		Payload:   json.RawMessage(job.Payload),
+		Attempts:  job.Attempt,
+		Error:     reason,
+		Time:      time.Now(),
+	})
+	if err != nil {
+		slog.Error("webhook: failed to encode dead letter", slog.Any("error", err))
+		return
+	}
+
+	dlqMu.Lock()
+	defer dlqMu.Unlock()
+
+	f, err := os.OpenFile(dlqPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		slog.Error("webhook: failed to open dead letter file", slog.Any("error", err))
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		slog.Error("webhook: failed to write dead letter", slog.Any("error", err))
+	}
+}
+
+// readDeadLetters 读取全部死信记录，调用方需持有锁
+func readDeadLetters() ([]models.WebhookDeadLetter, error) {
+	f, err := os.Open(dlqPath())
+	if os.IsNotExist(err) {
+		return []models.WebhookDeadLetter{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	letters := []models.WebhookDeadLetter{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var letter models.WebhookDeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			continue
+		}
+		letters = append(letters, letter)
+	}
+	return letters, scanner.Err()
+}
+
+// DeadLetters 获取最近 limit 条投递失败的 webhook，最新的在前
+func (w *WebhookService) DeadLetters(limit int) ([]models.WebhookDeadLetter, error) {
+	dlqMu.Lock()
+	letters, err := readDeadLetters()
+	dlqMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && len(letters) > limit {
+		letters = letters[len(letters)-limit:]
+	}
+	for i, j := 0, len(letters)-1; i < j; i, j = i+1, j-1 {
+		letters[i], letters[j] = letters[j], letters[i]
+	}
+	return letters, nil
+}
+
+// ReplayDeadLetters 清空死信文件并将其中的记录重新加入投递队列，返回数量
+func (w *WebhookService) ReplayDeadLetters() (int, error) {
+	dlqMu.Lock()
+	letters, err := readDeadLetters()
+	if err == nil && len(letters) > 0 {
+		err = os.Remove(dlqPath())
+	}
+	dlqMu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, letter := range letters {
+		webhook := models.Webhook{ID: letter.WebhookID, Name: letter.Name, URL: letter.URL}
+		// 死信不保存签名密钥和重试次数，按当前配置投递
+		if current, err := database.Detail(letter.WebhookID); err == nil {
+			webhook.Secret, webhook.MaxRetries = current.Secret, current.MaxRetries
+		}
+		enqueueWebhook(webhookJob{Webhook: webhook, Payload: letter.Payload})
+	}
+	return len(letters), nil
+}
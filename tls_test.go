@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestTLSFilesRequiresBoth(t *testing.T) {
+	t.Setenv("TLS_SELF_SIGNED", "")
+	for _, env := range [][2]string{{"cert.pem", ""}, {"", "key.pem"}} {
+		t.Setenv("TLS_CERT_FILE", env[0])
+		t.Setenv("TLS_KEY_FILE", env[1])
+		if _, _, err := tlsFiles(); err == nil {
+			t.Errorf("cert %q key %q: no error", env[0], env[1])
+		}
+	}
+
+	t.Setenv("TLS_CERT_FILE", "")
+	t.Setenv("TLS_KEY_FILE", "")
+	if cert, key, err := tlsFiles(); err != nil || cert != "" || key != "" {
+		t.Fatalf("tls disabled: %q %q %v", cert, key, err)
+	}
+}
@@ -32,7 +32,8 @@ async function init() {
         
         // 初始化 WebSocket 服务
         app.webSocketService = new WebSocketService();
-        app.webSocketService.connect(`ws://${location.host}/ws/modem`);
+        const wsScheme = location.protocol === 'https:' ? 'wss' : 'ws';
+        app.webSocketService.connect(`${wsScheme}://${location.host}/ws/modem`);
 
         // 初始化各个功能管理器
         app.modemManager = new ModemManager();
@@ -5,6 +5,46 @@
 import { apiRequest, buildQueryString } from '../utils/api.js';
 import { $, addToTerminal } from '../utils/dom.js';
 
+// GSM 7-bit 默认字符表
+const GSM7_BASIC = '@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !"#¤%&\'()*+,-./0123456789:;<=>?' +
+    '¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà';
+
+// GSM 7-bit 扩展字符表，每个字符占用两个 septet（转义符 + 字符）
+const GSM7_EXTENDED = '\f^{}\\[~]|€';
+
+/**
+ * 计算短信编码、长度和分段数量
+ * 与服务端分段逻辑一致：扩展字符计为 2 个 septet，且不会被拆分到两个分段
+ * @param {string} message - 短信内容
+ * @returns {{encoding: string, units: number, perSegment: number, parts: number}}
+ */
+export function countSMS(message) {
+    const chars = Array.from(message);
+    const isGSM7 = chars.every(c => GSM7_BASIC.includes(c) || GSM7_EXTENDED.includes(c));
+
+    if (!isGSM7) {
+        // UCS2 按 UTF-16 码元计数，代理对不拆分
+        const units = message.length;
+        const perSegment = units <= 70 ? 70 : 67;
+        let parts = 0, used = perSegment;
+        for (const c of chars) {
+            if (used + c.length > perSegment) { parts++; used = 0; }
+            used += c.length;
+        }
+        return { encoding: 'UCS2', units, perSegment, parts: Math.max(parts, 1) };
+    }
+
+    const septets = chars.map(c => GSM7_EXTENDED.includes(c) ? 2 : 1);
+    const units = septets.reduce((a, b) => a + b, 0);
+    const perSegment = units <= 160 ? 160 : 153;
+    let parts = 0, used = perSegment;
+    for (const n of septets) {
+        if (used + n > perSegment) { parts++; used = 0; }
+        used += n;
+    }
+    return { encoding: 'GSM7', units, perSegment, parts: Math.max(parts, 1) };
+}
+
 /**
  * Modem管理器类
  * 负责管理所有Modem相关的操作，包括连接、通信、短信处理等
@@ -137,8 +177,15 @@ export class ModemManager {
      */
     async listSMS() {
         app.logger.info('正在读取短信列表 ...');
-        const queryString = buildQueryString({ name: this.name });
-        const smsList = await apiRequest(`/modem/sms/list?${queryString}`);
+        // 按 nextCursor 逐页读取全部短信
+        const smsList = [];
+        let cursor = '';
+        do {
+            const queryString = buildQueryString({ name: this.name, cursor, page_size: 100 });
+            const page = await apiRequest(`/modem/sms/list?${queryString}`);
+            smsList.push(...page.messages);
+            cursor = page.nextCursor;
+        } while (cursor);
         app.logger.info(`已读取 ${smsList.length} 条短信`);
         // 渲染模板
         const container = $('#smsList');
@@ -235,12 +282,10 @@ export class ModemManager {
         if (!textarea || !counter) return;
 
         const message = textarea.value;
-        const hasUnicode = /[^\x00-\x7F]/.test(message);
-        const maxChars = hasUnicode ? (message.length <= 70 ? 70 : 67) : (message.length <= 160 ? 160 : 153);
-        const parts = Math.ceil(message.length / maxChars) || 1;
-        const encoding = hasUnicode ? 'UCS2 (中文)' : 'GSM 7-bit';
+        const { encoding, units, perSegment, parts } = countSMS(message);
+        const encodingName = encoding === 'UCS2' ? 'UCS2 (中文)' : 'GSM 7-bit';
 
-        let counterHtml = `<span>字符数: ${message.length} / ${maxChars}</span> | <span>短信条数: ${parts}</span> | <span>编码: ${encoding}</span>`;
+        let counterHtml = `<span>字符数: ${units} / ${perSegment}</span> | <span>短信条数: ${parts}</span> | <span>编码: ${encodingName}</span>`;
 
         if (parts > 3) {
             counter.style.color = '#ff4444';
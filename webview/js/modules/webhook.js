@@ -277,6 +277,9 @@ export class WebhookManager {
             $('#webhookFormTitle').textContent = '编辑 Webhook';
             $('#webhookName').value = webhook.name;
             $('#webhookURL').value = webhook.url;
+            $('#webhookModem').value = webhook.modem || '';
+            $('#webhookType').value = webhook.type || 'sms';
+            $('#webhookSecret').value = webhook.secret || '';
             $('#webhookTemplate').value = webhook.template;
             $('#webhookEnabledCheckbox').checked = webhook.enabled;
             $('#webhookTemplateSelect').value = 'custom';
@@ -290,6 +293,9 @@ export class WebhookManager {
         $('#webhookFormTitle').textContent = '创建 Webhook';
         $('#webhookName').value = '';
         $('#webhookURL').value = '';
+        $('#webhookModem').value = '';
+        $('#webhookType').value = 'sms';
+        $('#webhookSecret').value = '';
         $('#webhookTemplate').value = '{}';
         $('#webhookEnabledCheckbox').checked = true;
         $('#webhookTemplateSelect').value = 'custom';
@@ -322,6 +328,9 @@ export class WebhookManager {
     async saveWebhook() {
         const name = $('#webhookName').value.trim();
         const url = $('#webhookURL').value.trim();
+        const modem = $('#webhookModem').value.trim();
+        const type = $('#webhookType').value;
+        const secret = $('#webhookSecret').value;
         const template = $('#webhookTemplate').value.trim();
         const enabled = $('#webhookEnabledCheckbox').checked;
 
@@ -341,7 +350,7 @@ export class WebhookManager {
         }
 
         try {
-            const webhookData = { name, url, template, enabled };
+            const webhookData = { name, url, modem, type, secret, template, enabled };
 
             if (this.currentWebhookId) {
                 const queryString = buildQueryString({ id: this.currentWebhookId });
@@ -15,6 +15,8 @@ export class WebSocketService {
      */
     constructor() {
         this.ws = null;
+        this.url = null;
+        this.lastSeq = 0;
         this.eventListeners = new Map();
         this.reconnectTimeout = null;
     }
@@ -28,8 +30,11 @@ export class WebSocketService {
             return;
         }
 
+        this.url = url;
         try {
-            this.ws = new WebSocket(url);
+            // 重连时携带最后收到的事件序号，由服务端补发缺失的事件
+            const target = this.lastSeq > 0 ? `${url}?since=${this.lastSeq}` : url;
+            this.ws = new WebSocket(target);
             this.setupEventListeners();
         } catch (error) {
             console.error('WebSocket连接失败:', error);
@@ -47,6 +52,14 @@ export class WebSocketService {
         };
 
         this.ws.onmessage = (event) => {
+            try {
+                const data = JSON.parse(event.data);
+                if (data.type !== 'hello' && data.seq > this.lastSeq) {
+                    this.lastSeq = data.seq;
+                }
+            } catch (error) {
+                // 非 JSON 消息，忽略序号跟踪
+            }
             this.emit('message', event.data);
         };
 
@@ -58,7 +71,7 @@ export class WebSocketService {
         this.ws.onclose = () => {
             console.log('WebSocket 已断开');
             this.emit('disconnected');
-            this.scheduleReconnect(this.ws.url);
+            this.scheduleReconnect(this.url);
         };
     }
 